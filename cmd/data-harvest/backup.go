@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// backupIfDue stores a settings backup of a VNish miner if the last one is
+// older than the backup interval. Unchanged settings only refresh the
+// existing backup.
+func (h *Harvester) backupIfDue(ctx context.Context, client *vnish.HTTPClient, m *database.Miner) error {
+	if h.config.BackupInterval <= 0 {
+		return nil
+	}
+
+	// Don't retry failed backups on every harvest cycle
+	h.backupMu.Lock()
+	last, ok := h.backupAttempts[m.MACAddress]
+	if ok && time.Since(last) < h.config.BackupInterval {
+		h.backupMu.Unlock()
+		return nil
+	}
+	h.backupAttempts[m.MACAddress] = time.Now()
+	h.backupMu.Unlock()
+
+	latest, err := h.repo.GetLatestMinerBackup(ctx, m.MACAddress)
+	if err != nil {
+		return fmt.Errorf("failed to get latest backup: %w", err)
+	}
+	if latest != nil && time.Since(latest.LastSeenAt) < h.config.BackupInterval {
+		return nil
+	}
+
+	return h.takeBackup(ctx, client, m)
+}
+
+// takeBackup downloads and stores a settings backup.
+func (h *Harvester) takeBackup(ctx context.Context, client *vnish.HTTPClient, m *database.Miner) error {
+	data, err := client.BackupSettings(ctx)
+	if err != nil {
+		return err
+	}
+
+	b := &database.MinerBackup{
+		MACAddress:      m.MACAddress,
+		IPAddress:       m.IPAddress,
+		FirmwareVersion: m.FirmwareVersion,
+		ContentHash:     vnish.BackupHash(data),
+		Data:            data,
+	}
+	created, err := h.repo.SaveMinerBackup(ctx, b)
+	if err != nil {
+		return fmt.Errorf("failed to save backup: %w", err)
+	}
+
+	if created {
+		if err := h.repo.PruneMinerBackups(ctx, m.MACAddress, h.config.BackupKeep); err != nil {
+			return fmt.Errorf("failed to prune backups: %w", err)
+		}
+	}
+	return nil
+}
+
+// minerByIP returns a known miner by IP address.
+func (h *Harvester) minerByIP(ctx context.Context, ip string) (*database.Miner, error) {
+	m, err := h.repo.GetMinerByIP(ctx, ip)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get miner: %w", err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("miner not found: %s", ip)
+	}
+	return m, nil
+}
+
+// resolveBackup returns the backup with the given ID, or the latest backup of the miner if id is 0.
+func (h *Harvester) resolveBackup(ctx context.Context, m *database.Miner, id int64) (*database.MinerBackup, error) {
+	var b *database.MinerBackup
+	var err error
+	if id > 0 {
+		b, err = h.repo.GetMinerBackup(ctx, id)
+	} else {
+		b, err = h.repo.GetLatestMinerBackup(ctx, m.MACAddress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup: %w", err)
+	}
+	if b == nil {
+		if id > 0 {
+			return nil, fmt.Errorf("backup not found: %d", id)
+		}
+		return nil, fmt.Errorf("no backups for %s (%s)", m.IPAddress, m.MACAddress)
+	}
+	return b, nil
+}
+
+// ListBackups lists the stored backups of a miner.
+func (h *Harvester) ListBackups(ctx context.Context, ip string) error {
+	m, err := h.minerByIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+
+	backups, err := h.repo.ListMinerBackups(ctx, m.MACAddress)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	if len(backups) == 0 {
+		fmt.Printf("No backups for %s (%s)\n", m.IPAddress, m.MACAddress)
+		return nil
+	}
+
+	fmt.Printf("Backups for %s (%s):\n\n", m.IPAddress, m.MACAddress)
+	fmt.Printf("%-6s %-17s %-17s %-16s %-20s %8s %s\n", "ID", "CREATED", "LAST SEEN", "IP", "FIRMWARE", "SIZE", "HASH")
+	fmt.Println("------------------------------------------------------------------------------------------------------------")
+
+	for _, b := range backups {
+		fmt.Printf("%-6d %-17s %-17s %-16s %-20s %8d %s\n",
+			b.ID,
+			b.CreatedAt.Format("2006-01-02 15:04"),
+			b.LastSeenAt.Format("2006-01-02 15:04"),
+			b.IPAddress,
+			truncate(b.FirmwareVersion, 20),
+			b.Size,
+			b.ContentHash[:12],
+		)
+	}
+
+	return nil
+}
+
+// ShowBackup prints a backup's metadata and the files it contains.
+func (h *Harvester) ShowBackup(ctx context.Context, ip string, id int64) error {
+	m, err := h.minerByIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+
+	b, err := h.resolveBackup(ctx, m, id)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("=== Backup %d ===\n", b.ID)
+	fmt.Printf("MAC:        %s\n", b.MACAddress)
+	fmt.Printf("IP:         %s\n", b.IPAddress)
+	fmt.Printf("Firmware:   %s\n", b.FirmwareVersion)
+	fmt.Printf("Created:    %s\n", b.CreatedAt.Format(time.RFC3339))
+	fmt.Printf("Last Seen:  %s\n", b.LastSeenAt.Format(time.RFC3339))
+	fmt.Printf("Size:       %d bytes\n", b.Size)
+	fmt.Printf("Hash:       %s\n", b.ContentHash)
+
+	files, err := vnish.ListBackupFiles(b.Data)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== Files ===\n")
+	for _, f := range files {
+		fmt.Printf("%8d  %s\n", f.Size, f.Name)
+	}
+
+	return nil
+}
+
+// RestoreBackup restores a backup to a VNish miner. The current settings are
+// backed up first so the restore can be undone.
+func (h *Harvester) RestoreBackup(ctx context.Context, ip string, id int64) error {
+	m, err := h.minerByIP(ctx, ip)
+	if err != nil {
+		return err
+	}
+
+	b, err := h.resolveBackup(ctx, m, id)
+	if err != nil {
+		return err
+	}
+
+	client, _, err := h.detector.GetClient(ctx, ip)
+	if err != nil {
+		return fmt.Errorf("failed to detect miner: %w", err)
+	}
+	vnishClient, ok := client.(*vnish.HTTPClient)
+	if !ok {
+		return fmt.Errorf("settings restore is only supported on VNish firmware")
+	}
+
+	if b.MACAddress != m.MACAddress {
+		fmt.Printf("Note: backup %d was taken from %s (%s), restoring to %s (%s)\n",
+			b.ID, b.IPAddress, b.MACAddress, m.IPAddress, m.MACAddress)
+	}
+
+	fmt.Printf("Backing up current settings of %s...\n", ip)
+	if err := h.takeBackup(ctx, vnishClient, m); err != nil {
+		return fmt.Errorf("failed to back up current settings: %w", err)
+	}
+
+	fmt.Printf("Restoring backup %d to %s...\n", b.ID, ip)
+	if err := vnishClient.RestoreSettings(ctx, b.Data); err != nil {
+		return fmt.Errorf("failed to restore settings: %w", err)
+	}
+
+	fmt.Println("Restore complete. The miner may restart mining to apply the settings.")
+	return nil
+}
@@ -6,10 +6,13 @@ import (
 	"log"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/braiins"
+	"github.com/powerhive/powerhive-v2/pkg/cgminer"
 	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
 	"github.com/powerhive/powerhive-v2/pkg/stock"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
 )
 
 // CollectedData holds all data collected from a miner.
@@ -23,42 +26,237 @@ type CollectedData struct {
 	Pools      []*database.MinerPool
 	Fans       []*database.MinerFan
 	Metric     *database.MinerMetric
-	FanMetrics []*database.FanMetric // Per-fan time-series data
-	Presets    []*database.AutotunePreset // VNish only
+	FanMetrics []*database.FanMetric      // Per-fan time-series data
+	Presets    []*database.AutotunePreset // VNish presets, Braiins power target profiles
 }
 
 // Collector handles data collection from miners.
 type Collector struct {
-	vnishMapper *database.VNishMapper
-	stockMapper *database.StockMapper
+	vnishMapper      *database.VNishMapper
+	stockMapper      *database.StockMapper
+	braiinsMapper    *database.BraiinsMapper
+	whatsminerMapper *database.WhatsminerMapper
+	cgminerMapper    *database.CGMinerMapper
 }
 
 // NewCollector creates a new data collector.
 func NewCollector() *Collector {
 	return &Collector{
-		vnishMapper: database.NewVNishMapper(),
-		stockMapper: database.NewStockMapper(),
+		vnishMapper:      database.NewVNishMapper(),
+		stockMapper:      database.NewStockMapper(),
+		braiinsMapper:    database.NewBraiinsMapper(),
+		whatsminerMapper: database.NewWhatsminerMapper(),
+		cgminerMapper:    database.NewCGMinerMapper(),
 	}
 }
 
 // Collect fetches all available data from a miner.
+// When the firmware's HTTP API doesn't provide a summary, the missing
+// performance data is filled in from the cgminer API (port 4028).
 func (c *Collector) Collect(ctx context.Context, client miner.Client, fwType miner.FirmwareType) (*CollectedData, error) {
+	var data *CollectedData
+	var err error
+
 	switch fwType {
 	case miner.FirmwareVNish:
 		vnishClient, ok := client.(*vnish.HTTPClient)
 		if !ok {
 			return nil, fmt.Errorf("expected VNish client, got %T", client)
 		}
-		return c.collectVNish(ctx, vnishClient)
+		data, err = c.collectVNish(ctx, vnishClient)
 	case miner.FirmwareStock:
 		stockClient, ok := client.(*stock.HTTPClient)
 		if !ok {
 			return nil, fmt.Errorf("expected Stock client, got %T", client)
 		}
-		return c.collectStock(ctx, stockClient)
+		data, err = c.collectStock(ctx, stockClient)
+	case miner.FirmwareBraiins:
+		braiinsClient, ok := client.(*braiins.HTTPClient)
+		if !ok {
+			return nil, fmt.Errorf("expected Braiins client, got %T", client)
+		}
+		data, err = c.collectBraiins(ctx, braiinsClient)
+	case miner.FirmwareWhatsminer:
+		whatsminerClient, ok := client.(*whatsminer.TCPClient)
+		if !ok {
+			return nil, fmt.Errorf("expected Whatsminer client, got %T", client)
+		}
+		data, err = c.collectWhatsminer(ctx, whatsminerClient)
 	default:
 		return nil, fmt.Errorf("unsupported firmware type: %s", fwType)
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	if data.Summary == nil {
+		c.fillFromCGMiner(ctx, client.Host(), data)
+	}
+
+	return data, nil
+}
+
+// collectWhatsminer fetches all data from a Whatsminer.
+func (c *Collector) collectWhatsminer(ctx context.Context, client *whatsminer.TCPClient) (*CollectedData, error) {
+	data := &CollectedData{}
+	ip := client.Host()
+
+	// Get version (required, identifies the firmware)
+	version, err := client.GetVersion(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get version: %w", err)
+	}
+
+	// Get model from hashboard details
+	var model string
+	if details, err := client.GetDevDetails(ctx); err != nil {
+		log.Printf("[%s] warning: failed to get dev details: %v", ip, err)
+	} else {
+		model = whatsminer.ModelFromDevDetails(details)
+	}
+
+	// Get network info
+	netInfo, err := client.GetNetworkInfo(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get network info: %v", ip, err)
+		netInfo = nil
+	} else {
+		data.Network = c.whatsminerMapper.MapNetwork(netInfo, 0)
+	}
+
+	// Get summary (hashrate, power, temperatures, fans)
+	var summary *whatsminer.Summary
+	summaryResp, err := client.GetSummary(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get summary: %v", ip, err)
+	} else if len(summaryResp.Summary) > 0 {
+		summary = &summaryResp.Summary[0]
+	}
+
+	data.Miner = c.whatsminerMapper.MapMinerInfo(version, model, netInfo, summary, ip)
+
+	// Get hashboards
+	var devs []whatsminer.Dev
+	devsResp, err := client.GetDevs(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get devs: %v", ip, err)
+	} else {
+		devs = devsResp.Devs
+		data.Chains = c.whatsminerMapper.MapChains(devs, 0)
+	}
+
+	// PSU info is optional (not available on all models)
+	psu, err := client.GetPSU(ctx)
+	if err != nil {
+		psu = nil
+	}
+	data.Hardware = c.whatsminerMapper.MapHardware(devs, psu, 0)
+
+	if summary != nil {
+		state, err := client.GetState(ctx)
+		if err != nil {
+			state = nil
+		}
+		data.Status = c.whatsminerMapper.MapStatus(summary, state, 0)
+		data.Summary = c.whatsminerMapper.MapSummary(summary, devs, 0)
+		data.Fans = c.whatsminerMapper.MapFans(summary, 0)
+		data.Metric = &database.MinerMetric{
+			Timestamp:        time.Now(),
+			Hashrate:         data.Summary.HashrateInstant,
+			PowerConsumption: data.Summary.PowerConsumption,
+			PCBTempMax:       data.Summary.PCBTempMax,
+			ChipTempMax:      data.Summary.ChipTempMax,
+		}
+	}
+
+	// Get pools
+	pools, err := client.GetPools(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get pools: %v", ip, err)
+	} else {
+		data.Pools = c.whatsminerMapper.MapPools(pools.Pools, 0)
+	}
+
+	// Create per-fan metrics from fans
+	now := time.Now()
+	for _, fan := range data.Fans {
+		rpm := fan.RPM
+		if fan.Status == "failed" {
+			rpm = -1 // Mark failed fan with -1 RPM
+		}
+		data.FanMetrics = append(data.FanMetrics, &database.FanMetric{
+			FanIndex:  fan.FanIndex,
+			Timestamp: now,
+			RPM:       rpm,
+		})
+	}
+
+	return data, nil
+}
+
+// fillFromCGMiner fills missing status, summary, chains, pools and fans
+// from the cgminer API. Data already collected over HTTP is kept.
+func (c *Collector) fillFromCGMiner(ctx context.Context, ip string, data *CollectedData) {
+	client := cgminer.NewClient(ip)
+
+	summary, err := client.Summary(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: cgminer fallback failed: %v", ip, err)
+		return
+	}
+
+	// Stats and pools are optional, the summary alone is still useful
+	stats, err := client.Stats(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get cgminer stats: %v", ip, err)
+		stats = nil
+	}
+
+	if data.Status == nil {
+		data.Status = c.cgminerMapper.MapStatus(summary, 0)
+	}
+	data.Summary = c.cgminerMapper.MapSummary(summary, stats, 0)
+
+	if stats != nil {
+		if len(data.Chains) == 0 {
+			data.Chains = c.cgminerMapper.MapChains(stats, 0)
+		}
+		if len(data.Fans) == 0 {
+			data.Fans = c.cgminerMapper.MapFans(stats, 0)
+		}
+	}
+
+	if len(data.Pools) == 0 {
+		if pools, err := client.Pools(ctx); err != nil {
+			log.Printf("[%s] warning: failed to get cgminer pools: %v", ip, err)
+		} else {
+			data.Pools = c.cgminerMapper.MapPools(pools.Pools, 0)
+		}
+	}
+
+	now := time.Now()
+	if data.Metric == nil {
+		data.Metric = &database.MinerMetric{
+			Timestamp:   now,
+			Hashrate:    data.Summary.HashrateInstant,
+			PCBTempMax:  data.Summary.PCBTempMax,
+			ChipTempMax: data.Summary.ChipTempMax,
+		}
+	}
+
+	if len(data.FanMetrics) == 0 {
+		for _, fan := range data.Fans {
+			rpm := fan.RPM
+			if fan.Status == "failed" {
+				rpm = -1 // Mark failed fan with -1 RPM
+			}
+			data.FanMetrics = append(data.FanMetrics, &database.FanMetric{
+				FanIndex:  fan.FanIndex,
+				Timestamp: now,
+				RPM:       rpm,
+			})
+		}
+	}
 }
 
 // collectVNish fetches all data from a VNish miner.
@@ -184,10 +382,10 @@ func (c *Collector) collectStock(ctx context.Context, client *stock.HTTPClient)
 
 		// Create metric
 		data.Metric = &database.MinerMetric{
-			Timestamp:        time.Now(),
-			Hashrate:         statsData.RateAvg,
-			PCBTempMax:       data.Summary.PCBTempMax,
-			ChipTempMax:      data.Summary.ChipTempMax,
+			Timestamp:   time.Now(),
+			Hashrate:    statsData.RateAvg,
+			PCBTempMax:  data.Summary.PCBTempMax,
+			ChipTempMax: data.Summary.ChipTempMax,
 		}
 
 		// Merge with summary data if available
@@ -281,6 +479,100 @@ func (c *Collector) collectStock(ctx context.Context, client *stock.HTTPClient)
 	return data, nil
 }
 
+// collectBraiins fetches all data from a Braiins OS miner.
+func (c *Collector) collectBraiins(ctx context.Context, client *braiins.HTTPClient) (*CollectedData, error) {
+	data := &CollectedData{}
+	ip := client.Host()
+
+	// Get miner details (identity, version, status)
+	details, err := client.GetMinerDetails(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get miner details: %w", err)
+	}
+	data.Miner = c.braiinsMapper.MapMinerInfo(details, ip)
+	data.Status = c.braiinsMapper.MapStatus(details, 0)
+
+	// Get network info
+	netInfo, err := client.GetNetworkInfo(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get network info: %v", ip, err)
+	} else {
+		data.Network = c.braiinsMapper.MapNetwork(netInfo, 0)
+	}
+
+	// Get hashboards and cooling (used by hardware, summary, chains and fans)
+	var boards []braiins.Hashboard
+	hashboards, err := client.GetHashboards(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get hashboards: %v", ip, err)
+	} else {
+		boards = hashboards.Hashboards
+		data.Chains = c.braiinsMapper.MapChains(boards, 0)
+	}
+
+	cooling, err := client.GetCoolingState(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get cooling state: %v", ip, err)
+		cooling = nil
+	} else {
+		data.Fans = c.braiinsMapper.MapFans(cooling, 0)
+	}
+
+	data.Hardware = c.braiinsMapper.MapHardware(boards, cooling, details, 0)
+
+	// Get aggregated stats
+	stats, err := client.GetMinerStats(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get stats: %v", ip, err)
+	} else {
+		data.Summary = c.braiinsMapper.MapSummary(stats, boards, cooling, 0)
+		data.Metric = &database.MinerMetric{
+			Timestamp:        time.Now(),
+			Hashrate:         data.Summary.HashrateInstant,
+			PowerConsumption: data.Summary.PowerConsumption,
+			PCBTempMax:       data.Summary.PCBTempMax,
+			ChipTempMax:      data.Summary.ChipTempMax,
+			FanDuty:          data.Summary.FanDuty,
+		}
+	}
+
+	// Get pools
+	pools, err := client.GetPools(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get pools: %v", ip, err)
+	} else {
+		data.Pools = c.braiinsMapper.MapPools(pools.PoolGroups, 0)
+	}
+
+	// Get tuner power target profiles
+	profiles, err := client.GetTargetProfiles(ctx)
+	if err != nil {
+		log.Printf("[%s] warning: failed to get target profiles: %v", ip, err)
+	} else {
+		currentTarget := 0
+		if tuner, err := client.GetTunerState(ctx); err == nil && tuner.PowerTargetModeState != nil {
+			currentTarget = tuner.PowerTargetModeState.CurrentTarget.Watt
+		}
+		data.Presets = c.braiinsMapper.MapTargetProfiles(profiles.PowerTargetProfiles, currentTarget, 0)
+	}
+
+	// Create per-fan metrics from fans
+	now := time.Now()
+	for _, fan := range data.Fans {
+		rpm := fan.RPM
+		if fan.Status == "failed" || fan.Status == "error" {
+			rpm = -1 // Mark failed fan with -1 RPM
+		}
+		data.FanMetrics = append(data.FanMetrics, &database.FanMetric{
+			FanIndex:  fan.FanIndex,
+			Timestamp: now,
+			RPM:       rpm,
+		})
+	}
+
+	return data, nil
+}
+
 // SetMinerID updates all collected data with the miner ID.
 func (data *CollectedData) SetMinerID(minerID int64) {
 	if data.Miner != nil {
@@ -15,15 +15,28 @@ type Config struct {
 	DBPath string
 
 	// Authentication
-	VNishPassword string
-	StockUsername string
-	StockPassword string
+	VNishPassword      string
+	StockUsername      string
+	StockPassword      string
+	BraiinsUsername    string
+	BraiinsPassword    string
+	WhatsminerPassword string
+
+	// Key file encrypting persisted API keys (default: DBPath + ".key")
+	CredentialKeyFile string
+
+	// Per-miner credential sets (optional, see pkg/credentials)
+	CredentialsFile string
 
 	// Harvesting
 	HarvestInterval time.Duration
 	Concurrency     int
 	Timeout         time.Duration
 
+	// VNish settings backups (0 disables scheduled backups)
+	BackupInterval time.Duration
+	BackupKeep     int
+
 	// Network (comma-separated CIDRs supported via NETWORK_CIDR env var)
 	NetworkCIDRs []string
 }
@@ -31,13 +44,18 @@ type Config struct {
 // DefaultConfig returns configuration with default values.
 func DefaultConfig() *Config {
 	return &Config{
-		DBPath:          "powerhive.db",
-		VNishPassword:   "admin",
-		StockUsername:   "root",
-		StockPassword:   "root",
-		HarvestInterval: 30 * time.Second,
-		Concurrency:     25,
-		Timeout:         10 * time.Second,
+		DBPath:             "powerhive.db",
+		VNishPassword:      "admin",
+		StockUsername:      "root",
+		StockPassword:      "root",
+		BraiinsUsername:    "root",
+		BraiinsPassword:    "",
+		WhatsminerPassword: "admin",
+		HarvestInterval:    30 * time.Second,
+		Concurrency:        25,
+		Timeout:            10 * time.Second,
+		BackupInterval:     24 * time.Hour,
+		BackupKeep:         20,
 	}
 }
 
@@ -60,6 +78,21 @@ func LoadConfig() *Config {
 	if v := os.Getenv("STOCK_PASSWORD"); v != "" {
 		cfg.StockPassword = v
 	}
+	if v := os.Getenv("BRAIINS_USERNAME"); v != "" {
+		cfg.BraiinsUsername = v
+	}
+	if v, ok := os.LookupEnv("BRAIINS_PASSWORD"); ok {
+		cfg.BraiinsPassword = v
+	}
+	if v := os.Getenv("WHATSMINER_PASSWORD"); v != "" {
+		cfg.WhatsminerPassword = v
+	}
+	if v := os.Getenv("CREDENTIAL_KEY_FILE"); v != "" {
+		cfg.CredentialKeyFile = v
+	}
+	if v := os.Getenv("CREDENTIALS_FILE"); v != "" {
+		cfg.CredentialsFile = v
+	}
 	if v := os.Getenv("HARVEST_INTERVAL"); v != "" {
 		if d, err := time.ParseDuration(v); err == nil {
 			cfg.HarvestInterval = d
@@ -75,6 +108,16 @@ func LoadConfig() *Config {
 			cfg.Timeout = d
 		}
 	}
+	if v := os.Getenv("BACKUP_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.BackupInterval = d
+		}
+	}
+	if v := os.Getenv("BACKUP_KEEP"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.BackupKeep = n
+		}
+	}
 	// Parse NETWORK_CIDR (comma-separated CIDRs, same format as power-balancer)
 	if v := os.Getenv("NETWORK_CIDR"); v != "" {
 		cidrs := strings.Split(v, ",")
@@ -86,5 +129,9 @@ func LoadConfig() *Config {
 		}
 	}
 
+	if cfg.CredentialKeyFile == "" {
+		cfg.CredentialKeyFile = cfg.DBPath + ".key"
+	}
+
 	return cfg
 }
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/credentials"
 	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/discovery"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
@@ -21,18 +22,30 @@ type Harvester struct {
 	scanner      *discovery.Scanner
 	collector    *Collector
 	logCollector *LogCollector
+	creds        *credentials.Resolver // nil without a credentials file
 	config       *Config
+
+	backupMu       sync.Mutex
+	backupAttempts map[string]time.Time // MAC -> last backup attempt
+
+	pruneMu    sync.Mutex
+	keysPruned map[string]bool // MACs whose stale API keys were pruned
 }
 
 // NewHarvester creates a new harvester.
-func NewHarvester(repo database.Repository, probers []miner.FirmwareProber, cfg *Config) *Harvester {
+// creds may be nil if per-miner credentials are not configured.
+func NewHarvester(repo database.Repository, probers []miner.FirmwareProber, creds *credentials.Resolver, cfg *Config) *Harvester {
 	return &Harvester{
 		repo:         repo,
+		creds:        creds,
 		detector:     discovery.NewDetector(probers, discovery.WithDetectorTimeout(cfg.Timeout)),
 		scanner:      discovery.NewScanner(probers, discovery.WithTimeout(cfg.Timeout), discovery.WithConcurrency(cfg.Concurrency)),
 		collector:    NewCollector(),
 		logCollector: NewLogCollector(repo),
 		config:       cfg,
+
+		backupAttempts: make(map[string]time.Time),
+		keysPruned:     make(map[string]bool),
 	}
 }
 
@@ -138,6 +151,16 @@ func (h *Harvester) harvestOne(ctx context.Context, ip string) (int64, error) {
 		log.Printf("[%s] warning: failed to update miner: %v", ip, err)
 	}
 
+	// Record which credential set worked, and let MAC rules apply from now on
+	if h.creds != nil {
+		h.creds.LearnMAC(ip, data.Miner.MACAddress)
+		if set := h.creds.Confirmed(ip, fwType); set != "" {
+			if err := h.repo.UpsertMinerCredential(ctx, &database.MinerCredential{MinerID: minerID, CredentialSet: set}); err != nil {
+				log.Printf("[%s] warning: failed to record credential set: %v", ip, err)
+			}
+		}
+	}
+
 	// Upsert all related data
 	if data.Network != nil {
 		if err := h.repo.UpsertMinerNetwork(ctx, data.Network); err != nil {
@@ -244,9 +267,43 @@ func (h *Harvester) harvestOne(ctx context.Context, ip string) (int64, error) {
 		}
 	}
 
+	// Back up settings and prune stale API keys (VNish only)
+	if vnishClient, ok := client.(*vnish.HTTPClient); ok {
+		if err := h.backupIfDue(ctx, vnishClient, data.Miner); err != nil {
+			log.Printf("[%s] warning: failed to back up settings: %v", ip, err)
+		}
+		if err := h.pruneKeysOnce(ctx, vnishClient, data.Miner); err != nil {
+			log.Printf("[%s] warning: failed to prune stale API keys: %v", ip, err)
+		}
+	}
+
 	return minerID, nil
 }
 
+// pruneKeysOnce deletes API keys left on a VNish miner by earlier runs, the
+// first time the miner is harvested. Failed prunes are retried next harvest.
+func (h *Harvester) pruneKeysOnce(ctx context.Context, client *vnish.HTTPClient, m *database.Miner) error {
+	h.pruneMu.Lock()
+	done := h.keysPruned[m.MACAddress]
+	h.pruneMu.Unlock()
+	if done {
+		return nil
+	}
+
+	n, err := client.PruneAPIKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Printf("[%s] Pruned %d stale API keys", m.IPAddress, n)
+	}
+
+	h.pruneMu.Lock()
+	h.keysPruned[m.MACAddress] = true
+	h.pruneMu.Unlock()
+	return nil
+}
+
 // RunDaemon runs continuous harvesting.
 func (h *Harvester) RunDaemon(ctx context.Context, networks []string) error {
 	log.Printf("Starting daemon mode (interval: %s)", h.config.HarvestInterval)
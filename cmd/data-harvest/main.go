@@ -8,13 +8,17 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/braiins"
+	"github.com/powerhive/powerhive-v2/pkg/credentials"
 	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
 	"github.com/powerhive/powerhive-v2/pkg/stock"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
 )
 
 const usage = `data-harvest - Miner data collection tool
@@ -40,15 +44,31 @@ Commands:
   debug-api <ip>       Fetch and print raw API response (for debugging)
                        Example: data-harvest debug-api 192.168.1.21
 
+  backup list <ip>     List stored settings backups of a VNish miner
+  backup show <ip> [backup-id]
+                       Show the files in a backup (default: latest)
+  backup restore <ip> [backup-id]
+                       Restore a backup to a miner (default: its latest backup).
+                       The backup may belong to another miner, e.g. after a
+                       control board replacement
+                       Example: data-harvest backup restore 192.168.1.27 42
+
 Environment Variables:
   POWERHIVE_DB         SQLite database path (default: powerhive.db)
   VNISH_PASSWORD       VNish firmware password (default: admin)
   STOCK_USERNAME       Stock firmware username (default: root)
   STOCK_PASSWORD       Stock firmware password (default: root)
+  BRAIINS_USERNAME     Braiins OS username (default: root)
+  BRAIINS_PASSWORD     Braiins OS password (default: empty)
+  WHATSMINER_PASSWORD  Whatsminer admin password (default: admin)
+  CREDENTIAL_KEY_FILE  Key file encrypting stored API keys (default: <db>.key)
+  CREDENTIALS_FILE     JSON file with per-subnet/IP/MAC credential sets (optional)
   HARVEST_INTERVAL     Daemon polling interval (default: 60s)
   HARVEST_CONCURRENCY  Parallel harvest workers (default: 10)
   HARVEST_TIMEOUT      Per-miner timeout (default: 10s)
   NETWORK_CIDR         Comma-separated CIDRs for daemon mode (e.g., 10.40.36.0/24,10.40.37.0/24)
+  BACKUP_INTERVAL      VNish settings backup interval, 0 to disable (default: 24h)
+  BACKUP_KEEP          Distinct backups kept per miner (default: 20)
 `
 
 func main() {
@@ -67,16 +87,28 @@ func main() {
 	}
 	defer repo.Close()
 
-	// Create probers
-	probers := createProbers(cfg)
-
-	// Create harvester
-	harvester := NewHarvester(repo, probers, cfg)
+	// Persist VNish API keys so restarts don't create new keys on every miner
+	credStore, err := database.NewSQLiteCredentialStore(repo.DB(), cfg.CredentialKeyFile)
+	if err != nil {
+		log.Fatalf("Failed to open credential store: %v", err)
+	}
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	// Per-miner credentials (optional)
+	creds, err := loadCredentials(ctx, cfg, repo)
+	if err != nil {
+		log.Fatalf("Failed to load credentials: %v", err)
+	}
+
+	// Create probers
+	probers := createProbers(cfg, credStore, creds)
+
+	// Create harvester
+	harvester := NewHarvester(repo, probers, creds, cfg)
+
 	// Handle signals for graceful shutdown
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
@@ -100,7 +132,9 @@ func main() {
 	case "show":
 		runShow(ctx, harvester)
 	case "debug-api":
-		runDebugAPI(ctx, cfg)
+		runDebugAPI(ctx, cfg, credStore)
+	case "backup":
+		runBackup(ctx, harvester)
 	case "help", "-h", "--help":
 		fmt.Print(usage)
 	default:
@@ -110,9 +144,50 @@ func main() {
 	}
 }
 
-func createProbers(cfg *Config) []miner.FirmwareProber {
+// loadCredentials loads the credentials file, if configured, with the global
+// credentials as the last fallback. Known MAC addresses are taken from the database.
+func loadCredentials(ctx context.Context, cfg *Config, repo database.Repository) (*credentials.Resolver, error) {
+	if cfg.CredentialsFile == "" {
+		return nil, nil
+	}
+
+	creds, err := credentials.Load(cfg.CredentialsFile)
+	if err != nil {
+		return nil, err
+	}
+	creds.WithFallback(credentials.Set{
+		Name:          "env",
+		VNishPassword: cfg.VNishPassword,
+		StockUsername: cfg.StockUsername,
+		StockPassword: cfg.StockPassword,
+	})
+
+	miners, err := repo.ListMiners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list miners: %w", err)
+	}
+	for _, m := range miners {
+		creds.LearnMAC(m.IPAddress, m.MACAddress)
+	}
+
+	return creds, nil
+}
+
+// newVNishAuth creates VNish authentication with its API keys persisted in
+// credStore, so every command reuses the key the harvester created on a miner.
+func newVNishAuth(cfg *Config, credStore vnish.CredentialStore) *vnish.AuthManager {
+	return vnish.NewAuthManager(cfg.VNishPassword).
+		WithCredentialStore(credStore).
+		WithAPIKeyDescription("powerhive-data-harvest").
+		WithStoreErrorHandler(func(err error) { log.Printf("Credential store: %v", err) }).
+		WithPruneErrorHandler(func(host string, err error) {
+			log.Printf("[%s] warning: failed to prune stale API keys: %v", host, err)
+		})
+}
+
+func createProbers(cfg *Config, credStore vnish.CredentialStore, creds *credentials.Resolver) []miner.FirmwareProber {
 	// VNish prober
-	vnishAuth := vnish.NewAuthManager(cfg.VNishPassword)
+	vnishAuth := newVNishAuth(cfg, credStore)
 	vnishProber := vnish.NewProber(vnishAuth, vnish.WithProberTimeout(cfg.Timeout))
 
 	// Stock prober
@@ -121,7 +196,20 @@ func createProbers(cfg *Config) []miner.FirmwareProber {
 	stockAuth.Password = cfg.StockPassword
 	stockProber := stock.NewProber(stockAuth, stock.WithProberTimeout(cfg.Timeout))
 
-	return []miner.FirmwareProber{vnishProber, stockProber}
+	if creds != nil {
+		vnishAuth.WithCredentialResolver(creds)
+		stockAuth.WithCredentialResolver(creds)
+	}
+
+	// Braiins OS prober
+	braiinsAuth := braiins.NewAuthManager(cfg.BraiinsUsername, cfg.BraiinsPassword)
+	braiinsProber := braiins.NewProber(braiinsAuth, braiins.WithProberTimeout(cfg.Timeout))
+
+	// Whatsminer prober (btminer API on port 4028)
+	whatsminerAuth := whatsminer.NewAuthManager(cfg.WhatsminerPassword)
+	whatsminerProber := whatsminer.NewProber(whatsminerAuth, whatsminer.WithProberTimeout(cfg.Timeout))
+
+	return []miner.FirmwareProber{vnishProber, stockProber, braiinsProber, whatsminerProber}
 }
 
 func runScan(ctx context.Context, h *Harvester, cfg *Config) {
@@ -200,7 +288,7 @@ func runShow(ctx context.Context, h *Harvester) {
 	}
 }
 
-func runDebugAPI(ctx context.Context, cfg *Config) {
+func runDebugAPI(ctx context.Context, cfg *Config, credStore vnish.CredentialStore) {
 	if len(os.Args) < 3 {
 		fmt.Fprintln(os.Stderr, "Error: IP address required")
 		fmt.Fprintln(os.Stderr, "Usage: data-harvest debug-api <ip>")
@@ -210,8 +298,7 @@ func runDebugAPI(ctx context.Context, cfg *Config) {
 	ip := os.Args[2]
 
 	// Create VNish client
-	auth := vnish.NewAuthManager(cfg.VNishPassword)
-	client := vnish.NewClient(ip, auth, vnish.WithTimeout(cfg.Timeout))
+	client := vnish.NewClient(ip, newVNishAuth(cfg, credStore), vnish.WithTimeout(cfg.Timeout))
 
 	// Fetch raw summary
 	fmt.Printf("Fetching raw /api/v1/summary from %s...\n\n", ip)
@@ -222,3 +309,40 @@ func runDebugAPI(ctx context.Context, cfg *Config) {
 
 	fmt.Println(string(raw))
 }
+
+func runBackup(ctx context.Context, h *Harvester) {
+	if len(os.Args) < 4 {
+		fmt.Fprintln(os.Stderr, "Error: subcommand and IP address required")
+		fmt.Fprintln(os.Stderr, "Usage: data-harvest backup list|show|restore <ip> [backup-id]")
+		os.Exit(1)
+	}
+
+	sub, ip := os.Args[2], os.Args[3]
+
+	var id int64
+	if len(os.Args) >= 5 {
+		n, err := strconv.ParseInt(os.Args[4], 10, 64)
+		if err != nil || n <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid backup ID: %s\n", os.Args[4])
+			os.Exit(1)
+		}
+		id = n
+	}
+
+	var err error
+	switch sub {
+	case "list":
+		err = h.ListBackups(ctx, ip)
+	case "show":
+		err = h.ShowBackup(ctx, ip, id)
+	case "restore":
+		err = h.RestoreBackup(ctx, ip, id)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown backup subcommand: %s\n", sub)
+		fmt.Fprintln(os.Stderr, "Usage: data-harvest backup list|show|restore <ip> [backup-id]")
+		os.Exit(1)
+	}
+	if err != nil {
+		log.Fatalf("Backup %s failed: %v", sub, err)
+	}
+}
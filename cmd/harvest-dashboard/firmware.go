@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/powerhive/powerhive-v2/pkg/fleet"
+)
+
+// firmwareReport builds the firmware report for all known miners.
+// The policy file is re-read so edits apply without a restart.
+func (s *Server) firmwareReport(ctx context.Context) (*fleet.FirmwareReport, error) {
+	var policy *fleet.FirmwarePolicy
+	if s.policyFile != "" {
+		var err error
+		policy, err = fleet.LoadFirmwarePolicy(s.policyFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	miners, err := s.repo.ListMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return fleet.BuildFirmwareReport(miners, policy), nil
+}
+
+func (s *Server) handleFirmware(w http.ResponseWriter, r *http.Request) {
+	report, err := s.firmwareReport(r.Context())
+	if err != nil {
+		log.Printf("Firmware report error: %v", err)
+		http.Error(w, "Failed to build firmware report", http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Title":     "Firmware - PowerHive",
+		"Report":    report,
+		"HasPolicy": s.policyFile != "",
+	}
+
+	s.render(w, "firmware.html", data)
+}
+
+func (s *Server) handleAPIFirmwareReport(w http.ResponseWriter, r *http.Request) {
+	report, err := s.firmwareReport(r.Context())
+	if err != nil {
+		log.Printf("Firmware report error: %v", err)
+		s.jsonError(w, "Failed to build firmware report", http.StatusInternalServerError)
+		return
+	}
+
+	s.jsonResponse(w, report)
+}
+
+// handleAPIFirmwareViolations exports non-compliant miners (?format=csv|json).
+func (s *Server) handleAPIFirmwareViolations(w http.ResponseWriter, r *http.Request) {
+	report, err := s.firmwareReport(r.Context())
+	if err != nil {
+		log.Printf("Firmware report error: %v", err)
+		s.jsonError(w, "Failed to build firmware report", http.StatusInternalServerError)
+		return
+	}
+
+	switch r.URL.Query().Get("format") {
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="firmware-noncompliant.csv"`)
+		err = report.WriteViolationsCSV(w)
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="firmware-noncompliant.json"`)
+		err = report.WriteViolationsJSON(w)
+	default:
+		s.jsonError(w, "Invalid format (expected csv or json)", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Firmware export error: %v", err)
+	}
+}
@@ -24,15 +24,17 @@ var staticFS embed.FS
 
 // Server holds the dashboard server dependencies.
 type Server struct {
-	repo      database.Repository
-	templates *template.Template
-	sseHub    *SSEHub
+	repo       database.Repository
+	templates  *template.Template
+	sseHub     *SSEHub
+	policyFile string // Firmware policy, re-read on each report
 }
 
 // Config holds server configuration.
 type Config struct {
-	Port   string
-	DBPath string
+	Port           string
+	DBPath         string
+	FirmwarePolicy string
 }
 
 func main() {
@@ -55,9 +57,10 @@ func main() {
 	sseHub := NewSSEHub(repo)
 
 	server := &Server{
-		repo:      repo,
-		templates: tmpl,
-		sseHub:    sseHub,
+		repo:       repo,
+		templates:  tmpl,
+		sseHub:     sseHub,
+		policyFile: cfg.FirmwarePolicy,
 	}
 
 	// Setup routes
@@ -69,11 +72,14 @@ func main() {
 	// Pages
 	mux.HandleFunc("/", server.handleIndex)
 	mux.HandleFunc("/miner/", server.handleMinerDetail)
+	mux.HandleFunc("/firmware", server.handleFirmware)
 
 	// API endpoints
 	mux.HandleFunc("/api/miners", server.handleAPIMiners)
 	mux.HandleFunc("/api/miner/", server.handleAPIMiner)
 	mux.HandleFunc("/api/metrics/aggregate", server.handleAPIAggregateMetrics)
+	mux.HandleFunc("/api/firmware/report", server.handleAPIFirmwareReport)
+	mux.HandleFunc("/api/firmware/violations", server.handleAPIFirmwareViolations)
 
 	// SSE endpoints for live updates
 	mux.HandleFunc("/api/sse/dashboard", sseHub.handleDashboardSSE)
@@ -97,6 +103,9 @@ func loadConfig() *Config {
 	if db := os.Getenv("POWERHIVE_DB"); db != "" {
 		cfg.DBPath = db
 	}
+	if p := os.Getenv("FIRMWARE_POLICY"); p != "" {
+		cfg.FirmwarePolicy = p
+	}
 
 	return cfg
 }
@@ -0,0 +1,65 @@
+// miner-sim serves a farm of simulated VNish and stock miners on loopback
+// addresses, for developing and testing without hardware.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+)
+
+func main() {
+	cfg := minersim.DefaultConfig()
+
+	flag.StringVar(&cfg.FirstIP, "first-ip", cfg.FirstIP, "Address of the first miner (loopback, e.g. 127.0.1.1)")
+	flag.IntVar(&cfg.Port, "port", cfg.Port, "HTTP port of every miner (discovery probes port 80)")
+	flag.IntVar(&cfg.VNish, "vnish", cfg.VNish, "Number of VNish miners")
+	flag.IntVar(&cfg.Stock, "stock", cfg.Stock, "Number of stock miners")
+	flag.StringVar(&cfg.VNishPassword, "vnish-password", cfg.VNishPassword, "VNish unlock password")
+	flag.StringVar(&cfg.StockUsername, "stock-username", cfg.StockUsername, "Stock digest auth username")
+	flag.StringVar(&cfg.StockPassword, "stock-password", cfg.StockPassword, "Stock digest auth password")
+	flag.StringVar(&cfg.VNishVersion, "vnish-version", cfg.VNishVersion, "VNish firmware version")
+	flag.StringVar(&cfg.StockVersion, "stock-version", cfg.StockVersion, "Stock firmware build date")
+	flag.DurationVar(&cfg.Timing.Boot, "boot", cfg.Timing.Boot, "Time a reboot keeps the miner unreachable")
+	flag.DurationVar(&cfg.Timing.Restart, "restart", cfg.Timing.Restart, "Time a mining restart takes before hashing")
+	flag.DurationVar(&cfg.Timing.Tune, "tune", cfg.Timing.Tune, "Time auto-tuning takes after a preset change")
+	flag.Float64Var(&cfg.Timing.FailureRate, "failure-rate", cfg.Timing.FailureRate, "Random failures per miner per mining hour")
+	flag.Int64Var(&cfg.Seed, "seed", cfg.Seed, "Random seed for sensor noise and failures")
+	flag.Parse()
+
+	farm, err := minersim.NewFarm(cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := farm.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if cfg.Port < 1024 {
+			fmt.Fprintf(os.Stderr, "Ports below 1024 need root or CAP_NET_BIND_SERVICE, or use --port\n")
+		}
+		os.Exit(1)
+	}
+	defer farm.Close()
+
+	fmt.Println("============================================================")
+	fmt.Println("                  POWERHIVE MINER SIMULATOR")
+	fmt.Println("============================================================")
+	fmt.Printf("%-15s %-8s %-18s %-14s %-28s\n", "IP", "FIRMWARE", "MODEL", "HOSTNAME", "VERSION")
+	for _, m := range farm.Miners() {
+		spec := m.Spec()
+		fmt.Printf("%-15s %-8s %-18s %-14s %-28s\n",
+			spec.IP, spec.Firmware, spec.Model.Name, spec.Hostname, spec.FirmwareVersion)
+	}
+	fmt.Println("============================================================")
+	fmt.Printf("Port %d. Control: GET /sim/status, POST /sim/fail?code=N, /sim/restart, /sim/reboot\n", cfg.Port)
+	fmt.Println("Press Ctrl+C to stop.")
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+	<-sigCh
+	fmt.Println("\nStopping simulator...")
+}
@@ -6,72 +6,81 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"time"
 )
 
 // Aggregator fetches energy generation/consumption data from the energy aggregator API.
 type Aggregator struct {
+	name   string
 	url    string
 	apiKey string
+	plants []PlantConfig
 	client *http.Client
 }
 
 // AggregatorResponse represents the JSON response from the energy aggregator.
+// Generation and consumption are keyed by plant (e.g., "generoso", "container_eles").
 type AggregatorResponse struct {
 	Reading struct {
-		CollectionTimestamp string `json:"collection_timestamp"`
-		Consumption         struct {
-			ContainerEles struct {
-				SourceTimestamp string  `json:"source_timestamp"`
-				Status          string  `json:"status"`
-				ValueMW         float64 `json:"value_mw"`
-			} `json:"container_eles"`
-			ContainerMazp struct {
-				SourceTimestamp string  `json:"source_timestamp"`
-				Status          string  `json:"status"`
-				ValueMW         float64 `json:"value_mw"`
-			} `json:"container_mazp"`
-		} `json:"consumption"`
-		Generation struct {
-			Generoso struct {
-				SourceTimestamp string  `json:"source_timestamp"`
-				Status          string  `json:"status"`
-				ValueMW         float64 `json:"value_mw"`
-			} `json:"generoso"`
-			Nogueira struct {
-				SourceTimestamp string  `json:"source_timestamp"`
-				Status          string  `json:"status"`
-				ValueMW         float64 `json:"value_mw"`
-			} `json:"nogueira"`
-		} `json:"generation"`
-		ID      int    `json:"id"`
-		PlantID string `json:"plant_id"`
-		Totals  struct {
+		CollectionTimestamp string                     `json:"collection_timestamp"`
+		Consumption         map[string]AggregatorValue `json:"consumption"`
+		Generation          map[string]AggregatorValue `json:"generation"`
+		ID                  int                        `json:"id"`
+		PlantID             string                     `json:"plant_id"`
+		Totals              struct {
 			ConsumptionMW float64 `json:"consumption_mw"`
 			ExportedMW    float64 `json:"exported_mw"`
 			GenerationMW  float64 `json:"generation_mw"`
 		} `json:"totals"`
 		Trust struct {
-			ConfidenceScore float64 `json:"confidence_score"`
-			Status          string  `json:"status"`
-			Summary         string  `json:"summary"`
+			ConfidenceScore *float64 `json:"confidence_score"` // Absent when the aggregator doesn't score trust
+			Status          string   `json:"status"`
+			Summary         string   `json:"summary"`
 		} `json:"trust"`
 	} `json:"reading"`
 }
 
+// AggregatorValue is one plant in the aggregator response.
+type AggregatorValue struct {
+	SourceTimestamp string  `json:"source_timestamp"`
+	Status          string  `json:"status"`
+	ValueMW         float64 `json:"value_mw"`
+}
+
+// aggregatorScale converts aggregator values to MW.
+// Note: API returns values in KW despite field names containing "mw".
+const aggregatorScale = 0.001
+
 // NewAggregator creates a new aggregator client.
-func NewAggregator(url, apiKey string) *Aggregator {
+func NewAggregator(sc SourceConfig) *Aggregator {
 	return &Aggregator{
-		url:    url,
-		apiKey: apiKey,
+		name:   sc.Name,
+		url:    sc.URL,
+		apiKey: sc.APIKey,
+		plants: sc.Plants,
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
 	}
 }
 
-// FetchLatest fetches the latest energy data from the aggregator.
-func (a *Aggregator) FetchLatest(ctx context.Context) (*EnergyReading, error) {
+// Name returns the source name (implements EnergySource).
+func (a *Aggregator) Name() string {
+	return a.name
+}
+
+// ReadPlants fetches the latest data and returns the configured plants (implements EnergySource).
+func (a *Aggregator) ReadPlants(ctx context.Context) ([]PlantReading, error) {
+	data, err := a.fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return a.toPlants(data), nil
+}
+
+// fetch fetches the latest response from the aggregator.
+func (a *Aggregator) fetch(ctx context.Context) (*AggregatorResponse, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", a.url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("create request: %w", err)
@@ -95,35 +104,56 @@ func (a *Aggregator) FetchLatest(ctx context.Context) (*EnergyReading, error) {
 	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
 		return nil, fmt.Errorf("decode response: %w", err)
 	}
-
-	reading := a.toEnergyReading(&data)
-	return reading, nil
+	return &data, nil
 }
 
-// toEnergyReading converts the API response to our internal model.
-// Note: API returns values in KW despite field names containing "mw", so we divide by 1000.
-func (a *Aggregator) toEnergyReading(data *AggregatorResponse) *EnergyReading {
+// toPlants converts the API response to plant readings. Without configured
+// plants, every generation and consumption entry is reported, sorted by key.
+func (a *Aggregator) toPlants(data *AggregatorResponse) []PlantReading {
 	r := data.Reading
 
-	// Convert KW to MW (API returns KW despite field names)
-	generationMW := r.Totals.GenerationMW / 1000.0
-	consumptionMW := r.Totals.ConsumptionMW / 1000.0
-	marginMW := generationMW - consumptionMW
+	confidence := 1.0
+	if r.Trust.ConfidenceScore != nil {
+		confidence = *r.Trust.ConfidenceScore
+	}
 
-	var marginPercent float64
-	if generationMW > 0 {
-		marginPercent = (marginMW / generationMW) * 100
+	plants := a.plants
+	if len(plants) == 0 {
+		for _, kind := range []PlantKind{PlantGeneration, PlantConsumption} {
+			values := r.Generation
+			if kind == PlantConsumption {
+				values = r.Consumption
+			}
+			keys := make([]string, 0, len(values))
+			for k := range values {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				plants = append(plants, PlantConfig{Name: displayName(k), Kind: kind, Field: k})
+			}
+		}
 	}
 
-	return &EnergyReading{
-		Timestamp:      time.Now(),
-		GenerationMW:   generationMW,
-		ConsumptionMW:  consumptionMW,
-		MarginMW:       marginMW,
-		MarginPercent:  marginPercent,
-		GenerosoMW:     r.Generation.Generoso.ValueMW / 1000.0,
-		GenerosoStatus: r.Generation.Generoso.Status,
-		NogueiraMW:     r.Generation.Nogueira.ValueMW / 1000.0,
-		NogueiraStatus: r.Generation.Nogueira.Status,
+	readings := make([]PlantReading, 0, len(plants))
+	for _, p := range plants {
+		values := r.Generation
+		if p.Kind == PlantConsumption {
+			values = r.Consumption
+		}
+
+		field := p.Field
+		if field == "" {
+			field = p.Name
+		}
+
+		pr := PlantReading{Name: p.Name, Kind: p.Kind, Status: "missing", Confidence: confidence}
+		if v, ok := values[field]; ok {
+			pr.MW = v.ValueMW * p.scale(aggregatorScale)
+			pr.Status = v.Status
+			pr.SourceTimestamp, _ = time.Parse(time.RFC3339, v.SourceTimestamp)
+		}
+		readings = append(readings, pr)
 	}
+	return readings
 }
@@ -4,8 +4,6 @@ import (
 	"context"
 	"fmt"
 	"log"
-	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -16,12 +14,15 @@ import (
 
 // Balancer is the main power balancing daemon.
 type Balancer struct {
-	repo       *Repository
-	aggregator *Aggregator
-	controller *Controller
-	strategy   *Strategy
-	cooldowns  *CooldownManager
-	scanner    *discovery.Scanner
+	repo        *Repository
+	energy      *EnergyMeter
+	controller  *Controller
+	strategy    Strategy
+	calibration *Calibration
+	domains     *PowerDomain // nil without DOMAINS_FILE
+	scheduler   *Scheduler
+	cooldowns   *CooldownManager
+	scanner     *discovery.Scanner
 
 	cfg   *Config
 	state BalancerState
@@ -30,6 +31,41 @@ type Balancer struct {
 	// Track when we entered recovery margin for hysteresis
 	recoveryEnteredAt *time.Time
 
+	// Consecutive readings that couldn't be fetched or trusted, and why the last one failed
+	badReadings int
+	dataProblem string
+
+	// Power domain loads of the current tick
+	domainLoads []*domainLoad
+
+	// Active schedule limits of the current tick (nil = none), and the
+	// strategy holding miners at their target presets
+	schedule *ScheduleLimits
+	active   Strategy
+
+	// Demand-response target of the current tick (nil = no active event)
+	demand *demandState
+
+	// Reason of the change held by the ramp limits, by miner, so each is logged once
+	rampHeld map[int64]string
+
+	// Miners restarting, or queued to, to apply a preset
+	restarts restartQueue
+
+	// When the reconciler last found each miner on its recorded preset, the
+	// unknown preset it last recorded a miner drifting to, and the reasserts
+	// it queued for the tick
+	presetSeen   map[int64]time.Time
+	unknownDrift map[int64]string
+	reasserts    reassertQueue
+
+	// Miners parked under the stop data loss policy, to resume once data is back
+	dataLossParked map[int64]bool
+
+	// Models whose presets without power figures were fetched again this
+	// run (discovery loop only)
+	presetsChecked map[int64]bool
+
 	// Current status for dashboard
 	status *SystemStatus
 }
@@ -37,26 +73,43 @@ type Balancer struct {
 // NewBalancer creates a new balancer instance.
 func NewBalancer(
 	repo *Repository,
-	aggregator *Aggregator,
+	energy *EnergyMeter,
 	controller *Controller,
-	strategy *Strategy,
+	strategy Strategy,
+	calibration *Calibration,
+	domains *PowerDomain,
+	scheduler *Scheduler,
 	probers []miner.FirmwareProber,
 	cfg *Config,
 ) *Balancer {
 	b := &Balancer{
-		repo:       repo,
-		aggregator: aggregator,
-		controller: controller,
-		strategy:   strategy,
-		cfg:        cfg,
-		state:      StateIdle,
-		status:     &SystemStatus{State: StateIdle},
+		repo:           repo,
+		energy:         energy,
+		controller:     controller,
+		strategy:       strategy,
+		calibration:    calibration,
+		domains:        domains,
+		scheduler:      scheduler,
+		active:         strategy,
+		cfg:            cfg,
+		state:          StateIdle,
+		status:         &SystemStatus{State: StateIdle},
+		rampHeld:       make(map[int64]string),
+		presetSeen:     make(map[int64]time.Time),
+		unknownDrift:   make(map[int64]string),
+		reasserts:      reassertQueue{presets: make(map[int64]reassertJob)},
+		dataLossParked: make(map[int64]bool),
+		presetsChecked: make(map[int64]bool),
 	}
 
 	if repo != nil {
 		b.cooldowns = NewCooldownManager(repo, cfg.CooldownDuration)
 	}
 
+	if strategy != nil {
+		b.status.Strategy = strategy.Name()
+	}
+
 	if len(probers) > 0 {
 		b.scanner = discovery.NewScanner(probers)
 	}
@@ -69,6 +122,12 @@ func (b *Balancer) Run(ctx context.Context) error {
 	// Start discovery in background
 	go b.runDiscoveryLoop(ctx)
 
+	// Sample miner power for preset calibration in background
+	go b.runCalibrationLoop(ctx)
+
+	// Correct presets changed outside the balancer in background
+	go b.runReconcileLoop(ctx)
+
 	// Main balancing loop
 	ticker := time.NewTicker(b.cfg.PollInterval)
 	defer ticker.Stop()
@@ -88,11 +147,10 @@ func (b *Balancer) Run(ctx context.Context) error {
 // tick performs one iteration of the balancing loop.
 func (b *Balancer) tick(ctx context.Context) error {
 	// 1. Fetch latest energy data
-	reading, err := b.aggregator.FetchLatest(ctx)
+	reading, err := b.energy.FetchLatest(ctx)
 	if err != nil {
 		log.Printf("Failed to fetch energy data: %v", err)
-		// If we can't get data, be conservative
-		return nil
+		return b.handleBadData(ctx, fmt.Sprintf("fetch failed: %v", err))
 	}
 
 	// 2. Store the reading
@@ -100,7 +158,15 @@ func (b *Balancer) tick(ctx context.Context) error {
 		log.Printf("Failed to store reading: %v", err)
 	}
 
-	// 3. Clean up settled changes, expired cooldowns, and pending changes for offline miners
+	// 3. Reject stale or untrusted data
+	if problem := checkReading(reading, time.Now(), b.cfg); problem != "" {
+		log.Printf("Rejected energy reading: %s", problem)
+		return b.handleBadData(ctx, problem)
+	}
+	b.dataRestored(ctx, reading)
+
+	// 4. Verify and clean up settled changes, expired cooldowns, and pending changes for offline miners
+	b.verifySettledChanges(ctx)
 	if err := b.repo.ClearSettledChanges(ctx); err != nil {
 		log.Printf("Failed to clear settled changes: %v", err)
 	}
@@ -113,28 +179,60 @@ func (b *Balancer) tick(ctx context.Context) error {
 	} else if cleared > 0 {
 		log.Printf("Cleared %d pending changes for offline miners", cleared)
 	}
+	// Restart the next miner waiting to apply a preset, staggered across the fleet
+	b.processRestarts(ctx)
+	// Move miners changed outside the balancer back, as queued by the reconciler
+	b.processReasserts(ctx, reading.MarginPercent)
+
+	// 5. Apply the time-of-use schedule: target presets, and a consumption cap
+	// standing in for generation
+	budget := b.applySchedule(ctx, reading)
+
+	// 6. Meet demand-response targets, whatever the site margin
+	b.applyDemandResponse(ctx, reading)
+
+	// 7. Keep every power domain within its cap, whatever the site margin
+	b.domainLoads = nil
+	if b.domains != nil {
+		loads, err := b.evaluateDomains(ctx, reading)
+		if err != nil {
+			log.Printf("Failed to evaluate power domains: %v", err)
+		} else {
+			b.enforceDomainCaps(ctx, loads, reading.MarginPercent)
+			b.domainLoads = loads
+		}
+	}
 
-	// 4. Calculate effective margin (accounting for pending changes)
+	// 8. Calculate effective margin (accounting for pending changes)
 	pendingDelta, _ := b.repo.SumPendingDelta(ctx)
 	pendingDeltaMW := float64(pendingDelta) / 1_000_000.0
 
-	effectiveConsumption := reading.ConsumptionMW - pendingDeltaMW
-	effectiveMargin := reading.GenerationMW - effectiveConsumption
+	effectiveConsumption := budget.ConsumptionMW - pendingDeltaMW
+	effectiveMargin := budget.GenerationMW - effectiveConsumption
 	var effectiveMarginPercent float64
-	if reading.GenerationMW > 0 {
-		effectiveMarginPercent = (effectiveMargin / reading.GenerationMW) * 100
+	if budget.GenerationMW > 0 {
+		effectiveMarginPercent = (effectiveMargin / budget.GenerationMW) * 100
 	}
 
-	// 5. Update status
+	// 9. Update status
 	b.updateStatus(reading, pendingDelta, effectiveMarginPercent)
 
-	// 6. Log current state
-	log.Printf("[%s] Gen=%.2fMW Con=%.2fMW Margin=%.1f%% (Eff=%.1f%%) Pending=%dW",
-		b.state, reading.GenerationMW, reading.ConsumptionMW,
-		reading.MarginPercent, effectiveMarginPercent, pendingDelta)
+	// 10. Log current state
+	if budget != reading {
+		log.Printf("[%s] Gen=%.2fMW Cap=%.2fMW Con=%.2fMW Margin=%.1f%% (Eff=%.1f%%) Pending=%dW",
+			b.state, reading.GenerationMW, budget.GenerationMW, reading.ConsumptionMW,
+			budget.MarginPercent, effectiveMarginPercent, pendingDelta)
+	} else {
+		log.Printf("[%s] Gen=%.2fMW Con=%.2fMW Margin=%.1f%% (Eff=%.1f%%) Pending=%dW",
+			b.state, reading.GenerationMW, reading.ConsumptionMW,
+			reading.MarginPercent, effectiveMarginPercent, pendingDelta)
+	}
+
+	// 11. Resume miners parked for data loss, now that it's back
+	b.resumeDataLossParked(ctx, budget, effectiveMarginPercent)
 
-	// 7. State machine
-	return b.runStateMachine(ctx, reading, effectiveMarginPercent, pendingDelta)
+	// 12. State machine
+	return b.runStateMachine(ctx, budget, effectiveMarginPercent, pendingDelta)
 }
 
 // runStateMachine executes the state machine logic.
@@ -156,7 +254,7 @@ func (b *Balancer) runStateMachine(ctx context.Context, reading *EnergyReading,
 		return b.handleIncreasing(ctx, reading, effectiveMarginPercent)
 
 	case StateEmergency:
-		return b.handleEmergency(ctx, reading, effectiveMarginPercent)
+		return b.handleEmergency(ctx, reading, effectiveMarginPercent, pendingDelta)
 	}
 
 	return nil
@@ -219,7 +317,7 @@ func (b *Balancer) handleReducing(ctx context.Context, reading *EnergyReading, e
 	}
 
 	// Get one change to make
-	changes, err := b.strategy.CalculateReduction(ctx, reductionNeededW)
+	changes, err := b.active.CalculateReduction(ctx, reductionNeededW)
 	if err != nil {
 		return fmt.Errorf("calculate reduction: %w", err)
 	}
@@ -229,7 +327,10 @@ func (b *Balancer) handleReducing(ctx context.Context, reading *EnergyReading, e
 		return nil
 	}
 
-	// Execute first change only (sequential in normal mode)
+	// Execute first change only (sequential in normal mode), if the ramp allows
+	if !b.admitChange(ctx, changes[0], "reduce", reading.MarginPercent) {
+		return nil
+	}
 	return b.executeChange(ctx, changes[0], "reduce", reading.MarginPercent)
 }
 
@@ -273,30 +374,55 @@ func (b *Balancer) handleIncreasing(ctx context.Context, reading *EnergyReading,
 	increaseRoomMW := targetConsumption - reading.ConsumptionMW
 	increaseRoomW := int(increaseRoomMW * 1_000_000)
 
+	// Stay within the demand-response target
+	if b.demand != nil {
+		increaseRoomW = min(increaseRoomW, b.demand.headroomW)
+	}
+
 	if increaseRoomW <= 0 {
 		log.Printf("No room to increase, switching to IDLE")
 		b.state = StateIdle
 		return nil
 	}
 
+	// Resume parked miners before raising any preset, skipping those
+	// their power domains can't take
+	parked, err := b.active.CalculateUnpark(ctx, increaseRoomW)
+	if err != nil {
+		return fmt.Errorf("calculate unpark: %w", err)
+	}
+	for _, p := range parked {
+		if fitsDomains(b.domainLoads, p.MinerID, p.Preset.Watts) {
+			if !b.admitUnpark(ctx, p, reading.MarginPercent) {
+				return nil // Wait for the ramp
+			}
+			return b.unparkMiner(ctx, p, reading.MarginPercent)
+		}
+	}
+
 	// Get one change to make (conservative increase)
-	changes, err := b.strategy.CalculateIncrease(ctx, increaseRoomW)
+	changes, err := b.active.CalculateIncrease(ctx, increaseRoomW)
 	if err != nil {
 		return fmt.Errorf("calculate increase: %w", err)
 	}
 
-	if len(changes) == 0 {
-		log.Printf("No miners available for increase, switching to IDLE")
-		b.state = StateIdle
-		return nil
+	for _, change := range changes {
+		if fitsDomains(b.domainLoads, change.Miner.Miner.ID, -change.ExpectedDeltaW) {
+			if !b.admitChange(ctx, change, "increase", reading.MarginPercent) {
+				return nil // Wait for the ramp
+			}
+			// Execute one change only with longer spacing
+			return b.executeChange(ctx, change, "increase", reading.MarginPercent)
+		}
 	}
 
-	// Execute first change only with longer spacing
-	return b.executeChange(ctx, changes[0], "increase", reading.MarginPercent)
+	log.Printf("No miners available for increase, switching to IDLE")
+	b.state = StateIdle
+	return nil
 }
 
 // handleEmergency handles the EMERGENCY state.
-func (b *Balancer) handleEmergency(ctx context.Context, reading *EnergyReading, effectiveMarginPercent float64) error {
+func (b *Balancer) handleEmergency(ctx context.Context, reading *EnergyReading, effectiveMarginPercent float64, pendingDelta int) error {
 	// Check if we've recovered from emergency
 	if effectiveMarginPercent >= b.cfg.CriticalMargin {
 		log.Printf("Recovered from emergency (margin %.1f%%), switching to REDUCING",
@@ -315,36 +441,166 @@ func (b *Balancer) handleEmergency(ctx context.Context, reading *EnergyReading,
 	}
 
 	// Get multiple changes in emergency mode
-	changes, err := b.strategy.CalculateReduction(ctx, reductionNeededW)
+	changes, err := b.active.CalculateReduction(ctx, reductionNeededW)
 	if err != nil {
 		return fmt.Errorf("calculate emergency reduction: %w", err)
 	}
 
 	if len(changes) == 0 {
+		if b.cfg.ParkMiners {
+			return b.parkForEmergency(ctx, reductionNeededW-pendingDelta, reading.MarginPercent)
+		}
 		log.Printf("EMERGENCY: No miners available for reduction!")
 		return nil
 	}
 
-	// Execute multiple changes in parallel (up to MaxParallelEmergency)
-	count := len(changes)
-	if count > b.cfg.MaxParallelEmergency {
-		count = b.cfg.MaxParallelEmergency
+	log.Printf("EMERGENCY: Executing %d parallel changes", min(len(changes), b.cfg.MaxParallelEmergency))
+	b.executeParallel(ctx, changes, "emergency", reading.MarginPercent)
+
+	return nil
+}
+
+// parkForEmergency parks miners to save targetW when no preset can be
+// reduced. targetW excludes what pending changes will already save.
+func (b *Balancer) parkForEmergency(ctx context.Context, targetW int, margin float64) error {
+	if targetW <= 0 {
+		return nil
+	}
+
+	miners, err := b.active.CalculatePark(ctx, targetW)
+	if err != nil {
+		return fmt.Errorf("calculate park: %w", err)
+	}
+
+	if len(miners) == 0 {
+		log.Printf("EMERGENCY: No miners available for reduction or parking!")
+		return nil
+	}
+
+	log.Printf("EMERGENCY: All miners at minimum preset, parking %d miners",
+		min(len(miners), b.cfg.MaxParallelEmergency))
+	b.parkMiners(ctx, miners, "emergency", margin)
+
+	return nil
+}
+
+// handleBadData counts a reading that couldn't be fetched or trusted. The
+// balancer holds until DataLossReadings bad readings in a row, then enters
+// DATA_LOSS and applies the data loss policy on every tick until good data returns.
+func (b *Balancer) handleBadData(ctx context.Context, problem string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.badReadings++
+	b.dataProblem = problem
+
+	if b.badReadings >= b.cfg.DataLossReadings && b.state != StateDataLoss {
+		log.Printf("DATA_LOSS: %d consecutive bad readings (%s), applying %s policy",
+			b.badReadings, problem, b.cfg.DataLossPolicy)
+		b.logStateChange(ctx, b.state, StateDataLoss, "data_loss", b.status.MarginPercent)
+		b.state = StateDataLoss
+		b.recoveryEnteredAt = nil
+	}
+
+	// Keep the last good values on the dashboard
+	status := *b.status
+	status.State = b.state
+	status.BadReadings = b.badReadings
+	status.DataProblem = b.dataProblem
+	status.LastUpdated = time.Now()
+	b.status = &status
+
+	if b.state != StateDataLoss || b.cfg.DataLossPolicy == DataLossHold {
+		return nil
+	}
+
+	if b.cfg.DataLossPolicy == DataLossStop {
+		miners, err := b.strategy.CalculateStop(ctx)
+		if err != nil {
+			return fmt.Errorf("calculate miners to stop: %w", err)
+		}
+		if len(miners) == 0 {
+			return nil
+		}
+		log.Printf("DATA_LOSS: Parking %d miners (%d remaining)",
+			min(len(miners), b.cfg.MaxParallelEmergency), len(miners))
+		b.parkMiners(ctx, miners, "data_loss", status.MarginPercent)
+		return nil
+	}
+
+	changes, err := b.strategy.CalculateFailSafe(ctx)
+	if err != nil {
+		return fmt.Errorf("calculate fail-safe changes: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	log.Printf("DATA_LOSS: Executing %d fail-safe changes (%d remaining)",
+		min(len(changes), b.cfg.MaxParallelEmergency), len(changes))
+	b.executeParallel(ctx, changes, "data_loss", status.MarginPercent)
+
+	return nil
+}
+
+// dataRestored resets the bad reading count after a good reading. DATA_LOSS
+// is left through HOLDING, so the state machine reassesses the margin first.
+func (b *Balancer) dataRestored(ctx context.Context, reading *EnergyReading) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.badReadings = 0
+	b.dataProblem = ""
+
+	if b.state == StateDataLoss {
+		log.Printf("Energy data restored, switching to HOLDING")
+		b.logStateChange(ctx, StateDataLoss, StateHolding, "data_restored", reading.MarginPercent)
+		b.state = StateHolding
+	}
+}
+
+// logStateChange records a state change in the change log, with the states
+// in place of the presets.
+func (b *Balancer) logStateChange(ctx context.Context, from, to BalancerState, reason string, margin float64) {
+	entry := &ChangeLog{
+		FromPreset:   string(from),
+		ToPreset:     string(to),
+		Reason:       reason,
+		MarginAtTime: margin,
+		IssuedAt:     time.Now(),
+		Success:      true,
+	}
+	if err := b.repo.InsertChangeLog(ctx, entry); err != nil {
+		log.Printf("Failed to log state change: %v", err)
+	}
+}
+
+// executeParallel executes up to MaxParallelEmergency changes at once, in
+// order until one doesn't fit the ramp limits.
+func (b *Balancer) executeParallel(ctx context.Context, changes []*PresetChange, reason string, margin float64) {
+	count := min(len(changes), b.cfg.MaxParallelEmergency)
+
+	if r, err := b.currentRamp(ctx); err != nil {
+		log.Printf("Failed to compute load ramp: %v", err)
+	} else {
+		for i := 0; i < count; i++ {
+			if !b.rampAllowsChange(ctx, r, changes[i], reason, margin) {
+				count = i
+			}
+		}
 	}
 
-	log.Printf("EMERGENCY: Executing %d parallel changes", count)
 	var wg sync.WaitGroup
 	for i := 0; i < count; i++ {
 		wg.Add(1)
 		go func(change *PresetChange) {
 			defer wg.Done()
-			if err := b.executeChange(ctx, change, "emergency", reading.MarginPercent); err != nil {
-				log.Printf("Emergency change failed: %v", err)
+			if err := b.executeChange(ctx, change, reason, margin); err != nil {
+				log.Printf("Change (%s) failed: %v", reason, err)
 			}
 		}(changes[i])
 	}
 	wg.Wait()
-
-	return nil
 }
 
 // executeChange executes a single preset change.
@@ -354,8 +610,14 @@ func (b *Balancer) executeChange(ctx context.Context, change *PresetChange, reas
 		miner.Miner.IPAddress, change.FromPreset.Name, change.ToPreset.Name,
 		change.ExpectedDeltaW, reason)
 
+	// Measure the miner's power before the change, for settlement verification
+	var beforeW int
+	if m, err := b.controller.Measure(ctx, miner.Miner.IPAddress, miner.Miner.FirmwareType); err == nil {
+		beforeW = m.Watts
+	}
+
 	// Execute the change
-	err := b.controller.SetPreset(ctx, miner.Miner.IPAddress, change.ToPreset.Name)
+	restart, err := b.controller.SetPreset(ctx, miner.Miner.IPAddress, miner.Miner.FirmwareType, change.ToPreset.Name)
 
 	// Log the change
 	logEntry := &ChangeLog{
@@ -373,11 +635,7 @@ func (b *Balancer) executeChange(ctx context.Context, change *PresetChange, reas
 	if err != nil {
 		logEntry.ErrorMessage = err.Error()
 		// Mark miner as offline since we couldn't reach it
-		if markErr := b.repo.SetMinerOnlineStatus(ctx, miner.Miner.ID, false); markErr != nil {
-			log.Printf("Failed to mark miner %s offline: %v", miner.Miner.IPAddress, markErr)
-		} else {
-			log.Printf("Marked miner %s as offline due to connection failure", miner.Miner.IPAddress)
-		}
+		b.markUnreachable(ctx, miner.Miner)
 	}
 	if logErr := b.repo.InsertChangeLog(ctx, logEntry); logErr != nil {
 		log.Printf("Failed to log change: %v", logErr)
@@ -403,6 +661,24 @@ func (b *Balancer) executeChange(ctx context.Context, change *PresetChange, reas
 	}
 	if err := b.repo.CreatePendingChange(ctx, pending); err != nil {
 		log.Printf("Failed to record pending change: %v", err)
+	} else {
+		verification := &ChangeVerification{
+			ChangeLogID:     logEntry.ID,
+			PendingChangeID: pending.ID,
+			MinerID:         miner.Miner.ID,
+			FromPresetID:    &change.FromPreset.ID,
+			ToPresetID:      &change.ToPreset.ID,
+			Attempt:         change.Attempt,
+			ExpectedDeltaW:  change.ExpectedDeltaW,
+			BeforeW:         beforeW,
+			Outcome:         VerifyPending,
+		}
+		if err := b.repo.CreateChangeVerification(ctx, verification); err != nil {
+			log.Printf("Failed to record change verification: %v", err)
+		}
+	}
+	if restart != RestartNone {
+		b.queueRestart(ctx, change, pending, restart)
 	}
 
 	// Update miner's current preset in database
@@ -420,6 +696,12 @@ func (b *Balancer) updateStatus(reading *EnergyReading, pendingDelta int, effect
 
 	count, _ := b.repo.CountManagedMiners(context.Background())
 	cooldownCount, _ := b.cooldowns.CountActive(context.Background())
+	parked, _ := b.repo.GetParkedMiners(context.Background())
+	restarting, queued := b.restartCounts()
+	var parkedW int
+	for _, p := range parked {
+		parkedW += p.SavedW
+	}
 
 	b.status = &SystemStatus{
 		State:                  b.state,
@@ -431,12 +713,24 @@ func (b *Balancer) updateStatus(reading *EnergyReading, pendingDelta int, effect
 		EffectiveMarginPercent: effectiveMarginPercent,
 		ManagedMinersCount:     count,
 		MinersOnCooldown:       cooldownCount,
-		GenerosoStatus:         reading.GenerosoStatus,
-		GenerosoMW:             reading.GenerosoMW,
-		NogueiraStatus:         reading.NogueiraStatus,
-		NogueiraMW:             reading.NogueiraMW,
+		ParkedMiners:           len(parked),
+		ParkedW:                parkedW,
+		Plants:                 reading.Plants,
+		BadReadings:            b.badReadings,
+		DataProblem:            b.dataProblem,
+		Strategy:               b.strategy.Name(),
+		Domains:                domainStatuses(b.domainLoads),
+		DemandResponse:         demandStatus(b.demand),
+		Ramp:                   b.rampStatus(context.Background()),
+		RestartingMiners:       restarting,
+		RestartsQueued:         queued,
 		LastUpdated:            time.Now(),
 	}
+	if b.schedule != nil {
+		b.status.Schedules = b.schedule.Names
+		b.status.ScheduleCapMW = float64(b.schedule.MaxConsumptionW) / 1_000_000
+		b.status.ScheduleTargets = b.schedule.TargetPresets
+	}
 }
 
 // GetStatus returns the current system status.
@@ -446,6 +740,22 @@ func (b *Balancer) GetStatus() *SystemStatus {
 	return b.status
 }
 
+// SetBudget changes a plant value of a static energy source (e.g., the
+// contracted grid budget). It takes effect on the next tick.
+func (b *Balancer) SetBudget(source, plant string, mw float64) error {
+	return b.energy.SetStatic(source, plant, mw)
+}
+
+// Calibration returns the preset calibration.
+func (b *Balancer) Calibration() *Calibration {
+	return b.calibration
+}
+
+// Scheduler returns the time-of-use scheduler.
+func (b *Balancer) Scheduler() *Scheduler {
+	return b.scheduler
+}
+
 // GetState returns the current balancer state.
 func (b *Balancer) GetState() BalancerState {
 	b.mu.RLock()
@@ -459,6 +769,7 @@ func (b *Balancer) runDiscoveryLoop(ctx context.Context) {
 	if _, err := b.DiscoverMinersOnNetworks(ctx, b.cfg.NetworkCIDRs); err != nil {
 		log.Printf("Initial discovery failed: %v", err)
 	}
+	b.pruneAPIKeys(ctx)
 
 	ticker := time.NewTicker(b.cfg.DiscoveryInterval)
 	defer ticker.Stop()
@@ -475,6 +786,31 @@ func (b *Balancer) runDiscoveryLoop(ctx context.Context) {
 	}
 }
 
+// pruneAPIKeys deletes API keys left on online miners by earlier runs, which
+// minted new keys when theirs were lost or rejected.
+func (b *Balancer) pruneAPIKeys(ctx context.Context) {
+	miners, err := b.repo.ListMiners(ctx)
+	if err != nil {
+		log.Printf("Failed to list miners for API key pruning: %v", err)
+		return
+	}
+
+	var total int
+	for _, m := range miners {
+		if !m.IsOnline {
+			continue
+		}
+		n, err := b.controller.PruneAPIKeys(ctx, m.IPAddress, m.FirmwareType)
+		if err != nil {
+			log.Printf("[%s] Failed to prune stale API keys: %v", m.IPAddress, err)
+		}
+		total += n
+	}
+	if total > 0 {
+		log.Printf("Pruned %d stale API keys", total)
+	}
+}
+
 // DiscoverMinersOnNetworks scans multiple networks and discovers VNish miners.
 func (b *Balancer) DiscoverMinersOnNetworks(ctx context.Context, networks []string) (int, error) {
 	if b.scanner == nil {
@@ -501,11 +837,11 @@ func (b *Balancer) DiscoverMinersOnNetworks(ctx context.Context, networks []stri
 		totalCount += count
 	}
 
-	log.Printf("Total discovered: %d VNish miners across %d networks", totalCount, len(networks))
+	log.Printf("Total discovered: %d manageable miners across %d networks", totalCount, len(networks))
 	return totalCount, nil
 }
 
-// discoverMinersOnNetwork scans a single network and discovers VNish miners.
+// discoverMinersOnNetwork scans a single network and discovers manageable miners.
 // This is an internal helper - use DiscoverMinersOnNetworks for multi-network scanning.
 func (b *Balancer) discoverMinersOnNetwork(ctx context.Context, network string) (int, error) {
 	log.Printf("Discovering miners on %s...", network)
@@ -517,8 +853,8 @@ func (b *Balancer) discoverMinersOnNetwork(ctx context.Context, network string)
 
 	var count int
 	for _, m := range result.Miners {
-		if m.FirmwareType != miner.FirmwareVNish {
-			continue // Only manage VNish miners
+		if !b.controller.Supports(m.FirmwareType) {
+			continue // Only manage firmwares with power control
 		}
 
 		if err := b.processDiscoveredMiner(ctx, m); err != nil {
@@ -528,20 +864,37 @@ func (b *Balancer) discoverMinersOnNetwork(ctx context.Context, network string)
 		count++
 	}
 
-	log.Printf("Discovered %d VNish miners", count)
+	log.Printf("Discovered %d manageable miners", count)
 	return count, nil
 }
 
+// unpoweredPresets returns the names of the presets without a power figure.
+func unpoweredPresets(presets []*ModelPreset) []string {
+	var names []string
+	for _, p := range presets {
+		if p.Watts <= 0 {
+			names = append(names, p.Name)
+		}
+	}
+	return names
+}
+
 // processDiscoveredMiner processes a discovered miner.
 func (b *Balancer) processDiscoveredMiner(ctx context.Context, dm discovery.DiscoveredMiner) error {
 	// Get miner info for more details
-	info, err := b.controller.GetMinerInfo(ctx, dm.IP)
+	fwType := string(dm.FirmwareType)
+	info, err := b.controller.GetMinerInfo(ctx, dm.IP, fwType)
 	if err != nil {
 		return fmt.Errorf("get info: %w", err)
 	}
 
-	// Get or create model
-	model, err := b.repo.GetOrCreateModel(ctx, info.Model)
+	// Get or create model. Presets differ per firmware, so non-VNish models
+	// are keyed with their firmware (VNish keeps the plain model name).
+	modelName := info.Model
+	if dm.FirmwareType != miner.FirmwareVNish {
+		modelName = fmt.Sprintf("%s (%s)", info.Model, fwType)
+	}
+	model, err := b.repo.GetOrCreateModel(ctx, modelName)
 	if err != nil {
 		return fmt.Errorf("get/create model: %w", err)
 	}
@@ -552,32 +905,55 @@ func (b *Balancer) processDiscoveredMiner(ctx context.Context, dm discovery.Disc
 		return fmt.Errorf("get model presets: %w", err)
 	}
 
-	if len(presets) == 0 {
+	// Presets saved without a power figure (stock work modes before they
+	// were rated) are filled in from the miner once a run, for firmwares
+	// calibration can't measure
+	var unpowered []string
+	if !b.controller.ReportsPower(fwType) {
+		unpowered = unpoweredPresets(presets)
+	}
+	if len(presets) == 0 || (len(unpowered) > 0 && !b.presetsChecked[model.ID]) {
+		b.presetsChecked[model.ID] = true
+
 		// Fetch presets from miner
-		vnishPresets, err := b.controller.GetAvailablePresets(ctx, dm.IP)
+		profiles, err := b.controller.GetAvailablePresets(ctx, dm.IP, fwType)
 		if err != nil {
 			log.Printf("Failed to get presets for model %s: %v", model.Name, err)
 		} else {
-			for i, p := range vnishPresets {
-				watts, hashrate := parsePresetPretty(p.Pretty)
+			known := make(map[string]bool)
+			for _, p := range presets {
+				known[p.Name] = p.Watts > 0
+			}
+			unpowered = nil
+			for i, p := range profiles {
+				if known[p.Name] {
+					continue // Keep power figures set by the operator
+				}
+				if p.Watts <= 0 {
+					unpowered = append(unpowered, p.Name)
+				}
 				preset := &ModelPreset{
 					ModelID:           model.ID,
 					Name:              p.Name,
-					Watts:             watts,
-					HashrateTH:        hashrate,
-					DisplayName:       p.Pretty,
-					RequiresModdedPSU: p.ModdedPSURequired,
+					Watts:             p.Watts,
+					HashrateTH:        p.HashrateTH,
+					DisplayName:       p.DisplayName,
+					RequiresModdedPSU: p.RequiresModdedPSU,
 					SortOrder:         i,
 				}
 				if err := b.repo.UpsertModelPreset(ctx, preset); err != nil {
 					log.Printf("Failed to save preset %s: %v", p.Name, err)
 				}
 			}
+			if len(unpowered) > 0 && !b.controller.ReportsPower(fwType) {
+				log.Printf("Model %s has presets without power figures (%s): the strategies skip them until their watts are set on the dashboard",
+					model.Name, strings.Join(unpowered, ", "))
+			}
 		}
 	}
 
 	// Get current preset
-	currentPresetName, err := b.controller.GetCurrentPreset(ctx, dm.IP)
+	currentPresetName, err := b.controller.GetCurrentPreset(ctx, dm.IP, fwType)
 	if err != nil {
 		return fmt.Errorf("get current preset: %w", err)
 	}
@@ -592,7 +968,7 @@ func (b *Balancer) processDiscoveredMiner(ctx context.Context, dm discovery.Disc
 	// Upsert miner - use MAC from discovery or from info
 	macAddr := dm.MAC
 	if macAddr == "" {
-		macAddr = info.System.NetworkStatus.MAC
+		macAddr = info.MAC
 	}
 
 	// Skip miners without MAC address - they'll be added later if MAC becomes available
@@ -605,7 +981,7 @@ func (b *Balancer) processDiscoveredMiner(ctx context.Context, dm discovery.Disc
 		MACAddress:      macAddr,
 		IPAddress:       dm.IP,
 		ModelID:         &model.ID,
-		FirmwareType:    "vnish",
+		FirmwareType:    fwType,
 		CurrentPresetID: currentPresetID,
 		IsOnline:        true, // Miner was just discovered/reached
 		LastSeen:        func() *time.Time { t := time.Now(); return &t }(),
@@ -615,29 +991,15 @@ func (b *Balancer) processDiscoveredMiner(ctx context.Context, dm discovery.Disc
 	if _, err := b.repo.UpsertMinerWithBalanceConfig(ctx, m); err != nil {
 		return fmt.Errorf("upsert miner with balance config: %w", err)
 	}
-	log.Printf("DEBUG: Miner %s (MAC: %s) upserted with ID: %d", dm.IP, m.MACAddress, m.ID)
 
-	return nil
-}
-
-// parsePresetPretty extracts watts and hashrate from a pretty string like "1100 watt ~ 53 TH".
-func parsePresetPretty(pretty string) (int, float64) {
-	// Try to extract watts from preset name pattern "XXXX watt ~ YY TH"
-	re := regexp.MustCompile(`(\d+)\s*watt.*?~\s*([\d.]+)\s*TH`)
-	matches := re.FindStringSubmatch(pretty)
-	if len(matches) >= 3 {
-		watts, _ := strconv.Atoi(matches[1])
-		hashrate, _ := strconv.ParseFloat(matches[2], 64)
-		return watts, hashrate
-	}
-
-	// Fallback: try to get just the number at the start
-	re2 := regexp.MustCompile(`^(\d+)`)
-	matches2 := re2.FindStringSubmatch(strings.TrimSpace(pretty))
-	if len(matches2) >= 2 {
-		watts, _ := strconv.Atoi(matches2[1])
-		return watts, 0
+	// Known miners keep their recorded preset for the reconciler to check.
+	// Without it, discovery keeps the preset in sync instead.
+	if b.cfg.ReconcileInterval <= 0 && currentPresetID != nil {
+		if err := b.repo.UpdateMinerPreset(ctx, m.ID, *currentPresetID); err != nil {
+			log.Printf("Failed to update miner preset: %v", err)
+		}
 	}
+	log.Printf("DEBUG: Miner %s (MAC: %s) upserted with ID: %d", dm.IP, m.MACAddress, m.ID)
 
-	return 0, 0
+	return nil
 }
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/discovery"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
+)
+
+// testTiming makes reboots, mining restarts and auto-tuning near instant.
+var testTiming = minersim.Timing{
+	Boot:    50 * time.Millisecond,
+	Restart: 10 * time.Millisecond,
+	Tune:    10 * time.Millisecond,
+}
+
+// newTestBalancer creates a balancer with its DB in a temp dir, managing
+// simulated miners added with addMiner.
+func newTestBalancer(t *testing.T, cfg *Config) *Balancer {
+	t.Helper()
+
+	repo, err := NewRepository(filepath.Join(t.TempDir(), "balancer.db"))
+	if err != nil {
+		t.Fatalf("NewRepository: %v", err)
+	}
+	t.Cleanup(func() { repo.Close() })
+
+	controller := NewController(
+		vnish.NewAuthManager(cfg.VNishPassword),
+		stock.NewDigestAuthWithCredentials(cfg.StockUsername, cfg.StockPassword),
+		whatsminer.NewAuthManager(cfg.WhatsminerPassword),
+		cfg)
+	calibration := NewCalibration(repo, cfg)
+	strategy, err := NewStrategy(cfg.Strategy, repo, calibration, cfg)
+	if err != nil {
+		t.Fatalf("NewStrategy: %v", err)
+	}
+	return NewBalancer(repo, nil, controller, strategy, calibration, nil, nil, nil, cfg)
+}
+
+// testMiner is a simulated miner managed by a test balancer.
+type testMiner struct {
+	sim  *minersim.Miner
+	host string
+	dm   discovery.DiscoveredMiner
+	id   int64
+}
+
+// addMiner serves the n-th simulated miner of a firmware, sets it on preset
+// (a work mode for stock), discovers it and enables it for balancing between
+// the lowest and highest powered presets of its model.
+func addMiner(t *testing.T, b *Balancer, fw miner.FirmwareType, n int, preset string) *testMiner {
+	t.Helper()
+	ctx := context.Background()
+
+	spec := minersim.MinerSpec{
+		IP:       "127.0.0.1",
+		Firmware: fw,
+		MAC:      fmt.Sprintf("02:00:7f:00:00:%02x", n),
+		Hostname: fmt.Sprintf("sim-%s-%03d", fw, n),
+		Username: b.cfg.StockUsername,
+		Password: b.cfg.VNishPassword,
+	}
+	if fw == miner.FirmwareStock {
+		spec.Password = b.cfg.StockPassword
+	}
+	sim := minersim.NewMiner(spec, testTiming, int64(n))
+	srv := httptest.NewServer(sim.Handler())
+	t.Cleanup(srv.Close)
+
+	tm := &testMiner{sim: sim, host: strings.TrimPrefix(srv.URL, "http://")}
+	tm.dm = discovery.DiscoveredMiner{IP: tm.host, MAC: spec.MAC, FirmwareType: fw}
+	setSimPreset(t, b, tm, preset)
+
+	if err := b.processDiscoveredMiner(ctx, tm.dm); err != nil {
+		t.Fatalf("processDiscoveredMiner: %v", err)
+	}
+	m, err := b.repo.GetMinerByMAC(ctx, spec.MAC)
+	if err != nil {
+		t.Fatalf("GetMinerByMAC: %v", err)
+	}
+	tm.id = m.ID
+
+	presets, err := b.repo.GetModelPresets(ctx, *m.ModelID)
+	if err != nil {
+		t.Fatalf("GetModelPresets: %v", err)
+	}
+	var lowest, highest *ModelPreset
+	for _, p := range presets {
+		if p.Watts <= 0 {
+			continue
+		}
+		if lowest == nil || p.Watts < lowest.Watts {
+			lowest = p
+		}
+		if highest == nil || p.Watts > highest.Watts {
+			highest = p
+		}
+	}
+	if err := b.repo.UpdateModelLimits(ctx, *m.ModelID, &lowest.ID, &highest.ID); err != nil {
+		t.Fatalf("UpdateModelLimits: %v", err)
+	}
+	if err := b.repo.UpdateBalanceConfig(ctx, &BalanceConfig{MinerID: m.ID, Enabled: true, Priority: 50}); err != nil {
+		t.Fatalf("UpdateBalanceConfig: %v", err)
+	}
+	return tm
+}
+
+// setSimPreset changes the preset of a simulated miner behind the balancer's
+// back, restarting it if the firmware needs to.
+func setSimPreset(t *testing.T, b *Balancer, tm *testMiner, preset string) {
+	t.Helper()
+	ctx := context.Background()
+	fw := string(tm.dm.FirmwareType)
+
+	restart, err := b.controller.SetPreset(ctx, tm.host, fw, preset)
+	if err != nil {
+		t.Fatalf("SetPreset %s: %v", preset, err)
+	}
+	if restart != RestartNone {
+		if err := b.controller.Restart(ctx, tm.host, fw, restart); err != nil {
+			t.Fatalf("Restart: %v", err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		current, err := b.controller.GetCurrentPreset(ctx, tm.host, fw)
+		if err == nil && current == preset {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("miner on %q (err: %v), want %q", current, err, preset)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// manageable returns a miner as the strategies see it.
+func manageable(t *testing.T, b *Balancer, id int64) *MinerWithContext {
+	t.Helper()
+
+	miners, err := b.repo.GetManageableMiners(context.Background())
+	if err != nil {
+		t.Fatalf("GetManageableMiners: %v", err)
+	}
+	for _, m := range miners {
+		if m.Miner.ID == id {
+			return m
+		}
+	}
+	t.Fatalf("miner %d is not manageable", id)
+	return nil
+}
+
+// modelPreset returns a preset of a managed miner's model.
+func modelPreset(t *testing.T, b *Balancer, m *MinerWithContext, name string) *ModelPreset {
+	t.Helper()
+
+	p, err := b.repo.GetPresetByModelAndName(context.Background(), m.Model.ID, name)
+	if err != nil {
+		t.Fatalf("GetPresetByModelAndName %s: %v", name, err)
+	}
+	return p
+}
+
+func TestDiscoveryKeepsDriftForReconcile(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.ReconcileReassert = true
+	b := newTestBalancer(t, cfg)
+
+	tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+	b.reconcilePresets(ctx)
+
+	// Changed outside the balancer, then rediscovered before the reconciler runs
+	setSimPreset(t, b, tm, "3200")
+	if err := b.processDiscoveredMiner(ctx, tm.dm); err != nil {
+		t.Fatalf("processDiscoveredMiner: %v", err)
+	}
+	if m := manageable(t, b, tm.id); m.CurrentPreset.Name != "2100" {
+		t.Fatalf("discovery recorded %s, want 2100 left for the reconciler", m.CurrentPreset.Name)
+	}
+
+	b.reconcilePresets(ctx)
+
+	drifts, err := b.repo.ListPresetDrifts(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListPresetDrifts: %v", err)
+	}
+	if len(drifts) != 1 {
+		t.Fatalf("got %d drifts, want 1", len(drifts))
+	}
+	d := drifts[0]
+	if d.ExpectedPreset != "2100" || d.ActualPreset != "3200" || d.Origin != DriftUnknown || !d.Reasserted {
+		t.Errorf("drift = %+v", d)
+	}
+	logs, err := b.repo.GetRecentChangeLogs(ctx, 10)
+	if err != nil {
+		t.Fatalf("GetRecentChangeLogs: %v", err)
+	}
+	if len(logs) != 1 || logs[0].Reason != "drift" || logs[0].ToPreset != "3200" {
+		t.Fatalf("change log = %+v, want one drift to 3200", logs)
+	}
+
+	b.processReasserts(ctx, 50)
+
+	current, err := b.controller.GetCurrentPreset(ctx, tm.host, string(tm.dm.FirmwareType))
+	if err != nil {
+		t.Fatalf("GetCurrentPreset: %v", err)
+	}
+	if current != "2100" {
+		t.Errorf("miner on %s after reassert, want 2100", current)
+	}
+	if m := manageable(t, b, tm.id); m.CurrentPreset.Name != "2100" {
+		t.Errorf("recorded preset %s after reassert, want 2100", m.CurrentPreset.Name)
+	}
+}
+
+func TestDiscoverySyncsPresetsWithoutReconcile(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.ReconcileInterval = 0
+	b := newTestBalancer(t, cfg)
+
+	tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+	setSimPreset(t, b, tm, "3200")
+	if err := b.processDiscoveredMiner(ctx, tm.dm); err != nil {
+		t.Fatalf("processDiscoveredMiner: %v", err)
+	}
+	if m := manageable(t, b, tm.id); m.CurrentPreset.Name != "3200" {
+		t.Errorf("recorded preset %s, want 3200", m.CurrentPreset.Name)
+	}
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+)
+
+// PresetStats summarizes the measured power and hashrate of a preset, across a
+// model's miners or for a single miner.
+type PresetStats struct {
+	PresetID int64   `json:"preset_id"`
+	MinerID  int64   `json:"miner_id,omitempty"` // 0 for model stats
+	Samples  int     `json:"samples"`
+	MedianW  int     `json:"median_w"`
+	P90W     int     `json:"p90_w"`
+	MedianTH float64 `json:"median_th"`
+	P90TH    float64 `json:"p90_th"`
+}
+
+// minerPreset identifies a preset on one miner.
+type minerPreset struct {
+	minerID  int64
+	presetID int64
+}
+
+// Calibration learns the real power and hashrate of each preset from measured
+// samples. The firmware's nameplate values are often 5-15% off, so the strategy
+// uses a miner's own stats when it has enough samples, then its model's, and
+// only then the nameplate.
+type Calibration struct {
+	repo      *Repository
+	cfg       *Config
+	summaries summarySource // Harvested summaries to sample (nil = none)
+
+	mu     sync.RWMutex
+	models map[int64]*PresetStats // By preset ID (presets belong to one model)
+	miners map[minerPreset]*PresetStats
+}
+
+// NewCalibration creates a calibration with no stats; call Refresh to load them.
+func NewCalibration(repo *Repository, cfg *Config) *Calibration {
+	return &Calibration{
+		repo:   repo,
+		cfg:    cfg,
+		models: make(map[int64]*PresetStats),
+		miners: make(map[minerPreset]*PresetStats),
+	}
+}
+
+// Record stores a sample. It's reflected in the stats on the next Refresh.
+func (c *Calibration) Record(ctx context.Context, s *PresetSample) error {
+	if s.SampledAt.IsZero() {
+		s.SampledAt = time.Now()
+	}
+	return c.repo.InsertPresetSample(ctx, s)
+}
+
+// Refresh drops samples older than the window and recomputes the stats.
+func (c *Calibration) Refresh(ctx context.Context) error {
+	cutoff := time.Now().Add(-c.cfg.CalibrationWindow)
+	if _, err := c.repo.DeletePresetSamplesBefore(ctx, cutoff); err != nil {
+		return fmt.Errorf("prune samples: %w", err)
+	}
+
+	samples, err := c.repo.GetPresetSamplesSince(ctx, cutoff)
+	if err != nil {
+		return fmt.Errorf("get samples: %w", err)
+	}
+
+	byPreset := make(map[int64][]*PresetSample)
+	byMiner := make(map[minerPreset][]*PresetSample)
+	for _, s := range samples {
+		byPreset[s.PresetID] = append(byPreset[s.PresetID], s)
+		key := minerPreset{s.MinerID, s.PresetID}
+		byMiner[key] = append(byMiner[key], s)
+	}
+
+	models := make(map[int64]*PresetStats, len(byPreset))
+	for id, ss := range byPreset {
+		models[id] = summarize(ss)
+	}
+	miners := make(map[minerPreset]*PresetStats, len(byMiner))
+	for key, ss := range byMiner {
+		stats := summarize(ss)
+		stats.MinerID = key.minerID
+		miners[key] = stats
+	}
+
+	c.mu.Lock()
+	c.models = models
+	c.miners = miners
+	c.mu.Unlock()
+	return nil
+}
+
+// ModelStats returns the stats of a preset across its model's miners, or nil
+// if it has no samples.
+func (c *Calibration) ModelStats(presetID int64) *PresetStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.models[presetID]
+}
+
+// Stats returns the stats used for a preset on a miner: the miner's own if it
+// has enough samples, otherwise the model's if it has, otherwise nil.
+func (c *Calibration) Stats(minerID, presetID int64) *PresetStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if s := c.miners[minerPreset{minerID, presetID}]; s != nil && s.Samples >= c.cfg.CalibrationMinSamples {
+		return s
+	}
+	if s := c.models[presetID]; s != nil && s.Samples >= c.cfg.CalibrationMinSamples {
+		return s
+	}
+	return nil
+}
+
+// Apply returns the preset with its calibrated watts and hashrate for a miner.
+// The preset is returned unchanged if it isn't calibrated.
+func (c *Calibration) Apply(minerID int64, p *ModelPreset) *ModelPreset {
+	s := c.Stats(minerID, p.ID)
+	if s == nil {
+		return p
+	}
+	calibrated := *p
+	calibrated.Watts = s.MedianW
+	calibrated.HashrateTH = s.MedianTH
+	return &calibrated
+}
+
+// All returns the model and miner stats, ordered by preset then miner.
+func (c *Calibration) All() (models, miners []*PresetStats) {
+	c.mu.RLock()
+	for _, s := range c.models {
+		models = append(models, s)
+	}
+	for _, s := range c.miners {
+		miners = append(miners, s)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(models, func(i, j int) bool { return models[i].PresetID < models[j].PresetID })
+	sort.Slice(miners, func(i, j int) bool {
+		if miners[i].PresetID != miners[j].PresetID {
+			return miners[i].PresetID < miners[j].PresetID
+		}
+		return miners[i].MinerID < miners[j].MinerID
+	})
+	return models, miners
+}
+
+// summarize computes the stats of a preset's samples.
+func summarize(samples []*PresetSample) *PresetStats {
+	watts := make([]float64, len(samples))
+	hashrates := make([]float64, len(samples))
+	for i, s := range samples {
+		watts[i] = float64(s.Watts)
+		hashrates[i] = s.HashrateTH
+	}
+	sort.Float64s(watts)
+	sort.Float64s(hashrates)
+
+	return &PresetStats{
+		PresetID: samples[0].PresetID,
+		Samples:  len(samples),
+		MedianW:  int(math.Round(median(watts))),
+		P90W:     int(math.Round(percentile(watts, 90))),
+		MedianTH: median(hashrates),
+		P90TH:    percentile(hashrates, 90),
+	}
+}
+
+// median returns the median of sorted values.
+func median(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+// percentile returns the nearest-rank percentile of sorted values.
+func percentile(sorted []float64, p float64) float64 {
+	rank := int(math.Ceil(p/100*float64(len(sorted)))) - 1
+	return sorted[max(0, min(rank, len(sorted)-1))]
+}
+
+// =============================================================================
+// Sampling
+// =============================================================================
+
+// summarySource lists the miner summaries harvested by data-harvest, with the
+// preset each was taken on.
+type summarySource interface {
+	ListPresetSummaries(ctx context.Context) ([]*database.PresetSummary, error)
+}
+
+// WithSummaries samples the summaries harvested into src.
+func (c *Calibration) WithSummaries(src summarySource) *Calibration {
+	c.summaries = src
+	return c
+}
+
+// runCalibrationLoop periodically samples the summaries data-harvest
+// collected since the last run.
+func (b *Balancer) runCalibrationLoop(ctx context.Context) {
+	if err := b.calibration.Refresh(ctx); err != nil {
+		log.Printf("Failed to load calibration: %v", err)
+	}
+	if b.cfg.CalibrationInterval <= 0 || b.calibration.summaries == nil {
+		return
+	}
+
+	// Summaries sampled before a restart aren't sampled again
+	sampled, err := b.repo.GetLatestSampleTimes(ctx, SampleSummary)
+	if err != nil {
+		log.Printf("Calibration: failed to get sampled summaries: %v", err)
+		sampled = make(map[int64]time.Time)
+	}
+
+	ticker := time.NewTicker(b.cfg.CalibrationInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.sampleSummaries(ctx, sampled)
+			if err := b.calibration.Refresh(ctx); err != nil {
+				log.Printf("Failed to refresh calibration: %v", err)
+			}
+		}
+	}
+}
+
+// sampleSummaries records a sample of every harvested summary newer than the
+// last one sampled for its miner (sampled, by miner, updated). Summaries of
+// miners with a change in flight, or harvested before their last change
+// settled, are skipped, as are those of miners not hashing.
+func (b *Balancer) sampleSummaries(ctx context.Context, sampled map[int64]time.Time) {
+	summaries, err := b.calibration.summaries.ListPresetSummaries(ctx)
+	if err != nil {
+		log.Printf("Calibration: failed to list harvested summaries: %v", err)
+		return
+	}
+	miners, err := b.repo.ListMiners(ctx)
+	if err != nil {
+		log.Printf("Calibration: failed to list miners: %v", err)
+		return
+	}
+	changing, err := b.changingMiners(ctx)
+	if err != nil {
+		log.Printf("Calibration: failed to get pending changes: %v", err)
+		return
+	}
+
+	byMAC := make(map[string]*Miner, len(miners))
+	for _, m := range miners {
+		byMAC[strings.ToLower(m.MACAddress)] = m
+	}
+
+	var count int
+	for _, s := range summaries {
+		m := byMAC[strings.ToLower(s.MACAddress)]
+		hashrateTH, ok := toTH(s.HashrateInstant, s.HRMeasure)
+		if m == nil || m.ModelID == nil || changing[m.ID] || !ok ||
+			s.PowerConsumption <= 0 || hashrateTH <= 0 || !s.UpdatedAt.After(sampled[m.ID]) {
+			continue
+		}
+
+		last, err := b.repo.GetLastPresetChange(ctx, m.ID)
+		if err == nil && s.UpdatedAt.Before(last.IssuedAt.Add(b.cfg.SettleTime)) {
+			continue
+		}
+		preset, err := b.repo.GetPresetByModelAndName(ctx, *m.ModelID, s.Preset)
+		if err != nil {
+			continue
+		}
+
+		err = b.calibration.Record(ctx, &PresetSample{
+			MinerID:    m.ID,
+			PresetID:   preset.ID,
+			Watts:      s.PowerConsumption,
+			HashrateTH: hashrateTH,
+			Source:     SampleSummary,
+			SampledAt:  s.UpdatedAt,
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to record calibration sample: %v", m.IPAddress, err)
+			continue
+		}
+		sampled[m.ID] = s.UpdatedAt
+		count++
+	}
+
+	log.Printf("Calibration: sampled %d harvested summaries", count)
+}
+
+// toTH converts a hashrate to TH/s from its unit, returning false for units it
+// doesn't know. Hashrates without a unit are in GH/s, as VNish reports them.
+func toTH(hashrate float64, unit string) (float64, bool) {
+	switch strings.ToUpper(strings.TrimSpace(unit)) {
+	case "MH/S":
+		return hashrate / 1e6, true
+	case "", "GH/S":
+		return hashrate / 1000, true
+	case "TH/S":
+		return hashrate, true
+	case "PH/S":
+		return hashrate * 1000, true
+	}
+	return 0, false
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// harvestSummary records a summary of a miner on preset in a data-harvest
+// database, as data-harvest would.
+func harvestSummary(t *testing.T, harvest *database.SQLiteRepository, minerID int64, preset string, watts int, hashrateGH float64) {
+	t.Helper()
+	ctx := context.Background()
+
+	err := harvest.UpsertMinerSummary(ctx, &database.MinerSummary{
+		MinerID: minerID, HashrateInstant: hashrateGH, PowerConsumption: watts})
+	if err != nil {
+		t.Fatalf("UpsertMinerSummary: %v", err)
+	}
+	if err := harvest.UpsertAutotunePreset(ctx, &database.AutotunePreset{MinerID: minerID, Name: preset, IsCurrent: true}); err != nil {
+		t.Fatalf("UpsertAutotunePreset: %v", err)
+	}
+}
+
+func TestSampleSummaries(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	b := newTestBalancer(t, cfg)
+	tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+	m := manageable(t, b, tm.id)
+
+	harvest, err := database.NewSQLiteRepository(filepath.Join(t.TempDir(), "powerhive.db"))
+	if err != nil {
+		t.Fatalf("NewSQLiteRepository: %v", err)
+	}
+	t.Cleanup(func() { harvest.Close() })
+	b.calibration.WithSummaries(harvest)
+
+	hm := &database.Miner{MACAddress: tm.dm.MAC, IPAddress: tm.host, FirmwareType: miner.FirmwareVNish,
+		HRMeasure: "GH/s", IsOnline: true}
+	if err := harvest.CreateMiner(ctx, hm); err != nil {
+		t.Fatalf("CreateMiner: %v", err)
+	}
+	harvestSummary(t, harvest, hm.ID, "2100", 2150, 95_000)
+
+	samples := func() []*PresetSample {
+		t.Helper()
+		s, err := b.repo.GetPresetSamplesSince(ctx, time.Now().Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("GetPresetSamplesSince: %v", err)
+		}
+		return s
+	}
+
+	sampled := make(map[int64]time.Time)
+	b.sampleSummaries(ctx, sampled)
+	got := samples()
+	if len(got) != 1 {
+		t.Fatalf("got %d samples, want 1", len(got))
+	}
+	s := got[0]
+	if s.MinerID != tm.id || s.PresetID != modelPreset(t, b, m, "2100").ID || s.Source != SampleSummary ||
+		s.Watts != 2150 || s.HashrateTH != 95 {
+		t.Errorf("sample = %+v, want 2150 W and 95 TH/s on 2100", s)
+	}
+
+	// A summary is sampled once
+	b.sampleSummaries(ctx, sampled)
+	if got := samples(); len(got) != 1 {
+		t.Errorf("got %d samples after sampling again, want 1", len(got))
+	}
+
+	// Nor is one harvested before the balancer's last change settled
+	err = b.repo.InsertChangeLog(ctx, &ChangeLog{MinerID: &tm.id, MinerIP: tm.host, FromPreset: "2100",
+		ToPreset: "1700", Reason: "reduce", IssuedAt: time.Now(), Success: true})
+	if err != nil {
+		t.Fatalf("InsertChangeLog: %v", err)
+	}
+	harvestSummary(t, harvest, hm.ID, "1700", 1750, 80_000)
+	b.sampleSummaries(ctx, sampled)
+	if got := samples(); len(got) != 1 {
+		t.Errorf("got %d samples, want the unsettled summary skipped", len(got))
+	}
+}
+
+func TestToTH(t *testing.T) {
+	tests := []struct {
+		hashrate float64
+		unit     string
+		want     float64
+		wantOK   bool
+	}{
+		{95_000_000, "MH/s", 95, true},
+		{95_000, "GH/s", 95, true},
+		{95_000, "", 95, true},
+		{95, "TH/s", 95, true},
+		{0.095, "PH/s", 95, true},
+		{95, "H/s", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := toTH(tt.hashrate, tt.unit)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("toTH(%v, %q) = %v, %v, want %v, %v", tt.hashrate, tt.unit, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
@@ -18,12 +20,27 @@ type Config struct {
 	AggregatorURL    string
 	AggregatorAPIKey string
 
+	// Energy sources (optional JSON file; default: the aggregator above)
+	EnergySourcesFile string
+
+	// Power domain tree with per-domain caps (optional JSON file)
+	DomainsFile string
+
 	// Network discovery (comma-separated CIDRs supported)
 	NetworkCIDRs      []string
 	DiscoveryInterval time.Duration
 
-	// VNish authentication
-	VNishPassword string
+	// Miner authentication
+	VNishPassword      string
+	StockUsername      string
+	StockPassword      string
+	WhatsminerPassword string
+
+	// Key file encrypting persisted API keys (default: DBPath + ".key")
+	CredentialKeyFile string
+
+	// Per-miner credential sets (optional, see pkg/credentials)
+	CredentialsFile string
 
 	// Margin thresholds (percentage of generation)
 	EmergencyMargin float64 // Below this = EMERGENCY mode
@@ -38,6 +55,48 @@ type Config struct {
 	CooldownDuration time.Duration // Per-miner cooldown after change
 	SettleTime       time.Duration // Time for preset change to take effect
 
+	// Data quality
+	MaxDataAge       time.Duration // Readings measured longer ago are rejected
+	MinConfidence    float64       // Readings with a lower trust score are rejected
+	DataLossReadings int           // Consecutive bad readings before DATA_LOSS
+	DataLossPolicy   string        // What to do in DATA_LOSS: hold, step-down or stop
+
+	// Settlement verification
+	VerifyTolerance float64 // Max difference between measured and expected delta (percent)
+	VerifyRetries   int     // Retries of a change that didn't take before locking the miner
+
+	// Preset calibration
+	CalibrationInterval   time.Duration // How often to sample harvested summaries (0 = only on verification)
+	CalibrationWindow     time.Duration // Samples older than this are dropped
+	CalibrationMinSamples int           // Samples needed before a calibrated value replaces the nameplate
+	HarvestDBPath         string        // data-harvest database whose summaries are sampled ("" = none)
+
+	// Miner selection: efficiency, priority, fairness or knapsack
+	Strategy string
+
+	// Stop mining on miners already at their minimum preset during EMERGENCY
+	ParkMiners bool
+
+	// Timezone of schedule times (default: the host's)
+	ScheduleLocation *time.Location
+
+	// Demand-response API keys by caller name (API disabled if empty)
+	DemandResponseKeys map[string]string
+
+	// Load ramp limits in MW per minute (0 = unlimited)
+	RampUpMWPerMin        float64
+	RampDownMWPerMin      float64
+	RampEmergencyOverride bool // Emergency, data-loss and domain-cap reductions skip the down limit
+
+	// Restarts needed to apply a preset
+	RestartPolicy   string        // auto, restart or manual
+	RestartSpacing  time.Duration // Time between restarts across the fleet
+	RestartDuration time.Duration // Time for a restarted miner to hash again
+
+	// Preset reconciliation against the miners
+	ReconcileInterval time.Duration // How often to read every miner's preset (0 = never)
+	ReconcileReassert bool          // Move miners changed outside the balancer to more power back
+
 	// Limits
 	MaxParallelEmergency int // Max parallel changes in emergency
 
@@ -48,23 +107,43 @@ type Config struct {
 // DefaultConfig returns configuration with default values.
 func DefaultConfig() *Config {
 	return &Config{
-		DBPath:               "power-balancer.db",
-		AggregatorURL:        "https://energy-aggregator.fly.dev/data/latest",
-		AggregatorAPIKey:     "",
-		NetworkCIDRs:         []string{},
-		DiscoveryInterval:    5 * time.Minute,
-		VNishPassword:        "admin",
-		EmergencyMargin:      5.0,
-		CriticalMargin:       10.0,
-		SafeMargin:           15.0,
-		RecoveryMargin:       20.0,
-		PollInterval:         5 * time.Second,
-		ChangeSpacing:        10 * time.Second,
-		RecoverySpacing:      30 * time.Second,
-		CooldownDuration:     10 * time.Minute,
-		SettleTime:           5 * time.Minute,
-		MaxParallelEmergency: 5,
-		DashboardPort:        8081,
+		DBPath:                "power-balancer.db",
+		AggregatorURL:         "https://energy-aggregator.fly.dev/data/latest",
+		AggregatorAPIKey:      "",
+		NetworkCIDRs:          []string{},
+		DiscoveryInterval:     5 * time.Minute,
+		VNishPassword:         "admin",
+		StockUsername:         "root",
+		StockPassword:         "root",
+		WhatsminerPassword:    "admin",
+		EmergencyMargin:       5.0,
+		CriticalMargin:        10.0,
+		SafeMargin:            15.0,
+		RecoveryMargin:        20.0,
+		PollInterval:          5 * time.Second,
+		ChangeSpacing:         10 * time.Second,
+		RecoverySpacing:       30 * time.Second,
+		CooldownDuration:      10 * time.Minute,
+		SettleTime:            5 * time.Minute,
+		MaxDataAge:            2 * time.Minute,
+		MinConfidence:         0.5,
+		DataLossReadings:      3,
+		DataLossPolicy:        DataLossStepDown,
+		VerifyTolerance:       25.0,
+		VerifyRetries:         1,
+		CalibrationInterval:   10 * time.Minute,
+		CalibrationWindow:     7 * 24 * time.Hour,
+		CalibrationMinSamples: 5,
+		Strategy:              StrategyEfficiency,
+		ParkMiners:            true,
+		ScheduleLocation:      time.Local,
+		RampEmergencyOverride: true,
+		RestartPolicy:         RestartPolicyAuto,
+		RestartSpacing:        30 * time.Second,
+		RestartDuration:       3 * time.Minute,
+		ReconcileInterval:     15 * time.Minute,
+		MaxParallelEmergency:  5,
+		DashboardPort:         8081,
 	}
 }
 
@@ -84,6 +163,12 @@ func LoadConfig() *Config {
 	if v := os.Getenv("AGGREGATOR_API_KEY"); v != "" {
 		cfg.AggregatorAPIKey = v
 	}
+	if v := os.Getenv("ENERGY_SOURCES_FILE"); v != "" {
+		cfg.EnergySourcesFile = v
+	}
+	if v := os.Getenv("DOMAINS_FILE"); v != "" {
+		cfg.DomainsFile = v
+	}
 	if v := os.Getenv("NETWORK_CIDR"); v != "" {
 		// Parse comma-separated CIDRs
 		cidrs := strings.Split(v, ",")
@@ -102,6 +187,21 @@ func LoadConfig() *Config {
 	if v := os.Getenv("VNISH_PASSWORD"); v != "" {
 		cfg.VNishPassword = v
 	}
+	if v := os.Getenv("STOCK_USERNAME"); v != "" {
+		cfg.StockUsername = v
+	}
+	if v := os.Getenv("STOCK_PASSWORD"); v != "" {
+		cfg.StockPassword = v
+	}
+	if v := os.Getenv("WHATSMINER_PASSWORD"); v != "" {
+		cfg.WhatsminerPassword = v
+	}
+	if v := os.Getenv("CREDENTIAL_KEY_FILE"); v != "" {
+		cfg.CredentialKeyFile = v
+	}
+	if v := os.Getenv("CREDENTIALS_FILE"); v != "" {
+		cfg.CredentialsFile = v
+	}
 	if v := os.Getenv("EMERGENCY_MARGIN"); v != "" {
 		if f, err := strconv.ParseFloat(v, 64); err == nil {
 			cfg.EmergencyMargin = f
@@ -147,6 +247,119 @@ func LoadConfig() *Config {
 			cfg.SettleTime = d
 		}
 	}
+	if v := os.Getenv("DATA_MAX_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			cfg.MaxDataAge = d
+		}
+	}
+	if v := os.Getenv("MIN_CONFIDENCE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.MinConfidence = f
+		}
+	}
+	if v := os.Getenv("DATA_LOSS_READINGS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.DataLossReadings = n
+		}
+	}
+	if v := os.Getenv("DATA_LOSS_POLICY"); v != "" {
+		switch v {
+		case DataLossHold, DataLossStepDown, DataLossStop:
+			cfg.DataLossPolicy = v
+		}
+	}
+	if v := os.Getenv("VERIFY_TOLERANCE"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			cfg.VerifyTolerance = f
+		}
+	}
+	if v := os.Getenv("VERIFY_RETRIES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.VerifyRetries = n
+		}
+	}
+	if v := os.Getenv("CALIBRATION_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.CalibrationInterval = d
+		}
+	}
+	if v := os.Getenv("CALIBRATION_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.CalibrationWindow = d
+		}
+	}
+	if v := os.Getenv("CALIBRATION_MIN_SAMPLES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.CalibrationMinSamples = n
+		}
+	}
+	if v := os.Getenv("HARVEST_DB"); v != "" {
+		cfg.HarvestDBPath = v
+	}
+	if v := os.Getenv("STRATEGY"); v != "" {
+		cfg.Strategy = v
+	}
+	if v := os.Getenv("PARK_MINERS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.ParkMiners = b
+		}
+	}
+	if v := os.Getenv("SCHEDULE_TIMEZONE"); v != "" {
+		if loc, err := time.LoadLocation(v); err == nil {
+			cfg.ScheduleLocation = loc
+		}
+	}
+	if v := os.Getenv("DEMAND_RESPONSE_KEYS"); v != "" {
+		// Parse comma-separated name:key pairs
+		cfg.DemandResponseKeys = make(map[string]string)
+		for _, pair := range strings.Split(v, ",") {
+			name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if ok && name != "" && key != "" {
+				cfg.DemandResponseKeys[name] = key
+			}
+		}
+	}
+	if v := os.Getenv("RAMP_UP_MW_PER_MIN"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.RampUpMWPerMin = f
+		}
+	}
+	if v := os.Getenv("RAMP_DOWN_MW_PER_MIN"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.RampDownMWPerMin = f
+		}
+	}
+	if v := os.Getenv("RAMP_EMERGENCY_OVERRIDE"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.RampEmergencyOverride = b
+		}
+	}
+	if v := os.Getenv("RESTART_POLICY"); v != "" {
+		switch v {
+		case RestartPolicyAuto, RestartPolicyRestart, RestartPolicyManual:
+			cfg.RestartPolicy = v
+		}
+	}
+	if v := os.Getenv("RESTART_SPACING"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.RestartSpacing = d
+		}
+	}
+	if v := os.Getenv("RESTART_DURATION"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.RestartDuration = d
+		}
+	}
+	if v := os.Getenv("RECONCILE_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.ReconcileInterval = d
+		}
+	}
+	if v := os.Getenv("RECONCILE_REASSERT"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			cfg.ReconcileReassert = b
+		}
+	}
 	if v := os.Getenv("MAX_PARALLEL_EMERGENCY"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil && n > 0 {
 			cfg.MaxParallelEmergency = n
@@ -158,5 +371,31 @@ func LoadConfig() *Config {
 		}
 	}
 
+	if cfg.CredentialKeyFile == "" {
+		cfg.CredentialKeyFile = cfg.DBPath + ".key"
+	}
+
 	return cfg
 }
+
+// JSONDuration is a time.Duration written as a string ("90s", "5m") in JSON files.
+type JSONDuration time.Duration
+
+// UnmarshalJSON parses a duration string.
+func (d *JSONDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"5m\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = JSONDuration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d JSONDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
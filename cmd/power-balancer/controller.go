@@ -6,81 +6,274 @@ import (
 	"log"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
 )
 
-// Controller handles VNish miner operations.
+// managedClient is a miner client that supports power profile changes.
+type managedClient interface {
+	miner.Client
+	miner.PowerController
+}
+
+// Controller handles miner power operations, dispatching by firmware type.
 type Controller struct {
-	auth *vnish.AuthManager
-	cfg  *Config
+	vnishAuth      *vnish.AuthManager
+	stockAuth      *stock.DigestAuth
+	whatsminerAuth *whatsminer.AuthManager
+	cfg            *Config
 }
 
 // NewController creates a new controller instance.
-func NewController(auth *vnish.AuthManager, cfg *Config) *Controller {
+func NewController(vnishAuth *vnish.AuthManager, stockAuth *stock.DigestAuth, whatsminerAuth *whatsminer.AuthManager, cfg *Config) *Controller {
 	return &Controller{
-		auth: auth,
-		cfg:  cfg,
+		vnishAuth:      vnishAuth,
+		stockAuth:      stockAuth,
+		whatsminerAuth: whatsminerAuth,
+		cfg:            cfg,
 	}
 }
 
-// SetPreset changes the preset on a miner.
-func (c *Controller) SetPreset(ctx context.Context, ip string, presetName string) error {
-	client := vnish.NewClient(ip, c.auth, vnish.WithTimeout(30*time.Second))
+// Supports returns true if miners with the given firmware can be balanced.
+func (c *Controller) Supports(fwType miner.FirmwareType) bool {
+	switch fwType {
+	case miner.FirmwareVNish, miner.FirmwareStock, miner.FirmwareWhatsminer:
+		return true
+	}
+	return false
+}
 
-	// Ensure authenticated
-	if err := client.EnsureAuthenticated(ctx); err != nil {
-		return fmt.Errorf("authenticate: %w", err)
+// client returns a power-capable client for the miner's firmware.
+func (c *Controller) client(ip string, fwType string, timeout time.Duration) (managedClient, error) {
+	switch miner.FirmwareType(fwType) {
+	case miner.FirmwareVNish:
+		return vnish.NewClient(ip, c.vnishAuth, vnish.WithTimeout(timeout)), nil
+	case miner.FirmwareStock:
+		return stock.NewClient(ip, c.stockAuth, stock.WithTimeout(timeout)), nil
+	case miner.FirmwareWhatsminer:
+		return whatsminer.NewClient(ip, c.whatsminerAuth, whatsminer.WithTimeout(timeout)), nil
+	default:
+		return nil, fmt.Errorf("unsupported firmware: %q", fwType)
 	}
+}
+
+// Restarts a miner can need before a new preset takes effect.
+const (
+	RestartNone   = ""
+	RestartMining = "restart"
+	RestartReboot = "reboot"
+)
 
-	// Ensure API key for settings changes
-	if err := client.EnsureAPIKey(ctx); err != nil {
-		return fmt.Errorf("get API key: %w", err)
+// SetPreset changes the preset on a miner and returns the restart it needs
+// to apply it: RestartNone, RestartMining or RestartReboot.
+func (c *Controller) SetPreset(ctx context.Context, ip string, fwType string, presetName string) (string, error) {
+	client, err := c.client(ip, fwType, 30*time.Second)
+	if err != nil {
+		return RestartNone, err
 	}
 
-	// Set the preset
-	if err := client.SetPreset(ctx, presetName); err != nil {
-		return fmt.Errorf("set preset: %w", err)
+	if err := client.ApplyPowerProfile(ctx, presetName); err != nil {
+		return RestartNone, fmt.Errorf("set preset: %w", err)
 	}
 
 	// Check if restart is required
-	status, err := client.GetStatus(ctx)
+	restart, err := restartNeeded(ctx, client)
 	if err != nil {
 		log.Printf("[%s] Warning: couldn't check restart status: %v", ip, err)
-		return nil
+		return RestartNone, nil
+	}
+
+	return restart, nil
+}
+
+// restartNeeded returns the restart a miner needs to apply its settings.
+// Only VNish reports reboots; it needs one for some settings.
+func restartNeeded(ctx context.Context, client managedClient) (string, error) {
+	if cl, ok := client.(*vnish.HTTPClient); ok {
+		status, err := cl.GetStatus(ctx)
+		if err != nil {
+			return RestartNone, err
+		}
+		switch {
+		case status.RebootRequired:
+			return RestartReboot, nil
+		case status.RestartRequired:
+			return RestartMining, nil
+		}
+		return RestartNone, nil
+	}
+
+	restart, err := client.RestartRequired(ctx)
+	if err != nil || !restart {
+		return RestartNone, err
+	}
+	return RestartMining, nil
+}
+
+// Restart restarts mining on a miner (RestartMining) or reboots it
+// (RestartReboot). Stock firmware applies work modes itself and never needs
+// either.
+func (c *Controller) Restart(ctx context.Context, ip string, fwType string, kind string) error {
+	client, err := c.client(ip, fwType, 30*time.Second)
+	if err != nil {
+		return err
 	}
 
-	if status.RestartRequired {
-		log.Printf("[%s] Restart required, restart it manually: ", ip)
-		// if err := client.RestartMining(ctx); err != nil {
-		// 	log.Printf("[%s] Warning: restart failed: %v", ip, err)
-		// }
+	switch cl := client.(type) {
+	case *vnish.HTTPClient:
+		if kind == RestartReboot {
+			err = cl.Reboot(ctx)
+		} else {
+			err = cl.RestartMining(ctx)
+		}
+	case *whatsminer.TCPClient:
+		if kind == RestartReboot {
+			err = cl.Reboot(ctx)
+		} else {
+			err = cl.RestartMining(ctx)
+		}
+	default:
+		return fmt.Errorf("%s: not supported for %s firmware", kind, fwType)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", kind, err)
+	}
+
+	return nil
+}
+
+// Park stops a miner hashing without rebooting it: VNish miners are paused
+// (stopped if they can't be paused), stock miners put in sleep mode and
+// Whatsminers have their hashboards powered off.
+func (c *Controller) Park(ctx context.Context, ip string, fwType string) error {
+	client, err := c.client(ip, fwType, 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	switch cl := client.(type) {
+	case *vnish.HTTPClient:
+		if err := cl.PauseMining(ctx); err != nil {
+			log.Printf("[%s] Pause failed, stopping mining instead: %v", ip, err)
+			if err := cl.StopMining(ctx); err != nil {
+				return fmt.Errorf("stop mining: %w", err)
+			}
+		}
+	case *stock.HTTPClient:
+		if err := cl.ApplyPowerProfile(ctx, stock.WorkModeSleep); err != nil {
+			return fmt.Errorf("set sleep mode: %w", err)
+		}
+	case *whatsminer.TCPClient:
+		if err := cl.PowerOff(ctx); err != nil {
+			return fmt.Errorf("power off: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Unpark resumes mining on a parked miner. Stock miners are woken by
+// switching back to presetName; the others resume on the preset they had.
+func (c *Controller) Unpark(ctx context.Context, ip string, fwType string, presetName string) error {
+	client, err := c.client(ip, fwType, 30*time.Second)
+	if err != nil {
+		return err
+	}
+
+	switch cl := client.(type) {
+	case *vnish.HTTPClient:
+		if err := cl.ResumeMining(ctx); err != nil {
+			// Not paused: it was stopped, or restarted since
+			if err := cl.StartMining(ctx); err != nil {
+				return fmt.Errorf("start mining: %w", err)
+			}
+		}
+	case *stock.HTTPClient:
+		if err := cl.ApplyPowerProfile(ctx, presetName); err != nil {
+			return fmt.Errorf("set work mode: %w", err)
+		}
+	case *whatsminer.TCPClient:
+		if err := cl.PowerOn(ctx); err != nil {
+			return fmt.Errorf("power on: %w", err)
+		}
 	}
 
 	return nil
 }
 
 // GetCurrentPreset gets the current preset from a miner.
-func (c *Controller) GetCurrentPreset(ctx context.Context, ip string) (string, error) {
-	client := vnish.NewClient(ip, c.auth, vnish.WithTimeout(10*time.Second))
+func (c *Controller) GetCurrentPreset(ctx context.Context, ip string, fwType string) (string, error) {
+	client, err := c.client(ip, fwType, 10*time.Second)
+	if err != nil {
+		return "", err
+	}
 
-	perf, err := client.GetPerfSummary(ctx)
+	name, err := client.GetCurrentPowerProfile(ctx)
 	if err != nil {
-		return "", fmt.Errorf("get perf summary: %w", err)
+		return "", fmt.Errorf("get current preset: %w", err)
 	}
 
-	return perf.CurrentPreset.Name, nil
+	return name, nil
 }
 
-// GetAvailablePresets gets all available presets for a miner.
-func (c *Controller) GetAvailablePresets(ctx context.Context, ip string) ([]vnish.AutotunePreset, error) {
-	client := vnish.NewClient(ip, c.auth, vnish.WithTimeout(10*time.Second))
+// ReportsPower returns true if miners with the given firmware report their
+// power draw, so calibration can measure their presets. Stock firmware doesn't.
+func (c *Controller) ReportsPower(fwType string) bool {
+	return miner.FirmwareType(fwType) != miner.FirmwareStock
+}
+
+// Measurement is a miner's active preset, measured power draw and hashrate.
+type Measurement struct {
+	Preset     string
+	Watts      int     // 0 if the firmware doesn't report power (stock)
+	HashrateTH float64 // 0 if not hashing or not reported (stock)
+}
 
-	// Ensure authenticated for presets endpoint
-	if err := client.EnsureAuthenticated(ctx); err != nil {
-		return nil, fmt.Errorf("authenticate: %w", err)
+// Measure reads the active preset, measured power and hashrate of a miner.
+func (c *Controller) Measure(ctx context.Context, ip string, fwType string) (*Measurement, error) {
+	client, err := c.client(ip, fwType, 10*time.Second)
+	if err != nil {
+		return nil, err
 	}
 
-	presets, err := client.GetAutotunePresets(ctx)
+	m := &Measurement{}
+	m.Preset, err = client.GetCurrentPowerProfile(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current preset: %w", err)
+	}
+
+	switch cl := client.(type) {
+	case *vnish.HTTPClient:
+		summary, err := cl.GetSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get summary: %w", err)
+		}
+		m.Watts = summary.Miner.PowerConsumption
+		m.HashrateTH = summary.Miner.InstantHashrate / 1000 // GH/s
+	case *whatsminer.TCPClient:
+		summary, err := cl.GetSummary(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("get summary: %w", err)
+		}
+		if len(summary.Summary) > 0 {
+			m.Watts = summary.Summary[0].Power
+			m.HashrateTH = summary.Summary[0].MHS5s / 1e6
+		}
+	}
+
+	return m, nil
+}
+
+// GetAvailablePresets gets all available presets for a miner.
+func (c *Controller) GetAvailablePresets(ctx context.Context, ip string, fwType string) ([]miner.PowerProfile, error) {
+	client, err := c.client(ip, fwType, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	presets, err := client.ListPowerProfiles(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("get presets: %w", err)
 	}
@@ -89,7 +282,19 @@ func (c *Controller) GetAvailablePresets(ctx context.Context, ip string) ([]vnis
 }
 
 // GetMinerInfo gets info from a miner.
-func (c *Controller) GetMinerInfo(ctx context.Context, ip string) (*vnish.MinerInfo, error) {
-	client := vnish.NewClient(ip, c.auth, vnish.WithTimeout(10*time.Second))
-	return client.GetInfo(ctx)
+func (c *Controller) GetMinerInfo(ctx context.Context, ip string, fwType string) (*miner.Info, error) {
+	client, err := c.client(ip, fwType, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetMinerInfo(ctx)
+}
+
+// PruneAPIKeys deletes stale API keys the balancer created on a VNish miner,
+// keeping the current one. Other firmwares don't use API keys.
+func (c *Controller) PruneAPIKeys(ctx context.Context, ip string, fwType string) (int, error) {
+	if miner.FirmwareType(fwType) != miner.FirmwareVNish {
+		return 0, nil
+	}
+	return vnish.NewClient(ip, c.vnishAuth, vnish.WithTimeout(10*time.Second)).PruneAPIKeys(ctx)
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// Data loss policies, applied after DataLossReadings consecutive bad readings.
+const (
+	DataLossHold     = "hold"      // Leave miners as they are
+	DataLossStepDown = "step-down" // Move miners to their model's minimum preset
+	DataLossStop     = "stop"      // Park miners, resuming them once data is back
+)
+
+// checkReading returns why a reading can't be trusted, or "" if it can.
+// Generation plants that aren't OK already count as zero, which is safe;
+// a consumption plant that isn't OK leaves the margin unknown.
+func checkReading(r *EnergyReading, now time.Time, cfg *Config) string {
+	for _, p := range r.Plants {
+		if p.Kind == PlantConsumption && !p.OK() {
+			return fmt.Sprintf("consumption plant %s is %s", p.Name, p.Status)
+		}
+		if !p.SourceTimestamp.IsZero() {
+			if age := now.Sub(p.SourceTimestamp); age > cfg.MaxDataAge {
+				return fmt.Sprintf("plant %s data is %s old", p.Name, age.Round(time.Second))
+			}
+		}
+	}
+
+	if r.Confidence < cfg.MinConfidence {
+		return fmt.Sprintf("confidence %.2f below %.2f", r.Confidence, cfg.MinConfidence)
+	}
+	return ""
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// drCompliantPercent is the share of samples after the ramp deadline that
+// must be at or below the target for an event to count as met.
+const drCompliantPercent = 95.0
+
+// EndAt returns when the event ends.
+func (e *DemandResponseEvent) EndAt() time.Time {
+	return e.StartAt.Add(time.Duration(e.Duration))
+}
+
+// RampDeadline returns when consumption must be at the target.
+func (e *DemandResponseEvent) RampDeadline() time.Time {
+	return e.StartAt.Add(time.Duration(e.Ramp))
+}
+
+// Validate checks the target and timing of a new event.
+func (e *DemandResponseEvent) Validate() error {
+	if (e.TargetMW == nil) == (e.ReduceMW == nil) {
+		return fmt.Errorf("set exactly one of target_mw and reduce_mw")
+	}
+	if e.TargetMW != nil && *e.TargetMW < 0 {
+		return fmt.Errorf("target_mw must not be negative")
+	}
+	if e.ReduceMW != nil && *e.ReduceMW <= 0 {
+		return fmt.Errorf("reduce_mw must be positive")
+	}
+	if e.Duration <= 0 {
+		return fmt.Errorf("duration is required")
+	}
+	if e.Ramp < 0 || e.Ramp > e.Duration {
+		return fmt.Errorf("ramp must be between 0 and the duration")
+	}
+	return nil
+}
+
+// targetMW returns the consumption target of a started event.
+func (e *DemandResponseEvent) targetMW() float64 {
+	if e.TargetMW != nil {
+		return *e.TargetMW
+	}
+	if e.BaselineMW != nil && e.ReduceMW != nil {
+		return max(0, *e.BaselineMW-*e.ReduceMW)
+	}
+	return 0
+}
+
+// label names the event in logs.
+func (e *DemandResponseEvent) label() string {
+	if e.Reference != "" {
+		return fmt.Sprintf("DR #%d %s/%s", e.ID, e.Source, e.Reference)
+	}
+	return fmt.Sprintf("DR #%d %s", e.ID, e.Source)
+}
+
+// DRStatus is the demand-response target in force, for the dashboard.
+type DRStatus struct {
+	Events     []string `json:"events"`
+	TargetMW   float64  `json:"target_mw"`   // Lowest target of the active events
+	HeadroomMW float64  `json:"headroom_mw"` // Target less consumption and pending changes (negative = over)
+}
+
+// =============================================================================
+// Enforcement
+// =============================================================================
+
+// demandState is the demand-response target of the current tick.
+type demandState struct {
+	events    []*DemandResponseEvent
+	targetW   int
+	headroomW int
+}
+
+// applyDemandResponse starts and ends the events due, samples the active ones
+// for compliance and reduces consumption, less pending changes, to the lowest
+// active target, whatever the margin. Reductions run in parallel so the target
+// is met well within a ramp of a few minutes.
+func (b *Balancer) applyDemandResponse(ctx context.Context, reading *EnergyReading) {
+	b.demand = nil
+
+	events, err := b.repo.GetOpenDemandResponseEvents(ctx)
+	if err != nil {
+		log.Printf("Failed to get demand-response events: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var active []*DemandResponseEvent
+	for _, e := range events {
+		switch {
+		case !now.Before(e.EndAt()):
+			if err := b.endDemandResponse(ctx, e, DRCompleted, now, reading.MarginPercent); err != nil &&
+				!errors.Is(err, sql.ErrNoRows) {
+				log.Printf("Failed to end %s: %v", e.label(), err)
+			}
+		case e.Status == DRScheduled && !now.Before(e.StartAt):
+			if err := b.startDemandResponse(ctx, e, reading); err != nil {
+				log.Printf("Failed to start %s: %v", e.label(), err)
+				continue
+			}
+			active = append(active, e)
+		case e.Status == DRActive:
+			active = append(active, e)
+		}
+	}
+	if len(active) == 0 {
+		return
+	}
+
+	d := &demandState{events: active}
+	for i, e := range active {
+		sample := &DRSample{Timestamp: now, ConsumptionMW: reading.ConsumptionMW, TargetMW: e.targetMW()}
+		if err := b.repo.InsertDRSample(ctx, e.ID, sample); err != nil {
+			log.Printf("Failed to record demand-response sample: %v", err)
+		}
+		if w := int(e.targetMW() * 1_000_000); i == 0 || w < d.targetW {
+			d.targetW = w
+		}
+	}
+
+	pendingDelta, _ := b.repo.SumPendingDelta(ctx)
+	effectiveW := int(reading.ConsumptionMW*1_000_000) - pendingDelta
+	d.headroomW = d.targetW - effectiveW
+	b.demand = d
+
+	if d.headroomW >= 0 {
+		return
+	}
+	excess := -d.headroomW
+	log.Printf("Demand response: consumption %dW (less pending) over target %dW, reducing %dW",
+		effectiveW, d.targetW, excess)
+
+	changes, err := b.active.CalculateReduction(ctx, excess)
+	if err != nil {
+		log.Printf("Demand response: failed to calculate reduction: %v", err)
+		return
+	}
+	if len(changes) > 0 {
+		b.executeParallel(ctx, changes, "demand_response", reading.MarginPercent)
+		return
+	}
+
+	if !b.cfg.ParkMiners {
+		log.Printf("Demand response: no miners available for reduction!")
+		return
+	}
+	miners, err := b.active.CalculatePark(ctx, excess)
+	if err != nil {
+		log.Printf("Demand response: failed to calculate park: %v", err)
+		return
+	}
+	if len(miners) == 0 {
+		log.Printf("Demand response: no miners available for reduction or parking!")
+		return
+	}
+	b.parkMiners(ctx, miners, "demand_response", reading.MarginPercent)
+}
+
+// startDemandResponse activates an event, taking the current consumption as
+// its baseline.
+func (b *Balancer) startDemandResponse(ctx context.Context, e *DemandResponseEvent, reading *EnergyReading) error {
+	baselineMW := reading.ConsumptionMW
+	if err := b.repo.StartDemandResponseEvent(ctx, e.ID, baselineMW); err != nil {
+		return err
+	}
+	e.Status = DRActive
+	e.BaselineMW = &baselineMW
+
+	targetMW := e.targetMW()
+	log.Printf("Demand response %s started: %.3f MW -> %.3f MW by %s, until %s",
+		e.label(), baselineMW, targetMW, e.RampDeadline().Format("15:04:05"), e.EndAt().Format("15:04:05"))
+
+	entry := &ChangeLog{
+		FromPreset:     e.label(),
+		ToPreset:       fmt.Sprintf("<= %.3f MW", targetMW),
+		ExpectedDeltaW: int((baselineMW - targetMW) * 1_000_000),
+		Reason:         "demand_response",
+		MarginAtTime:   reading.MarginPercent,
+		IssuedAt:       time.Now(),
+		Success:        true,
+	}
+	if err := b.repo.InsertChangeLog(ctx, entry); err != nil {
+		log.Printf("Failed to log demand response: %v", err)
+	}
+	return nil
+}
+
+// endDemandResponse completes or cancels an event and logs its compliance.
+// Returns sql.ErrNoRows if the event has already ended.
+func (b *Balancer) endDemandResponse(ctx context.Context, e *DemandResponseEvent, status string, at time.Time, margin float64) error {
+	if err := b.repo.EndDemandResponseEvent(ctx, e.ID, status, at); err != nil {
+		return err
+	}
+
+	entry := &ChangeLog{
+		FromPreset:   e.label(),
+		ToPreset:     status,
+		Reason:       "demand_response",
+		MarginAtTime: margin,
+		IssuedAt:     at,
+		Success:      true,
+	}
+
+	if e.BaselineMW != nil {
+		c, err := b.repo.GetDRCompliance(ctx, e)
+		if err != nil {
+			return fmt.Errorf("get compliance: %w", err)
+		}
+		entry.ExpectedDeltaW = int(c.MeanAchievedMW * 1_000_000)
+		if c.Samples > 0 && c.CompliancePercent < drCompliantPercent {
+			entry.Success = false
+			entry.ErrorMessage = fmt.Sprintf("compliance %.0f%% (%d/%d samples at or below target, worst %.3f MW over)",
+				c.CompliancePercent, c.CompliantSamples, c.Samples, c.MaxOverMW)
+		}
+		log.Printf("Demand response %s %s: compliance %.0f%% over %d samples, mean reduction %.3f MW",
+			e.label(), status, c.CompliancePercent, c.Samples, c.MeanAchievedMW)
+	} else {
+		log.Printf("Demand response %s %s before starting", e.label(), status)
+	}
+
+	if err := b.repo.InsertChangeLog(ctx, entry); err != nil {
+		log.Printf("Failed to log demand response: %v", err)
+	}
+	return nil
+}
+
+// CancelDemandResponse cancels a scheduled or active event. Returns
+// sql.ErrNoRows if there is no such open event.
+func (b *Balancer) CancelDemandResponse(ctx context.Context, id int64) error {
+	e, err := b.repo.GetDemandResponseEvent(ctx, id)
+	if err != nil {
+		return err
+	}
+	return b.endDemandResponse(ctx, e, DRCancelled, time.Now(), b.GetStatus().MarginPercent)
+}
+
+// demandStatus returns the dashboard view of the demand-response target.
+func demandStatus(d *demandState) *DRStatus {
+	if d == nil {
+		return nil
+	}
+	s := &DRStatus{
+		TargetMW:   float64(d.targetW) / 1_000_000,
+		HeadroomMW: float64(d.headroomW) / 1_000_000,
+	}
+	for _, e := range d.events {
+		s.Events = append(s.Events, e.label())
+	}
+	return s
+}
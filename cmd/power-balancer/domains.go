@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+)
+
+// DomainConfig configures a power domain: the site, a container or feeder,
+// or a rack. Miners belong to the deepest domain listing them; miners no
+// domain lists belong to the root.
+type DomainConfig struct {
+	Name     string         `json:"name"`
+	CapKW    float64        `json:"cap_kw,omitempty"`   // Breaker or transformer limit (0 = no cap)
+	Plant    string         `json:"plant,omitempty"`    // Consumption plant measuring the domain (default: its miners' presets)
+	Miners   []string       `json:"miners,omitempty"`   // IPs, CIDRs or MAC addresses
+	Children []DomainConfig `json:"children,omitempty"` // Sub-domains fed by this one
+}
+
+// PowerDomain is a node of the power domain tree.
+type PowerDomain struct {
+	Name     string
+	CapW     int
+	Plant    string
+	Parent   *PowerDomain
+	Children []*PowerDomain
+
+	ips  map[string]bool
+	nets []*net.IPNet
+	macs map[string]bool
+}
+
+// LoadDomains reads the power domain tree.
+func LoadDomains(path string) (*PowerDomain, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read power domains: %w", err)
+	}
+
+	var dc DomainConfig
+	if err := json.Unmarshal(data, &dc); err != nil {
+		return nil, fmt.Errorf("parse power domains %s: %w", path, err)
+	}
+
+	root, err := newDomain(dc, nil, make(map[string]bool))
+	if err != nil {
+		return nil, fmt.Errorf("power domains %s: %w", path, err)
+	}
+	return root, nil
+}
+
+// newDomain builds a domain and its children, checking names are unique.
+func newDomain(dc DomainConfig, parent *PowerDomain, names map[string]bool) (*PowerDomain, error) {
+	if dc.Name == "" {
+		return nil, fmt.Errorf("domain name is required")
+	}
+	if names[dc.Name] {
+		return nil, fmt.Errorf("duplicate domain %q", dc.Name)
+	}
+	names[dc.Name] = true
+
+	if dc.CapKW < 0 {
+		return nil, fmt.Errorf("domain %q: cap_kw must not be negative", dc.Name)
+	}
+
+	d := &PowerDomain{
+		Name:   dc.Name,
+		CapW:   int(dc.CapKW * 1000),
+		Plant:  dc.Plant,
+		Parent: parent,
+		ips:    make(map[string]bool),
+		macs:   make(map[string]bool),
+	}
+
+	for _, m := range dc.Miners {
+		if _, ipNet, err := net.ParseCIDR(m); err == nil {
+			d.nets = append(d.nets, ipNet)
+		} else if ip := net.ParseIP(m); ip != nil {
+			d.ips[ip.String()] = true
+		} else if mac, err := net.ParseMAC(m); err == nil {
+			d.macs[mac.String()] = true
+		} else {
+			return nil, fmt.Errorf("domain %q: %q is not an IP, CIDR or MAC address", dc.Name, m)
+		}
+	}
+
+	for _, cc := range dc.Children {
+		child, err := newDomain(cc, d, names)
+		if err != nil {
+			return nil, err
+		}
+		d.Children = append(d.Children, child)
+	}
+	return d, nil
+}
+
+// lists returns true if the domain itself lists the miner.
+func (d *PowerDomain) lists(m *Miner) bool {
+	if mac, err := net.ParseMAC(m.MACAddress); err == nil && d.macs[mac.String()] {
+		return true
+	}
+	ip := net.ParseIP(m.IPAddress)
+	if ip == nil {
+		return false
+	}
+	if d.ips[ip.String()] {
+		return true
+	}
+	for _, n := range d.nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// Assign returns the deepest domain listing the miner, or d if none does.
+func (d *PowerDomain) Assign(m *Miner) *PowerDomain {
+	for _, c := range d.Children {
+		if found := c.assign(m); found != nil {
+			return found
+		}
+	}
+	return d
+}
+
+// assign returns the deepest domain under and including d listing the miner,
+// or nil if none does.
+func (d *PowerDomain) assign(m *Miner) *PowerDomain {
+	for _, c := range d.Children {
+		if found := c.assign(m); found != nil {
+			return found
+		}
+	}
+	if d.lists(m) {
+		return d
+	}
+	return nil
+}
+
+// Walk calls fn for d and every domain under it, parents first.
+func (d *PowerDomain) Walk(fn func(*PowerDomain, int)) {
+	d.walk(fn, 0)
+}
+
+func (d *PowerDomain) walk(fn func(*PowerDomain, int), depth int) {
+	fn(d, depth)
+	for _, c := range d.Children {
+		c.walk(fn, depth+1)
+	}
+}
+
+// Count returns the number of domains under and including d.
+func (d *PowerDomain) Count() int {
+	n := 0
+	d.Walk(func(*PowerDomain, int) { n++ })
+	return n
+}
+
+// DomainStatus is the load of a power domain, for the dashboard.
+type DomainStatus struct {
+	Name     string  `json:"name"`
+	Parent   string  `json:"parent,omitempty"`
+	Depth    int     `json:"depth"`
+	CapKW    float64 `json:"cap_kw"` // 0 = no cap
+	LoadKW   float64 `json:"load_kw"`
+	Measured bool    `json:"measured"` // Load from a plant rather than the miners' presets
+	Miners   int     `json:"miners"`
+}
+
+// =============================================================================
+// Enforcement
+// =============================================================================
+
+// domainLoad is the effective load of a domain and the miners under it.
+type domainLoad struct {
+	domain   *PowerDomain
+	depth    int
+	loadW    int // Measured minus pending changes, or the sum of its miners' presets
+	measured bool
+	miners   map[int64]bool
+}
+
+// over returns how far the domain's load is above its cap, or 0.
+func (l *domainLoad) over() int {
+	if l.domain.CapW <= 0 {
+		return 0
+	}
+	return max(0, l.loadW-l.domain.CapW)
+}
+
+// evaluateDomains computes the effective load of every domain, parents first.
+// A domain with a plant in the reading is measured, less the pending changes
+// of its miners, like the site margin. Other domains add up the (calibrated)
+// presets of their online miners, which already reflect issued changes.
+func (b *Balancer) evaluateDomains(ctx context.Context, reading *EnergyReading) ([]*domainLoad, error) {
+	miners, err := b.repo.ListMiners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list miners: %w", err)
+	}
+	parked, err := b.repo.GetParkedMiners(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get parked miners: %w", err)
+	}
+	pending, err := b.repo.GetPendingChanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get pending changes: %w", err)
+	}
+
+	isParked := make(map[int64]bool, len(parked))
+	for _, p := range parked {
+		isParked[p.MinerID] = true
+	}
+	pendingW := make(map[int64]int)
+	for _, p := range pending {
+		pendingW[p.MinerID] += p.ExpectedDeltaW
+	}
+
+	var loads []*domainLoad
+	byDomain := make(map[*PowerDomain]*domainLoad)
+	b.domains.Walk(func(d *PowerDomain, depth int) {
+		l := &domainLoad{domain: d, depth: depth, miners: make(map[int64]bool)}
+		loads = append(loads, l)
+		byDomain[d] = l
+	})
+
+	estimatedW := make(map[*PowerDomain]int)
+	subtreePendingW := make(map[*PowerDomain]int)
+	presets := make(map[int64]*ModelPreset)
+	for _, m := range miners {
+		watts := 0
+		if m.IsOnline && !isParked[m.ID] && m.CurrentPresetID != nil {
+			p, ok := presets[*m.CurrentPresetID]
+			if !ok {
+				if p, err = b.repo.GetPresetByID(ctx, *m.CurrentPresetID); err != nil {
+					p = nil
+				}
+				presets[*m.CurrentPresetID] = p
+			}
+			if p != nil {
+				watts = b.calibration.Apply(m.ID, p).Watts
+			}
+		}
+
+		for d := b.domains.Assign(m); d != nil; d = d.Parent {
+			byDomain[d].miners[m.ID] = true
+			estimatedW[d] += watts
+			subtreePendingW[d] += pendingW[m.ID]
+		}
+	}
+
+	plantW := make(map[string]int)
+	for _, p := range reading.Plants {
+		if p.Kind == PlantConsumption && p.OK() {
+			plantW[p.Name] += int(p.MW * 1_000_000)
+		}
+	}
+
+	for _, l := range loads {
+		if w, ok := plantW[l.domain.Plant]; ok && l.domain.Plant != "" {
+			l.loadW = w - subtreePendingW[l.domain]
+			l.measured = true
+		} else {
+			l.loadW = estimatedW[l.domain]
+		}
+	}
+	return loads, nil
+}
+
+// enforceDomainCaps reduces the miners of every domain above its cap, whatever
+// the site margin, deepest domains first. Domains above one that was acted on
+// wait for the next tick, when the changes count as pending.
+func (b *Balancer) enforceDomainCaps(ctx context.Context, loads []*domainLoad, margin float64) {
+	handled := make(map[*PowerDomain]bool)
+
+	for i := len(loads) - 1; i >= 0; i-- {
+		l := loads[i]
+		excess := l.over()
+		if excess == 0 || handled[l.domain] {
+			continue
+		}
+
+		log.Printf("Domain %s over cap: load %dW > cap %dW, reducing %dW",
+			l.domain.Name, l.loadW, l.domain.CapW, excess)
+
+		strategy := b.active.Within(l.miners)
+		changes, err := strategy.CalculateReduction(ctx, excess)
+		if err != nil {
+			log.Printf("Domain %s: failed to calculate reduction: %v", l.domain.Name, err)
+			continue
+		}
+
+		if len(changes) > 0 {
+			b.executeParallel(ctx, changes, "domain_cap", margin)
+		} else if b.cfg.ParkMiners {
+			miners, err := strategy.CalculatePark(ctx, excess)
+			if err != nil {
+				log.Printf("Domain %s: failed to calculate park: %v", l.domain.Name, err)
+				continue
+			}
+			if len(miners) == 0 {
+				log.Printf("Domain %s: no miners available for reduction or parking!", l.domain.Name)
+				continue
+			}
+			b.parkMiners(ctx, miners, "domain_cap", margin)
+		} else {
+			log.Printf("Domain %s: no miners available for reduction!", l.domain.Name)
+			continue
+		}
+
+		for d := l.domain; d != nil; d = d.Parent {
+			handled[d] = true
+		}
+	}
+}
+
+// fitsDomains returns true if adding addW to a miner keeps every capped
+// domain above it within its cap.
+func fitsDomains(loads []*domainLoad, minerID int64, addW int) bool {
+	for _, l := range loads {
+		if l.domain.CapW > 0 && l.miners[minerID] && l.loadW+addW > l.domain.CapW {
+			return false
+		}
+	}
+	return true
+}
+
+// domainStatuses returns the dashboard view of the domain loads.
+func domainStatuses(loads []*domainLoad) []DomainStatus {
+	statuses := make([]DomainStatus, 0, len(loads))
+	for _, l := range loads {
+		s := DomainStatus{
+			Name:     l.domain.Name,
+			Depth:    l.depth,
+			CapKW:    float64(l.domain.CapW) / 1000,
+			LoadKW:   float64(l.loadW) / 1000,
+			Measured: l.measured,
+			Miners:   len(l.miners),
+		}
+		if l.domain.Parent != nil {
+			s.Parent = l.domain.Parent.Name
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses
+}
+
+// describeDomains returns a one-line summary of the tree for the startup log.
+func describeDomains(root *PowerDomain) string {
+	var caps []string
+	root.Walk(func(d *PowerDomain, _ int) {
+		if d.CapW > 0 {
+			caps = append(caps, fmt.Sprintf("%s %.0f kW", d.Name, float64(d.CapW)/1000))
+		}
+	})
+	if len(caps) == 0 {
+		return fmt.Sprintf("%d domains, no caps", root.Count())
+	}
+	return fmt.Sprintf("%d domains, caps: %s", root.Count(), strings.Join(caps, ", "))
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// PlantKind says whether a plant produces or draws power.
+type PlantKind string
+
+const (
+	PlantGeneration  PlantKind = "generation"
+	PlantConsumption PlantKind = "consumption"
+)
+
+// PlantStatusOK is the status of a plant reporting normally.
+// It matches the aggregator's per-source status.
+const PlantStatusOK = "success"
+
+// PlantReading is the latest value of one generation or consumption plant.
+type PlantReading struct {
+	Source          string    `json:"source"`
+	Name            string    `json:"name"`
+	Kind            PlantKind `json:"kind"`
+	MW              float64   `json:"mw"`
+	Status          string    `json:"status"`                     // PlantStatusOK, or why the value is unusable
+	SourceTimestamp time.Time `json:"source_timestamp,omitempty"` // When the source measured the value
+	Confidence      float64   `json:"confidence"`                 // Trust score (0-1); 1 for sources without one
+}
+
+// OK returns true if the plant reported normally.
+func (p *PlantReading) OK() bool {
+	return p.Status == PlantStatusOK
+}
+
+// EnergySource provides plant readings to the balancer.
+type EnergySource interface {
+	// Name identifies the source in logs and readings.
+	Name() string
+
+	// ReadPlants returns the current value of every plant of the source.
+	ReadPlants(ctx context.Context) ([]PlantReading, error)
+}
+
+// EnergyMeter combines the plants of all sources into one EnergyReading.
+type EnergyMeter struct {
+	sources []EnergySource
+}
+
+// NewEnergyMeter creates a meter over the given sources.
+func NewEnergyMeter(sources ...EnergySource) *EnergyMeter {
+	return &EnergyMeter{sources: sources}
+}
+
+// Sources returns the meter's sources.
+func (m *EnergyMeter) Sources() []EnergySource {
+	return m.sources
+}
+
+// SetStatic changes a plant value of a static source.
+func (m *EnergyMeter) SetStatic(source, plant string, mw float64) error {
+	for _, s := range m.sources {
+		if s.Name() != source {
+			continue
+		}
+		static, ok := s.(*StaticSource)
+		if !ok {
+			return fmt.Errorf("source %q is not static", source)
+		}
+		return static.Set(plant, mw)
+	}
+	return fmt.Errorf("unknown source %q", source)
+}
+
+// FetchLatest reads every source and totals generation and consumption.
+// Any source failing fails the reading, as a partial total would misstate the margin.
+// Generation plants not reporting OK count as producing nothing; consumption
+// plants always count, and the balancer rejects readings where they aren't OK.
+// The reading's confidence is the lowest of its plants.
+func (m *EnergyMeter) FetchLatest(ctx context.Context) (*EnergyReading, error) {
+	reading := &EnergyReading{Timestamp: time.Now(), Confidence: 1}
+
+	for _, s := range m.sources {
+		plants, err := s.ReadPlants(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", s.Name(), err)
+		}
+		for _, p := range plants {
+			p.Source = s.Name()
+			switch p.Kind {
+			case PlantGeneration:
+				if p.OK() {
+					reading.GenerationMW += p.MW
+				}
+			case PlantConsumption:
+				reading.ConsumptionMW += p.MW
+			}
+			if p.Confidence < reading.Confidence {
+				reading.Confidence = p.Confidence
+			}
+			reading.Plants = append(reading.Plants, p)
+		}
+	}
+
+	reading.MarginMW = reading.GenerationMW - reading.ConsumptionMW
+	if reading.GenerationMW > 0 {
+		reading.MarginPercent = (reading.MarginMW / reading.GenerationMW) * 100
+	}
+	return reading, nil
+}
+
+// =============================================================================
+// Configuration
+// =============================================================================
+
+// EnergyConfig describes where the balancer reads generation and consumption.
+type EnergyConfig struct {
+	Sources []SourceConfig `json:"sources"`
+}
+
+// Source types.
+const (
+	SourceAggregator = "aggregator"
+	SourceModbus     = "modbus"
+	SourceMQTT       = "mqtt"
+	SourceStatic     = "static"
+)
+
+// SourceConfig configures one energy source. Fields apply to the types noted.
+type SourceConfig struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // aggregator, modbus, mqtt or static
+
+	// aggregator (default: AGGREGATOR_URL and AGGREGATOR_API_KEY)
+	URL    string `json:"url,omitempty"`
+	APIKey string `json:"api_key,omitempty"`
+
+	// modbus
+	Address string `json:"address,omitempty"` // host:port (default port 502)
+	UnitID  int    `json:"unit_id,omitempty"`
+
+	// mqtt
+	Broker   string       `json:"broker,omitempty"` // tcp://host:port
+	Username string       `json:"username,omitempty"`
+	Password string       `json:"password,omitempty"`
+	MaxAge   JSONDuration `json:"max_age,omitempty"`   // Values older than this are stale (default: 1m)
+	OnChange bool         `json:"on_change,omitempty"` // Topics are only published on change: values hold while connected
+
+	// Plants lists the plants to read. An aggregator without plants reports
+	// every plant in its response.
+	Plants []PlantConfig `json:"plants"`
+}
+
+// PlantConfig configures one plant of a source.
+type PlantConfig struct {
+	Name string    `json:"name"`
+	Kind PlantKind `json:"kind"` // generation or consumption
+
+	// Scale converts the raw value to MW (default: 1, aggregator: 0.001).
+	Scale float64 `json:"scale,omitempty"`
+
+	Field string `json:"field,omitempty"` // aggregator: key under generation/consumption
+
+	Register     int    `json:"register,omitempty"`      // modbus: first register of the value (0-based)
+	RegisterType string `json:"register_type,omitempty"` // modbus: holding or input (default: holding)
+	DataType     string `json:"data_type,omitempty"`     // modbus: (u)int16, (u)int32, (u)int64, float32 or float64 (default: float32)
+	WordOrder    string `json:"word_order,omitempty"`    // modbus: ABCD, CDAB, BADC or DCBA (default: ABCD)
+
+	Topic     string `json:"topic,omitempty"`      // mqtt: topic carrying the value
+	JSONField string `json:"json_field,omitempty"` // mqtt: field of a JSON payload (default: plain number)
+
+	MW float64 `json:"mw,omitempty"` // static: fixed value
+}
+
+// scale returns the plant scale, or def if unset.
+func (p *PlantConfig) scale(def float64) float64 {
+	if p.Scale == 0 {
+		return def
+	}
+	return p.Scale
+}
+
+// LoadEnergyConfig reads the energy sources file.
+func LoadEnergyConfig(path string) (*EnergyConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read energy sources: %w", err)
+	}
+
+	var ec EnergyConfig
+	if err := json.Unmarshal(data, &ec); err != nil {
+		return nil, fmt.Errorf("parse energy sources %s: %w", path, err)
+	}
+	if err := ec.validate(); err != nil {
+		return nil, fmt.Errorf("energy sources %s: %w", path, err)
+	}
+	return &ec, nil
+}
+
+func (ec *EnergyConfig) validate() error {
+	if len(ec.Sources) == 0 {
+		return fmt.Errorf("no sources")
+	}
+
+	names := make(map[string]bool)
+	for i, s := range ec.Sources {
+		if s.Name == "" {
+			return fmt.Errorf("source %d: name is required", i+1)
+		}
+		if names[s.Name] {
+			return fmt.Errorf("duplicate source %q", s.Name)
+		}
+		names[s.Name] = true
+
+		switch s.Type {
+		case SourceAggregator, SourceStatic:
+		case SourceModbus:
+			if s.Address == "" {
+				return fmt.Errorf("source %q: address is required", s.Name)
+			}
+		case SourceMQTT:
+			if s.Broker == "" {
+				return fmt.Errorf("source %q: broker is required", s.Name)
+			}
+		default:
+			return fmt.Errorf("source %q: unknown type %q", s.Name, s.Type)
+		}
+
+		if len(s.Plants) == 0 && s.Type != SourceAggregator {
+			return fmt.Errorf("source %q: no plants", s.Name)
+		}
+		for _, p := range s.Plants {
+			if p.Name == "" {
+				return fmt.Errorf("source %q: plant name is required", s.Name)
+			}
+			if p.Kind != PlantGeneration && p.Kind != PlantConsumption {
+				return fmt.Errorf("source %q plant %q: kind must be generation or consumption", s.Name, p.Name)
+			}
+			if s.Type == SourceMQTT && p.Topic == "" {
+				return fmt.Errorf("source %q plant %q: topic is required", s.Name, p.Name)
+			}
+			if s.Type == SourceModbus {
+				if _, err := parseModbusPlant(p); err != nil {
+					return fmt.Errorf("source %q plant %q: %w", s.Name, p.Name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// DefaultEnergyConfig is the production aggregator reporting all its plants.
+func DefaultEnergyConfig() *EnergyConfig {
+	return &EnergyConfig{Sources: []SourceConfig{{Name: "aggregator", Type: SourceAggregator}}}
+}
+
+// NewEnergySources creates the configured sources. Subscription sources (MQTT)
+// run in the background until ctx is done.
+func NewEnergySources(ctx context.Context, cfg *Config, ec *EnergyConfig) ([]EnergySource, error) {
+	var sources []EnergySource
+	for _, sc := range ec.Sources {
+		switch sc.Type {
+		case SourceAggregator:
+			if sc.URL == "" {
+				sc.URL = cfg.AggregatorURL
+			}
+			if sc.APIKey == "" {
+				sc.APIKey = cfg.AggregatorAPIKey
+			}
+			if sc.APIKey == "" {
+				return nil, fmt.Errorf("source %q: AGGREGATOR_API_KEY or api_key is required", sc.Name)
+			}
+			sources = append(sources, NewAggregator(sc))
+		case SourceModbus:
+			source, err := NewModbusSource(sc)
+			if err != nil {
+				return nil, fmt.Errorf("source %q: %w", sc.Name, err)
+			}
+			sources = append(sources, source)
+		case SourceMQTT:
+			sources = append(sources, NewMQTTSource(ctx, sc))
+		case SourceStatic:
+			sources = append(sources, NewStaticSource(sc))
+		default:
+			return nil, fmt.Errorf("source %q: unknown type %q", sc.Name, sc.Type)
+		}
+	}
+	return sources, nil
+}
+
+// displayName turns a source key into a display name (e.g., "container_eles" -> "Container Eles").
+func displayName(key string) string {
+	words := strings.Fields(strings.ReplaceAll(key, "_", " "))
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
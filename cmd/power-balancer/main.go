@@ -10,8 +10,12 @@ import (
 	"os/signal"
 	"syscall"
 
+	"github.com/powerhive/powerhive-v2/pkg/credentials"
+	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
 )
 
 const usage = `power-balancer - Dynamic miner power management
@@ -23,16 +27,28 @@ Commands:
   start                Start the power balancer daemon and dashboard
   discover <network>   Discover miners on a network (one-time scan)
   status               Show current system status
+  simulate [scenario]  Serve a simulated aggregator and miners for testing
   help                 Show this help message
 
 Environment Variables (or set in .env file):
   POWER_BALANCER_DB       SQLite database path (default: power-balancer.db)
   AGGREGATOR_URL          Energy aggregator API URL
   AGGREGATOR_API_KEY      API key for energy aggregator
+  ENERGY_SOURCES_FILE     JSON file with energy sources and plants (optional,
+                          default: all plants of the aggregator)
+  DOMAINS_FILE            JSON file with the power domain tree (site, containers,
+                          racks), each with an optional cap in kW, the plant
+                          measuring it and its miners; the balancer keeps every
+                          domain within its cap (optional)
   NETWORK_CIDR            Networks to scan for miners (comma-separated CIDRs)
                           Example: 10.40.36.0/24,10.40.37.0/24,10.40.38.0/24
   DISCOVERY_INTERVAL      How often to scan for new miners (default: 5m)
   VNISH_PASSWORD          VNish firmware password (default: admin)
+  STOCK_USERNAME          Stock firmware username (default: root)
+  STOCK_PASSWORD          Stock firmware password (default: root)
+  WHATSMINER_PASSWORD     Whatsminer admin password (default: admin)
+  CREDENTIAL_KEY_FILE     Key file encrypting stored API keys (default: <db>.key)
+  CREDENTIALS_FILE        JSON file with per-subnet/IP/MAC credential sets (optional)
   EMERGENCY_MARGIN        Emergency threshold percent (default: 5)
   CRITICAL_MARGIN         Critical threshold percent (default: 10)
   SAFE_MARGIN             Target margin percent (default: 15)
@@ -41,6 +57,64 @@ Environment Variables (or set in .env file):
   CHANGE_SPACING          Time between preset changes (default: 10s)
   COOLDOWN_DURATION       Per-miner cooldown (default: 10m)
   SETTLE_TIME             Time for changes to take effect (default: 5m)
+  DATA_MAX_AGE            Reject energy data measured longer ago (default: 2m)
+  MIN_CONFIDENCE          Reject energy data with a lower trust score (default: 0.5)
+  DATA_LOSS_READINGS      Consecutive rejected readings before DATA_LOSS (default: 3)
+  DATA_LOSS_POLICY        In DATA_LOSS: hold, step-down (to each model's minimum
+                          preset) or stop (park miners until data is back)
+                          (default: step-down)
+  VERIFY_TOLERANCE        Max difference between measured and expected change
+                          delta, percent (default: 25)
+  VERIFY_RETRIES          Retries of a change that didn't take before the miner
+                          is locked for a manual check (default: 1)
+  CALIBRATION_INTERVAL    How often to sample the miner summaries harvested
+                          into HARVEST_DB for preset calibration, 0 to
+                          disable (default: 10m)
+  CALIBRATION_WINDOW      Age of the oldest calibration sample kept (default: 168h)
+  CALIBRATION_MIN_SAMPLES Samples needed before measured preset power replaces
+                          the firmware value (default: 5)
+  HARVEST_DB              data-harvest database (its POWERHIVE_DB) to sample
+                          summaries from; unset, presets are only calibrated
+                          from settled changes
+  STRATEGY                How miners are picked for changes (default: efficiency):
+                          efficiency  least efficient reduced first, most
+                                      efficient increased first
+                          priority    lowest priority tier reduced first,
+                                      highest restored first
+                          fairness    miners reduced least recently reduced
+                                      first, reduced longest ago restored first
+                          knapsack    presets chosen across the fleet to lose
+                                      the least hashrate
+  PARK_MINERS             In EMERGENCY, once every miner is at its minimum
+                          preset, stop mining on the lowest priority miners
+                          and resume them when the margin recovers
+                          (default: true)
+  SCHEDULE_TIMEZONE       Timezone of the time-of-use schedules edited on the
+                          dashboard, e.g. America/Sao_Paulo (default: local)
+  DEMAND_RESPONSE_KEYS    API keys of the demand-response setpoint API as
+                          comma-separated name:key pairs, e.g.
+                          grid:s3cret,trading:0th3r (default: API disabled)
+  RAMP_UP_MW_PER_MIN      Max rate of load increases, in MW per minute,
+                          counting unsettled changes over their settle time
+                          (default: 0, unlimited)
+  RAMP_DOWN_MW_PER_MIN    Max rate of load reductions, in MW per minute
+                          (default: 0, unlimited)
+  RAMP_EMERGENCY_OVERRIDE Let emergency, data-loss and domain-cap reductions
+                          exceed RAMP_DOWN_MW_PER_MIN (default: true)
+  RESTART_POLICY          What to do when a preset only applies after a
+                          restart: auto (restart mining, or reboot when
+                          required), restart (restart mining, leave reboots
+                          to the operator) or manual (default: auto)
+  RESTART_SPACING         Time between restarts across the fleet
+                          (default: 30s)
+  RESTART_DURATION        Time for a restarted miner to hash again
+                          (default: 3m)
+  RECONCILE_INTERVAL      How often to read the preset of every managed
+                          miner and correct presets changed outside the
+                          balancer (default: 15m, 0 = never, leaving
+                          discovery to sync presets)
+  RECONCILE_REASSERT      Move miners changed outside the balancer to a
+                          preset drawing more power back (default: false)
   DASHBOARD_PORT          Dashboard HTTP port (default: 8081)
 `
 
@@ -75,6 +149,8 @@ func main() {
 		runDiscover(ctx, cfg)
 	case "status":
 		runStatus(ctx, cfg)
+	case "simulate":
+		runSimulate(ctx, cfg, os.Args[2:])
 	case "help", "-h", "--help":
 		fmt.Print(usage)
 	default:
@@ -86,9 +162,6 @@ func main() {
 
 func runStart(ctx context.Context, cfg *Config) {
 	// Validate required config
-	if cfg.AggregatorAPIKey == "" {
-		log.Fatal("AGGREGATOR_API_KEY is required")
-	}
 	if len(cfg.NetworkCIDRs) == 0 {
 		log.Fatal("NETWORK_CIDR is required (comma-separated CIDRs supported)")
 	}
@@ -100,23 +173,53 @@ func runStart(ctx context.Context, cfg *Config) {
 	}
 	defer repo.Close()
 
-	// Create VNish prober
-	vnishAuth := vnish.NewAuthManager(cfg.VNishPassword)
-	probers := []miner.FirmwareProber{
-		vnish.NewProber(vnishAuth, vnish.WithProberTimeout(cfg.ChangeSpacing)),
+	// Create probers and controller for the supported firmwares
+	probers, controller, err := setupFirmware(cfg, repo)
+	if err != nil {
+		log.Fatalf("Failed to set up firmware clients: %v", err)
 	}
 
-	// Create aggregator client
-	aggregator := NewAggregator(cfg.AggregatorURL, cfg.AggregatorAPIKey)
-
-	// Create controller
-	controller := NewController(vnishAuth, cfg)
+	// Create energy sources
+	energyCfg := DefaultEnergyConfig()
+	if cfg.EnergySourcesFile != "" {
+		energyCfg, err = LoadEnergyConfig(cfg.EnergySourcesFile)
+		if err != nil {
+			log.Fatalf("Failed to load energy sources: %v", err)
+		}
+	}
+	sources, err := NewEnergySources(ctx, cfg, energyCfg)
+	if err != nil {
+		log.Fatalf("Failed to create energy sources: %v", err)
+	}
+	energy := NewEnergyMeter(sources...)
+
+	// Create strategy, using measured preset power where available
+	calibration := NewCalibration(repo, cfg)
+	if cfg.HarvestDBPath != "" {
+		harvest, err := database.NewSQLiteRepository(cfg.HarvestDBPath)
+		if err != nil {
+			log.Fatalf("Failed to open harvest database: %v", err)
+		}
+		defer harvest.Close()
+		calibration.WithSummaries(harvest)
+	}
+	strategy, err := NewStrategy(cfg.Strategy, repo, calibration, cfg)
+	if err != nil {
+		log.Fatalf("Invalid STRATEGY: %v", err)
+	}
 
-	// Create strategy
-	strategy := NewStrategy(repo, cfg)
+	// Load power domains
+	var domains *PowerDomain
+	if cfg.DomainsFile != "" {
+		domains, err = LoadDomains(cfg.DomainsFile)
+		if err != nil {
+			log.Fatalf("Failed to load power domains: %v", err)
+		}
+	}
 
 	// Create balancer
-	balancer := NewBalancer(repo, aggregator, controller, strategy, probers, cfg)
+	scheduler := NewScheduler(repo, cfg.ScheduleLocation)
+	balancer := NewBalancer(repo, energy, controller, strategy, calibration, domains, scheduler, probers, cfg)
 
 	// Create HTTP server
 	server := NewServer(repo, balancer, cfg)
@@ -124,6 +227,27 @@ func runStart(ctx context.Context, cfg *Config) {
 	log.Printf("Power Balancer starting...")
 	log.Printf("Database: %s", cfg.DBPath)
 	log.Printf("Networks: %v", cfg.NetworkCIDRs)
+	for _, sc := range energyCfg.Sources {
+		log.Printf("Energy source: %s (%s, %d plants)", sc.Name, sc.Type, len(sc.Plants))
+	}
+	if domains != nil {
+		log.Printf("Power domains: %s", describeDomains(domains))
+	}
+	if schedules, err := repo.ListSchedules(ctx); err == nil {
+		log.Printf("Schedules (%s): %s", cfg.ScheduleLocation, describeSchedules(schedules))
+	}
+	if len(cfg.DemandResponseKeys) > 0 {
+		log.Printf("Demand response API: %d keys", len(cfg.DemandResponseKeys))
+	}
+	if cfg.RampUpMWPerMin > 0 || cfg.RampDownMWPerMin > 0 {
+		log.Printf("Load ramp limits: up %.3f, down %.3f MW/min (0 = unlimited, emergency override: %v)",
+			cfg.RampUpMWPerMin, cfg.RampDownMWPerMin, cfg.RampEmergencyOverride)
+	}
+	log.Printf("Restart policy: %s (spacing %s, duration %s)", cfg.RestartPolicy, cfg.RestartSpacing, cfg.RestartDuration)
+	if cfg.ReconcileInterval > 0 {
+		log.Printf("Preset reconciliation: every %s (reassert: %v)", cfg.ReconcileInterval, cfg.ReconcileReassert)
+	}
+	log.Printf("Strategy: %s", strategy.Name())
 	log.Printf("Dashboard: http://localhost:%d", cfg.DashboardPort)
 	log.Printf("Thresholds: Emergency=%.0f%%, Critical=%.0f%%, Safe=%.0f%%, Recovery=%.0f%%",
 		cfg.EmergencyMargin, cfg.CriticalMargin, cfg.SafeMargin, cfg.RecoveryMargin)
@@ -175,17 +299,14 @@ func runDiscover(ctx context.Context, cfg *Config) {
 	}
 	defer repo.Close()
 
-	// Create VNish prober
-	vnishAuth := vnish.NewAuthManager(cfg.VNishPassword)
-	probers := []miner.FirmwareProber{
-		vnish.NewProber(vnishAuth, vnish.WithProberTimeout(cfg.ChangeSpacing)),
+	// Create probers and controller for discovery
+	probers, controller, err := setupFirmware(cfg, repo)
+	if err != nil {
+		log.Fatalf("Failed to set up firmware clients: %v", err)
 	}
 
-	// Create controller for discovery
-	controller := NewController(vnishAuth, cfg)
-
 	// Create balancer just for discovery
-	balancer := NewBalancer(repo, nil, controller, nil, probers, cfg)
+	balancer := NewBalancer(repo, nil, controller, nil, nil, nil, nil, probers, cfg)
 
 	log.Printf("Discovering miners on %d network(s): %v", len(networks), networks)
 	count, err := balancer.DiscoverMinersOnNetworks(ctx, networks)
@@ -212,8 +333,9 @@ func runStatus(ctx context.Context, cfg *Config) {
 		fmt.Printf("  Generation:  %.2f MW\n", reading.GenerationMW)
 		fmt.Printf("  Consumption: %.2f MW\n", reading.ConsumptionMW)
 		fmt.Printf("  Margin:      %.2f MW (%.1f%%)\n", reading.MarginMW, reading.MarginPercent)
-		fmt.Printf("  Generoso:    %.2f MW (%s)\n", reading.GenerosoMW, reading.GenerosoStatus)
-		fmt.Printf("  Nogueira:    %.2f MW (%s)\n", reading.NogueiraMW, reading.NogueiraStatus)
+		for _, p := range reading.Plants {
+			fmt.Printf("  %-16s %.2f MW (%s, %s)\n", p.Name+":", p.MW, p.Kind, p.Status)
+		}
 	}
 
 	// Get managed miners count
@@ -242,3 +364,57 @@ func runStatus(ctx context.Context, cfg *Config) {
 		}
 	}
 }
+
+// setupFirmware creates the probers and controller for the firmwares the
+// balancer can manage (VNish, Stock and Whatsminer).
+// VNish API keys are persisted in the balancer database.
+// Known miner MACs from the database make MAC credential rules apply.
+func setupFirmware(cfg *Config, repo *Repository) ([]miner.FirmwareProber, *Controller, error) {
+	credStore, err := database.NewSQLiteCredentialStore(repo.DB(), cfg.CredentialKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open credential store: %w", err)
+	}
+
+	vnishAuth := vnish.NewAuthManager(cfg.VNishPassword).
+		WithCredentialStore(credStore).
+		WithAPIKeyDescription("powerhive-power-balancer").
+		WithStoreErrorHandler(func(err error) { log.Printf("Credential store: %v", err) }).
+		WithPruneErrorHandler(func(host string, err error) {
+			log.Printf("[%s] Failed to prune stale API keys: %v", host, err)
+		})
+	stockAuth := stock.NewDigestAuthWithCredentials(cfg.StockUsername, cfg.StockPassword)
+	whatsminerAuth := whatsminer.NewAuthManager(cfg.WhatsminerPassword)
+
+	// Per-miner credentials, with the global ones as the last fallback
+	if cfg.CredentialsFile != "" {
+		creds, err := credentials.Load(cfg.CredentialsFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds.WithFallback(credentials.Set{
+			Name:          "env",
+			VNishPassword: cfg.VNishPassword,
+			StockUsername: cfg.StockUsername,
+			StockPassword: cfg.StockPassword,
+		})
+
+		miners, err := repo.ListMiners(context.Background())
+		if err != nil {
+			return nil, nil, fmt.Errorf("list miners: %w", err)
+		}
+		for _, m := range miners {
+			creds.LearnMAC(m.IPAddress, m.MACAddress)
+		}
+
+		vnishAuth.WithCredentialResolver(creds)
+		stockAuth.WithCredentialResolver(creds)
+	}
+
+	probers := []miner.FirmwareProber{
+		vnish.NewProber(vnishAuth, vnish.WithProberTimeout(cfg.ChangeSpacing)),
+		stock.NewProber(stockAuth, stock.WithProberTimeout(cfg.ChangeSpacing)),
+		whatsminer.NewProber(whatsminerAuth, whatsminer.WithProberTimeout(cfg.ChangeSpacing)),
+	}
+
+	return probers, NewController(vnishAuth, stockAuth, whatsminerAuth, cfg), nil
+}
@@ -13,6 +13,7 @@ const (
 	StateHolding    BalancerState = "HOLDING"
 	StateIncreasing BalancerState = "INCREASING"
 	StateEmergency  BalancerState = "EMERGENCY"
+	StateDataLoss   BalancerState = "DATA_LOSS"
 )
 
 // Model represents a discovered miner model with its preset limits.
@@ -42,6 +43,9 @@ type ModelPreset struct {
 	DisplayName      string  `json:"display_name"`
 	RequiresModdedPSU bool   `json:"requires_modded_psu"`
 	SortOrder        int     `json:"sort_order"`
+
+	// Joined fields
+	Calibration *PresetStats `json:"calibration,omitempty"` // Measured across the model's miners
 }
 
 // Miner represents a discovered miner.
@@ -61,6 +65,7 @@ type Miner struct {
 	CurrentPreset *ModelPreset `json:"current_preset,omitempty"`
 	Config        *BalanceConfig `json:"config,omitempty"`
 	Cooldown      *Cooldown    `json:"cooldown,omitempty"`
+	Parked        *ParkedMiner `json:"parked,omitempty"`
 }
 
 // BalanceConfig holds per-miner balancing configuration.
@@ -87,6 +92,24 @@ type PendingChange struct {
 	Miner      *Miner       `json:"miner,omitempty"`
 }
 
+// ParkedPreset stands in for the preset in the change log of a parked miner.
+const ParkedPreset = "parked"
+
+// ParkedMiner is a miner whose mining was stopped as a last resort, with the
+// preset it resumes on.
+type ParkedMiner struct {
+	MinerID  int64     `json:"miner_id"`
+	PresetID int64     `json:"preset_id"`
+	SavedW   int       `json:"saved_w"` // Expected power saved by parking
+	ParkedAt time.Time `json:"parked_at"`
+
+	// Joined fields
+	Miner      *Miner         `json:"-"`
+	Preset     *ModelPreset   `json:"preset,omitempty"`
+	Config     *BalanceConfig `json:"-"`
+	OnCooldown bool           `json:"-"`
+}
+
 // Cooldown represents a miner's cooldown period.
 type Cooldown struct {
 	MinerID int64     `json:"miner_id"`
@@ -101,10 +124,8 @@ type EnergyReading struct {
 	ConsumptionMW  float64   `json:"consumption_mw"`
 	MarginMW       float64   `json:"margin_mw"`
 	MarginPercent  float64   `json:"margin_percent"`
-	GenerosoMW     float64   `json:"generoso_mw"`
-	GenerosoStatus string    `json:"generoso_status"`
-	NogueiraMW     float64   `json:"nogueira_mw"`
-	NogueiraStatus string    `json:"nogueira_status"`
+	Confidence     float64   `json:"confidence"`
+	Plants         []PlantReading `json:"plants"`
 }
 
 // ChangeLog records preset changes for audit purposes.
@@ -121,6 +142,152 @@ type ChangeLog struct {
 	IssuedAt       time.Time  `json:"issued_at"`
 	Success        bool       `json:"success"`
 	ErrorMessage   string     `json:"error_message,omitempty"`
+
+	// Joined fields
+	Verification *ChangeVerification `json:"verification,omitempty"`
+}
+
+// Preset drift origins.
+const (
+	DriftBalancer = "balancer" // On a preset of the balancer's last change to the miner
+	DriftUnknown  = "unknown"  // Changed outside the balancer (e.g., in the VNish UI)
+)
+
+// PresetDrift is a miner found on a preset other than the one recorded in the
+// DB for it.
+type PresetDrift struct {
+	ID             int64      `json:"id"`
+	MinerID        int64      `json:"miner_id"`
+	MinerIP        string     `json:"miner_ip"`
+	ExpectedPreset string     `json:"expected_preset"` // Recorded in the DB
+	ActualPreset   string     `json:"actual_preset"`   // Reported by the miner
+	Origin         string     `json:"origin"`
+	Since          *time.Time `json:"since,omitempty"` // Balancer change issued, or last seen on the expected preset
+	DetectedAt     time.Time  `json:"detected_at"`
+	Reasserted     bool       `json:"reasserted"`
+}
+
+// Verification outcomes.
+const (
+	VerifyPending       = "pending"
+	VerifyConfirmed     = "confirmed"
+	VerifyReverted      = "reverted"       // Miner is back on (or never left) the old preset
+	VerifyWrongPreset   = "wrong_preset"   // Miner is on a preset that wasn't requested
+	VerifyPowerMismatch = "power_mismatch" // Preset applied, but measured delta far from expected
+	VerifyUnreachable   = "unreachable"    // Miner couldn't be measured when the change settled
+	VerifyUnverified    = "unverified"     // Pending change dropped before settling (miner offline)
+)
+
+// ChangeVerification compares a settled preset change with the miner's measured state.
+type ChangeVerification struct {
+	ID              int64      `json:"id"`
+	ChangeLogID     int64      `json:"change_log_id"`
+	PendingChangeID int64      `json:"pending_change_id"`
+	MinerID         int64      `json:"miner_id"`
+	FromPresetID    *int64     `json:"from_preset_id"`
+	ToPresetID      *int64     `json:"to_preset_id"`
+	Attempt         int        `json:"attempt"`          // 0 for the original change, n for the nth retry
+	ExpectedDeltaW  int        `json:"expected_delta_w"` // Positive = reduction
+	BeforeW         int        `json:"before_w"`         // Measured before the change, 0 if unknown
+	AfterW          int        `json:"after_w"`          // Measured at settlement, 0 if unknown
+	ActualDeltaW    *int       `json:"actual_delta_w"`   // BeforeW - AfterW, if both were measured
+	MeasuredPreset  string     `json:"measured_preset"`
+	Outcome         string     `json:"outcome"`
+	VerifiedAt      *time.Time `json:"verified_at"`
+}
+
+// Preset sample sources.
+const (
+	SampleSummary      = "summary"      // Harvested summary of a miner with no change in flight
+	SampleVerification = "verification" // Measured when a change settled
+)
+
+// PresetSample is one measurement of a miner running a preset.
+type PresetSample struct {
+	ID         int64     `json:"id"`
+	MinerID    int64     `json:"miner_id"`
+	PresetID   int64     `json:"preset_id"`
+	Watts      int       `json:"watts"`
+	HashrateTH float64   `json:"hashrate_th"`
+	Source     string    `json:"source"`
+	SampledAt  time.Time `json:"sampled_at"`
+}
+
+// Schedule kinds.
+const (
+	ScheduleWeekly  = "weekly"  // Recurring window on days of the week
+	ScheduleOnce    = "once"    // One-off window
+	ScheduleHoliday = "holiday" // Days without weekly windows
+)
+
+// ScheduleMinPreset targets each model's minimum preset.
+const ScheduleMinPreset = "min"
+
+// Schedule is a time-of-use window limiting the site, or a holiday exception.
+// Times are in SCHEDULE_TIMEZONE.
+type Schedule struct {
+	ID               int64     `json:"id"`
+	Name             string    `json:"name"`
+	Kind             string    `json:"kind"`
+	Enabled          bool      `json:"enabled"`
+	Days             []string  `json:"days,omitempty"`               // weekly: mon, tue, wed, thu, fri, sat, sun
+	Start            string    `json:"start"`                        // weekly: HH:MM, once: YYYY-MM-DD HH:MM, holiday: YYYY-MM-DD
+	End              string    `json:"end"`                          // As start; holiday: last day (default: start)
+	MaxConsumptionKW float64   `json:"max_consumption_kw,omitempty"` // Site consumption cap (0 = none)
+	TargetPreset     string    `json:"target_preset,omitempty"`      // "min" or a preset name miners are held at or below
+	CreatedAt        time.Time `json:"created_at"`
+
+	// Computed fields
+	Active bool `json:"active"`
+}
+
+// Demand-response event statuses.
+const (
+	DRScheduled = "scheduled" // Accepted, not started yet
+	DRActive    = "active"
+	DRCompleted = "completed"
+	DRCancelled = "cancelled"
+)
+
+// DemandResponseEvent is a curtailment request from the grid operator or the
+// trading desk: hold site consumption at or below a target from StartAt for
+// Duration, reaching it within Ramp of the start. The target is absolute
+// (TargetMW) or a reduction below the consumption when the event starts
+// (ReduceMW).
+type DemandResponseEvent struct {
+	ID         int64        `json:"id"`
+	Source     string       `json:"source"`              // Name of the API key that sent it
+	Reference  string       `json:"reference,omitempty"` // The caller's own event ID
+	StartAt    time.Time    `json:"start_at"`
+	Duration   JSONDuration `json:"duration"`
+	Ramp       JSONDuration `json:"ramp"`
+	TargetMW   *float64     `json:"target_mw,omitempty"`
+	ReduceMW   *float64     `json:"reduce_mw,omitempty"`
+	BaselineMW *float64     `json:"baseline_mw,omitempty"` // Consumption when the event started
+	Status     string       `json:"status"`
+	CreatedAt  time.Time    `json:"created_at"`
+	EndedAt    *time.Time   `json:"ended_at,omitempty"`
+
+	// Computed fields
+	Compliance *DRCompliance `json:"compliance,omitempty"`
+}
+
+// DRSample is the site consumption against the target at one tick of an event.
+type DRSample struct {
+	Timestamp     time.Time `json:"timestamp"`
+	ConsumptionMW float64   `json:"consumption_mw"`
+	TargetMW      float64   `json:"target_mw"`
+	AchievedMW    float64   `json:"achieved_mw"` // Reduction below the baseline
+}
+
+// DRCompliance summarizes the samples of an event taken after its ramp
+// deadline.
+type DRCompliance struct {
+	Samples           int     `json:"samples"`
+	CompliantSamples  int     `json:"compliant_samples"` // Consumption at or below the target
+	CompliancePercent float64 `json:"compliance_percent"`
+	MeanAchievedMW    float64 `json:"mean_achieved_mw"` // Mean reduction below the baseline
+	MaxOverMW         float64 `json:"max_over_mw"`      // Worst excess over the target
 }
 
 // MinerWithContext is a miner with all related data for balancing decisions.
@@ -148,9 +315,19 @@ type SystemStatus struct {
 	EffectiveMarginPercent float64       `json:"effective_margin_percent"`
 	ManagedMinersCount     int           `json:"managed_miners_count"`
 	MinersOnCooldown       int           `json:"miners_on_cooldown"`
-	GenerosoStatus         string        `json:"generoso_status"`
-	GenerosoMW             float64       `json:"generoso_mw"`
-	NogueiraStatus         string        `json:"nogueira_status"`
-	NogueiraMW             float64       `json:"nogueira_mw"`
+	ParkedMiners           int           `json:"parked_miners"`
+	ParkedW                int           `json:"parked_w"`
+	Plants                 []PlantReading `json:"plants"`
+	BadReadings            int           `json:"bad_readings"`
+	DataProblem            string        `json:"data_problem,omitempty"`
+	Strategy               string        `json:"strategy"`
+	Domains                []DomainStatus `json:"domains,omitempty"`
+	Schedules              []string      `json:"schedules,omitempty"`       // Active schedule windows
+	ScheduleCapMW          float64       `json:"schedule_cap_mw,omitempty"` // Lowest active consumption cap
+	ScheduleTargets        []string      `json:"schedule_targets,omitempty"`
+	DemandResponse         *DRStatus     `json:"demand_response,omitempty"`
+	Ramp                   *RampStatus   `json:"ramp,omitempty"`
+	RestartingMiners       int           `json:"restarting_miners"`
+	RestartsQueued         int           `json:"restarts_queued"`
 	LastUpdated            time.Time     `json:"last_updated"`
 }
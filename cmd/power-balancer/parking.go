@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CalculatePark picks miners to park, lowest priority first and least
+// efficient first within a priority, until they save targetW. Parking is the
+// last resort: nothing is picked while any manageable miner is above its
+// minimum preset, even one on cooldown.
+func (s *planner) CalculatePark(ctx context.Context, targetW int) ([]*MinerWithContext, error) {
+	if targetW <= 0 {
+		return nil, nil
+	}
+
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*MinerWithContext
+	for _, m := range miners {
+		if m.HeadroomWatts > 0 {
+			return nil, nil
+		}
+		if !m.OnCooldown && m.CurrentPreset.Watts > 0 {
+			candidates = append(candidates, m)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Config.Priority != candidates[j].Config.Priority {
+			return candidates[i].Config.Priority < candidates[j].Config.Priority
+		}
+		return candidates[i].Efficiency < candidates[j].Efficiency
+	})
+
+	var park []*MinerWithContext
+	var savedW int
+	for _, m := range candidates {
+		if savedW >= targetW {
+			break
+		}
+		park = append(park, m)
+		savedW += m.CurrentPreset.Watts
+	}
+	return park, nil
+}
+
+// CalculateStop returns every manageable miner drawing power, biggest draw
+// first. Cooldowns are ignored, as for the fail-safe changes.
+func (s *planner) CalculateStop(ctx context.Context) ([]*MinerWithContext, error) {
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var stop []*MinerWithContext
+	for _, m := range miners {
+		if m.CurrentPreset.Watts > 0 {
+			stop = append(stop, m)
+		}
+	}
+	sort.SliceStable(stop, func(i, j int) bool {
+		return stop[i].CurrentPreset.Watts > stop[j].CurrentPreset.Watts
+	})
+	return stop, nil
+}
+
+// CalculateUnpark picks the online parked miners off cooldown that can resume
+// within roomW, highest priority first and most efficient first within a
+// priority. Their resume preset is calibrated; miners parked on a preset above
+// their schedule target stay parked.
+func (s *planner) CalculateUnpark(ctx context.Context, roomW int) ([]*ParkedMiner, error) {
+	if roomW <= 0 {
+		return nil, nil
+	}
+
+	parked, err := s.repo.GetParkedMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []*ParkedMiner
+	for _, p := range parked {
+		if !p.Miner.IsOnline || p.OnCooldown || !s.inScope(p.MinerID) {
+			continue
+		}
+		p.Preset = s.calibration.Apply(p.MinerID, p.Preset)
+		if t := s.parkedTarget(ctx, p); t != nil && p.Preset.Watts > t.Watts {
+			continue // Parked above the schedule target
+		}
+		candidates = append(candidates, p)
+	}
+
+	efficiency := func(p *ParkedMiner) float64 {
+		if p.Preset.Watts <= 0 {
+			return 0
+		}
+		return p.Preset.HashrateTH / float64(p.Preset.Watts)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].Config.Priority != candidates[j].Config.Priority {
+			return candidates[i].Config.Priority > candidates[j].Config.Priority
+		}
+		return efficiency(candidates[i]) > efficiency(candidates[j])
+	})
+
+	var resume []*ParkedMiner
+	var addedW int
+	for _, p := range candidates {
+		if addedW+p.Preset.Watts > roomW {
+			continue
+		}
+		resume = append(resume, p)
+		addedW += p.Preset.Watts
+	}
+	return resume, nil
+}
+
+// =============================================================================
+// Parking
+// =============================================================================
+
+// parkMiners parks up to MaxParallelEmergency miners at once, in order until
+// one doesn't fit the ramp limits. cause is why they're parked (emergency,
+// domain_cap, demand_response, data_loss), for the ramp override. Miners
+// parked for data loss are recorded, to resume once the data is back.
+func (b *Balancer) parkMiners(ctx context.Context, miners []*MinerWithContext, cause string, margin float64) {
+	count := min(len(miners), b.cfg.MaxParallelEmergency)
+
+	if r, err := b.currentRamp(ctx); err != nil {
+		log.Printf("Failed to compute load ramp: %v", err)
+	} else {
+		for i := 0; i < count; i++ {
+			m := miners[i]
+			if !b.rampAllows(ctx, r, &ChangeLog{
+				MinerID:        &m.Miner.ID,
+				MinerIP:        m.Miner.IPAddress,
+				ModelName:      m.Model.Name,
+				FromPreset:     m.CurrentPreset.Name,
+				ToPreset:       ParkedPreset,
+				ExpectedDeltaW: m.CurrentPreset.Watts,
+				Reason:         cause,
+				MarginAtTime:   margin,
+			}) {
+				count = i
+			}
+		}
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		parked []int64
+	)
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		go func(m *MinerWithContext) {
+			defer wg.Done()
+			if err := b.parkMiner(ctx, m, margin); err != nil {
+				log.Printf("Park failed: %v", err)
+				return
+			}
+			mu.Lock()
+			parked = append(parked, m.Miner.ID)
+			mu.Unlock()
+		}(miners[i])
+	}
+	wg.Wait()
+
+	if cause == "data_loss" {
+		for _, id := range parked {
+			b.dataLossParked[id] = true
+		}
+	}
+}
+
+// resumeDataLossParked resumes the miners parked for data loss that are still
+// parked, once the effective margin is back above SafeMargin. Up to
+// MaxParallelEmergency resume per tick, within the room left under SafeMargin,
+// the demand-response target, the power domains and the ramp limits.
+func (b *Balancer) resumeDataLossParked(ctx context.Context, reading *EnergyReading, effectiveMarginPercent float64) {
+	if len(b.dataLossParked) == 0 || b.state == StateDataLoss || effectiveMarginPercent < b.cfg.SafeMargin {
+		return
+	}
+
+	parked, err := b.repo.GetParkedMiners(ctx)
+	if err != nil {
+		log.Printf("Failed to get parked miners: %v", err)
+		return
+	}
+
+	roomW := int((reading.GenerationMW*(1-b.cfg.SafeMargin/100) - reading.ConsumptionMW) * 1_000_000)
+	if b.demand != nil {
+		roomW = min(roomW, b.demand.headroomW)
+	}
+
+	// Miners unparked some other way are forgotten
+	still := make(map[int64]bool)
+	var resumed int
+	for _, p := range parked {
+		if !b.dataLossParked[p.MinerID] {
+			continue
+		}
+		still[p.MinerID] = true
+
+		p.Preset = b.calibration.Apply(p.MinerID, p.Preset)
+		if resumed >= b.cfg.MaxParallelEmergency || !p.Miner.IsOnline || p.OnCooldown ||
+			p.Preset.Watts > roomW || !fitsDomains(b.domainLoads, p.MinerID, p.Preset.Watts) {
+			continue
+		}
+		if !b.admitUnpark(ctx, p, reading.MarginPercent) {
+			break // Wait for the ramp
+		}
+		if err := b.unparkMiner(ctx, p, reading.MarginPercent); err != nil {
+			log.Printf("Unpark failed: %v", err)
+			continue
+		}
+		delete(still, p.MinerID)
+		roomW -= p.Preset.Watts
+		resumed++
+	}
+	b.dataLossParked = still
+}
+
+// parkMiner stops mining on a miner. Until it settles, the power it saves
+// counts as a pending change like a preset reduction's.
+func (b *Balancer) parkMiner(ctx context.Context, m *MinerWithContext, margin float64) error {
+	savedW := m.CurrentPreset.Watts
+	log.Printf("Parking %s on %s (delta: %dW, reason: park)",
+		m.Miner.IPAddress, m.CurrentPreset.Name, savedW)
+
+	err := b.controller.Park(ctx, m.Miner.IPAddress, m.Miner.FirmwareType)
+
+	logEntry := &ChangeLog{
+		MinerID:        &m.Miner.ID,
+		MinerIP:        m.Miner.IPAddress,
+		ModelName:      m.Model.Name,
+		FromPreset:     m.CurrentPreset.Name,
+		ToPreset:       ParkedPreset,
+		ExpectedDeltaW: savedW,
+		Reason:         "park",
+		MarginAtTime:   margin,
+		IssuedAt:       time.Now(),
+		Success:        err == nil,
+	}
+	if err != nil {
+		logEntry.ErrorMessage = err.Error()
+		b.markUnreachable(ctx, m.Miner)
+	}
+	if logErr := b.repo.InsertChangeLog(ctx, logEntry); logErr != nil {
+		log.Printf("Failed to log park: %v", logErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("park %s: %w", m.Miner.IPAddress, err)
+	}
+
+	parked := &ParkedMiner{
+		MinerID:  m.Miner.ID,
+		PresetID: m.CurrentPreset.ID,
+		SavedW:   savedW,
+		ParkedAt: time.Now(),
+	}
+	if err := b.repo.ParkMiner(ctx, parked); err != nil {
+		log.Printf("Failed to record parked miner: %v", err)
+	}
+
+	b.recordParkingChange(ctx, m.Miner.ID, &m.CurrentPreset.ID, nil, savedW)
+	return nil
+}
+
+// unparkMiner resumes mining on a parked miner, on the preset it was parked on.
+func (b *Balancer) unparkMiner(ctx context.Context, p *ParkedMiner, margin float64) error {
+	deltaW := -p.Preset.Watts // Negative = increase
+	log.Printf("Resuming %s on %s (delta: %dW, reason: unpark)",
+		p.Miner.IPAddress, p.Preset.Name, deltaW)
+
+	err := b.controller.Unpark(ctx, p.Miner.IPAddress, p.Miner.FirmwareType, p.Preset.Name)
+
+	logEntry := &ChangeLog{
+		MinerID:        &p.MinerID,
+		MinerIP:        p.Miner.IPAddress,
+		ModelName:      p.Miner.Model.Name,
+		FromPreset:     ParkedPreset,
+		ToPreset:       p.Preset.Name,
+		ExpectedDeltaW: deltaW,
+		Reason:         "unpark",
+		MarginAtTime:   margin,
+		IssuedAt:       time.Now(),
+		Success:        err == nil,
+	}
+	if err != nil {
+		logEntry.ErrorMessage = err.Error()
+		b.markUnreachable(ctx, p.Miner)
+	}
+	if logErr := b.repo.InsertChangeLog(ctx, logEntry); logErr != nil {
+		log.Printf("Failed to log unpark: %v", logErr)
+	}
+
+	if err != nil {
+		return fmt.Errorf("unpark %s: %w", p.Miner.IPAddress, err)
+	}
+
+	if err := b.repo.UnparkMiner(ctx, p.MinerID); err != nil {
+		log.Printf("Failed to clear parked miner: %v", err)
+	}
+	if err := b.repo.UpdateMinerPreset(ctx, p.MinerID, p.PresetID); err != nil {
+		log.Printf("Failed to update miner preset: %v", err)
+	}
+
+	b.recordParkingChange(ctx, p.MinerID, nil, &p.PresetID, deltaW)
+	return nil
+}
+
+// recordParkingChange sets the miner's cooldown and records the pending
+// change of a park (no to preset) or unpark (no from preset).
+func (b *Balancer) recordParkingChange(ctx context.Context, minerID int64, fromPresetID, toPresetID *int64, deltaW int) {
+	if err := b.cooldowns.SetCooldown(ctx, minerID); err != nil {
+		log.Printf("Failed to set cooldown: %v", err)
+	}
+
+	pending := &PendingChange{
+		MinerID:        minerID,
+		FromPresetID:   fromPresetID,
+		ToPresetID:     toPresetID,
+		ExpectedDeltaW: deltaW,
+		IssuedAt:       time.Now(),
+		SettlesAt:      time.Now().Add(b.cfg.SettleTime),
+	}
+	if err := b.repo.CreatePendingChange(ctx, pending); err != nil {
+		log.Printf("Failed to record pending change: %v", err)
+	}
+}
+
+// markUnreachable marks a miner offline after a failed command.
+func (b *Balancer) markUnreachable(ctx context.Context, m *Miner) {
+	if err := b.repo.SetMinerOnlineStatus(ctx, m.ID, false); err != nil {
+		log.Printf("Failed to mark miner %s offline: %v", m.IPAddress, err)
+	} else {
+		log.Printf("Marked miner %s as offline due to connection failure", m.IPAddress)
+	}
+}
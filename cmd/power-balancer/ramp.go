@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// rampExempt lists the reasons whose reductions skip the ramp-down limit with
+// RAMP_EMERGENCY_OVERRIDE: those protecting the site rather than balancing it.
+var rampExempt = map[string]bool{
+	"emergency":  true,
+	"data_loss":  true,
+	"domain_cap": true,
+}
+
+// rampWindow returns the time over which a change moves the load: its settle
+// window, or a minute for changes settling faster, which move the load within
+// that minute.
+func rampWindow(issuedAt, settlesAt time.Time) time.Duration {
+	return max(settlesAt.Sub(issuedAt), time.Minute)
+}
+
+// rampBudget is the load ramp of the unsettled changes and the changes
+// admitted this tick, down (reductions) and up (increases), in W per minute.
+type rampBudget struct {
+	downW float64
+	upW   float64
+}
+
+// currentRamp spreads the delta of every unsettled change evenly over its
+// settle window and adds up the rates, by direction.
+func (b *Balancer) currentRamp(ctx context.Context) (*rampBudget, error) {
+	pending, err := b.repo.GetPendingChanges(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get pending changes: %w", err)
+	}
+
+	r := &rampBudget{}
+	for _, p := range pending {
+		rate := float64(p.ExpectedDeltaW) / rampWindow(p.IssuedAt, p.SettlesAt).Minutes()
+		if rate > 0 {
+			r.downW += rate
+		} else {
+			r.upW -= rate
+		}
+	}
+	return r, nil
+}
+
+// rampAllows returns true if a change (positive delta = reduction) fits the
+// ramp limits, and counts it against the budget. A change always fits
+// when nothing else ramps the load its way, so no change is held forever. A
+// held change is logged as ramp_limited once per miner and reason.
+func (b *Balancer) rampAllows(ctx context.Context, r *rampBudget, entry *ChangeLog) bool {
+	limitMW := b.cfg.RampUpMWPerMin
+	current := &r.upW
+	if entry.ExpectedDeltaW > 0 {
+		limitMW = b.cfg.RampDownMWPerMin
+		current = &r.downW
+		if b.cfg.RampEmergencyOverride && rampExempt[entry.Reason] {
+			limitMW = 0
+		}
+	}
+
+	window := rampWindow(time.Now(), time.Now().Add(b.cfg.SettleTime))
+	rate := float64(abs(entry.ExpectedDeltaW)) / window.Minutes()
+
+	if limitMW <= 0 || *current == 0 || *current+rate <= limitMW*1_000_000 {
+		*current += rate
+		if entry.MinerID != nil {
+			delete(b.rampHeld, *entry.MinerID)
+		}
+		return true
+	}
+
+	direction := "up"
+	if entry.ExpectedDeltaW > 0 {
+		direction = "down"
+	}
+	if entry.MinerID != nil {
+		if b.rampHeld[*entry.MinerID] == entry.Reason {
+			return false
+		}
+		b.rampHeld[*entry.MinerID] = entry.Reason
+	}
+
+	log.Printf("Holding %s %s -> %s (delta: %dW, reason: %s): ramp %s at %.3f of %.3f MW/min",
+		entry.MinerIP, entry.FromPreset, entry.ToPreset, entry.ExpectedDeltaW, entry.Reason,
+		direction, *current/1_000_000, limitMW)
+
+	entry.ErrorMessage = fmt.Sprintf("%s held: ramp %s at %.3f of %.3f MW/min",
+		entry.Reason, direction, *current/1_000_000, limitMW)
+	entry.Reason = "ramp_limited"
+	entry.IssuedAt = time.Now()
+	if err := b.repo.InsertChangeLog(ctx, entry); err != nil {
+		log.Printf("Failed to log ramp-limited change: %v", err)
+	}
+	return false
+}
+
+// rampAllowsChange returns true if a preset change fits the ramp limits.
+func (b *Balancer) rampAllowsChange(ctx context.Context, r *rampBudget, change *PresetChange, reason string, margin float64) bool {
+	return b.rampAllows(ctx, r, &ChangeLog{
+		MinerID:        &change.Miner.Miner.ID,
+		MinerIP:        change.Miner.Miner.IPAddress,
+		ModelName:      change.Miner.Model.Name,
+		FromPreset:     change.FromPreset.Name,
+		ToPreset:       change.ToPreset.Name,
+		ExpectedDeltaW: change.ExpectedDeltaW,
+		Reason:         reason,
+		MarginAtTime:   margin,
+	})
+}
+
+// admitChange returns true if a single change fits the ramp limits.
+func (b *Balancer) admitChange(ctx context.Context, change *PresetChange, reason string, margin float64) bool {
+	r, err := b.currentRamp(ctx)
+	if err != nil {
+		log.Printf("Failed to compute load ramp: %v", err)
+		return true
+	}
+	return b.rampAllowsChange(ctx, r, change, reason, margin)
+}
+
+// admitUnpark returns true if resuming a parked miner fits the ramp limits.
+func (b *Balancer) admitUnpark(ctx context.Context, p *ParkedMiner, margin float64) bool {
+	r, err := b.currentRamp(ctx)
+	if err != nil {
+		log.Printf("Failed to compute load ramp: %v", err)
+		return true
+	}
+	return b.rampAllows(ctx, r, &ChangeLog{
+		MinerID:        &p.MinerID,
+		MinerIP:        p.Miner.IPAddress,
+		ModelName:      p.Miner.Model.Name,
+		FromPreset:     ParkedPreset,
+		ToPreset:       p.Preset.Name,
+		ExpectedDeltaW: -p.Preset.Watts,
+		Reason:         "unpark",
+		MarginAtTime:   margin,
+	})
+}
+
+// RampStatus is the current load ramp against the limits, for the dashboard.
+type RampStatus struct {
+	DownMW      float64 `json:"down_mw"` // MW per minute
+	UpMW        float64 `json:"up_mw"`
+	DownLimitMW float64 `json:"down_limit_mw"` // 0 = unlimited
+	UpLimitMW   float64 `json:"up_limit_mw"`
+}
+
+// rampStatus returns the current load ramp, or nil without ramp limits.
+func (b *Balancer) rampStatus(ctx context.Context) *RampStatus {
+	if b.cfg.RampUpMWPerMin <= 0 && b.cfg.RampDownMWPerMin <= 0 {
+		return nil
+	}
+	r, err := b.currentRamp(ctx)
+	if err != nil {
+		return nil
+	}
+	return &RampStatus{
+		DownMW:      r.downW / 1_000_000,
+		UpMW:        r.upW / 1_000_000,
+		DownLimitMW: b.cfg.RampDownMWPerMin,
+		UpLimitMW:   b.cfg.RampUpMWPerMin,
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
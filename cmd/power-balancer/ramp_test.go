@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+func TestRampWindow(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		settlesIn time.Duration
+		want      time.Duration
+	}{
+		{5 * time.Minute, 5 * time.Minute},
+		{time.Minute, time.Minute},
+		{10 * time.Second, time.Minute},
+		{0, time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := rampWindow(now, now.Add(tt.settlesIn)); got != tt.want {
+			t.Errorf("rampWindow(%v) = %v, want %v", tt.settlesIn, got, tt.want)
+		}
+	}
+}
+
+func TestAdmitChange(t *testing.T) {
+	// Unsettled changes of another miner (positive delta = reduction), each
+	// settling window after it was issued
+	type pending struct {
+		deltaW int
+		window time.Duration
+	}
+
+	// The change admitted moves 400 W over the 2m settle time: 200 W/min,
+	// against limits of 500 W/min
+	tests := []struct {
+		name     string
+		from, to string
+		reason   string
+		noLimit  bool
+		override bool
+		pending  []pending
+
+		want bool
+	}{
+		{name: "no limit", from: "2100", to: "1700", reason: "reduce", noLimit: true,
+			pending: []pending{{1000, time.Minute}}, want: true},
+		{name: "first change always fits", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{-1000, time.Minute}}, want: true},
+		{name: "fits under the limit", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{200, 2 * time.Minute}, {100, time.Minute}}, want: true},
+		{name: "fits at the limit", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{600, 2 * time.Minute}}, want: true},
+		{name: "held over the limit", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{800, 2 * time.Minute}}, want: false},
+		{name: "later deadline spreads the ramp", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{800, 4 * time.Minute}}, want: true},
+		{name: "settling within a minute ramps over a minute", from: "2100", to: "1700", reason: "reduce",
+			pending: []pending{{250, 10 * time.Second}}, want: true},
+		{name: "increase held by the up limit", from: "1700", to: "2100", reason: "increase",
+			pending: []pending{{-800, 2 * time.Minute}, {800, time.Minute}}, want: false},
+		{name: "emergency with override", from: "2100", to: "1700", reason: "emergency", override: true,
+			pending: []pending{{800, 2 * time.Minute}}, want: true},
+		{name: "emergency without override", from: "2100", to: "1700", reason: "emergency",
+			pending: []pending{{800, 2 * time.Minute}}, want: false},
+		{name: "override only exempts site protection", from: "2100", to: "1700", reason: "reduce", override: true,
+			pending: []pending{{800, 2 * time.Minute}}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := DefaultConfig()
+			cfg.SettleTime = 2 * time.Minute
+			cfg.RampEmergencyOverride = tt.override
+			if !tt.noLimit {
+				cfg.RampDownMWPerMin = 0.0005
+				cfg.RampUpMWPerMin = 0.0005
+			}
+			b := newTestBalancer(t, cfg)
+			tm := addMiner(t, b, miner.FirmwareVNish, 1, tt.from)
+			other := addMiner(t, b, miner.FirmwareVNish, 2, "2100")
+
+			now := time.Now()
+			for _, p := range tt.pending {
+				err := b.repo.CreatePendingChange(ctx, &PendingChange{
+					MinerID:        other.id,
+					ExpectedDeltaW: p.deltaW,
+					IssuedAt:       now,
+					SettlesAt:      now.Add(p.window),
+				})
+				if err != nil {
+					t.Fatalf("CreatePendingChange: %v", err)
+				}
+			}
+
+			m := manageable(t, b, tm.id)
+			change := &PresetChange{Miner: m, FromPreset: m.CurrentPreset, ToPreset: modelPreset(t, b, m, tt.to)}
+			change.ExpectedDeltaW = change.FromPreset.Watts - change.ToPreset.Watts
+			if abs(change.ExpectedDeltaW) != 400 {
+				t.Fatalf("change moves %d W, want 400", change.ExpectedDeltaW)
+			}
+
+			if got := b.admitChange(ctx, change, tt.reason, 5); got != tt.want {
+				t.Fatalf("admitChange = %v, want %v", got, tt.want)
+			}
+
+			// A held change is logged once, however often it's retried
+			b.admitChange(ctx, change, tt.reason, 5)
+			logs, err := b.repo.GetRecentChangeLogs(ctx, 10)
+			if err != nil {
+				t.Fatalf("GetRecentChangeLogs: %v", err)
+			}
+			if tt.want {
+				if len(logs) != 0 {
+					t.Errorf("logged %d changes for an admitted change, want none", len(logs))
+				}
+				return
+			}
+			if len(logs) != 1 {
+				t.Fatalf("logged %d changes, want 1", len(logs))
+			}
+			if l := logs[0]; l.Reason != "ramp_limited" || !strings.HasPrefix(l.ErrorMessage, tt.reason+" held") ||
+				l.ToPreset != tt.to || *l.MinerID != tm.id {
+				t.Errorf("change log = %+v, want %s to %s held", l, tt.reason, tt.to)
+			}
+		})
+	}
+}
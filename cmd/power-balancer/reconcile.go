@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// reconcileWorkers is how many miners are read at once when reconciling.
+const reconcileWorkers = 10
+
+// runReconcileLoop periodically checks the preset of every managed miner
+// against the DB, so the strategy doesn't plan from presets changed behind
+// the balancer's back.
+func (b *Balancer) runReconcileLoop(ctx context.Context) {
+	if b.cfg.ReconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(b.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.reconcilePresets(ctx)
+		}
+	}
+}
+
+// reconcilePresets reads the preset of every managed miner with no change in
+// flight and corrects the DB where it drifted. Miners with a change issued or
+// a restart pending while they were read are left for the next run.
+func (b *Balancer) reconcilePresets(ctx context.Context) {
+	miners, err := b.repo.GetManageableMiners(ctx)
+	if err != nil {
+		log.Printf("Reconcile: failed to list miners: %v", err)
+		return
+	}
+	before, err := b.changingMiners(ctx)
+	if err != nil {
+		log.Printf("Reconcile: failed to get pending changes: %v", err)
+		return
+	}
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		actual = make(map[int64]string)
+	)
+	sem := make(chan struct{}, reconcileWorkers)
+
+	for _, m := range miners {
+		if before[m.Miner.ID] {
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(m *MinerWithContext) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			name, err := b.controller.GetCurrentPreset(ctx, m.Miner.IPAddress, m.Miner.FirmwareType)
+			if err != nil {
+				return // Unreachable miners are left to discovery
+			}
+			mu.Lock()
+			actual[m.Miner.ID] = name
+			mu.Unlock()
+		}(m)
+	}
+	wg.Wait()
+
+	after, err := b.changingMiners(ctx)
+	if err != nil {
+		log.Printf("Reconcile: failed to get pending changes: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var checked, drifted int
+	for _, m := range miners {
+		name, ok := actual[m.Miner.ID]
+		if !ok || after[m.Miner.ID] {
+			continue
+		}
+		checked++
+		if name == m.CurrentPreset.Name {
+			b.presetSeen[m.Miner.ID] = now
+			delete(b.unknownDrift, m.Miner.ID)
+			continue
+		}
+		drifted++
+		if err := b.reconcileMiner(ctx, m, name, now); err != nil {
+			log.Printf("[%s] Failed to reconcile preset: %v", m.Miner.IPAddress, err)
+		}
+	}
+
+	log.Printf("Reconcile: checked %d miners, %d drifted", checked, drifted)
+}
+
+// changingMiners returns the miners with an unsettled change or a restart
+// queued or in progress.
+func (b *Balancer) changingMiners(ctx context.Context) (map[int64]bool, error) {
+	pending, err := b.repo.GetPendingChanges(ctx)
+	if err != nil {
+		return nil, err
+	}
+	changing := make(map[int64]bool)
+	for _, p := range pending {
+		changing[p.MinerID] = true
+	}
+
+	b.restarts.mu.Lock()
+	for _, j := range b.restarts.jobs {
+		changing[j.minerID] = true
+	}
+	b.restarts.mu.Unlock()
+
+	return changing, nil
+}
+
+// reconcileMiner records the drift of a miner found on actualName, moves it
+// to that preset in the DB and, with ReconcileReassert, queues the move back
+// to the expected preset if it was changed outside the balancer to one
+// drawing more. Drifts to less power are left to the strategy, which can now
+// see them. A drift to a preset the model doesn't have is recorded once.
+func (b *Balancer) reconcileMiner(ctx context.Context, m *MinerWithContext, actualName string, now time.Time) error {
+	expected := m.CurrentPreset
+
+	actual, err := b.repo.GetPresetByModelAndName(ctx, m.Model.ID, actualName)
+	if err != nil {
+		// Not a preset of the model; the DB is left as is, so the miner
+		// drifts on every run until it's moved back
+		if b.unknownDrift[m.Miner.ID] == actualName {
+			return nil
+		}
+		b.unknownDrift[m.Miner.ID] = actualName
+		actual = nil
+	} else {
+		delete(b.unknownDrift, m.Miner.ID)
+	}
+
+	drift := &PresetDrift{
+		MinerID:        m.Miner.ID,
+		MinerIP:        m.Miner.IPAddress,
+		ExpectedPreset: expected.Name,
+		ActualPreset:   actualName,
+		Origin:         DriftUnknown,
+		DetectedAt:     now,
+	}
+
+	// The balancer's last change is to blame if the miner is on its preset
+	// (recorded wrong), or still on the one it moved the miner from and was
+	// never seen off it since (didn't take)
+	seen, wasSeen := b.presetSeen[m.Miner.ID]
+	last, err := b.repo.GetLastPresetChange(ctx, m.Miner.ID)
+	switch {
+	case err == nil && (actualName == last.ToPreset ||
+		(actualName == last.FromPreset && (!wasSeen || seen.Before(last.IssuedAt)))):
+		drift.Origin = DriftBalancer
+		drift.Since = &last.IssuedAt
+	case err != nil && !errors.Is(err, sql.ErrNoRows):
+		return fmt.Errorf("get last change: %w", err)
+	case wasSeen:
+		drift.Since = &seen
+	}
+
+	logEntry := &ChangeLog{
+		MinerID:      &m.Miner.ID,
+		MinerIP:      m.Miner.IPAddress,
+		ModelName:    m.Model.Name,
+		FromPreset:   expected.Name,
+		ToPreset:     actualName,
+		Reason:       "drift",
+		MarginAtTime: b.GetStatus().MarginPercent,
+		IssuedAt:     now,
+		Success:      actual != nil,
+	}
+	if actual != nil {
+		logEntry.ExpectedDeltaW = expected.Watts - actual.Watts
+	} else {
+		logEntry.ErrorMessage = fmt.Sprintf("%s is not a preset of %s", actualName, m.Model.Name)
+	}
+
+	since := "unknown"
+	if drift.Since != nil {
+		since = drift.Since.Format("2006-01-02 15:04:05")
+	}
+	log.Printf("[%s] Preset drifted from %s to %s (origin: %s, since: %s)",
+		m.Miner.IPAddress, expected.Name, actualName, drift.Origin, since)
+
+	if actual != nil {
+		if err := b.repo.UpdateMinerPreset(ctx, m.Miner.ID, actual.ID); err != nil {
+			return fmt.Errorf("update miner preset: %w", err)
+		}
+	}
+
+	reassert := b.cfg.ReconcileReassert && drift.Origin == DriftUnknown &&
+		actual != nil && actual.Watts > expected.Watts
+	drift.Reasserted = reassert
+
+	if err := b.repo.InsertPresetDrift(ctx, drift); err != nil {
+		log.Printf("Failed to record preset drift: %v", err)
+	}
+	if err := b.repo.InsertChangeLog(ctx, logEntry); err != nil {
+		log.Printf("Failed to log preset drift: %v", err)
+	}
+
+	if reassert {
+		b.reasserts.mu.Lock()
+		b.reasserts.presets[m.Miner.ID] = reassertJob{from: actual.ID, to: expected}
+		b.reasserts.mu.Unlock()
+	}
+	return nil
+}
+
+// reassertJob moves a drifted miner back from the preset it was found on
+// (from) to the one it was expected on (to).
+type reassertJob struct {
+	from int64
+	to   *ModelPreset
+}
+
+// reassertQueue holds the reasserts found by the reconciler, by miner, until
+// the tick issues them.
+type reassertQueue struct {
+	mu      sync.Mutex
+	presets map[int64]reassertJob
+}
+
+// processReasserts issues the queued reasserts from the tick, so they go
+// through the cooldown and ramp checks of every other change. Reasserts
+// waiting on a cooldown, a change in flight or the ramp stay queued; those
+// whose miner moved off the drifted preset since are dropped.
+func (b *Balancer) processReasserts(ctx context.Context, margin float64) {
+	b.reasserts.mu.Lock()
+	queued := b.reasserts.presets
+	b.reasserts.presets = make(map[int64]reassertJob)
+	b.reasserts.mu.Unlock()
+
+	if len(queued) == 0 {
+		return
+	}
+
+	requeue := func(minerID int64, job reassertJob) {
+		b.reasserts.mu.Lock()
+		if _, ok := b.reasserts.presets[minerID]; !ok {
+			b.reasserts.presets[minerID] = job
+		}
+		b.reasserts.mu.Unlock()
+	}
+
+	miners, err := b.repo.GetManageableMiners(ctx)
+	if err != nil {
+		log.Printf("Failed to list miners for reassert: %v", err)
+		for id, job := range queued {
+			requeue(id, job)
+		}
+		return
+	}
+	changing, err := b.changingMiners(ctx)
+	if err != nil {
+		log.Printf("Failed to get pending changes: %v", err)
+		for id, job := range queued {
+			requeue(id, job)
+		}
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, m := range miners {
+		job, ok := queued[m.Miner.ID]
+		if !ok || m.CurrentPreset.ID != job.from {
+			continue
+		}
+		if m.OnCooldown || changing[m.Miner.ID] {
+			requeue(m.Miner.ID, job)
+			continue
+		}
+
+		change := &PresetChange{
+			Miner:          m,
+			FromPreset:     m.CurrentPreset,
+			ToPreset:       job.to,
+			ExpectedDeltaW: m.CurrentPreset.Watts - job.to.Watts,
+		}
+		if !b.admitChange(ctx, change, "reassert", margin) {
+			requeue(m.Miner.ID, job)
+			continue
+		}
+		if err := b.executeChange(ctx, change, "reassert", margin); err != nil {
+			log.Printf("[%s] Failed to reassert preset: %v", m.Miner.IPAddress, err)
+		}
+	}
+}
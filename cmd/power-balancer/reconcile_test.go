@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+func TestReconcileMiner(t *testing.T) {
+	// The miner is recorded on 2100 and found on actual
+	tests := []struct {
+		name     string
+		reassert bool
+		last     *ChangeLog // Last change the balancer made, if any
+		seenAgo  time.Duration
+		actual   string
+
+		wantOrigin     string
+		wantReasserted bool
+		wantPreset     string // Recorded after reconciling
+	}{
+		{name: "changed outside to more power", reassert: true, actual: "3200",
+			wantOrigin: DriftUnknown, wantReasserted: true, wantPreset: "3200"},
+		{name: "changed outside without reassert", actual: "3200",
+			wantOrigin: DriftUnknown, wantPreset: "3200"},
+		{name: "changed outside to less power", reassert: true, actual: "1500",
+			wantOrigin: DriftUnknown, wantPreset: "1500"},
+		{name: "last change didn't take", reassert: true, actual: "3200",
+			last:       &ChangeLog{FromPreset: "3200", ToPreset: "2100"},
+			wantOrigin: DriftBalancer, wantPreset: "3200"},
+		{name: "changed outside after the last change took", reassert: true, actual: "3200",
+			last: &ChangeLog{FromPreset: "3200", ToPreset: "2100"}, seenAgo: time.Second,
+			wantOrigin: DriftUnknown, wantReasserted: true, wantPreset: "3200"},
+		{name: "last change recorded wrong", reassert: true, actual: "3200",
+			last:       &ChangeLog{FromPreset: "1700", ToPreset: "3200"},
+			wantOrigin: DriftBalancer, wantPreset: "3200"},
+		{name: "not a preset of the model", reassert: true, actual: "custom",
+			wantOrigin: DriftUnknown, wantPreset: "2100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := DefaultConfig()
+			cfg.ReconcileReassert = tt.reassert
+			b := newTestBalancer(t, cfg)
+			tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+			m := manageable(t, b, tm.id)
+
+			now := time.Now()
+			if tt.last != nil {
+				tt.last.MinerID = &tm.id
+				tt.last.MinerIP = tm.host
+				tt.last.Reason = "reduce"
+				tt.last.IssuedAt = now.Add(-time.Minute)
+				tt.last.Success = true
+				if err := b.repo.InsertChangeLog(ctx, tt.last); err != nil {
+					t.Fatalf("InsertChangeLog: %v", err)
+				}
+			}
+			if tt.seenAgo > 0 {
+				b.presetSeen[tm.id] = now.Add(-tt.seenAgo)
+			}
+
+			if err := b.reconcileMiner(ctx, m, tt.actual, now); err != nil {
+				t.Fatalf("reconcileMiner: %v", err)
+			}
+
+			drifts, err := b.repo.ListPresetDrifts(ctx, 10)
+			if err != nil {
+				t.Fatalf("ListPresetDrifts: %v", err)
+			}
+			if len(drifts) != 1 {
+				t.Fatalf("got %d drifts, want 1", len(drifts))
+			}
+			d := drifts[0]
+			if d.ExpectedPreset != "2100" || d.ActualPreset != tt.actual {
+				t.Errorf("drift %s -> %s, want 2100 -> %s", d.ExpectedPreset, d.ActualPreset, tt.actual)
+			}
+			if d.Origin != tt.wantOrigin || d.Reasserted != tt.wantReasserted {
+				t.Errorf("origin %s, reasserted %v, want %s, %v", d.Origin, d.Reasserted, tt.wantOrigin, tt.wantReasserted)
+			}
+
+			job, queued := b.reasserts.presets[tm.id]
+			if queued != tt.wantReasserted {
+				t.Errorf("reassert queued = %v, want %v", queued, tt.wantReasserted)
+			}
+			if queued && job.to.Name != "2100" {
+				t.Errorf("reassert to %s, want 2100", job.to.Name)
+			}
+
+			if got := manageable(t, b, tm.id).CurrentPreset.Name; got != tt.wantPreset {
+				t.Errorf("recorded preset %s, want %s", got, tt.wantPreset)
+			}
+
+			// A drift is recorded once per preset it's found on
+			if tt.actual == "custom" {
+				if err := b.reconcileMiner(ctx, m, tt.actual, now.Add(time.Minute)); err != nil {
+					t.Fatalf("reconcileMiner: %v", err)
+				}
+				if drifts, _ := b.repo.ListPresetDrifts(ctx, 10); len(drifts) != 1 {
+					t.Errorf("got %d drifts after reconciling again, want 1", len(drifts))
+				}
+			}
+		})
+	}
+}
+
+func TestProcessReassertsWaitsForCooldown(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	cfg.ReconcileReassert = true
+	b := newTestBalancer(t, cfg)
+
+	tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+	setSimPreset(t, b, tm, "3200")
+	if err := b.cooldowns.SetCooldown(ctx, tm.id); err != nil {
+		t.Fatalf("SetCooldown: %v", err)
+	}
+	b.reconcilePresets(ctx)
+
+	// Held while on cooldown, and issued once it's over
+	b.processReasserts(ctx, 50)
+	if _, ok := b.reasserts.presets[tm.id]; !ok {
+		t.Fatal("reassert dropped during the cooldown")
+	}
+	if got := manageable(t, b, tm.id).CurrentPreset.Name; got != "3200" {
+		t.Fatalf("recorded preset %s during the cooldown, want 3200", got)
+	}
+
+	if _, err := b.repo.db.ExecContext(ctx, `DELETE FROM cooldowns`); err != nil {
+		t.Fatalf("clear cooldowns: %v", err)
+	}
+	b.processReasserts(ctx, 50)
+	if _, ok := b.reasserts.presets[tm.id]; ok {
+		t.Error("reassert still queued after the cooldown")
+	}
+	if got := manageable(t, b, tm.id).CurrentPreset.Name; got != "2100" {
+		t.Errorf("recorded preset %s, want 2100", got)
+	}
+}
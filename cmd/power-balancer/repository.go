@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -35,6 +36,11 @@ func (r *Repository) Close() error {
 	return r.db.Close()
 }
 
+// DB returns the underlying database connection.
+func (r *Repository) DB() *sql.DB {
+	return r.db
+}
+
 // --- Models ---
 
 // GetOrCreateModel gets or creates a model by name.
@@ -139,6 +145,15 @@ func (r *Repository) UpsertModelPreset(ctx context.Context, p *ModelPreset) erro
 	return err
 }
 
+// UpdatePresetPower sets the measured power and hashrate of a preset.
+// Used for firmwares that don't report per-preset consumption.
+func (r *Repository) UpdatePresetPower(ctx context.Context, presetID int64, watts int, hashrateTH float64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE model_presets SET watts = ?, hashrate_th = ? WHERE id = ?`,
+		watts, hashrateTH, presetID)
+	return err
+}
+
 // GetModelPresets returns all presets for a model, ordered by watts.
 func (r *Repository) GetModelPresets(ctx context.Context, modelID int64) ([]*ModelPreset, error) {
 	rows, err := r.db.QueryContext(ctx, `
@@ -180,6 +195,25 @@ func (r *Repository) GetPresetByModelAndName(ctx context.Context, modelID int64,
 	return p, err
 }
 
+// ListPresetNames returns the distinct preset names across all models.
+func (r *Repository) ListPresetNames(ctx context.Context) ([]string, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT DISTINCT name FROM model_presets ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
 // --- Miners ---
 
 // UpsertMiner inserts or updates a miner by MAC address.
@@ -222,6 +256,8 @@ func (r *Repository) UpsertMiner(ctx context.Context, m *Miner) error {
 
 // UpsertMinerWithBalanceConfig atomically upserts a miner and ensures balance config exists.
 // This wraps both operations in a single transaction to avoid FK constraint failures.
+// A known miner keeps its recorded preset, so presets changed behind the balancer's
+// back are left for the reconciler to find, unless it has none or changed model.
 func (r *Repository) UpsertMinerWithBalanceConfig(ctx context.Context, m *Miner) (*BalanceConfig, error) {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
@@ -237,7 +273,11 @@ func (r *Repository) UpsertMinerWithBalanceConfig(ctx context.Context, m *Miner)
 			ip_address = excluded.ip_address,
 			model_id = excluded.model_id,
 			firmware_type = excluded.firmware_type,
-			current_preset_id = excluded.current_preset_id,
+			current_preset_id = CASE
+				WHEN miners.current_preset_id IS NULL OR miners.model_id IS NOT excluded.model_id
+				THEN excluded.current_preset_id
+				ELSE miners.current_preset_id
+			END,
 			is_online = excluded.is_online,
 			last_seen = excluded.last_seen`,
 		m.MACAddress, m.IPAddress, m.ModelID, m.FirmwareType, m.CurrentPresetID, m.IsOnline, m.LastSeen, time.Now())
@@ -444,6 +484,15 @@ func (r *Repository) GetPendingChanges(ctx context.Context) ([]*PendingChange, e
 	return changes, rows.Err()
 }
 
+// UpdatePendingChange changes the expected delta and settle time of a
+// pending change.
+func (r *Repository) UpdatePendingChange(ctx context.Context, id int64, deltaW int, settlesAt time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE pending_changes SET expected_delta_w = ?, settles_at = ? WHERE id = ?`,
+		deltaW, settlesAt, id)
+	return err
+}
+
 // SumPendingDelta returns the sum of expected power changes from pending changes.
 func (r *Repository) SumPendingDelta(ctx context.Context) (int, error) {
 	var sum sql.NullInt64
@@ -464,38 +513,88 @@ func (r *Repository) ClearSettledChanges(ctx context.Context) error {
 
 // --- Energy Readings ---
 
-// InsertEnergyReading stores an energy reading.
+// InsertEnergyReading stores an energy reading and its plant values.
 func (r *Repository) InsertEnergyReading(ctx context.Context, e *EnergyReading) error {
-	result, err := r.db.ExecContext(ctx, `
-		INSERT INTO energy_readings (timestamp, generation_mw, consumption_mw, margin_mw, margin_percent,
-			generoso_mw, generoso_status, nogueira_mw, nogueira_status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
-		e.Timestamp, e.GenerationMW, e.ConsumptionMW, e.MarginMW, e.MarginPercent,
-		e.GenerosoMW, e.GenerosoStatus, e.NogueiraMW, e.NogueiraStatus)
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	e.ID, _ = result.LastInsertId()
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO energy_readings (timestamp, generation_mw, consumption_mw, margin_mw, margin_percent)
+		VALUES (?, ?, ?, ?, ?)`,
+		e.Timestamp, e.GenerationMW, e.ConsumptionMW, e.MarginMW, e.MarginPercent)
+	if err != nil {
+		return err
+	}
+	id, _ := result.LastInsertId()
+
+	for _, p := range e.Plants {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO energy_reading_plants (reading_id, source, name, kind, mw, status, source_timestamp, confidence)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			id, p.Source, p.Name, p.Kind, p.MW, p.Status, p.SourceTimestamp, p.Confidence)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	e.ID = id
 	return nil
 }
 
-// GetLatestEnergyReading returns the most recent energy reading.
+// GetLatestEnergyReading returns the most recent energy reading with its plant values.
 func (r *Repository) GetLatestEnergyReading(ctx context.Context) (*EnergyReading, error) {
 	e := &EnergyReading{}
 	err := r.db.QueryRowContext(ctx, `
-		SELECT id, timestamp, generation_mw, consumption_mw, margin_mw, margin_percent,
-			generoso_mw, generoso_status, nogueira_mw, nogueira_status
+		SELECT id, timestamp, generation_mw, consumption_mw, margin_mw, margin_percent
 		FROM energy_readings ORDER BY timestamp DESC LIMIT 1`).Scan(
-		&e.ID, &e.Timestamp, &e.GenerationMW, &e.ConsumptionMW, &e.MarginMW, &e.MarginPercent,
-		&e.GenerosoMW, &e.GenerosoStatus, &e.NogueiraMW, &e.NogueiraStatus)
+		&e.ID, &e.Timestamp, &e.GenerationMW, &e.ConsumptionMW, &e.MarginMW, &e.MarginPercent)
+	if err != nil {
+		return e, err
+	}
+
+	e.Plants, err = r.getEnergyReadingPlants(ctx, e.ID)
+	e.Confidence = 1
+	for _, p := range e.Plants {
+		if p.Confidence < e.Confidence {
+			e.Confidence = p.Confidence
+		}
+	}
 	return e, err
 }
 
+// getEnergyReadingPlants returns the plant values of a reading.
+func (r *Repository) getEnergyReadingPlants(ctx context.Context, readingID int64) ([]PlantReading, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT source, name, kind, mw, status, source_timestamp, COALESCE(confidence, 1)
+		FROM energy_reading_plants WHERE reading_id = ? ORDER BY rowid`, readingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var plants []PlantReading
+	for rows.Next() {
+		var p PlantReading
+		var ts sql.NullTime
+		if err := rows.Scan(&p.Source, &p.Name, &p.Kind, &p.MW, &p.Status, &ts, &p.Confidence); err != nil {
+			return nil, err
+		}
+		p.SourceTimestamp = ts.Time
+		plants = append(plants, p)
+	}
+	return plants, rows.Err()
+}
+
 // GetRecentEnergyReadings returns recent energy readings for charting.
 func (r *Repository) GetRecentEnergyReadings(ctx context.Context, limit int) ([]*EnergyReading, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, timestamp, generation_mw, consumption_mw, margin_mw, margin_percent,
-			generoso_mw, generoso_status, nogueira_mw, nogueira_status
+		SELECT id, timestamp, generation_mw, consumption_mw, margin_mw, margin_percent
 		FROM energy_readings ORDER BY timestamp DESC LIMIT ?`, limit)
 	if err != nil {
 		return nil, err
@@ -505,8 +604,7 @@ func (r *Repository) GetRecentEnergyReadings(ctx context.Context, limit int) ([]
 	var readings []*EnergyReading
 	for rows.Next() {
 		e := &EnergyReading{}
-		if err := rows.Scan(&e.ID, &e.Timestamp, &e.GenerationMW, &e.ConsumptionMW, &e.MarginMW, &e.MarginPercent,
-			&e.GenerosoMW, &e.GenerosoStatus, &e.NogueiraMW, &e.NogueiraStatus); err != nil {
+		if err := rows.Scan(&e.ID, &e.Timestamp, &e.GenerationMW, &e.ConsumptionMW, &e.MarginMW, &e.MarginPercent); err != nil {
 			return nil, err
 		}
 		readings = append(readings, e)
@@ -534,9 +632,12 @@ func (r *Repository) InsertChangeLog(ctx context.Context, c *ChangeLog) error {
 // GetRecentChangeLogs returns recent change logs.
 func (r *Repository) GetRecentChangeLogs(ctx context.Context, limit int) ([]*ChangeLog, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, miner_id, miner_ip, model_name, from_preset, to_preset,
-			expected_delta_w, reason, margin_at_time, issued_at, success, error_message
-		FROM change_log ORDER BY issued_at DESC LIMIT ?`, limit)
+		SELECT c.id, c.miner_id, c.miner_ip, c.model_name, c.from_preset, c.to_preset,
+			c.expected_delta_w, c.reason, c.margin_at_time, c.issued_at, c.success, c.error_message,
+			v.outcome, v.attempt, v.before_w, v.after_w, v.actual_delta_w, v.measured_preset
+		FROM change_log c
+		LEFT JOIN change_verifications v ON v.change_log_id = c.id
+		ORDER BY c.issued_at DESC LIMIT ?`, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -545,17 +646,568 @@ func (r *Repository) GetRecentChangeLogs(ctx context.Context, limit int) ([]*Cha
 	var logs []*ChangeLog
 	for rows.Next() {
 		c := &ChangeLog{}
-		var errMsg sql.NullString
+		var errMsg, outcome, measuredPreset sql.NullString
+		var attempt, beforeW, afterW, actualDelta sql.NullInt64
 		if err := rows.Scan(&c.ID, &c.MinerID, &c.MinerIP, &c.ModelName, &c.FromPreset, &c.ToPreset,
-			&c.ExpectedDeltaW, &c.Reason, &c.MarginAtTime, &c.IssuedAt, &c.Success, &errMsg); err != nil {
+			&c.ExpectedDeltaW, &c.Reason, &c.MarginAtTime, &c.IssuedAt, &c.Success, &errMsg,
+			&outcome, &attempt, &beforeW, &afterW, &actualDelta, &measuredPreset); err != nil {
 			return nil, err
 		}
 		c.ErrorMessage = errMsg.String
+		if outcome.Valid {
+			c.Verification = &ChangeVerification{
+				ChangeLogID:    c.ID,
+				Outcome:        outcome.String,
+				Attempt:        int(attempt.Int64),
+				ExpectedDeltaW: c.ExpectedDeltaW,
+				BeforeW:        int(beforeW.Int64),
+				AfterW:         int(afterW.Int64),
+				MeasuredPreset: measuredPreset.String,
+			}
+			if actualDelta.Valid {
+				d := int(actualDelta.Int64)
+				c.Verification.ActualDeltaW = &d
+			}
+		}
 		logs = append(logs, c)
 	}
 	return logs, rows.Err()
 }
 
+// GetLastReductions returns when each miner was last reduced successfully.
+// Miners never reduced are absent.
+func (r *Repository) GetLastReductions(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT miner_id, issued_at FROM change_log
+		WHERE id IN (
+			SELECT MAX(id) FROM change_log
+			WHERE miner_id IS NOT NULL AND success = 1 AND expected_delta_w > 0
+			GROUP BY miner_id)`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	last := make(map[int64]time.Time)
+	for rows.Next() {
+		var minerID int64
+		var issuedAt time.Time
+		if err := rows.Scan(&minerID, &issuedAt); err != nil {
+			return nil, err
+		}
+		last[minerID] = issuedAt
+	}
+	return last, rows.Err()
+}
+
+// --- Change Verifications ---
+
+// CreateChangeVerification records a change awaiting verification.
+func (r *Repository) CreateChangeVerification(ctx context.Context, v *ChangeVerification) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO change_verifications (change_log_id, pending_change_id, miner_id, from_preset_id, to_preset_id,
+			attempt, expected_delta_w, before_w, outcome)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		v.ChangeLogID, v.PendingChangeID, v.MinerID, v.FromPresetID, v.ToPresetID,
+		v.Attempt, v.ExpectedDeltaW, v.BeforeW, v.Outcome)
+	if err != nil {
+		return err
+	}
+	v.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetSettledVerifications returns pending verifications whose change has settled.
+func (r *Repository) GetSettledVerifications(ctx context.Context) ([]*ChangeVerification, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT v.id, v.change_log_id, v.pending_change_id, v.miner_id, v.from_preset_id, v.to_preset_id,
+			v.attempt, v.expected_delta_w, v.before_w
+		FROM change_verifications v
+		JOIN pending_changes p ON p.id = v.pending_change_id
+		WHERE v.outcome = ? AND p.settles_at <= ?
+		ORDER BY p.settles_at`, VerifyPending, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var verifications []*ChangeVerification
+	for rows.Next() {
+		v := &ChangeVerification{Outcome: VerifyPending}
+		if err := rows.Scan(&v.ID, &v.ChangeLogID, &v.PendingChangeID, &v.MinerID, &v.FromPresetID, &v.ToPresetID,
+			&v.Attempt, &v.ExpectedDeltaW, &v.BeforeW); err != nil {
+			return nil, err
+		}
+		verifications = append(verifications, v)
+	}
+	return verifications, rows.Err()
+}
+
+// UpdateChangeVerification stores the result of a verification.
+func (r *Repository) UpdateChangeVerification(ctx context.Context, v *ChangeVerification) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE change_verifications
+		SET after_w = ?, actual_delta_w = ?, measured_preset = ?, outcome = ?, verified_at = ?
+		WHERE id = ?`,
+		v.AfterW, v.ActualDeltaW, v.MeasuredPreset, v.Outcome, v.VerifiedAt, v.ID)
+	return err
+}
+
+// MarkOrphanedVerifications marks pending verifications whose pending change
+// was dropped before settling (e.g., the miner went offline) as unverified.
+func (r *Repository) MarkOrphanedVerifications(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE change_verifications SET outcome = ?, verified_at = ?
+		WHERE outcome = ? AND pending_change_id NOT IN (SELECT id FROM pending_changes)`,
+		VerifyUnverified, time.Now(), VerifyPending)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// --- Preset Samples ---
+
+// InsertPresetSample stores a preset measurement.
+func (r *Repository) InsertPresetSample(ctx context.Context, p *PresetSample) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO preset_samples (miner_id, preset_id, watts, hashrate_th, source, sampled_at)
+		VALUES (?, ?, ?, ?, ?, ?)`,
+		p.MinerID, p.PresetID, p.Watts, p.HashrateTH, p.Source, p.SampledAt)
+	if err != nil {
+		return err
+	}
+	p.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetPresetSamplesSince returns the preset measurements taken after since.
+func (r *Repository) GetPresetSamplesSince(ctx context.Context, since time.Time) ([]*PresetSample, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, miner_id, preset_id, watts, COALESCE(hashrate_th, 0), source, sampled_at
+		FROM preset_samples WHERE sampled_at > ? ORDER BY sampled_at`, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*PresetSample
+	for rows.Next() {
+		p := &PresetSample{}
+		if err := rows.Scan(&p.ID, &p.MinerID, &p.PresetID, &p.Watts, &p.HashrateTH, &p.Source, &p.SampledAt); err != nil {
+			return nil, err
+		}
+		samples = append(samples, p)
+	}
+	return samples, rows.Err()
+}
+
+// GetLatestSampleTimes returns when the latest sample from a source was
+// taken, by miner.
+func (r *Repository) GetLatestSampleTimes(ctx context.Context, source string) (map[int64]time.Time, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT s.miner_id, s.sampled_at FROM preset_samples s
+		WHERE s.source = ? AND s.sampled_at = (
+			SELECT MAX(sampled_at) FROM preset_samples WHERE miner_id = s.miner_id AND source = s.source)`, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	latest := make(map[int64]time.Time)
+	for rows.Next() {
+		var minerID int64
+		var sampledAt time.Time
+		if err := rows.Scan(&minerID, &sampledAt); err != nil {
+			return nil, err
+		}
+		latest[minerID] = sampledAt
+	}
+	return latest, rows.Err()
+}
+
+// DeletePresetSamplesBefore removes preset measurements taken before cutoff.
+func (r *Repository) DeletePresetSamplesBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM preset_samples WHERE sampled_at < ?`, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// --- Parked Miners ---
+
+// ParkMiner records a parked miner.
+func (r *Repository) ParkMiner(ctx context.Context, p *ParkedMiner) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO parked_miners (miner_id, preset_id, saved_w, parked_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(miner_id) DO UPDATE SET
+			preset_id = excluded.preset_id,
+			saved_w = excluded.saved_w,
+			parked_at = excluded.parked_at`,
+		p.MinerID, p.PresetID, p.SavedW, p.ParkedAt)
+	return err
+}
+
+// UnparkMiner removes a miner from the parked miners.
+func (r *Repository) UnparkMiner(ctx context.Context, minerID int64) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM parked_miners WHERE miner_id = ?`, minerID)
+	return err
+}
+
+// GetParkedMiners returns the parked miners with their miner and model name,
+// resume preset, balance config and cooldown, oldest first.
+func (r *Repository) GetParkedMiners(ctx context.Context) ([]*ParkedMiner, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT
+			pm.miner_id, pm.preset_id, pm.saved_w, pm.parked_at,
+			m.mac_address, m.ip_address, m.model_id, m.firmware_type, m.is_online, COALESCE(mo.name, ''),
+			p.model_id, p.name, p.watts, COALESCE(p.hashrate_th, 0), COALESCE(p.display_name, ''),
+			COALESCE(bc.enabled, 0), COALESCE(bc.priority, 50), COALESCE(bc.locked, 0),
+			c.until
+		FROM parked_miners pm
+		JOIN miners m ON pm.miner_id = m.id
+		JOIN model_presets p ON pm.preset_id = p.id
+		LEFT JOIN models mo ON m.model_id = mo.id
+		LEFT JOIN balance_config bc ON pm.miner_id = bc.miner_id
+		LEFT JOIN cooldowns c ON pm.miner_id = c.miner_id
+		ORDER BY pm.parked_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parked []*ParkedMiner
+	now := time.Now()
+	for rows.Next() {
+		p := &ParkedMiner{
+			Miner:  &Miner{Model: &Model{}},
+			Preset: &ModelPreset{},
+			Config: &BalanceConfig{},
+		}
+		var cooldownUntil sql.NullTime
+
+		if err := rows.Scan(
+			&p.MinerID, &p.PresetID, &p.SavedW, &p.ParkedAt,
+			&p.Miner.MACAddress, &p.Miner.IPAddress, &p.Miner.ModelID, &p.Miner.FirmwareType, &p.Miner.IsOnline, &p.Miner.Model.Name,
+			&p.Preset.ModelID, &p.Preset.Name, &p.Preset.Watts, &p.Preset.HashrateTH, &p.Preset.DisplayName,
+			&p.Config.Enabled, &p.Config.Priority, &p.Config.Locked,
+			&cooldownUntil,
+		); err != nil {
+			return nil, err
+		}
+		p.Miner.ID = p.MinerID
+		p.Miner.CurrentPresetID = &p.PresetID
+		p.Preset.ID = p.PresetID
+		p.Config.MinerID = p.MinerID
+		p.OnCooldown = cooldownUntil.Valid && cooldownUntil.Time.After(now)
+
+		parked = append(parked, p)
+	}
+	return parked, rows.Err()
+}
+
+// --- Schedules ---
+
+// ListSchedules returns all schedules, by kind then start.
+func (r *Repository) ListSchedules(ctx context.Context) ([]*Schedule, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, name, kind, enabled, COALESCE(days, ''), start_at, COALESCE(end_at, ''),
+			COALESCE(max_consumption_kw, 0), COALESCE(target_preset, ''), created_at
+		FROM schedules ORDER BY kind, start_at, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var schedules []*Schedule
+	for rows.Next() {
+		s := &Schedule{}
+		var days string
+		if err := rows.Scan(&s.ID, &s.Name, &s.Kind, &s.Enabled, &days, &s.Start, &s.End,
+			&s.MaxConsumptionKW, &s.TargetPreset, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		if days != "" {
+			s.Days = strings.Split(days, ",")
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// SaveSchedule inserts a schedule, or updates it if it has an ID.
+func (r *Repository) SaveSchedule(ctx context.Context, s *Schedule) error {
+	days := strings.Join(s.Days, ",")
+	if s.ID == 0 {
+		result, err := r.db.ExecContext(ctx, `
+			INSERT INTO schedules (name, kind, enabled, days, start_at, end_at, max_consumption_kw, target_preset)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			s.Name, s.Kind, s.Enabled, days, s.Start, s.End, s.MaxConsumptionKW, s.TargetPreset)
+		if err != nil {
+			return err
+		}
+		s.ID, _ = result.LastInsertId()
+		return nil
+	}
+
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE schedules SET name = ?, kind = ?, enabled = ?, days = ?, start_at = ?, end_at = ?,
+			max_consumption_kw = ?, target_preset = ?
+		WHERE id = ?`,
+		s.Name, s.Kind, s.Enabled, days, s.Start, s.End, s.MaxConsumptionKW, s.TargetPreset, s.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule.
+func (r *Repository) DeleteSchedule(ctx context.Context, id int64) error {
+	result, err := r.db.ExecContext(ctx, `DELETE FROM schedules WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// --- Demand Response ---
+
+// demandResponseColumns are the columns scanned by scanDemandResponseEvent.
+const demandResponseColumns = `id, source, COALESCE(reference, ''), start_at, duration_s, ramp_s,
+	target_mw, reduce_mw, baseline_mw, status, created_at, ended_at`
+
+// scanDemandResponseEvent scans a row of demandResponseColumns.
+func scanDemandResponseEvent(row interface{ Scan(...interface{}) error }) (*DemandResponseEvent, error) {
+	e := &DemandResponseEvent{}
+	var durationS, rampS int64
+	var targetMW, reduceMW, baselineMW sql.NullFloat64
+	var endedAt sql.NullTime
+	if err := row.Scan(&e.ID, &e.Source, &e.Reference, &e.StartAt, &durationS, &rampS,
+		&targetMW, &reduceMW, &baselineMW, &e.Status, &e.CreatedAt, &endedAt); err != nil {
+		return nil, err
+	}
+	e.Duration = JSONDuration(time.Duration(durationS) * time.Second)
+	e.Ramp = JSONDuration(time.Duration(rampS) * time.Second)
+	if targetMW.Valid {
+		e.TargetMW = &targetMW.Float64
+	}
+	if reduceMW.Valid {
+		e.ReduceMW = &reduceMW.Float64
+	}
+	if baselineMW.Valid {
+		e.BaselineMW = &baselineMW.Float64
+	}
+	if endedAt.Valid {
+		e.EndedAt = &endedAt.Time
+	}
+	return e, nil
+}
+
+// InsertDemandResponseEvent queues a demand-response event.
+func (r *Repository) InsertDemandResponseEvent(ctx context.Context, e *DemandResponseEvent) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO demand_response_events (source, reference, start_at, duration_s, ramp_s,
+			target_mw, reduce_mw, status, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		e.Source, e.Reference, e.StartAt,
+		int64(time.Duration(e.Duration)/time.Second), int64(time.Duration(e.Ramp)/time.Second),
+		e.TargetMW, e.ReduceMW, e.Status, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetDemandResponseEvent retrieves a demand-response event by ID.
+func (r *Repository) GetDemandResponseEvent(ctx context.Context, id int64) (*DemandResponseEvent, error) {
+	return scanDemandResponseEvent(r.db.QueryRowContext(ctx,
+		`SELECT `+demandResponseColumns+` FROM demand_response_events WHERE id = ?`, id))
+}
+
+// ListDemandResponseEvents returns the most recent demand-response events,
+// latest start first.
+func (r *Repository) ListDemandResponseEvents(ctx context.Context, limit int) ([]*DemandResponseEvent, error) {
+	return r.queryDemandResponseEvents(ctx,
+		`SELECT `+demandResponseColumns+` FROM demand_response_events ORDER BY start_at DESC, id DESC LIMIT ?`, limit)
+}
+
+// GetOpenDemandResponseEvents returns the scheduled and active events,
+// earliest start first.
+func (r *Repository) GetOpenDemandResponseEvents(ctx context.Context) ([]*DemandResponseEvent, error) {
+	return r.queryDemandResponseEvents(ctx,
+		`SELECT `+demandResponseColumns+` FROM demand_response_events
+		WHERE status IN (?, ?) ORDER BY start_at, id`, DRScheduled, DRActive)
+}
+
+func (r *Repository) queryDemandResponseEvents(ctx context.Context, query string, args ...interface{}) ([]*DemandResponseEvent, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []*DemandResponseEvent
+	for rows.Next() {
+		e, err := scanDemandResponseEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// StartDemandResponseEvent marks an event active with the consumption it
+// started from.
+func (r *Repository) StartDemandResponseEvent(ctx context.Context, id int64, baselineMW float64) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE demand_response_events SET status = ?, baseline_mw = ? WHERE id = ?`,
+		DRActive, baselineMW, id)
+	return err
+}
+
+// EndDemandResponseEvent completes or cancels an open event. Returns
+// sql.ErrNoRows if the event doesn't exist or has already ended.
+func (r *Repository) EndDemandResponseEvent(ctx context.Context, id int64, status string, at time.Time) error {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE demand_response_events SET status = ?, ended_at = ?
+		WHERE id = ? AND status IN (?, ?)`,
+		status, at, id, DRScheduled, DRActive)
+	if err != nil {
+		return err
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// InsertDRSample records the site consumption against an event's target.
+func (r *Repository) InsertDRSample(ctx context.Context, eventID int64, s *DRSample) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO demand_response_samples (event_id, timestamp, consumption_mw, target_mw)
+		VALUES (?, ?, ?, ?)`,
+		eventID, s.Timestamp, s.ConsumptionMW, s.TargetMW)
+	return err
+}
+
+// GetDRSamples returns the samples of an event, oldest first, with the
+// reduction achieved below its baseline.
+func (r *Repository) GetDRSamples(ctx context.Context, e *DemandResponseEvent) ([]*DRSample, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT timestamp, consumption_mw, target_mw FROM demand_response_samples
+		WHERE event_id = ? ORDER BY timestamp`, e.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var samples []*DRSample
+	for rows.Next() {
+		s := &DRSample{}
+		if err := rows.Scan(&s.Timestamp, &s.ConsumptionMW, &s.TargetMW); err != nil {
+			return nil, err
+		}
+		if e.BaselineMW != nil {
+			s.AchievedMW = *e.BaselineMW - s.ConsumptionMW
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// GetDRCompliance summarizes the samples of an event taken after its ramp
+// deadline.
+func (r *Repository) GetDRCompliance(ctx context.Context, e *DemandResponseEvent) (*DRCompliance, error) {
+	c := &DRCompliance{}
+	var meanConsumptionMW float64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT COUNT(*), COALESCE(SUM(consumption_mw <= target_mw), 0),
+			COALESCE(AVG(consumption_mw), 0), COALESCE(MAX(consumption_mw - target_mw), 0)
+		FROM demand_response_samples
+		WHERE event_id = ? AND timestamp >= ?`,
+		e.ID, e.RampDeadline()).Scan(&c.Samples, &c.CompliantSamples, &meanConsumptionMW, &c.MaxOverMW)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Samples > 0 {
+		c.CompliancePercent = float64(c.CompliantSamples) / float64(c.Samples) * 100
+		if e.BaselineMW != nil {
+			c.MeanAchievedMW = *e.BaselineMW - meanConsumptionMW
+		}
+	}
+	c.MaxOverMW = max(0, c.MaxOverMW)
+	return c, nil
+}
+
+// --- Preset Drifts ---
+
+// InsertPresetDrift records a preset drift.
+func (r *Repository) InsertPresetDrift(ctx context.Context, d *PresetDrift) error {
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO preset_drifts (miner_id, miner_ip, expected_preset, actual_preset, origin, since, detected_at, reasserted)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.MinerID, d.MinerIP, d.ExpectedPreset, d.ActualPreset, d.Origin, d.Since, d.DetectedAt, d.Reasserted)
+	if err != nil {
+		return err
+	}
+	d.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// ListPresetDrifts returns the most recent preset drifts.
+func (r *Repository) ListPresetDrifts(ctx context.Context, limit int) ([]*PresetDrift, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, miner_id, miner_ip, expected_preset, actual_preset, origin, since, detected_at, reasserted
+		FROM preset_drifts ORDER BY detected_at DESC LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var drifts []*PresetDrift
+	for rows.Next() {
+		d := &PresetDrift{}
+		var since sql.NullTime
+		if err := rows.Scan(&d.ID, &d.MinerID, &d.MinerIP, &d.ExpectedPreset, &d.ActualPreset,
+			&d.Origin, &since, &d.DetectedAt, &d.Reasserted); err != nil {
+			return nil, err
+		}
+		if since.Valid {
+			d.Since = &since.Time
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts, rows.Err()
+}
+
+// GetLastPresetChange returns the last preset change the balancer made to a
+// miner successfully, or sql.ErrNoRows if it never made one.
+func (r *Repository) GetLastPresetChange(ctx context.Context, minerID int64) (*ChangeLog, error) {
+	c := &ChangeLog{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, miner_id, miner_ip, model_name, from_preset, to_preset,
+			expected_delta_w, reason, margin_at_time, issued_at, success
+		FROM change_log
+		WHERE miner_id = ? AND success = 1 AND from_preset != to_preset AND reason NOT IN ('ramp_limited', 'drift')
+		ORDER BY id DESC LIMIT 1`, minerID).Scan(
+		&c.ID, &c.MinerID, &c.MinerIP, &c.ModelName, &c.FromPreset, &c.ToPreset,
+		&c.ExpectedDeltaW, &c.Reason, &c.MarginAtTime, &c.IssuedAt, &c.Success)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // --- Aggregated Queries for Balancing ---
 
 // GetManageableMiners returns miners that can be managed (configured model, enabled, not locked, online).
@@ -576,7 +1228,8 @@ func (r *Repository) GetManageableMiners(ctx context.Context) ([]*MinerWithConte
 		JOIN model_presets maxp ON mo.max_preset_id = maxp.id
 		JOIN balance_config bc ON m.id = bc.miner_id
 		LEFT JOIN cooldowns c ON m.id = c.miner_id
-		WHERE bc.enabled = 1 AND bc.locked = 0 AND m.firmware_type = 'vnish' AND m.is_online = 1`)
+		WHERE bc.enabled = 1 AND bc.locked = 0 AND m.firmware_type IN ('vnish', 'stock', 'whatsminer') AND m.is_online = 1
+			AND m.id NOT IN (SELECT miner_id FROM parked_miners)`)
 	if err != nil {
 		return nil, err
 	}
@@ -608,12 +1261,7 @@ func (r *Repository) GetManageableMiners(ctx context.Context) ([]*MinerWithConte
 			return nil, err
 		}
 
-		mwc.HeadroomWatts = mwc.CurrentPreset.Watts - mwc.MinPreset.Watts
-
-		// Calculate efficiency: hashrate per watt (TH/W) - higher is better
-		if mwc.CurrentPreset.Watts > 0 && mwc.CurrentPreset.HashrateTH > 0 {
-			mwc.Efficiency = mwc.CurrentPreset.HashrateTH / float64(mwc.CurrentPreset.Watts)
-		}
+		mwc.updateHeadroom()
 
 		if cooldownUntil.Valid {
 			mwc.CooldownUntil = &cooldownUntil.Time
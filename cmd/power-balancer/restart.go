@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// Restart policies, for preset changes the firmware only applies after a
+// mining restart or a reboot.
+const (
+	RestartPolicyAuto    = "auto"    // Restart mining, or reboot when the firmware asks for it
+	RestartPolicyRestart = "restart" // Restart mining; reboots are left to the operator
+	RestartPolicyManual  = "manual"  // Only log it; restarts are left to the operator
+)
+
+// restartJob is a miner waiting for, or going through, a restart to apply its
+// new preset.
+type restartJob struct {
+	minerID   int64
+	ip        string
+	fwType    string
+	model     string
+	preset    string
+	kind      string // RestartMining or RestartReboot
+	pendingID int64
+	toW       int       // Expected power of the new preset
+	issuedAt  time.Time // Zero while queued
+}
+
+// restartQueue staggers restarts across the fleet: one is issued at a time,
+// RestartSpacing apart, so miners don't drop off and ramp back up together.
+type restartQueue struct {
+	mu         sync.Mutex
+	jobs       []*restartJob
+	lastIssued time.Time
+}
+
+// queueRestart queues the restart a miner needs to apply a preset change, per
+// RestartPolicy. A miner already queued keeps its place, with the new change.
+func (b *Balancer) queueRestart(ctx context.Context, change *PresetChange, pending *PendingChange, kind string) {
+	m := change.Miner.Miner
+	if b.cfg.RestartPolicy == RestartPolicyManual ||
+		(b.cfg.RestartPolicy == RestartPolicyRestart && kind == RestartReboot) {
+		log.Printf("[%s] Preset %s needs a %s, %s it manually", m.IPAddress, change.ToPreset.Name, kind, kind)
+		return
+	}
+
+	job := &restartJob{
+		minerID:   m.ID,
+		ip:        m.IPAddress,
+		fwType:    m.FirmwareType,
+		model:     change.Miner.Model.Name,
+		preset:    change.ToPreset.Name,
+		kind:      kind,
+		pendingID: pending.ID,
+		toW:       change.ToPreset.Watts,
+	}
+
+	b.restarts.mu.Lock()
+	position := len(b.restarts.jobs)
+	for i, j := range b.restarts.jobs {
+		if j.minerID == m.ID && j.issuedAt.IsZero() {
+			b.restarts.jobs[i] = job
+			position = i
+			break
+		}
+	}
+	if position == len(b.restarts.jobs) {
+		b.restarts.jobs = append(b.restarts.jobs, job)
+	}
+	b.restarts.mu.Unlock()
+
+	// Keep the change from settling, and being verified, before the restart
+	// can have applied it
+	settlesAt := time.Now().Add(time.Duration(position+1)*b.cfg.RestartSpacing + b.cfg.RestartDuration + b.cfg.SettleTime)
+	if err := b.repo.UpdatePendingChange(ctx, pending.ID, pending.ExpectedDeltaW, settlesAt); err != nil {
+		log.Printf("Failed to update pending change: %v", err)
+	}
+	log.Printf("[%s] Preset %s needs a %s, queued (%d ahead)", m.IPAddress, change.ToPreset.Name, kind, position)
+}
+
+// processRestarts drops the restarts that have had RestartDuration to
+// complete and issues the next queued one once RestartSpacing has passed
+// since the last.
+func (b *Balancer) processRestarts(ctx context.Context) {
+	now := time.Now()
+
+	b.restarts.mu.Lock()
+	var next *restartJob
+	jobs := b.restarts.jobs[:0]
+	for _, j := range b.restarts.jobs {
+		if !j.issuedAt.IsZero() && now.Sub(j.issuedAt) >= b.cfg.RestartDuration {
+			continue // Hashing again, ramping to the new preset
+		}
+		if next == nil && j.issuedAt.IsZero() && now.Sub(b.restarts.lastIssued) >= b.cfg.RestartSpacing {
+			next = j
+			j.issuedAt = now
+			b.restarts.lastIssued = now
+		}
+		jobs = append(jobs, j)
+	}
+	b.restarts.jobs = jobs
+	b.restarts.mu.Unlock()
+
+	if next != nil {
+		b.issueRestart(ctx, next)
+	}
+}
+
+// issueRestart restarts a queued miner. While it restarts the miner draws
+// next to nothing, so its pending change becomes the whole power of the new
+// preset coming back, settling once the restart and the preset had time to.
+func (b *Balancer) issueRestart(ctx context.Context, j *restartJob) {
+	log.Printf("[%s] Issuing %s to apply %s", j.ip, j.kind, j.preset)
+
+	err := b.controller.Restart(ctx, j.ip, j.fwType, j.kind)
+
+	logEntry := &ChangeLog{
+		MinerID:      &j.minerID,
+		MinerIP:      j.ip,
+		ModelName:    j.model,
+		FromPreset:   j.preset,
+		ToPreset:     j.preset,
+		Reason:       j.kind,
+		MarginAtTime: b.GetStatus().MarginPercent,
+		IssuedAt:     j.issuedAt,
+		Success:      err == nil,
+	}
+	if err != nil {
+		logEntry.ErrorMessage = err.Error()
+		b.markUnreachable(ctx, &Miner{ID: j.minerID, IPAddress: j.ip})
+	}
+	if logErr := b.repo.InsertChangeLog(ctx, logEntry); logErr != nil {
+		log.Printf("Failed to log %s: %v", j.kind, logErr)
+	}
+
+	if err != nil {
+		log.Printf("[%s] Failed to %s: %v", j.ip, j.kind, err)
+		return
+	}
+
+	settlesAt := j.issuedAt.Add(b.cfg.RestartDuration + b.cfg.SettleTime)
+	if err := b.repo.UpdatePendingChange(ctx, j.pendingID, -j.toW, settlesAt); err != nil {
+		log.Printf("Failed to update pending change: %v", err)
+	}
+}
+
+// restartCounts returns how many miners are restarting and queued to restart.
+func (b *Balancer) restartCounts() (restarting, queued int) {
+	b.restarts.mu.Lock()
+	defer b.restarts.mu.Unlock()
+
+	for _, j := range b.restarts.jobs {
+		if j.issuedAt.IsZero() {
+			queued++
+		} else {
+			restarting++
+		}
+	}
+	return restarting, queued
+}
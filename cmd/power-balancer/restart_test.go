@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+)
+
+// queueTestRestart changes a miner's preset and queues the restart applying
+// it, like executeChange does.
+func queueTestRestart(t *testing.T, b *Balancer, tm *testMiner, to string, kind string) *PendingChange {
+	t.Helper()
+	ctx := context.Background()
+
+	m := manageable(t, b, tm.id)
+	change := &PresetChange{Miner: m, FromPreset: m.CurrentPreset, ToPreset: modelPreset(t, b, m, to)}
+	change.ExpectedDeltaW = change.FromPreset.Watts - change.ToPreset.Watts
+	pending := &PendingChange{
+		MinerID:        tm.id,
+		FromPresetID:   &change.FromPreset.ID,
+		ToPresetID:     &change.ToPreset.ID,
+		ExpectedDeltaW: change.ExpectedDeltaW,
+		IssuedAt:       time.Now(),
+		SettlesAt:      time.Now().Add(b.cfg.SettleTime),
+	}
+	if err := b.repo.CreatePendingChange(ctx, pending); err != nil {
+		t.Fatalf("CreatePendingChange: %v", err)
+	}
+	b.queueRestart(ctx, change, pending, kind)
+	return pending
+}
+
+func TestQueueRestartPolicy(t *testing.T) {
+	tests := []struct {
+		policy     string
+		kind       string
+		wantQueued bool
+	}{
+		{RestartPolicyAuto, RestartMining, true},
+		{RestartPolicyAuto, RestartReboot, true},
+		{RestartPolicyRestart, RestartMining, true},
+		{RestartPolicyRestart, RestartReboot, false},
+		{RestartPolicyManual, RestartMining, false},
+		{RestartPolicyManual, RestartReboot, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy+"/"+tt.kind, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.RestartPolicy = tt.policy
+			b := newTestBalancer(t, cfg)
+			tm := addMiner(t, b, miner.FirmwareVNish, 1, "2100")
+
+			queueTestRestart(t, b, tm, "1700", tt.kind)
+			if queued := len(b.restarts.jobs) == 1; queued != tt.wantQueued {
+				t.Errorf("queued = %v, want %v", queued, tt.wantQueued)
+			}
+		})
+	}
+}
+
+func TestQueueRestartKeepsPlace(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	b := newTestBalancer(t, cfg)
+
+	var miners []*testMiner
+	for i := 1; i <= 3; i++ {
+		miners = append(miners, addMiner(t, b, miner.FirmwareVNish, i, "2100"))
+	}
+	var pending []*PendingChange
+	for _, tm := range miners {
+		pending = append(pending, queueTestRestart(t, b, tm, "1700", RestartMining))
+	}
+
+	// A new change to a queued miner replaces its job in place
+	queueTestRestart(t, b, miners[0], "1500", RestartReboot)
+
+	if len(b.restarts.jobs) != 3 {
+		t.Fatalf("got %d jobs, want 3", len(b.restarts.jobs))
+	}
+	for i, j := range b.restarts.jobs {
+		if j.minerID != miners[i].id {
+			t.Errorf("job %d is miner %d, want %d", i, j.minerID, miners[i].id)
+		}
+	}
+	if j := b.restarts.jobs[0]; j.preset != "1500" || j.kind != RestartReboot {
+		t.Errorf("first job %s to %s, want reboot to 1500", j.kind, j.preset)
+	}
+
+	// Changes settle after the restarts ahead of them
+	changes, err := b.repo.GetPendingChanges(ctx)
+	if err != nil {
+		t.Fatalf("GetPendingChanges: %v", err)
+	}
+	settles := make(map[int64]time.Time)
+	for _, p := range changes {
+		settles[p.ID] = p.SettlesAt
+	}
+	for i := 1; i < len(pending); i++ {
+		gap := settles[pending[i].ID].Sub(settles[pending[i-1].ID])
+		if gap < cfg.RestartSpacing-time.Second || gap > cfg.RestartSpacing+time.Second {
+			t.Errorf("change %d settles %v after the one ahead, want %v", i, gap, cfg.RestartSpacing)
+		}
+	}
+}
+
+func TestProcessRestarts(t *testing.T) {
+	// Three jobs, issued the given time ago (0 = queued)
+	tests := []struct {
+		name          string
+		issuedAgo     []time.Duration
+		lastIssuedAgo time.Duration
+		wantIssued    int   // Job issued by this run, -1 for none
+		wantJobs      []int // Jobs left
+	}{
+		{name: "first queued job", issuedAgo: []time.Duration{0, 0, 0}, lastIssuedAgo: time.Hour,
+			wantIssued: 0, wantJobs: []int{0, 1, 2}},
+		{name: "waits for the spacing", issuedAgo: []time.Duration{10 * time.Second, 0, 0}, lastIssuedAgo: 10 * time.Second,
+			wantIssued: -1, wantJobs: []int{0, 1, 2}},
+		{name: "next in order after the spacing", issuedAgo: []time.Duration{40 * time.Second, 0, 0}, lastIssuedAgo: 40 * time.Second,
+			wantIssued: 1, wantJobs: []int{0, 1, 2}},
+		{name: "drops restarted jobs", issuedAgo: []time.Duration{4 * time.Minute, 0, 0}, lastIssuedAgo: 4 * time.Minute,
+			wantIssued: 1, wantJobs: []int{1, 2}},
+		{name: "one at a time", issuedAgo: []time.Duration{4 * time.Minute, 2 * time.Minute, 0}, lastIssuedAgo: 2 * time.Minute,
+			wantIssued: 2, wantJobs: []int{1, 2}},
+		{name: "nothing queued", issuedAgo: []time.Duration{4 * time.Minute, 3 * time.Minute, time.Minute}, lastIssuedAgo: time.Minute,
+			wantIssued: -1, wantJobs: []int{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := DefaultConfig()
+			b := newTestBalancer(t, cfg)
+
+			now := time.Now()
+			var jobs []*restartJob
+			for i, ago := range tt.issuedAgo {
+				tm := addMiner(t, b, miner.FirmwareVNish, i+1, "2100")
+				j := &restartJob{minerID: tm.id, ip: tm.host, fwType: string(miner.FirmwareVNish),
+					preset: "1700", kind: RestartMining, toW: 1700}
+				if ago > 0 {
+					j.issuedAt = now.Add(-ago)
+				}
+				jobs = append(jobs, j)
+			}
+			b.restarts.jobs = append([]*restartJob(nil), jobs...)
+			b.restarts.lastIssued = now.Add(-tt.lastIssuedAgo)
+
+			b.processRestarts(ctx)
+
+			for i, j := range jobs {
+				issued := tt.issuedAgo[i] == 0 && !j.issuedAt.IsZero()
+				if issued != (i == tt.wantIssued) {
+					t.Errorf("job %d issued = %v, want %v", i, issued, i == tt.wantIssued)
+				}
+			}
+			if len(b.restarts.jobs) != len(tt.wantJobs) {
+				t.Fatalf("got %d jobs left, want %d", len(b.restarts.jobs), len(tt.wantJobs))
+			}
+			for i, want := range tt.wantJobs {
+				if b.restarts.jobs[i] != jobs[want] {
+					t.Errorf("job %d left is not job %d", i, want)
+				}
+			}
+
+			logs, err := b.repo.GetRecentChangeLogs(ctx, 10)
+			if err != nil {
+				t.Fatalf("GetRecentChangeLogs: %v", err)
+			}
+			if tt.wantIssued < 0 {
+				if len(logs) != 0 {
+					t.Errorf("logged %d restarts, want none", len(logs))
+				}
+				return
+			}
+			if len(logs) != 1 || logs[0].Reason != RestartMining || !logs[0].Success ||
+				logs[0].MinerIP != jobs[tt.wantIssued].ip {
+				t.Errorf("change log = %+v, want a restart of job %d", logs, tt.wantIssued)
+			}
+		})
+	}
+}
+
+func TestStockWorkModesNeedNoRestart(t *testing.T) {
+	ctx := context.Background()
+	b := newTestBalancer(t, DefaultConfig())
+	tm := addMiner(t, b, miner.FirmwareStock, 1, stock.WorkModeNormal)
+
+	restart, err := b.controller.SetPreset(ctx, tm.host, string(miner.FirmwareStock), stock.WorkModeLowPower)
+	if err != nil {
+		t.Fatalf("SetPreset: %v", err)
+	}
+	if restart != RestartNone {
+		t.Errorf("restart = %q, want none", restart)
+	}
+	if err := b.controller.Restart(ctx, tm.host, string(miner.FirmwareStock), RestartReboot); err == nil {
+		t.Error("Restart of a stock miner succeeded, want an error")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Scenario is a scripted timeline of plant generation and data quality,
+// replayed by the simulated aggregator.
+type Scenario struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Steps       []ScenarioStep `json:"steps"`
+}
+
+// ScenarioStep sets the plant values from At (offset from the scenario start)
+// until the next step. Values are in kW, like the aggregator reports them.
+type ScenarioStep struct {
+	At   JSONDuration `json:"at"`
+	Ramp JSONDuration `json:"ramp,omitempty"` // Reach the values linearly over this time
+
+	GenerosoKW   float64 `json:"generoso_kw"`
+	NogueiraKW   float64 `json:"nogueira_kw"`
+	GenerosoDown bool    `json:"generoso_down,omitempty"` // Source status "error"
+	NogueiraDown bool    `json:"nogueira_down,omitempty"`
+
+	// Stale freezes all values and source timestamps at the last fresh reading.
+	Stale bool `json:"stale,omitempty"`
+
+	// Confidence is the trust score (0-1); zero means fully trusted.
+	Confidence float64 `json:"confidence,omitempty"`
+}
+
+// LoadScenario reads a scenario from a JSON file.
+func LoadScenario(path string) (*Scenario, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read scenario: %w", err)
+	}
+
+	var s Scenario
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if err := s.validate(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func (s *Scenario) validate() error {
+	if len(s.Steps) == 0 {
+		return fmt.Errorf("no steps")
+	}
+	if s.Steps[0].Stale {
+		return fmt.Errorf("first step can't be stale")
+	}
+	for i := 1; i < len(s.Steps); i++ {
+		if s.Steps[i].At < s.Steps[i-1].At {
+			return fmt.Errorf("step %d starts before step %d", i+1, i)
+		}
+	}
+	return nil
+}
+
+// scenarioSample is the scenario state at one point in time.
+type scenarioSample struct {
+	GenerosoKW   float64
+	NogueiraKW   float64
+	GenerosoDown bool
+	NogueiraDown bool
+	Stale        bool
+	Confidence   float64
+}
+
+// At returns the state of the scenario elapsed after its start.
+// The last step holds forever.
+func (s *Scenario) At(elapsed time.Duration) scenarioSample {
+	i := sort.Search(len(s.Steps), func(i int) bool {
+		return time.Duration(s.Steps[i].At) > elapsed
+	}) - 1
+	if i < 0 {
+		i = 0
+	}
+	return s.sampleStep(i, elapsed)
+}
+
+// sampleStep evaluates step i at elapsed, ramping from where step i-1 left off.
+func (s *Scenario) sampleStep(i int, elapsed time.Duration) scenarioSample {
+	step := s.Steps[i]
+	if step.Stale && i > 0 {
+		// Values hold where the previous step was when the data froze
+		held := s.sampleStep(i-1, time.Duration(step.At))
+		held.Stale = true
+		return held
+	}
+
+	sample := scenarioSample{
+		GenerosoKW:   step.GenerosoKW,
+		NogueiraKW:   step.NogueiraKW,
+		GenerosoDown: step.GenerosoDown,
+		NogueiraDown: step.NogueiraDown,
+		Stale:        step.Stale,
+		Confidence:   step.Confidence,
+	}
+	if sample.Confidence == 0 {
+		sample.Confidence = 1
+	}
+
+	ramp := time.Duration(step.Ramp)
+	into := elapsed - time.Duration(step.At)
+	if i == 0 || ramp <= 0 || into >= ramp {
+		return sample
+	}
+
+	prev := s.sampleStep(i-1, time.Duration(step.At))
+	f := float64(into) / float64(ramp)
+	if f < 0 {
+		f = 0
+	}
+	sample.GenerosoKW = prev.GenerosoKW + (step.GenerosoKW-prev.GenerosoKW)*f
+	sample.NogueiraKW = prev.NogueiraKW + (step.NogueiraKW-prev.NogueiraKW)*f
+	sample.Confidence = prev.Confidence + (sample.Confidence-prev.Confidence)*f
+	return sample
+}
+
+// =============================================================================
+// Built-in scenarios
+// =============================================================================
+
+// scenarioNames lists the built-in scenarios in help order.
+var scenarioNames = []string{
+	"steady", "generation-drop", "generoso-trip", "nogueira-trip",
+	"slow-recovery", "stale-data", "trust-collapse",
+}
+
+// BuiltinScenario returns a built-in scenario scaled to a farm drawing up to
+// capacityKW, so the same incident exercises the balancer at any farm size.
+// Normal generation is 130% of capacity, 60% from Generoso and 40% from Nogueira.
+func BuiltinScenario(name string, capacityKW float64) (*Scenario, error) {
+	gen := func(fraction float64) (float64, float64) {
+		total := capacityKW * fraction
+		return total * 0.6, total * 0.4
+	}
+	g, n := gen(1.3)
+	at := func(d time.Duration) JSONDuration { return JSONDuration(d) }
+	normal := ScenarioStep{GenerosoKW: g, NogueiraKW: n}
+
+	s := &Scenario{Name: name}
+	switch name {
+	case "steady":
+		s.Description = "Constant generation at 130% of farm capacity"
+		s.Steps = []ScenarioStep{normal}
+
+	case "generation-drop":
+		s.Description = "Generation falls to 95% of capacity over 1m at 2m, then holds"
+		dg, dn := gen(0.95)
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(2 * time.Minute), Ramp: at(time.Minute), GenerosoKW: dg, NogueiraKW: dn},
+		}
+
+	case "generoso-trip":
+		s.Description = "Generoso trips at 2m, restarts at 12m and ramps back over 5m"
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(2 * time.Minute), GenerosoDown: true, NogueiraKW: n},
+			{At: at(12 * time.Minute), Ramp: at(5 * time.Minute), GenerosoKW: g, NogueiraKW: n},
+		}
+
+	case "nogueira-trip":
+		s.Description = "Nogueira trips at 2m, restarts at 12m and ramps back over 5m"
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(2 * time.Minute), GenerosoKW: g, NogueiraDown: true},
+			{At: at(12 * time.Minute), Ramp: at(5 * time.Minute), GenerosoKW: g, NogueiraKW: n},
+		}
+
+	case "slow-recovery":
+		s.Description = "Generation drops to 70% of capacity at 1m, recovers over 20m from 3m"
+		lg, ln := gen(0.7)
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(time.Minute), GenerosoKW: lg, NogueiraKW: ln},
+			{At: at(3 * time.Minute), Ramp: at(20 * time.Minute), GenerosoKW: g, NogueiraKW: n},
+		}
+
+	case "stale-data":
+		s.Description = "Data freezes at 2m for 10m while generation falls to 80% of capacity"
+		lg, ln := gen(0.8)
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(2 * time.Minute), Stale: true},
+			{At: at(12 * time.Minute), GenerosoKW: lg, NogueiraKW: ln},
+		}
+
+	case "trust-collapse":
+		s.Description = "Trust score collapses to 0.2 over 1m at 2m, recovers at 10m"
+		s.Steps = []ScenarioStep{
+			normal,
+			{At: at(2 * time.Minute), Ramp: at(time.Minute), GenerosoKW: g, NogueiraKW: n, Confidence: 0.2},
+			{At: at(10 * time.Minute), GenerosoKW: g, NogueiraKW: n},
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown scenario %q (built-in: %s)", name, strings.Join(scenarioNames, ", "))
+	}
+	return s, nil
+}
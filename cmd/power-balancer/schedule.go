@@ -0,0 +1,431 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+	_ "time/tzdata" // SCHEDULE_TIMEZONE on hosts without a zoneinfo database
+)
+
+// Schedule time layouts.
+const (
+	scheduleClock    = "15:04"
+	scheduleDate     = "2006-01-02"
+	scheduleDateTime = "2006-01-02 15:04"
+)
+
+// scheduleDays maps weekly schedule day names to weekdays.
+var scheduleDays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Validate normalizes a schedule and checks its times and limits.
+func (s *Schedule) Validate() error {
+	s.Name = strings.TrimSpace(s.Name)
+	s.Kind = strings.ToLower(strings.TrimSpace(s.Kind))
+	s.Start = strings.TrimSpace(s.Start)
+	s.End = strings.TrimSpace(s.End)
+	s.TargetPreset = strings.TrimSpace(s.TargetPreset)
+
+	if s.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch s.Kind {
+	case ScheduleWeekly:
+		if len(s.Days) == 0 {
+			return fmt.Errorf("weekly schedules need at least one day")
+		}
+		for i, d := range s.Days {
+			s.Days[i] = strings.ToLower(strings.TrimSpace(d))
+			if _, ok := scheduleDays[s.Days[i]]; !ok {
+				return fmt.Errorf("invalid day %q (use sun, mon, tue, wed, thu, fri, sat)", d)
+			}
+		}
+		if _, err := time.Parse(scheduleClock, s.Start); err != nil {
+			return fmt.Errorf("start must be HH:MM")
+		}
+		if _, err := time.Parse(scheduleClock, s.End); err != nil {
+			return fmt.Errorf("end must be HH:MM")
+		}
+		if s.Start == s.End {
+			return fmt.Errorf("start and end must differ")
+		}
+
+	case ScheduleOnce:
+		s.Days = nil
+		start, err := time.Parse(scheduleDateTime, s.Start)
+		if err != nil {
+			return fmt.Errorf("start must be YYYY-MM-DD HH:MM")
+		}
+		end, err := time.Parse(scheduleDateTime, s.End)
+		if err != nil {
+			return fmt.Errorf("end must be YYYY-MM-DD HH:MM")
+		}
+		if !end.After(start) {
+			return fmt.Errorf("end must be after start")
+		}
+
+	case ScheduleHoliday:
+		s.Days = nil
+		if s.End == "" {
+			s.End = s.Start
+		}
+		start, err := time.Parse(scheduleDate, s.Start)
+		if err != nil {
+			return fmt.Errorf("start must be YYYY-MM-DD")
+		}
+		end, err := time.Parse(scheduleDate, s.End)
+		if err != nil {
+			return fmt.Errorf("end must be YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return fmt.Errorf("end must not be before start")
+		}
+		if s.MaxConsumptionKW != 0 || s.TargetPreset != "" {
+			return fmt.Errorf("holidays take no limits")
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("kind must be %s, %s or %s", ScheduleWeekly, ScheduleOnce, ScheduleHoliday)
+	}
+
+	if s.MaxConsumptionKW < 0 {
+		return fmt.Errorf("max_consumption_kw must not be negative")
+	}
+	if s.MaxConsumptionKW == 0 && s.TargetPreset == "" {
+		return fmt.Errorf("set max_consumption_kw, target_preset or both")
+	}
+	return nil
+}
+
+// coversDay returns true if a holiday includes the day of t.
+func (s *Schedule) coversDay(t time.Time) bool {
+	day := t.Format(scheduleDate)
+	return day >= s.Start && day <= s.End
+}
+
+// activeAt returns true if the window covers t. A weekly window ending at or
+// before its start ends the next day, and is skipped when the day it starts
+// on is a holiday.
+func (s *Schedule) activeAt(t time.Time, loc *time.Location, holiday func(time.Time) bool) bool {
+	t = t.In(loc)
+
+	switch s.Kind {
+	case ScheduleOnce:
+		start, err := time.ParseInLocation(scheduleDateTime, s.Start, loc)
+		if err != nil {
+			return false
+		}
+		end, err := time.ParseInLocation(scheduleDateTime, s.End, loc)
+		if err != nil {
+			return false
+		}
+		return !t.Before(start) && t.Before(end)
+
+	case ScheduleWeekly:
+		from, err := time.Parse(scheduleClock, s.Start)
+		if err != nil {
+			return false
+		}
+		to, err := time.Parse(scheduleClock, s.End)
+		if err != nil {
+			return false
+		}
+
+		// The occurrence covering t started today or, past midnight, yesterday
+		for _, day := range []time.Time{t, t.AddDate(0, 0, -1)} {
+			y, m, d := day.Date()
+			start := time.Date(y, m, d, from.Hour(), from.Minute(), 0, 0, loc)
+			end := time.Date(y, m, d, to.Hour(), to.Minute(), 0, 0, loc)
+			if !end.After(start) {
+				end = time.Date(y, m, d+1, to.Hour(), to.Minute(), 0, 0, loc)
+			}
+			if t.Before(start) || !t.Before(end) {
+				continue
+			}
+			if s.onDay(start.Weekday()) && !holiday(start) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// onDay returns true if a weekly window starts on the weekday.
+func (s *Schedule) onDay(wd time.Weekday) bool {
+	for _, d := range s.Days {
+		if scheduleDays[d] == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns when a schedule applies, for logs.
+func (s *Schedule) describe() string {
+	switch s.Kind {
+	case ScheduleWeekly:
+		return fmt.Sprintf("%s %s-%s", strings.Join(s.Days, ","), s.Start, s.End)
+	case ScheduleHoliday:
+		if s.End != s.Start {
+			return fmt.Sprintf("%s to %s", s.Start, s.End)
+		}
+		return s.Start
+	}
+	return fmt.Sprintf("%s to %s", s.Start, s.End)
+}
+
+// ScheduleLimits are the combined limits of the schedule windows active at
+// one time: the lowest consumption cap, and every target preset (the lowest
+// of them applies to each miner).
+type ScheduleLimits struct {
+	Names           []string
+	MaxConsumptionW int // 0 = no cap
+	TargetPresets   []string
+}
+
+// Scheduler evaluates the time-of-use schedules in the site timezone.
+type Scheduler struct {
+	repo *Repository
+	loc  *time.Location
+}
+
+// NewScheduler creates a scheduler for schedules in the given timezone.
+func NewScheduler(repo *Repository, loc *time.Location) *Scheduler {
+	return &Scheduler{repo: repo, loc: loc}
+}
+
+// Location returns the timezone schedule times are in.
+func (s *Scheduler) Location() *time.Location {
+	return s.loc
+}
+
+// List returns all schedules, flagging the ones active at now.
+func (s *Scheduler) List(ctx context.Context, now time.Time) ([]*Schedule, error) {
+	schedules, err := s.repo.ListSchedules(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	holiday := func(t time.Time) bool {
+		for _, h := range schedules {
+			if h.Kind == ScheduleHoliday && h.Enabled && h.coversDay(t) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, sc := range schedules {
+		if !sc.Enabled {
+			continue
+		}
+		if sc.Kind == ScheduleHoliday {
+			sc.Active = sc.coversDay(now.In(s.loc))
+		} else {
+			sc.Active = sc.activeAt(now, s.loc, holiday)
+		}
+	}
+	return schedules, nil
+}
+
+// Active returns the combined limits of the windows active at now.
+func (s *Scheduler) Active(ctx context.Context, now time.Time) (*ScheduleLimits, error) {
+	schedules, err := s.List(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := &ScheduleLimits{}
+	for _, sc := range schedules {
+		if !sc.Active || sc.Kind == ScheduleHoliday {
+			continue
+		}
+		limits.Names = append(limits.Names, sc.Name)
+		if capW := int(sc.MaxConsumptionKW * 1000); capW > 0 &&
+			(limits.MaxConsumptionW == 0 || capW < limits.MaxConsumptionW) {
+			limits.MaxConsumptionW = capW
+		}
+		if sc.TargetPreset != "" {
+			limits.TargetPresets = append(limits.TargetPresets, sc.TargetPreset)
+		}
+	}
+	return limits, nil
+}
+
+// Validate checks a schedule, including that its target preset is "min" or
+// the name of some model's preset.
+func (s *Scheduler) Validate(ctx context.Context, sc *Schedule) error {
+	if err := sc.Validate(); err != nil {
+		return err
+	}
+	if sc.TargetPreset == "" || sc.TargetPreset == ScheduleMinPreset {
+		return nil
+	}
+
+	names, err := s.repo.ListPresetNames(ctx)
+	if err != nil {
+		return fmt.Errorf("list presets: %w", err)
+	}
+	for _, name := range names {
+		if name == sc.TargetPreset {
+			return nil
+		}
+	}
+	return fmt.Errorf("no model has a preset named %q", sc.TargetPreset)
+}
+
+// =============================================================================
+// Target presets
+// =============================================================================
+
+// targetPreset returns the lowest of the planner's target presets for a
+// miner's model, calibrated for the miner and never below minPreset, or nil
+// if no target names a preset of the model.
+func (s *planner) targetPreset(ctx context.Context, minerID, modelID int64, minPreset *ModelPreset) *ModelPreset {
+	var target *ModelPreset
+	for _, name := range s.targets {
+		p := minPreset
+		if name != ScheduleMinPreset {
+			named, err := s.repo.GetPresetByModelAndName(ctx, modelID, name)
+			if err != nil {
+				continue
+			}
+			p = s.calibration.Apply(minerID, named)
+		}
+		if p != nil && (target == nil || p.Watts < target.Watts) {
+			target = p
+		}
+	}
+
+	if target != nil && minPreset != nil && target.Watts < minPreset.Watts {
+		return minPreset
+	}
+	return target
+}
+
+// CalculateTarget returns the changes that move the manageable miners above
+// their maximum preset, lowered to the schedule target, down to it, biggest
+// reductions first. Cooldowns are ignored: the schedule is a commitment,
+// whatever the margin.
+func (s *planner) CalculateTarget(ctx context.Context) ([]*PresetChange, error) {
+	if len(s.targets) == 0 {
+		return nil, nil
+	}
+
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*PresetChange
+	for _, miner := range miners {
+		delta := miner.CurrentPreset.Watts - miner.MaxPreset.Watts
+		if delta <= 0 {
+			continue
+		}
+		changes = append(changes, &PresetChange{
+			Miner:          miner,
+			FromPreset:     miner.CurrentPreset,
+			ToPreset:       miner.MaxPreset,
+			ExpectedDeltaW: delta,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ExpectedDeltaW > changes[j].ExpectedDeltaW
+	})
+	return changes, nil
+}
+
+// parkedTarget returns the schedule target preset of a parked miner's
+// model, or nil without one.
+func (s *planner) parkedTarget(ctx context.Context, p *ParkedMiner) *ModelPreset {
+	if len(s.targets) == 0 || p.Miner.ModelID == nil {
+		return nil
+	}
+
+	var minPreset *ModelPreset
+	if model, err := s.repo.GetModelByID(ctx, *p.Miner.ModelID); err == nil && model.MinPresetID != nil {
+		if preset, err := s.repo.GetPresetByID(ctx, *model.MinPresetID); err == nil {
+			minPreset = s.calibration.Apply(p.MinerID, preset)
+		}
+	}
+	return s.targetPreset(ctx, p.MinerID, *p.Miner.ModelID, minPreset)
+}
+
+// =============================================================================
+// Enforcement
+// =============================================================================
+
+// applySchedule evaluates the schedule windows active now. It moves miners
+// down to their target presets and returns the reading to balance against:
+// the reading itself or, while a consumption cap below generation is active,
+// a copy with the cap in place of generation, so the margins apply below it.
+func (b *Balancer) applySchedule(ctx context.Context, reading *EnergyReading) *EnergyReading {
+	b.schedule = nil
+	b.active = b.strategy
+	if b.scheduler == nil {
+		return reading
+	}
+
+	limits, err := b.scheduler.Active(ctx, time.Now())
+	if err != nil {
+		log.Printf("Failed to evaluate schedules: %v", err)
+		return reading
+	}
+	if len(limits.Names) == 0 {
+		return reading
+	}
+	b.schedule = limits
+
+	if len(limits.TargetPresets) > 0 {
+		b.active = b.strategy.Targeting(limits.TargetPresets)
+
+		changes, err := b.active.CalculateTarget(ctx)
+		if err != nil {
+			log.Printf("Failed to calculate schedule targets: %v", err)
+		} else if len(changes) > 0 {
+			log.Printf("Schedule %s: moving %d miners down to target preset %s",
+				strings.Join(limits.Names, ", "), len(changes), strings.Join(limits.TargetPresets, "/"))
+			b.executeParallel(ctx, changes, "schedule", reading.MarginPercent)
+		}
+	}
+
+	capMW := float64(limits.MaxConsumptionW) / 1_000_000
+	if capMW <= 0 || capMW >= reading.GenerationMW {
+		return reading
+	}
+
+	budget := *reading
+	budget.GenerationMW = capMW
+	budget.MarginMW = capMW - reading.ConsumptionMW
+	budget.MarginPercent = budget.MarginMW / capMW * 100
+	return &budget
+}
+
+// describeSchedules returns a one-line summary of the schedules for the
+// startup log.
+func describeSchedules(schedules []*Schedule) string {
+	var enabled []string
+	for _, s := range schedules {
+		if s.Enabled {
+			enabled = append(enabled, fmt.Sprintf("%s (%s %s)", s.Name, s.Kind, s.describe()))
+		}
+	}
+	if len(enabled) == 0 {
+		return "none enabled"
+	}
+	return strings.Join(enabled, ", ")
+}
@@ -80,11 +80,20 @@ CREATE TABLE IF NOT EXISTS energy_readings (
     generation_mw REAL,
     consumption_mw REAL,
     margin_mw REAL,
-    margin_percent REAL,
-    generoso_mw REAL,
-    generoso_status TEXT,
-    nogueira_mw REAL,
-    nogueira_status TEXT
+    margin_percent REAL
+);
+
+-- Per-plant values of each energy reading
+CREATE TABLE IF NOT EXISTS energy_reading_plants (
+    reading_id INTEGER NOT NULL,
+    source TEXT NOT NULL,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    mw REAL,
+    status TEXT,
+    source_timestamp DATETIME,
+    confidence REAL,
+    FOREIGN KEY (reading_id) REFERENCES energy_readings(id) ON DELETE CASCADE
 );
 
 -- Audit log
@@ -103,11 +112,113 @@ CREATE TABLE IF NOT EXISTS change_log (
     error_message TEXT
 );
 
+-- Settlement checks of preset changes against the miner's measured state
+CREATE TABLE IF NOT EXISTS change_verifications (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    change_log_id INTEGER NOT NULL UNIQUE,
+    pending_change_id INTEGER,
+    miner_id INTEGER NOT NULL,
+    from_preset_id INTEGER,
+    to_preset_id INTEGER,
+    attempt INTEGER DEFAULT 0,
+    expected_delta_w INTEGER,
+    before_w INTEGER DEFAULT 0,
+    after_w INTEGER DEFAULT 0,
+    actual_delta_w INTEGER,
+    measured_preset TEXT,
+    outcome TEXT NOT NULL,
+    verified_at DATETIME,
+    FOREIGN KEY (change_log_id) REFERENCES change_log(id) ON DELETE CASCADE,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE
+);
+
+-- Measured power and hashrate of miners on a preset, for calibration
+CREATE TABLE IF NOT EXISTS preset_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    miner_id INTEGER NOT NULL,
+    preset_id INTEGER NOT NULL,
+    watts INTEGER NOT NULL,
+    hashrate_th REAL,
+    source TEXT NOT NULL,
+    sampled_at DATETIME NOT NULL,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE,
+    FOREIGN KEY (preset_id) REFERENCES model_presets(id) ON DELETE CASCADE
+);
+
+-- Miners whose mining was stopped as a last resort, with the preset to resume on
+CREATE TABLE IF NOT EXISTS parked_miners (
+    miner_id INTEGER PRIMARY KEY,
+    preset_id INTEGER NOT NULL,
+    saved_w INTEGER NOT NULL,
+    parked_at DATETIME NOT NULL,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE,
+    FOREIGN KEY (preset_id) REFERENCES model_presets(id)
+);
+
+-- Time-of-use windows and holiday exceptions
+CREATE TABLE IF NOT EXISTS schedules (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    enabled INTEGER DEFAULT 1,
+    days TEXT,
+    start_at TEXT NOT NULL,
+    end_at TEXT,
+    max_consumption_kw REAL,
+    target_preset TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Curtailment requests from the grid operator or trading desk
+CREATE TABLE IF NOT EXISTS demand_response_events (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    source TEXT NOT NULL,
+    reference TEXT,
+    start_at DATETIME NOT NULL,
+    duration_s INTEGER NOT NULL,
+    ramp_s INTEGER NOT NULL,
+    target_mw REAL,
+    reduce_mw REAL,
+    baseline_mw REAL,
+    status TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    ended_at DATETIME
+);
+
+-- Site consumption against the target while a demand-response event is active
+CREATE TABLE IF NOT EXISTS demand_response_samples (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    event_id INTEGER NOT NULL,
+    timestamp DATETIME NOT NULL,
+    consumption_mw REAL NOT NULL,
+    target_mw REAL NOT NULL,
+    FOREIGN KEY (event_id) REFERENCES demand_response_events(id) ON DELETE CASCADE
+);
+
+-- Miners found on a preset other than the one recorded here
+CREATE TABLE IF NOT EXISTS preset_drifts (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    miner_id INTEGER NOT NULL,
+    miner_ip TEXT,
+    expected_preset TEXT,
+    actual_preset TEXT,
+    origin TEXT NOT NULL,
+    since DATETIME,
+    detected_at DATETIME NOT NULL,
+    reasserted INTEGER DEFAULT 0,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE
+);
+
 -- Indexes for common queries
 CREATE INDEX IF NOT EXISTS idx_miners_model ON miners(model_id);
 CREATE INDEX IF NOT EXISTS idx_model_presets_model ON model_presets(model_id);
 CREATE INDEX IF NOT EXISTS idx_pending_settles ON pending_changes(settles_at);
 CREATE INDEX IF NOT EXISTS idx_cooldowns_until ON cooldowns(until);
 CREATE INDEX IF NOT EXISTS idx_energy_readings_timestamp ON energy_readings(timestamp);
+CREATE INDEX IF NOT EXISTS idx_energy_reading_plants_reading ON energy_reading_plants(reading_id);
 CREATE INDEX IF NOT EXISTS idx_change_log_issued ON change_log(issued_at);
+CREATE INDEX IF NOT EXISTS idx_change_verifications_outcome ON change_verifications(outcome);
+CREATE INDEX IF NOT EXISTS idx_preset_samples_sampled ON preset_samples(sampled_at);
+CREATE INDEX IF NOT EXISTS idx_dr_samples_event ON demand_response_samples(event_id, timestamp);
+CREATE INDEX IF NOT EXISTS idx_preset_drifts_detected ON preset_drifts(detected_at);
 `
@@ -2,12 +2,17 @@ package main
 
 import (
 	"context"
+	"crypto/subtle"
+	"database/sql"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -63,15 +68,27 @@ func (s *Server) Start(ctx context.Context) error {
 	mux.HandleFunc("/", s.handleDashboard)
 	mux.HandleFunc("/models", s.handleModels)
 	mux.HandleFunc("/logs", s.handleLogs)
+	mux.HandleFunc("/schedules", s.handleSchedules)
 
 	// API endpoints
 	mux.HandleFunc("/api/status", s.handleAPIStatus)
 	mux.HandleFunc("/api/miners", s.handleAPIMiners)
 	mux.HandleFunc("/api/models", s.handleAPIModels)
 	mux.HandleFunc("/api/models/", s.handleAPIModelUpdate)
+	mux.HandleFunc("/api/presets/", s.handleAPIPresetUpdate)
 	mux.HandleFunc("/api/miners/", s.handleAPIMinerConfig)
 	mux.HandleFunc("/api/logs", s.handleAPILogs)
 	mux.HandleFunc("/api/readings", s.handleAPIReadings)
+	mux.HandleFunc("/api/budget", s.handleAPIBudget)
+	mux.HandleFunc("/api/calibration", s.handleAPICalibration)
+	mux.HandleFunc("/api/drifts", s.handleAPIDrifts)
+	mux.HandleFunc("/api/schedules", s.handleAPISchedules)
+	mux.HandleFunc("/api/schedules/delete", s.handleAPIScheduleDelete)
+
+	// Demand-response setpoint API (requires a DEMAND_RESPONSE_KEYS key)
+	mux.HandleFunc("/api/demand-response/events", s.requireDemandResponseKey(s.handleAPIDemandResponseEvents))
+	mux.HandleFunc("/api/demand-response/compliance", s.requireDemandResponseKey(s.handleAPIDemandResponseCompliance))
+	mux.HandleFunc("/api/demand-response/cancel", s.requireDemandResponseKey(s.handleAPIDemandResponseCancel))
 
 	// SSE endpoint for live updates
 	mux.HandleFunc("/api/sse", s.handleSSE)
@@ -138,6 +155,7 @@ func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
 		if err == nil {
 			m.Presets = presets
 		}
+		s.attachCalibration(m.Presets)
 		if m.MinPresetID != nil {
 			m.MinPreset, _ = s.repo.GetPresetByID(ctx, *m.MinPresetID)
 		}
@@ -186,6 +204,40 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleSchedules renders the time-of-use schedules page.
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	scheduler := s.balancer.Scheduler()
+
+	schedules, err := scheduler.List(ctx, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	presets, err := s.repo.ListPresetNames(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Schedules": schedules,
+		"Presets":   presets,
+		"Timezone":  scheduler.Location().String(),
+		"Now":       time.Now().In(scheduler.Location()),
+		"Status":    s.balancer.GetStatus(),
+	}
+
+	if s.tmpl == nil {
+		json.NewEncoder(w).Encode(data)
+		return
+	}
+
+	if err := s.tmpl.ExecuteTemplate(w, "schedules.html", data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
 // handleAPIStatus returns the current system status as JSON.
 func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
@@ -202,6 +254,16 @@ func (s *Server) handleAPIMiners(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	parked, err := s.repo.GetParkedMiners(ctx)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	parkedByMiner := make(map[int64]*ParkedMiner, len(parked))
+	for _, p := range parked {
+		parkedByMiner[p.MinerID] = p
+	}
+
 	// Enrich with related data
 	for _, m := range miners {
 		if m.ModelID != nil {
@@ -212,6 +274,7 @@ func (s *Server) handleAPIMiners(w http.ResponseWriter, r *http.Request) {
 		}
 		m.Config, _ = s.repo.GetOrCreateBalanceConfig(ctx, m.ID)
 		m.Cooldown, _ = s.repo.GetCooldown(ctx, m.ID)
+		m.Parked = parkedByMiner[m.ID]
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -230,6 +293,7 @@ func (s *Server) handleAPIModels(w http.ResponseWriter, r *http.Request) {
 
 	for _, m := range models {
 		m.Presets, _ = s.repo.GetModelPresets(ctx, m.ID)
+		s.attachCalibration(m.Presets)
 		if m.MinPresetID != nil {
 			m.MinPreset, _ = s.repo.GetPresetByID(ctx, *m.MinPresetID)
 		}
@@ -242,6 +306,34 @@ func (s *Server) handleAPIModels(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(models)
 }
 
+// attachCalibration sets the measured stats of each preset across its model's miners.
+func (s *Server) attachCalibration(presets []*ModelPreset) {
+	calibration := s.balancer.Calibration()
+	if calibration == nil {
+		return
+	}
+	for _, p := range presets {
+		p.Calibration = calibration.ModelStats(p.ID)
+	}
+}
+
+// handleAPICalibration returns the measured preset stats per model and per miner.
+func (s *Server) handleAPICalibration(w http.ResponseWriter, r *http.Request) {
+	calibration := s.balancer.Calibration()
+	if calibration == nil {
+		http.Error(w, "calibration not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	models, miners := calibration.All()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"min_samples": s.cfg.CalibrationMinSamples,
+		"models":      models,
+		"miners":      miners,
+	})
+}
+
 // handleAPIModelUpdate handles model configuration updates.
 func (s *Server) handleAPIModelUpdate(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -269,6 +361,37 @@ func (s *Server) handleAPIModelUpdate(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
+// handleAPIPresetUpdate handles preset power updates.
+func (s *Server) handleAPIPresetUpdate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		PresetID   int64   `json:"preset_id"`
+		Watts      int     `json:"watts"`
+		HashrateTH float64 `json:"hashrate_th"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Watts < 0 || req.HashrateTH < 0 {
+		http.Error(w, "watts and hashrate must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.repo.UpdatePresetPower(r.Context(), req.PresetID, req.Watts, req.HashrateTH); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
 // handleAPIMinerConfig handles miner balance config updates.
 func (s *Server) handleAPIMinerConfig(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -303,6 +426,18 @@ func (s *Server) handleAPILogs(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(logs)
 }
 
+// handleAPIDrifts returns recent preset drifts as JSON.
+func (s *Server) handleAPIDrifts(w http.ResponseWriter, r *http.Request) {
+	drifts, err := s.repo.ListPresetDrifts(r.Context(), 100)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(drifts)
+}
+
 // handleAPIReadings returns recent energy readings as JSON.
 func (s *Server) handleAPIReadings(w http.ResponseWriter, r *http.Request) {
 	readings, err := s.repo.GetRecentEnergyReadings(r.Context(), 100)
@@ -315,6 +450,275 @@ func (s *Server) handleAPIReadings(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(readings)
 }
 
+// handleAPIBudget sets a plant value of a static energy source.
+func (s *Server) handleAPIBudget(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Source string  `json:"source"`
+		Plant  string  `json:"plant"`
+		MW     float64 `json:"mw"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.balancer.SetBudget(req.Source, req.Plant, req.MW); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// handleAPISchedules lists the schedules (GET), or creates a schedule or
+// updates the one with the given ID (POST).
+func (s *Server) handleAPISchedules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	scheduler := s.balancer.Scheduler()
+
+	switch r.Method {
+	case http.MethodGet:
+		schedules, err := scheduler.List(ctx, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"timezone":  scheduler.Location().String(),
+			"schedules": schedules,
+		})
+
+	case http.MethodPost:
+		var sc Schedule
+		if err := json.NewDecoder(r.Body).Decode(&sc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := scheduler.Validate(ctx, &sc); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if err := s.repo.SaveSchedule(ctx, &sc); errors.Is(err, sql.ErrNoRows) {
+			http.Error(w, "schedule not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "id": sc.ID})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIScheduleDelete deletes a schedule.
+func (s *Server) handleAPIScheduleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.repo.DeleteSchedule(r.Context(), req.ID); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "schedule not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// requireDemandResponseKey serves a demand-response handler only with
+// DEMAND_RESPONSE_KEYS set, and only to requests bearing one of the keys. The
+// handler gets the name of the key as the event source.
+func (s *Server) requireDemandResponseKey(next func(http.ResponseWriter, *http.Request, string)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if len(s.cfg.DemandResponseKeys) == 0 {
+			http.Error(w, "demand response API disabled", http.StatusNotFound)
+			return
+		}
+
+		key, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		source := ""
+		for name, k := range s.cfg.DemandResponseKeys {
+			if subtle.ConstantTimeCompare([]byte(key), []byte(k)) == 1 {
+				source = name
+			}
+		}
+		if source == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="demand-response"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		next(w, r, source)
+	}
+}
+
+// handleAPIDemandResponseEvents lists the recent demand-response events with
+// their compliance (GET), or queues a new event (POST).
+func (s *Server) handleAPIDemandResponseEvents(w http.ResponseWriter, r *http.Request, source string) {
+	ctx := r.Context()
+
+	switch r.Method {
+	case http.MethodGet:
+		events, err := s.repo.ListDemandResponseEvents(ctx, 100)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, e := range events {
+			if e.BaselineMW != nil {
+				e.Compliance, _ = s.repo.GetDRCompliance(ctx, e)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(events)
+
+	case http.MethodPost:
+		var req struct {
+			Reference string       `json:"reference"`
+			Start     *time.Time   `json:"start"` // RFC 3339 (default: now)
+			Duration  JSONDuration `json:"duration"`
+			Ramp      JSONDuration `json:"ramp"`
+			TargetMW  *float64     `json:"target_mw"`
+			ReduceMW  *float64     `json:"reduce_mw"`
+		}
+
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		e := &DemandResponseEvent{
+			Source:    source,
+			Reference: req.Reference,
+			StartAt:   now,
+			Duration:  req.Duration,
+			Ramp:      req.Ramp,
+			TargetMW:  req.TargetMW,
+			ReduceMW:  req.ReduceMW,
+			Status:    DRScheduled,
+			CreatedAt: now,
+		}
+		if req.Start != nil {
+			e.StartAt = *req.Start
+		}
+		if err := e.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !e.EndAt().After(now) {
+			http.Error(w, "event is already over", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.repo.InsertDemandResponseEvent(ctx, e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Demand response %s queued: start %s, duration %s, ramp %s",
+			e.label(), e.StartAt.Format(time.RFC3339), time.Duration(e.Duration), time.Duration(e.Ramp))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"status": "ok", "id": e.ID})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIDemandResponseCompliance returns an event with its compliance
+// summary and the consumption against the target over time.
+func (s *Server) handleAPIDemandResponseCompliance(w http.ResponseWriter, r *http.Request, _ string) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	e, err := s.repo.GetDemandResponseEvent(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "event not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	samples, err := s.repo.GetDRSamples(ctx, e)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if e.BaselineMW != nil {
+		if e.Compliance, err = s.repo.GetDRCompliance(ctx, e); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"event":   e,
+		"samples": samples,
+	})
+}
+
+// handleAPIDemandResponseCancel cancels a scheduled or active event.
+func (s *Server) handleAPIDemandResponseCancel(w http.ResponseWriter, r *http.Request, _ string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.balancer.CancelDemandResponse(r.Context(), req.ID); errors.Is(err, sql.ErrNoRows) {
+		http.Error(w, "no open event with that id", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
 // handleSSE handles Server-Sent Events for live updates.
 func (s *Server) handleSSE(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/event-stream")
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/modbus"
+)
+
+// AggregatorSim serves the energy aggregator API from a scripted scenario,
+// so incidents can be replayed against the balancer. Consumption comes from
+// the simulated miners, so preset changes feed back into the margin.
+type AggregatorSim struct {
+	mu          sync.Mutex
+	scenario    *Scenario
+	started     time.Time
+	capacityKW  float64
+	apiKey      string
+	consumption func() (elesKW, mazpKW float64)
+	nextID      int
+	lastFresh   *AggregatorResponse
+}
+
+// NewAggregatorSim creates a simulated aggregator starting the scenario now.
+// An empty apiKey accepts any bearer token.
+func NewAggregatorSim(scenario *Scenario, capacityKW float64, apiKey string, consumption func() (float64, float64)) *AggregatorSim {
+	return &AggregatorSim{
+		scenario:    scenario,
+		started:     time.Now(),
+		capacityKW:  capacityKW,
+		apiKey:      apiKey,
+		consumption: consumption,
+		nextID:      1,
+	}
+}
+
+// Restart replays a scenario from the beginning.
+func (s *AggregatorSim) Restart(scenario *Scenario) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scenario = scenario
+	s.started = time.Now()
+	s.lastFresh = nil
+}
+
+// Latest returns the reading at now, as the aggregator would report it.
+// Values are in kW despite the "_mw" field names, matching production.
+func (s *AggregatorSim) Latest(now time.Time) *AggregatorResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sample := s.scenario.At(now.Sub(s.started))
+	id := s.nextID
+	s.nextID++
+
+	// Stale data repeats the last fresh reading, source timestamps included
+	if sample.Stale && s.lastFresh != nil {
+		resp := *s.lastFresh
+		resp.Reading.ID = id
+		resp.Reading.CollectionTimestamp = now.UTC().Format(time.RFC3339)
+		return &resp
+	}
+
+	var resp AggregatorResponse
+	r := &resp.Reading
+	ts := now.UTC().Format(time.RFC3339)
+	r.ID = id
+	r.PlantID = "sim"
+	r.CollectionTimestamp = ts
+
+	generoso, nogueira := plantValue(ts, sample.GenerosoDown, sample.GenerosoKW), plantValue(ts, sample.NogueiraDown, sample.NogueiraKW)
+	r.Generation = map[string]AggregatorValue{"generoso": generoso, "nogueira": nogueira}
+
+	eles, mazp := s.consumption()
+	r.Consumption = map[string]AggregatorValue{
+		"container_eles": plantValue(ts, false, eles),
+		"container_mazp": plantValue(ts, false, mazp),
+	}
+
+	r.Totals.GenerationMW = generoso.ValueMW + nogueira.ValueMW
+	r.Totals.ConsumptionMW = eles + mazp
+	r.Totals.ExportedMW = r.Totals.GenerationMW - r.Totals.ConsumptionMW
+
+	confidence := sample.Confidence
+	r.Trust.ConfidenceScore = &confidence
+	switch {
+	case sample.Confidence >= 0.8:
+		r.Trust.Status = "trusted"
+		r.Trust.Summary = "All sources reporting consistently"
+	case sample.Confidence >= 0.5:
+		r.Trust.Status = "degraded"
+		r.Trust.Summary = "Sources disagree with recent history"
+	default:
+		r.Trust.Status = "untrusted"
+		r.Trust.Summary = "Readings inconsistent across sources"
+	}
+
+	s.lastFresh = &resp
+	return &resp
+}
+
+// plantValue returns a plant as the aggregator reports it. A plant that is
+// down reports status "error" and no value.
+func plantValue(ts string, down bool, kw float64) AggregatorValue {
+	if down {
+		return AggregatorValue{SourceTimestamp: ts, Status: "error"}
+	}
+	return AggregatorValue{SourceTimestamp: ts, Status: PlantStatusOK, ValueMW: kw}
+}
+
+// Handler returns the aggregator API (GET /data/latest) and the scenario
+// control endpoint (GET/POST /sim/scenario).
+func (s *AggregatorSim) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/data/latest", s.handleLatest)
+	mux.HandleFunc("/sim/scenario", s.handleScenario)
+	return mux
+}
+
+func (s *AggregatorSim) handleLatest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.apiKey != "" && r.Header.Get("Authorization") != "Bearer "+s.apiKey {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.Latest(time.Now()))
+}
+
+// handleScenario reports the running scenario, or restarts it on POST
+// (?name= switches to another built-in scenario).
+func (s *AggregatorSim) handleScenario(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		s.mu.Lock()
+		scenario := s.scenario
+		s.mu.Unlock()
+
+		if name := r.URL.Query().Get("name"); name != "" {
+			var err error
+			scenario, err = BuiltinScenario(name, s.capacityKW)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		s.Restart(scenario)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.Lock()
+	data := map[string]interface{}{
+		"scenario":  s.scenario,
+		"elapsed_s": int(time.Since(s.started).Seconds()),
+	}
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(data)
+}
+
+// simMeterRegisters is the holding register layout of the simulated Modbus
+// meter. Each plant is a float32 (ABCD) in kW.
+var simMeterRegisters = []struct {
+	kind     PlantKind
+	key      string
+	register uint16
+}{
+	{PlantGeneration, "generoso", 0},
+	{PlantGeneration, "nogueira", 2},
+	{PlantConsumption, "container_eles", 4},
+	{PlantConsumption, "container_mazp", 6},
+}
+
+// updateMeter copies a reading into the simulated meter's registers.
+func updateMeter(meter *modbus.Server, resp *AggregatorResponse) {
+	for _, reg := range simMeterRegisters {
+		values := resp.Reading.Generation
+		if reg.kind == PlantConsumption {
+			values = resp.Reading.Consumption
+		}
+		regs, _ := modbus.Encode(values[reg.key].ValueMW, modbus.Float32, modbus.OrderABCD)
+		meter.SetHoldingRegisters(reg.register, regs...)
+	}
+}
+
+// =============================================================================
+// simulate command
+// =============================================================================
+
+func runSimulate(ctx context.Context, cfg *Config, args []string) {
+	simCfg := minersim.DefaultConfig()
+	simCfg.VNishPassword = cfg.VNishPassword
+	simCfg.StockUsername = cfg.StockUsername
+	simCfg.StockPassword = cfg.StockPassword
+
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	listen := fs.String("listen", ":8090", "Aggregator API listen address")
+	apiKey := fs.String("api-key", "", "Required bearer token (default: accept any)")
+	baseLoad := fs.Float64("base-load", 5, "Consumption besides the miners, kW per container")
+	modbusAddr := fs.String("modbus", "", "Also serve the plants as a Modbus TCP meter on this address")
+	fs.StringVar(&simCfg.FirstIP, "first-ip", simCfg.FirstIP, "Address of the first simulated miner")
+	fs.IntVar(&simCfg.Port, "miner-port", simCfg.Port, "HTTP port of the simulated miners")
+	fs.IntVar(&simCfg.VNish, "vnish", simCfg.VNish, "Number of simulated VNish miners")
+	fs.IntVar(&simCfg.Stock, "stock", simCfg.Stock, "Number of simulated stock miners")
+	fs.DurationVar(&simCfg.Timing.Boot, "boot", simCfg.Timing.Boot, "Simulated miner reboot time")
+	fs.DurationVar(&simCfg.Timing.Restart, "restart", simCfg.Timing.Restart, "Simulated mining restart time")
+	fs.DurationVar(&simCfg.Timing.Tune, "tune", simCfg.Timing.Tune, "Simulated auto-tuning time")
+	fs.Float64Var(&simCfg.Timing.FailureRate, "failure-rate", simCfg.Timing.FailureRate, "Random failures per miner per mining hour")
+	fs.Int64Var(&simCfg.Seed, "seed", simCfg.Seed, "Random seed for the simulated miners")
+	fs.Usage = func() {
+		fmt.Println("Usage: power-balancer simulate [flags] [scenario | scenario.json]")
+		fmt.Printf("Built-in scenarios: %s (default: steady)\n", strings.Join(scenarioNames, ", "))
+		fmt.Println()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	farm, err := minersim.NewFarm(simCfg)
+	if err != nil {
+		log.Fatalf("Failed to create simulated miners: %v", err)
+	}
+	if err := farm.Start(); err != nil {
+		log.Fatalf("Failed to start simulated miners: %v", err)
+	}
+	defer farm.Close()
+
+	// The first half of the miners sit in container Eles, the rest in Mazp
+	miners := farm.Miners()
+	half := (len(miners) + 1) / 2
+	capacityKW := 2 * *baseLoad
+	for _, m := range miners {
+		capacityKW += float64(m.Spec().Model.StockWatts) / 1000
+	}
+	consumption := func() (float64, float64) {
+		eles, mazp := *baseLoad, *baseLoad
+		for i, m := range miners {
+			kw := float64(m.Status().Watts) / 1000
+			if i < half {
+				eles += kw
+			} else {
+				mazp += kw
+			}
+		}
+		return eles, mazp
+	}
+
+	name := "steady"
+	if fs.NArg() > 0 {
+		name = fs.Arg(0)
+	}
+	var scenario *Scenario
+	if strings.HasSuffix(name, ".json") {
+		scenario, err = LoadScenario(name)
+	} else {
+		scenario, err = BuiltinScenario(name, capacityKW)
+	}
+	if err != nil {
+		log.Fatalf("Failed to load scenario: %v", err)
+	}
+
+	sim := NewAggregatorSim(scenario, capacityKW, *apiKey, consumption)
+	srv := &http.Server{Addr: *listen, Handler: sim.Handler()}
+
+	log.Printf("Simulating %d miners from %s (port %d), capacity %.1f kW",
+		len(miners), simCfg.FirstIP, simCfg.Port, capacityKW)
+	log.Printf("Scenario %q: %s", scenario.Name, scenario.Description)
+	log.Printf("Aggregator: http://localhost%s/data/latest (restart: POST /sim/scenario?name=...)", *listen)
+
+	errCh := make(chan error, 2)
+	go func() {
+		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+			errCh <- err
+		}
+	}()
+
+	if *modbusAddr != "" {
+		meter := modbus.NewServer()
+		defer meter.Close()
+		updateMeter(meter, sim.Latest(time.Now()))
+
+		go func() {
+			if err := meter.ListenAndServe(*modbusAddr); err != nil {
+				errCh <- err
+			}
+		}()
+		go func() {
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					updateMeter(meter, sim.Latest(time.Now()))
+				}
+			}
+		}()
+
+		log.Printf("Modbus meter: %s (holding registers, float32 ABCD, kW: generoso 0, nogueira 2, container_eles 4, container_mazp 6)", *modbusAddr)
+	}
+
+	select {
+	case err := <-errCh:
+		log.Fatalf("Error: %v", err)
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"math"
+	"net"
+	"testing"
+
+	"github.com/powerhive/powerhive-v2/pkg/modbus"
+)
+
+func TestModbusSourceReadPlants(t *testing.T) {
+	server := modbus.NewServer()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go server.Serve(l)
+	defer server.Close()
+
+	// Meter registers as the plants below lay them out
+	set := func(input bool, addr uint16, v float64, dataType modbus.DataType, order modbus.WordOrder) {
+		regs, err := modbus.Encode(v, dataType, order)
+		if err != nil {
+			t.Fatalf("Encode: %v", err)
+		}
+		if input {
+			server.SetInputRegisters(addr, regs...)
+		} else {
+			server.SetHoldingRegisters(addr, regs...)
+		}
+	}
+	set(false, 0, 12.5, modbus.Float32, modbus.OrderABCD)
+	set(false, 10, 8250, modbus.Uint32, modbus.OrderCDAB)
+	set(true, 0, 4.75, modbus.Float64, modbus.OrderDCBA)
+	set(true, 20, -300, modbus.Int16, modbus.OrderBADC)
+
+	tests := []struct {
+		plant PlantConfig
+		want  float64
+	}{
+		{PlantConfig{Name: "solar", Kind: PlantGeneration, Register: 0}, 12.5},
+		{PlantConfig{Name: "hydro", Kind: PlantGeneration, Register: 10, DataType: "uint32", WordOrder: "cdab", Scale: 0.001}, 8.25},
+		{PlantConfig{Name: "farm", Kind: PlantConsumption, Register: 0, RegisterType: "input", DataType: "float64", WordOrder: "DCBA"}, 4.75},
+		{PlantConfig{Name: "export", Kind: PlantConsumption, Register: 20, RegisterType: "input", DataType: "int16", WordOrder: "BADC", Scale: 0.01}, -3},
+	}
+
+	sc := SourceConfig{Name: "meter", Type: "modbus", Address: l.Addr().String(), UnitID: 1}
+	for _, tt := range tests {
+		sc.Plants = append(sc.Plants, tt.plant)
+	}
+	source, err := NewModbusSource(sc)
+	if err != nil {
+		t.Fatalf("NewModbusSource: %v", err)
+	}
+	defer source.client.Close()
+
+	readings, err := source.ReadPlants(context.Background())
+	if err != nil {
+		t.Fatalf("ReadPlants: %v", err)
+	}
+	if len(readings) != len(tests) {
+		t.Fatalf("got %d readings, want %d", len(readings), len(tests))
+	}
+	for i, tt := range tests {
+		r := readings[i]
+		if r.Name != tt.plant.Name || r.Kind != tt.plant.Kind || r.Status != PlantStatusOK {
+			t.Errorf("%s: got %+v", tt.plant.Name, r)
+		}
+		if math.Abs(r.MW-tt.want) > 1e-9 {
+			t.Errorf("%s: got %v MW, want %v", tt.plant.Name, r.MW, tt.want)
+		}
+	}
+
+	// A register the meter doesn't have fails the whole read
+	sc.Plants = []PlantConfig{{Name: "missing", Kind: PlantGeneration, Register: 500}}
+	missing, err := NewModbusSource(sc)
+	if err != nil {
+		t.Fatalf("NewModbusSource: %v", err)
+	}
+	defer missing.client.Close()
+	if _, err := missing.ReadPlants(context.Background()); err == nil {
+		t.Error("expected an error reading a missing register")
+	}
+}
+
+func TestParseModbusPlant(t *testing.T) {
+	tests := []struct {
+		name    string
+		plant   PlantConfig
+		wantErr bool
+	}{
+		{"defaults", PlantConfig{Register: 0}, false},
+		{"input", PlantConfig{RegisterType: "Input", DataType: "int32", WordOrder: "cdab"}, false},
+		{"bad register type", PlantConfig{RegisterType: "coil"}, true},
+		{"bad data type", PlantConfig{DataType: "int8"}, true},
+		{"bad word order", PlantConfig{WordOrder: "ACBD"}, true},
+		{"negative register", PlantConfig{Register: -1}, true},
+		{"past last register", PlantConfig{Register: 65535, DataType: "float32"}, true},
+		{"last register", PlantConfig{Register: 65535, DataType: "uint16"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseModbusPlant(tt.plant); (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/mqtt"
+)
+
+// MQTTSource keeps the latest value published on each plant's topic.
+type MQTTSource struct {
+	name     string
+	plants   []PlantConfig
+	maxAge   time.Duration
+	onChange bool
+
+	mu     sync.Mutex
+	values map[string]mqttValue // By topic
+	lostAt time.Time            // When the broker connection was lost (zero = connected)
+}
+
+// mqttValue is the last value received on a topic.
+type mqttValue struct {
+	value float64
+	at    time.Time
+}
+
+// NewMQTTSource creates a source and subscribes in the background,
+// reconnecting until ctx is done.
+func NewMQTTSource(ctx context.Context, sc SourceConfig) *MQTTSource {
+	s := &MQTTSource{
+		name:     sc.Name,
+		plants:   sc.Plants,
+		maxAge:   time.Duration(sc.MaxAge),
+		onChange: sc.OnChange,
+		values:   make(map[string]mqttValue),
+	}
+	if s.maxAge <= 0 {
+		s.maxAge = time.Minute
+	}
+
+	var opts []mqtt.SubscriberOption
+	if sc.Username != "" {
+		opts = append(opts, mqtt.WithCredentials(sc.Username, sc.Password))
+	}
+	sub := mqtt.NewSubscriber(sc.Broker, opts...)
+
+	topics := make([]string, 0, len(sc.Plants))
+	for _, p := range sc.Plants {
+		topics = append(topics, p.Topic)
+	}
+
+	go func() {
+		for {
+			err := sub.Subscribe(ctx, topics, s.handle)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("MQTT source %s: %v, reconnecting", s.name, err)
+
+			s.mu.Lock()
+			if s.lostAt.IsZero() {
+				s.lostAt = time.Now()
+			}
+			s.mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+
+	return s
+}
+
+// Name returns the source name (implements EnergySource).
+func (s *MQTTSource) Name() string {
+	return s.name
+}
+
+// handle stores a published value for every plant on the topic.
+func (s *MQTTSource) handle(topic string, payload []byte) {
+	for _, p := range s.plants {
+		if p.Topic != topic {
+			continue
+		}
+		v, err := parseMQTTValue(payload, p.JSONField)
+		if err != nil {
+			log.Printf("MQTT source %s: topic %s: %v", s.name, topic, err)
+			continue
+		}
+
+		s.mu.Lock()
+		s.values[topic+"\x00"+p.JSONField] = mqttValue{value: v, at: time.Now()}
+		s.lostAt = time.Time{}
+		s.mu.Unlock()
+	}
+}
+
+// parseMQTTValue reads a plain number payload, or a number field of a JSON object.
+func parseMQTTValue(payload []byte, field string) (float64, error) {
+	if field == "" {
+		return strconv.ParseFloat(strings.TrimSpace(string(payload)), 64)
+	}
+
+	var obj map[string]interface{}
+	if err := json.Unmarshal(payload, &obj); err != nil {
+		return 0, fmt.Errorf("parse JSON payload: %w", err)
+	}
+	switch v := obj[field].(type) {
+	case float64:
+		return v, nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	}
+	return 0, fmt.Errorf("field %q is missing or not a number", field)
+}
+
+// ReadPlants returns the latest values (implements EnergySource).
+// Plants without a value within MaxAge are reported with status "stale".
+// With OnChange a value holds until the broker connection has been lost for
+// MaxAge, and is reported as measured now while connected.
+func (s *MQTTSource) ReadPlants(ctx context.Context) ([]PlantReading, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readings := make([]PlantReading, 0, len(s.plants))
+	for _, p := range s.plants {
+		v, ok := s.values[p.Topic+"\x00"+p.JSONField]
+		if !ok {
+			return nil, fmt.Errorf("no value received on %s yet", p.Topic)
+		}
+
+		at := v.at
+		if s.onChange {
+			at = s.lostAt
+			if at.IsZero() {
+				at = time.Now()
+			}
+		}
+		status := PlantStatusOK
+		if time.Since(at) > s.maxAge {
+			status = "stale"
+		}
+		readings = append(readings, PlantReading{
+			Name:            p.Name,
+			Kind:            p.Kind,
+			MW:              v.value * p.scale(1),
+			Status:          status,
+			Confidence:      1,
+			SourceTimestamp: at,
+		})
+	}
+	return readings, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StaticSource reports fixed plant values, e.g. a contracted grid budget.
+// Values can be changed at runtime from the dashboard API.
+type StaticSource struct {
+	name string
+
+	mu     sync.Mutex
+	plants []PlantConfig
+}
+
+// NewStaticSource creates a static source from its configured values.
+func NewStaticSource(sc SourceConfig) *StaticSource {
+	return &StaticSource{
+		name:   sc.Name,
+		plants: append([]PlantConfig(nil), sc.Plants...),
+	}
+}
+
+// Name returns the source name (implements EnergySource).
+func (s *StaticSource) Name() string {
+	return s.name
+}
+
+// ReadPlants returns the current values (implements EnergySource). Fixed
+// values don't age, so they're reported as measured now.
+func (s *StaticSource) ReadPlants(ctx context.Context) ([]PlantReading, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	readings := make([]PlantReading, 0, len(s.plants))
+	for _, p := range s.plants {
+		readings = append(readings, PlantReading{
+			Name:            p.Name,
+			Kind:            p.Kind,
+			MW:              p.MW,
+			Status:          PlantStatusOK,
+			Confidence:      1,
+			SourceTimestamp: time.Now(),
+		})
+	}
+	return readings, nil
+}
+
+// Set changes the value of a plant.
+func (s *StaticSource) Set(plant string, mw float64) error {
+	if mw < 0 {
+		return fmt.Errorf("value must not be negative")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.plants {
+		if s.plants[i].Name == plant {
+			s.plants[i].MW = mw
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown plant %q in source %q", plant, s.name)
+}
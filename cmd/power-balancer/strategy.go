@@ -2,13 +2,73 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
 )
 
-// Strategy handles miner selection and preset targeting logic.
-type Strategy struct {
-	repo *Repository
-	cfg  *Config
+// Strategy decides which miners to change, and to which presets, to reach a
+// target reduction or increase in consumption.
+type Strategy interface {
+	// Name returns the strategy name, as selected with STRATEGY.
+	Name() string
+
+	// CalculateReduction returns changes that would reduce consumption by
+	// about targetReductionW, in the order they should be applied.
+	CalculateReduction(ctx context.Context, targetReductionW int) ([]*PresetChange, error)
+
+	// CalculateIncrease returns changes that would increase consumption by
+	// up to about targetIncreaseW, in the order they should be applied.
+	CalculateIncrease(ctx context.Context, targetIncreaseW int) ([]*PresetChange, error)
+
+	// CalculateFailSafe returns the changes that step every manageable miner
+	// down when the energy data can't be trusted.
+	CalculateFailSafe(ctx context.Context) ([]*PresetChange, error)
+
+	// CalculateStop returns every manageable miner still drawing power, to
+	// park when the energy data can't be trusted.
+	CalculateStop(ctx context.Context) ([]*MinerWithContext, error)
+
+	// CalculatePark returns the miners to park, lowest priority first, to
+	// save about targetW once every manageable miner is at its minimum preset.
+	CalculatePark(ctx context.Context, targetW int) ([]*MinerWithContext, error)
+
+	// CalculateUnpark returns the parked miners that can resume within
+	// roomW, highest priority first.
+	CalculateUnpark(ctx context.Context, roomW int) ([]*ParkedMiner, error)
+
+	// CalculateTarget returns the changes that move miners above their
+	// schedule target preset down to it.
+	CalculateTarget(ctx context.Context) ([]*PresetChange, error)
+
+	// Within returns the strategy restricted to the given miners, for
+	// changes inside one power domain.
+	Within(minerIDs map[int64]bool) Strategy
+
+	// Targeting returns the strategy holding miners at or below the lowest
+	// of the target presets ("min" or a preset name) of the active schedules.
+	Targeting(presets []string) Strategy
+}
+
+// Strategy names.
+const (
+	StrategyEfficiency = "efficiency" // Least efficient miners reduced first
+	StrategyPriority   = "priority"   // Lowest priority tier reduced first
+	StrategyFairness   = "fairness"   // Miners curtailed least recently reduced first
+	StrategyKnapsack   = "knapsack"   // Presets chosen to lose the least hashrate
+)
+
+// strategyNames lists the strategies in help order.
+var strategyNames = []string{StrategyEfficiency, StrategyPriority, StrategyFairness, StrategyKnapsack}
+
+// planner holds what every strategy shares: the manageable miners with
+// calibrated presets, preset targeting, the fail-safe step down and parking.
+type planner struct {
+	repo        *Repository
+	calibration *Calibration
+	cfg         *Config
+	scope       map[int64]bool // Miners considered (nil = all)
+	targets     []string       // Schedule target presets (nil = none)
 }
 
 // PresetChange represents a planned preset change.
@@ -17,21 +77,153 @@ type PresetChange struct {
 	FromPreset   *ModelPreset
 	ToPreset     *ModelPreset
 	ExpectedDeltaW int // Positive = reduction
+	Attempt        int // Retries of a change that didn't take (0 for new changes)
+}
+
+// NewStrategy creates the named strategy.
+func NewStrategy(name string, repo *Repository, calibration *Calibration, cfg *Config) (Strategy, error) {
+	p := planner{
+		repo:        repo,
+		calibration: calibration,
+		cfg:         cfg,
+	}
+
+	switch name {
+	case StrategyEfficiency:
+		return &greedyStrategy{planner: p, name: name, order: orderByEfficiency}, nil
+	case StrategyPriority:
+		return &greedyStrategy{planner: p, name: name, order: orderByPriority}, nil
+	case StrategyFairness:
+		return &greedyStrategy{planner: p, name: name, order: p.orderByLastReduction}, nil
+	case StrategyKnapsack:
+		return &knapsackStrategy{planner: p}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q (available: %s)", name, strings.Join(strategyNames, ", "))
 }
 
-// NewStrategy creates a new strategy instance.
-func NewStrategy(repo *Repository, cfg *Config) *Strategy {
-	return &Strategy{
-		repo: repo,
-		cfg:  cfg,
+// manageableMiners returns the manageable miners with calibrated presets,
+// their maximum lowered to any schedule target. Miners whose current, minimum
+// or maximum preset has no power figure are left out.
+func (s *planner) manageableMiners(ctx context.Context) ([]*MinerWithContext, error) {
+	all, err := s.repo.GetManageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var miners []*MinerWithContext
+	for _, m := range all {
+		if !s.inScope(m.Miner.ID) {
+			continue
+		}
+		m.CurrentPreset = s.calibration.Apply(m.Miner.ID, m.CurrentPreset)
+		m.MinPreset = s.calibration.Apply(m.Miner.ID, m.MinPreset)
+		m.MaxPreset = s.calibration.Apply(m.Miner.ID, m.MaxPreset)
+		if m.CurrentPreset.Watts <= 0 || m.MinPreset.Watts <= 0 || m.MaxPreset.Watts <= 0 {
+			continue // No power figures to balance with
+		}
+		if t := s.targetPreset(ctx, m.Miner.ID, m.Model.ID, m.MinPreset); t != nil && t.Watts < m.MaxPreset.Watts {
+			m.MaxPreset = t
+		}
+		m.updateHeadroom()
+		miners = append(miners, m)
+	}
+	return miners, nil
+}
+
+// inScope returns true if the planner considers the miner.
+func (s *planner) inScope(minerID int64) bool {
+	return s.scope == nil || s.scope[minerID]
+}
+
+// modelPresets returns the presets of a miner's model, calibrated for the miner.
+// Presets without a power figure are left out: their effect is unknown.
+func (s *planner) modelPresets(ctx context.Context, miner *MinerWithContext) ([]*ModelPreset, error) {
+	all, err := s.repo.GetModelPresets(ctx, miner.Model.ID)
+	if err != nil {
+		return nil, err
+	}
+	var presets []*ModelPreset
+	for _, p := range all {
+		if p = s.calibration.Apply(miner.Miner.ID, p); p.Watts > 0 {
+			presets = append(presets, p)
+		}
+	}
+	return presets, nil
+}
+
+// CalculateFailSafe returns the changes that move every manageable miner down
+// to its model's minimum preset, biggest reductions first. Cooldowns are
+// ignored: without trustworthy energy data, getting consumption down matters
+// more than sparing the miners.
+func (s *planner) CalculateFailSafe(ctx context.Context) ([]*PresetChange, error) {
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []*PresetChange
+	for _, miner := range miners {
+		target := miner.MinPreset
+		delta := miner.CurrentPreset.Watts - target.Watts
+		if delta <= 0 {
+			continue
+		}
+
+		changes = append(changes, &PresetChange{
+			Miner:          miner,
+			FromPreset:     miner.CurrentPreset,
+			ToPreset:       target,
+			ExpectedDeltaW: delta,
+		})
 	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].ExpectedDeltaW > changes[j].ExpectedDeltaW
+	})
+
+	return changes, nil
+}
+
+// =============================================================================
+// Greedy strategies
+// =============================================================================
+
+// minerOrder sorts miners in the order they should be changed, for a
+// reduction or an increase.
+type minerOrder func(ctx context.Context, miners []*MinerWithContext, increase bool) error
+
+// greedyStrategy walks the miners in its order, moving each to the preset
+// closest to what's still needed.
+type greedyStrategy struct {
+	planner
+	name  string
+	order minerOrder
+}
+
+// Name returns the strategy name.
+func (s *greedyStrategy) Name() string {
+	return s.name
+}
+
+// Within returns a copy of the strategy restricted to the given miners.
+func (s *greedyStrategy) Within(minerIDs map[int64]bool) Strategy {
+	scoped := *s
+	scoped.scope = minerIDs
+	return &scoped
+}
+
+// Targeting returns a copy of the strategy holding miners at or below the
+// target presets.
+func (s *greedyStrategy) Targeting(presets []string) Strategy {
+	targeted := *s
+	targeted.targets = presets
+	return &targeted
 }
 
 // CalculateReduction calculates which miners to reduce and by how much.
 // Returns a list of preset changes that would achieve the target reduction.
-func (s *Strategy) CalculateReduction(ctx context.Context, targetReductionW int) ([]*PresetChange, error) {
+func (s *greedyStrategy) CalculateReduction(ctx context.Context, targetReductionW int) ([]*PresetChange, error) {
 	// Get all manageable miners (enabled, not locked, configured model)
-	miners, err := s.repo.GetManageableMiners(ctx)
+	miners, err := s.manageableMiners(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -44,11 +236,9 @@ func (s *Strategy) CalculateReduction(ctx context.Context, targetReductionW int)
 		}
 	}
 
-	// Sort by efficiency ascending (least efficient miners reduced first)
-	// This preserves the most hashrate per watt when reducing consumption
-	sort.Slice(available, func(i, j int) bool {
-		return available[i].Efficiency < available[j].Efficiency
-	})
+	if err := s.order(ctx, available, false); err != nil {
+		return nil, err
+	}
 
 	// Calculate changes to reach target reduction
 	var changes []*PresetChange
@@ -60,7 +250,7 @@ func (s *Strategy) CalculateReduction(ctx context.Context, targetReductionW int)
 		}
 
 		// Get available presets for this miner's model
-		presets, err := s.repo.GetModelPresets(ctx, miner.Model.ID)
+		presets, err := s.modelPresets(ctx, miner)
 		if err != nil {
 			continue
 		}
@@ -91,9 +281,9 @@ func (s *Strategy) CalculateReduction(ctx context.Context, targetReductionW int)
 
 // CalculateIncrease calculates which miners to increase and by how much.
 // Returns a list of preset changes that would achieve the target increase.
-func (s *Strategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) ([]*PresetChange, error) {
+func (s *greedyStrategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) ([]*PresetChange, error) {
 	// Get all manageable miners
-	miners, err := s.repo.GetManageableMiners(ctx)
+	miners, err := s.manageableMiners(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -110,11 +300,9 @@ func (s *Strategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) (
 		}
 	}
 
-	// Sort by efficiency descending (most efficient miners increased first)
-	// This maximizes hashrate gained per watt when increasing consumption
-	sort.Slice(available, func(i, j int) bool {
-		return available[i].Efficiency > available[j].Efficiency
-	})
+	if err := s.order(ctx, available, true); err != nil {
+		return nil, err
+	}
 
 	// Calculate changes to reach target increase
 	var changes []*PresetChange
@@ -126,7 +314,7 @@ func (s *Strategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) (
 		}
 
 		// Get available presets for this miner's model
-		presets, err := s.repo.GetModelPresets(ctx, miner.Model.ID)
+		presets, err := s.modelPresets(ctx, miner)
 		if err != nil {
 			continue
 		}
@@ -155,9 +343,54 @@ func (s *Strategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) (
 	return changes, nil
 }
 
+// orderByEfficiency reduces the least efficient miners first and increases the
+// most efficient first, preserving the most hashrate per watt.
+func orderByEfficiency(_ context.Context, miners []*MinerWithContext, increase bool) error {
+	sort.SliceStable(miners, func(i, j int) bool {
+		if increase {
+			return miners[i].Efficiency > miners[j].Efficiency
+		}
+		return miners[i].Efficiency < miners[j].Efficiency
+	})
+	return nil
+}
+
+// orderByPriority reduces the lowest priority tier first and increases the
+// highest first, by efficiency within a tier.
+func orderByPriority(ctx context.Context, miners []*MinerWithContext, increase bool) error {
+	orderByEfficiency(ctx, miners, increase)
+	sort.SliceStable(miners, func(i, j int) bool {
+		if increase {
+			return miners[i].Config.Priority > miners[j].Config.Priority
+		}
+		return miners[i].Config.Priority < miners[j].Config.Priority
+	})
+	return nil
+}
+
+// orderByLastReduction spreads curtailment across the fleet: miners reduced
+// least recently (or never) are reduced first, and miners reduced longest ago
+// are restored first, by efficiency among equals.
+func (s *planner) orderByLastReduction(ctx context.Context, miners []*MinerWithContext, increase bool) error {
+	last, err := s.repo.GetLastReductions(ctx)
+	if err != nil {
+		return fmt.Errorf("get last reductions: %w", err)
+	}
+
+	orderByEfficiency(ctx, miners, increase)
+	sort.SliceStable(miners, func(i, j int) bool {
+		return last[miners[i].Miner.ID].Before(last[miners[j].Miner.ID])
+	})
+	return nil
+}
+
+// =============================================================================
+// Preset targeting
+// =============================================================================
+
 // findReductionPreset finds the best preset to reduce to.
 // It tries to match the needed reduction without over-reducing.
-func (s *Strategy) findReductionPreset(miner *MinerWithContext, presets []*ModelPreset, neededReductionW int) *ModelPreset {
+func (s *planner) findReductionPreset(miner *MinerWithContext, presets []*ModelPreset, neededReductionW int) *ModelPreset {
 	currentWatts := miner.CurrentPreset.Watts
 	minWatts := miner.MinPreset.Watts
 
@@ -205,7 +438,7 @@ func (s *Strategy) findReductionPreset(miner *MinerWithContext, presets []*Model
 }
 
 // findIncreasePreset finds the best preset to increase to.
-func (s *Strategy) findIncreasePreset(miner *MinerWithContext, presets []*ModelPreset, neededIncreaseW int) *ModelPreset {
+func (s *planner) findIncreasePreset(miner *MinerWithContext, presets []*ModelPreset, neededIncreaseW int) *ModelPreset {
 	currentWatts := miner.CurrentPreset.Watts
 	maxWatts := miner.MaxPreset.Watts
 
@@ -245,8 +478,8 @@ func (s *Strategy) findIncreasePreset(miner *MinerWithContext, presets []*ModelP
 }
 
 // GetAvailableReductionCapacity returns the total watts that can be reduced.
-func (s *Strategy) GetAvailableReductionCapacity(ctx context.Context) (int, error) {
-	miners, err := s.repo.GetManageableMiners(ctx)
+func (s *planner) GetAvailableReductionCapacity(ctx context.Context) (int, error) {
+	miners, err := s.manageableMiners(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -261,8 +494,8 @@ func (s *Strategy) GetAvailableReductionCapacity(ctx context.Context) (int, erro
 }
 
 // GetAvailableIncreaseCapacity returns the total watts that can be increased.
-func (s *Strategy) GetAvailableIncreaseCapacity(ctx context.Context) (int, error) {
-	miners, err := s.repo.GetManageableMiners(ctx)
+func (s *planner) GetAvailableIncreaseCapacity(ctx context.Context) (int, error) {
+	miners, err := s.manageableMiners(ctx)
 	if err != nil {
 		return 0, err
 	}
@@ -278,3 +511,14 @@ func (s *Strategy) GetAvailableIncreaseCapacity(ctx context.Context) (int, error
 	}
 	return total, nil
 }
+
+// updateHeadroom computes the headroom and efficiency from the miner's presets.
+func (m *MinerWithContext) updateHeadroom() {
+	m.HeadroomWatts = m.CurrentPreset.Watts - m.MinPreset.Watts
+
+	// Calculate efficiency: hashrate per watt (TH/W) - higher is better
+	m.Efficiency = 0
+	if m.CurrentPreset.Watts > 0 && m.CurrentPreset.HashrateTH > 0 {
+		m.Efficiency = m.CurrentPreset.HashrateTH / float64(m.CurrentPreset.Watts)
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"math"
+	"sort"
+)
+
+// Knapsack resolution. Watts are bucketed at knapsackResolutionW, coarsened
+// for large targets so a plan never takes more than knapsackMaxBuckets buckets.
+const (
+	knapsackResolutionW = 50
+	knapsackMaxBuckets  = 2000
+)
+
+// knapsackStrategy picks one preset per miner so the fleet loses the least
+// hashrate for a reduction, or gains the most for an increase. Unlike the
+// greedy strategies it may take several small steps on efficient miners over
+// one big step on an inefficient one.
+type knapsackStrategy struct {
+	planner
+}
+
+// knapsackOption is a preset a miner could move to.
+type knapsackOption struct {
+	preset  *ModelPreset
+	deltaW  int     // Watts saved (reduction) or added (increase)
+	valueTH float64 // Hashrate lost (reduction) or gained (increase)
+}
+
+// knapsackItem is a miner and the presets it could move to.
+type knapsackItem struct {
+	miner   *MinerWithContext
+	options []knapsackOption
+}
+
+// knapsackCell is the best plan reaching one watt bucket.
+type knapsackCell struct {
+	reachable bool
+	valueTH   float64
+	watts     int
+}
+
+// Name returns the strategy name.
+func (s *knapsackStrategy) Name() string {
+	return StrategyKnapsack
+}
+
+// Within returns a copy of the strategy restricted to the given miners.
+func (s *knapsackStrategy) Within(minerIDs map[int64]bool) Strategy {
+	scoped := *s
+	scoped.scope = minerIDs
+	return &scoped
+}
+
+// Targeting returns a copy of the strategy holding miners at or below the
+// target presets.
+func (s *knapsackStrategy) Targeting(presets []string) Strategy {
+	targeted := *s
+	targeted.targets = presets
+	return &targeted
+}
+
+// CalculateReduction finds the presets that save at least targetReductionW
+// losing the least hashrate, or save as much as possible if the target can't
+// be reached. Changes are ordered by hashrate lost per watt saved.
+func (s *knapsackStrategy) CalculateReduction(ctx context.Context, targetReductionW int) ([]*PresetChange, error) {
+	if targetReductionW <= 0 {
+		return nil, nil
+	}
+
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []*MinerWithContext
+	for _, m := range miners {
+		if !m.OnCooldown && m.HeadroomWatts > 0 {
+			available = append(available, m)
+		}
+	}
+	value := hashrateEstimator(available)
+
+	var items []knapsackItem
+	for _, miner := range available {
+		presets, err := s.modelPresets(ctx, miner)
+		if err != nil {
+			continue
+		}
+
+		item := knapsackItem{miner: miner}
+		for _, p := range presets {
+			if p.Watts >= miner.CurrentPreset.Watts || p.Watts < miner.MinPreset.Watts {
+				continue
+			}
+			item.options = append(item.options, knapsackOption{
+				preset:  p,
+				deltaW:  miner.CurrentPreset.Watts - p.Watts,
+				valueTH: value(miner, miner.CurrentPreset) - value(miner, p),
+			})
+		}
+		if len(item.options) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	// Buckets round savings down, so reaching the last bucket reaches the target
+	res := knapsackResolution(targetReductionW)
+	target := (targetReductionW + res - 1) / res
+	buckets := func(o knapsackOption) int { return o.deltaW / res }
+
+	// Least hashrate lost, then fewest watts (least overshoot)
+	better := func(a, b knapsackCell) bool {
+		if !b.reachable {
+			return true
+		}
+		if a.valueTH != b.valueTH {
+			return a.valueTH < b.valueTH
+		}
+		return a.watts < b.watts
+	}
+
+	cells, picks := solveKnapsack(items, target, buckets, better, true)
+
+	// The target bucket, or the most reduction reachable
+	best := target
+	for best > 0 && !cells[best].reachable {
+		best--
+	}
+	changes := collectChanges(items, picks, best, true)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return valuePerWatt(changes[i]) < valuePerWatt(changes[j])
+	})
+	return stripValues(changes), nil
+}
+
+// CalculateIncrease finds the presets that add at most targetIncreaseW
+// gaining the most hashrate. Changes are ordered by hashrate gained per watt.
+func (s *knapsackStrategy) CalculateIncrease(ctx context.Context, targetIncreaseW int) ([]*PresetChange, error) {
+	if targetIncreaseW <= 0 {
+		return nil, nil
+	}
+
+	miners, err := s.manageableMiners(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var available []*MinerWithContext
+	for _, m := range miners {
+		if !m.OnCooldown && m.MaxPreset.Watts > m.CurrentPreset.Watts {
+			available = append(available, m)
+		}
+	}
+	value := hashrateEstimator(available)
+
+	var items []knapsackItem
+	for _, miner := range available {
+		presets, err := s.modelPresets(ctx, miner)
+		if err != nil {
+			continue
+		}
+
+		item := knapsackItem{miner: miner}
+		for _, p := range presets {
+			if p.Watts <= miner.CurrentPreset.Watts || p.Watts > miner.MaxPreset.Watts {
+				continue
+			}
+			item.options = append(item.options, knapsackOption{
+				preset:  p,
+				deltaW:  p.Watts - miner.CurrentPreset.Watts,
+				valueTH: value(miner, p) - value(miner, miner.CurrentPreset),
+			})
+		}
+		if len(item.options) > 0 {
+			items = append(items, item)
+		}
+	}
+
+	// Buckets round additions up, so staying within the buckets stays within the target
+	res := knapsackResolution(targetIncreaseW)
+	capacity := targetIncreaseW / res
+	buckets := func(o knapsackOption) int { return (o.deltaW + res - 1) / res }
+
+	// Most hashrate gained, then fewest watts
+	better := func(a, b knapsackCell) bool {
+		if !b.reachable {
+			return true
+		}
+		if a.valueTH != b.valueTH {
+			return a.valueTH > b.valueTH
+		}
+		return a.watts < b.watts
+	}
+
+	cells, picks := solveKnapsack(items, capacity, buckets, better, false)
+
+	best := 0
+	for j := 1; j <= capacity; j++ {
+		if cells[j].reachable && better(cells[j], cells[best]) {
+			best = j
+		}
+	}
+	changes := collectChanges(items, picks, best, false)
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return valuePerWatt(changes[i]) > valuePerWatt(changes[j])
+	})
+	return stripValues(changes), nil
+}
+
+// knapsackResolution returns the bucket size for a target.
+func knapsackResolution(targetW int) int {
+	return max(knapsackResolutionW, (targetW+knapsackMaxBuckets-1)/knapsackMaxBuckets)
+}
+
+// knapsackPick is the option taken for a miner to reach a bucket (-1 to stay)
+// and the bucket reached before it.
+type knapsackPick struct {
+	option int
+	from   int
+}
+
+// solveKnapsack is a multiple-choice knapsack over buckets 0..size: each item
+// contributes at most one option. With saturate, buckets past size count as
+// size (at least the target); otherwise options that don't fit are skipped.
+func solveKnapsack(items []knapsackItem, size int, buckets func(knapsackOption) int,
+	better func(a, b knapsackCell) bool, saturate bool) ([]knapsackCell, [][]knapsackPick) {
+	cells := make([]knapsackCell, size+1)
+	cells[0] = knapsackCell{reachable: true}
+	picks := make([][]knapsackPick, len(items))
+
+	for i, item := range items {
+		next := make([]knapsackCell, size+1)
+		copy(next, cells)
+		picks[i] = make([]knapsackPick, size+1)
+		for j := range picks[i] {
+			picks[i][j] = knapsackPick{option: -1, from: j}
+		}
+
+		for j, cell := range cells {
+			if !cell.reachable {
+				continue
+			}
+			for k, o := range item.options {
+				to := j + buckets(o)
+				if to > size {
+					if !saturate {
+						continue
+					}
+					to = size
+				}
+				candidate := knapsackCell{
+					reachable: true,
+					valueTH:   cell.valueTH + o.valueTH,
+					watts:     cell.watts + o.deltaW,
+				}
+				if better(candidate, next[to]) {
+					next[to] = candidate
+					picks[i][to] = knapsackPick{option: k, from: j}
+				}
+			}
+		}
+		cells = next
+	}
+	return cells, picks
+}
+
+// valuedChange is a planned change with the hashrate it loses or gains.
+type valuedChange struct {
+	*PresetChange
+	valueTH float64
+}
+
+// collectChanges walks the picks back from bucket best into changes.
+func collectChanges(items []knapsackItem, picks [][]knapsackPick, best int, reduction bool) []valuedChange {
+	var changes []valuedChange
+	j := best
+	for i := len(items) - 1; i >= 0; i-- {
+		pick := picks[i][j]
+		j = pick.from
+		if pick.option < 0 {
+			continue
+		}
+
+		item := items[i]
+		o := item.options[pick.option]
+		delta := o.deltaW
+		if !reduction {
+			delta = -delta // Negative = increase
+		}
+		changes = append(changes, valuedChange{
+			PresetChange: &PresetChange{
+				Miner:          item.miner,
+				FromPreset:     item.miner.CurrentPreset,
+				ToPreset:       o.preset,
+				ExpectedDeltaW: delta,
+			},
+			valueTH: o.valueTH,
+		})
+	}
+	return changes
+}
+
+// valuePerWatt returns the hashrate a change loses or gains per watt.
+func valuePerWatt(c valuedChange) float64 {
+	return c.valueTH / math.Abs(float64(c.ExpectedDeltaW))
+}
+
+// stripValues returns the plain changes.
+func stripValues(valued []valuedChange) []*PresetChange {
+	changes := make([]*PresetChange, len(valued))
+	for i, c := range valued {
+		changes[i] = c.PresetChange
+	}
+	return changes
+}
+
+// hashrateEstimator returns the hashrate of a preset on a miner. Presets
+// without a known hashrate are estimated at the miner's current efficiency,
+// or the fleet's if the miner's is unknown too. With no hashrate known at all,
+// watts stand in for hashrate, so the plan minimizes overshoot instead.
+func hashrateEstimator(miners []*MinerWithContext) func(*MinerWithContext, *ModelPreset) float64 {
+	var totalTH float64
+	var totalW int
+	for _, m := range miners {
+		if m.CurrentPreset.HashrateTH > 0 && m.CurrentPreset.Watts > 0 {
+			totalTH += m.CurrentPreset.HashrateTH
+			totalW += m.CurrentPreset.Watts
+		}
+	}
+	fleetEfficiency := 1.0
+	if totalW > 0 {
+		fleetEfficiency = totalTH / float64(totalW)
+	}
+
+	return func(m *MinerWithContext, p *ModelPreset) float64 {
+		if p.HashrateTH > 0 || p.Watts == 0 {
+			return p.HashrateTH
+		}
+		if m.Efficiency > 0 {
+			return float64(p.Watts) * m.Efficiency
+		}
+		return float64(p.Watts) * fleetEfficiency
+	}
+}
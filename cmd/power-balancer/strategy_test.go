@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+)
+
+func TestStrategiesNeverSleepStockMiners(t *testing.T) {
+	ctx := context.Background()
+	cfg := DefaultConfig()
+	b := newTestBalancer(t, cfg)
+
+	// Limited to the lowest and highest powered work modes listed for the model
+	for i := 1; i <= 2; i++ {
+		addMiner(t, b, miner.FirmwareStock, i, stock.WorkModeNormal)
+	}
+
+	for _, name := range strategyNames {
+		t.Run(name, func(t *testing.T) {
+			s, err := NewStrategy(name, b.repo, b.calibration, cfg)
+			if err != nil {
+				t.Fatalf("NewStrategy: %v", err)
+			}
+
+			reduction, err := s.CalculateReduction(ctx, 100_000)
+			if err != nil {
+				t.Fatalf("CalculateReduction: %v", err)
+			}
+			failSafe, err := s.CalculateFailSafe(ctx)
+			if err != nil {
+				t.Fatalf("CalculateFailSafe: %v", err)
+			}
+			if len(reduction) != 2 || len(failSafe) != 2 {
+				t.Fatalf("got %d reductions and %d fail-safe changes, want 2 each", len(reduction), len(failSafe))
+			}
+			for _, c := range append(reduction, failSafe...) {
+				if c.ToPreset.Name != stock.WorkModeLowPower {
+					t.Errorf("miner %d moved to work mode %s, want low power (%s)",
+						c.Miner.Miner.ID, c.ToPreset.Name, stock.WorkModeLowPower)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+// verifyMinToleranceW is the smallest delta difference flagged as a power
+// mismatch, so measurement noise on small changes isn't reported.
+const verifyMinToleranceW = 100
+
+// verifySettledChanges checks every change that settled since the last tick
+// against the miner's measured preset and power. Changes that didn't take are
+// retried up to VerifyRetries times, then escalated by locking the miner.
+func (b *Balancer) verifySettledChanges(ctx context.Context) {
+	if n, err := b.repo.MarkOrphanedVerifications(ctx); err != nil {
+		log.Printf("Failed to mark orphaned verifications: %v", err)
+	} else if n > 0 {
+		log.Printf("%d changes dropped before settling, left unverified", n)
+	}
+
+	settled, err := b.repo.GetSettledVerifications(ctx)
+	if err != nil {
+		log.Printf("Failed to get settled changes: %v", err)
+		return
+	}
+
+	for _, v := range settled {
+		if err := b.verifyChange(ctx, v); err != nil {
+			log.Printf("Failed to verify change %d: %v", v.ChangeLogID, err)
+		}
+	}
+
+	if len(settled) > 0 {
+		if err := b.calibration.Refresh(ctx); err != nil {
+			log.Printf("Failed to refresh calibration: %v", err)
+		}
+	}
+}
+
+// verifyChange measures the miner of a settled change and records the outcome.
+func (b *Balancer) verifyChange(ctx context.Context, v *ChangeVerification) error {
+	miner, err := b.repo.GetMinerByID(ctx, v.MinerID)
+	if err != nil {
+		return fmt.Errorf("get miner: %w", err)
+	}
+	from, to, err := b.verificationPresets(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	v.VerifiedAt = &now
+
+	m, err := b.controller.Measure(ctx, miner.IPAddress, miner.FirmwareType)
+	if err != nil {
+		v.Outcome = VerifyUnreachable
+		log.Printf("[%s] Couldn't verify change to %s: %v", miner.IPAddress, to.Name, err)
+		return b.repo.UpdateChangeVerification(ctx, v)
+	}
+
+	v.MeasuredPreset = m.Preset
+	v.AfterW = m.Watts
+	if v.BeforeW > 0 && v.AfterW > 0 {
+		delta := v.BeforeW - v.AfterW
+		v.ActualDeltaW = &delta
+	}
+	v.Outcome = classifyChange(v, from.Name, to.Name, b.cfg.VerifyTolerance)
+
+	if err := b.repo.UpdateChangeVerification(ctx, v); err != nil {
+		return fmt.Errorf("update verification: %w", err)
+	}
+
+	// A preset that took is a calibration sample, mismatched power most of all
+	if (v.Outcome == VerifyConfirmed || v.Outcome == VerifyPowerMismatch) && m.Watts > 0 && m.HashrateTH > 0 {
+		err := b.calibration.Record(ctx, &PresetSample{
+			MinerID:    miner.ID,
+			PresetID:   to.ID,
+			Watts:      m.Watts,
+			HashrateTH: m.HashrateTH,
+			Source:     SampleVerification,
+			SampledAt:  now,
+		})
+		if err != nil {
+			log.Printf("[%s] Failed to record calibration sample: %v", miner.IPAddress, err)
+		}
+	}
+
+	switch v.Outcome {
+	case VerifyConfirmed:
+		return nil
+	case VerifyPowerMismatch:
+		log.Printf("[%s] Change %s -> %s applied but moved %dW (expected %dW)",
+			miner.IPAddress, from.Name, to.Name, *v.ActualDeltaW, v.ExpectedDeltaW)
+		return nil
+	}
+
+	// The preset didn't take: record where the miner actually is, then retry
+	log.Printf("[%s] Change %s -> %s didn't take, miner is on %s (%s)",
+		miner.IPAddress, from.Name, to.Name, m.Preset, v.Outcome)
+
+	current := from
+	if miner.ModelID != nil {
+		if p, err := b.repo.GetPresetByModelAndName(ctx, *miner.ModelID, m.Preset); err == nil {
+			current = p
+		}
+	}
+	if err := b.repo.UpdateMinerPreset(ctx, miner.ID, current.ID); err != nil {
+		log.Printf("Failed to update miner preset: %v", err)
+	}
+
+	return b.retryOrEscalate(ctx, v, miner, current, to)
+}
+
+// verificationPresets loads the presets a change moved between.
+func (b *Balancer) verificationPresets(ctx context.Context, v *ChangeVerification) (*ModelPreset, *ModelPreset, error) {
+	if v.FromPresetID == nil || v.ToPresetID == nil {
+		return nil, nil, fmt.Errorf("change has no presets")
+	}
+	from, err := b.repo.GetPresetByID(ctx, *v.FromPresetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get preset %d: %w", *v.FromPresetID, err)
+	}
+	to, err := b.repo.GetPresetByID(ctx, *v.ToPresetID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get preset %d: %w", *v.ToPresetID, err)
+	}
+	return from, to, nil
+}
+
+// classifyChange returns the outcome of a measured change.
+func classifyChange(v *ChangeVerification, fromName, toName string, tolerancePercent float64) string {
+	switch {
+	case v.MeasuredPreset == toName:
+	case v.MeasuredPreset == fromName:
+		return VerifyReverted
+	default:
+		return VerifyWrongPreset
+	}
+
+	if v.ActualDeltaW != nil {
+		tolerance := math.Max(math.Abs(float64(v.ExpectedDeltaW))*tolerancePercent/100, verifyMinToleranceW)
+		if math.Abs(float64(*v.ActualDeltaW-v.ExpectedDeltaW)) > tolerance {
+			return VerifyPowerMismatch
+		}
+	}
+	return VerifyConfirmed
+}
+
+// retryOrEscalate reissues a change that didn't take, or locks the miner
+// once its retries are used up so it's left for a manual check.
+func (b *Balancer) retryOrEscalate(ctx context.Context, v *ChangeVerification, miner *Miner, current, to *ModelPreset) error {
+	config, err := b.repo.GetOrCreateBalanceConfig(ctx, miner.ID)
+	if err != nil {
+		return fmt.Errorf("get balance config: %w", err)
+	}
+	if !config.Enabled || config.Locked {
+		return nil // No longer balanced
+	}
+
+	var modelName string
+	if miner.ModelID != nil {
+		if model, err := b.repo.GetModelByID(ctx, *miner.ModelID); err == nil {
+			modelName = model.Name
+		}
+	}
+	margin := b.GetStatus().MarginPercent
+
+	if v.Attempt < b.cfg.VerifyRetries {
+		current, to = b.calibration.Apply(miner.ID, current), b.calibration.Apply(miner.ID, to)
+		change := &PresetChange{
+			Miner:          &MinerWithContext{Miner: miner, Model: &Model{ID: current.ModelID, Name: modelName}},
+			FromPreset:     current,
+			ToPreset:       to,
+			ExpectedDeltaW: current.Watts - to.Watts,
+			Attempt:        v.Attempt + 1,
+		}
+		if !b.admitChange(ctx, change, "retry", margin) {
+			return nil // Replanned once the ramp allows
+		}
+		return b.executeChange(ctx, change, "retry", margin)
+	}
+
+	log.Printf("[%s] Change to %s failed after %d attempts, locking miner for a manual check",
+		miner.IPAddress, to.Name, v.Attempt+1)
+	config.Locked = true
+	if err := b.repo.UpdateBalanceConfig(ctx, config); err != nil {
+		return fmt.Errorf("lock miner: %w", err)
+	}
+
+	entry := &ChangeLog{
+		MinerID:      &miner.ID,
+		MinerIP:      miner.IPAddress,
+		ModelName:    modelName,
+		FromPreset:   current.Name,
+		ToPreset:     to.Name,
+		Reason:       "escalated",
+		MarginAtTime: margin,
+		IssuedAt:     time.Now(),
+		Success:      false,
+		ErrorMessage: fmt.Sprintf("preset %s not applied after %d attempts (%s); miner locked", to.Name, v.Attempt+1, v.Outcome),
+	}
+	if err := b.repo.InsertChangeLog(ctx, entry); err != nil {
+		log.Printf("Failed to log escalation: %v", err)
+	}
+	return nil
+}
@@ -2,15 +2,26 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/braiins"
+	"github.com/powerhive/powerhive-v2/pkg/cgminer"
+	"github.com/powerhive/powerhive-v2/pkg/credentials"
+	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/discovery"
+	"github.com/powerhive/powerhive-v2/pkg/fleet"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
 	"github.com/powerhive/powerhive-v2/pkg/stock"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
 )
 
 func main() {
@@ -46,6 +57,25 @@ func main() {
 		}
 		runDetect(os.Args[2])
 
+	case "pools":
+		runPools(os.Args[2:])
+
+	case "firmware":
+		if len(os.Args) < 3 {
+			fmt.Println("Usage: powerhive firmware report|rollout [flags]")
+			fmt.Println("Example: powerhive firmware report --policy firmware-policy.json --format csv")
+			os.Exit(1)
+		}
+		switch os.Args[2] {
+		case "report":
+			runFirmwareReport(os.Args[3:])
+		case "rollout":
+			runFirmwareRollout(os.Args[3:])
+		default:
+			fmt.Printf("Unknown firmware command: %s\n", os.Args[2])
+			os.Exit(1)
+		}
+
 	default:
 		fmt.Printf("Unknown command: %s\n", command)
 		printUsage()
@@ -62,30 +92,48 @@ func printUsage() {
 	fmt.Println("  scan <cidr>      Scan network for miners (e.g., 192.168.1.0/24)")
 	fmt.Println("  detect <ip>      Detect miner type at IP address")
 	fmt.Println("  info <ip>        Get detailed miner information")
+	fmt.Println("  pools -f <file>  Apply pool configuration to miners (dry-run unless --apply)")
+	fmt.Println("  firmware report  Firmware inventory and version compliance")
+	fmt.Println("  firmware rollout Staged firmware upgrade (plan only unless --apply)")
 	fmt.Println()
 	fmt.Println("Environment Variables:")
 	fmt.Println("  VNISH_PASSWORD   VNish firmware password (default: admin)")
 	fmt.Println("  STOCK_USERNAME   Stock firmware username (default: root)")
 	fmt.Println("  STOCK_PASSWORD   Stock firmware password (default: root)")
+	fmt.Println("  BRAIINS_USERNAME Braiins OS username (default: root)")
+	fmt.Println("  BRAIINS_PASSWORD Braiins OS password (default: empty)")
+	fmt.Println("  WHATSMINER_PASSWORD Whatsminer admin password (default: admin)")
+	fmt.Println("  POWERHIVE_DB     SQLite database path, used by pools and to keep VNish API keys (default: powerhive.db)")
+	fmt.Println("  CREDENTIALS_FILE Per-subnet/IP/MAC credential sets (JSON), used by pools")
+	fmt.Println("  FIRMWARE_POLICY  Allowed firmware versions (JSON), used by firmware report")
 }
 
 // createProbers creates firmware probers for discovery.
-// Order matters: VNish first (more features), then Stock as fallback.
-func createProbers() []miner.FirmwareProber {
+// Order matters: VNish first (more features), then Stock, Braiins OS and Whatsminer.
+func createProbers(vnishAuth *vnish.AuthManager) []miner.FirmwareProber {
 	// VNish prober
-	vnishAuth := vnish.NewAuthManager(getVNishPassword())
 	vnishProber := vnish.NewProber(vnishAuth, vnish.WithProberTimeout(5*time.Second))
 
 	// Stock prober
 	stockAuth := stock.NewDigestAuthWithCredentials(getStockUsername(), getStockPassword())
 	stockProber := stock.NewProber(stockAuth, stock.WithProberTimeout(5*time.Second))
 
-	return []miner.FirmwareProber{vnishProber, stockProber}
+	// Braiins OS prober
+	braiinsAuth := braiins.NewAuthManager(getBraiinsUsername(), getBraiinsPassword())
+	braiinsProber := braiins.NewProber(braiinsAuth, braiins.WithProberTimeout(5*time.Second))
+
+	// Whatsminer prober
+	whatsminerAuth := whatsminer.NewAuthManager(getWhatsminerPassword())
+	whatsminerProber := whatsminer.NewProber(whatsminerAuth, whatsminer.WithProberTimeout(5*time.Second))
+
+	return []miner.FirmwareProber{vnishProber, stockProber, braiinsProber, whatsminerProber}
 }
 
 func runScan(cidr string) {
 	ctx := context.Background()
-	probers := createProbers()
+	vnishAuth, closeDB := openVNishAuth()
+	defer closeDB()
+	probers := createProbers(vnishAuth)
 
 	// Create scanner with probers
 	scanner := discovery.NewScanner(probers,
@@ -93,7 +141,7 @@ func runScan(cidr string) {
 		discovery.WithConcurrency(50),
 	)
 
-	fmt.Printf("Scanning %s for miners (VNish + Stock firmware)...\n", cidr)
+	fmt.Printf("Scanning %s for miners (VNish + Stock + Braiins OS + Whatsminer firmware)...\n", cidr)
 	startTime := time.Now()
 
 	result, err := scanner.ScanNetwork(ctx, cidr)
@@ -131,7 +179,9 @@ func runScan(cidr string) {
 
 func runDetect(ip string) {
 	ctx := context.Background()
-	probers := createProbers()
+	vnishAuth, closeDB := openVNishAuth()
+	defer closeDB()
+	probers := createProbers(vnishAuth)
 
 	detector := discovery.NewDetector(probers, discovery.WithDetectorTimeout(5*time.Second))
 
@@ -139,7 +189,19 @@ func runDetect(ip string) {
 
 	discovered, err := detector.DetectMiner(ctx, ip)
 	if err != nil {
-		log.Fatalf("Detection failed: %v", err)
+		// HTTP APIs unavailable, try identifying through the cgminer API
+		fwType, info, cgErr := cgminer.NewProber(miner.FirmwareUnknown).Identify(ctx, ip)
+		if cgErr != nil {
+			log.Fatalf("Detection failed: %v", err)
+		}
+
+		fmt.Printf("\nMiner identified via cgminer API (port %d):\n", cgminer.DefaultPort)
+		fmt.Printf("  IP:           %s\n", info.IP)
+		fmt.Printf("  Model:        %s\n", info.Model)
+		fmt.Printf("  Series:       %s\n", info.Series)
+		fmt.Printf("  Firmware:     %s %s\n", info.Firmware, info.FirmwareVersion)
+		fmt.Printf("  Firmware Type: %s\n", fwType)
+		return
 	}
 
 	fmt.Printf("\nMiner detected:\n")
@@ -155,7 +217,9 @@ func runDetect(ip string) {
 
 func runInfo(ip string) {
 	ctx := context.Background()
-	probers := createProbers()
+	vnishAuth, closeDB := openVNishAuth()
+	defer closeDB()
+	probers := createProbers(vnishAuth)
 
 	detector := discovery.NewDetector(probers, discovery.WithDetectorTimeout(5*time.Second))
 
@@ -196,12 +260,11 @@ func runInfo(ip string) {
 
 	// If VNish, try to get more detailed info
 	if fwType == miner.FirmwareVNish {
-		runVNishDetails(ctx, ip)
+		runVNishDetails(ctx, ip, vnishAuth)
 	}
 }
 
-func runVNishDetails(ctx context.Context, ip string) {
-	vnishAuth := vnish.NewAuthManager(getVNishPassword())
+func runVNishDetails(ctx context.Context, ip string, vnishAuth *vnish.AuthManager) {
 	client := vnish.NewClient(ip, vnishAuth, vnish.WithTimeout(30*time.Second))
 
 	// Get summary (requires auth)
@@ -221,6 +284,412 @@ func runVNishDetails(ctx context.Context, ip string) {
 	fmt.Printf("  HW Errors:  %.2f%%\n", summary.Miner.HWErrorsPercent)
 }
 
+func runPools(args []string) {
+	fs := flag.NewFlagSet("pools", flag.ExitOnError)
+	poolFile := fs.String("f", "", "Pool set file (JSON, see pools.example.json)")
+	site := fs.String("site", "", "Site name for {site} in worker templates (overrides the file)")
+	cidr := fs.String("cidr", "", "Only miners in these comma-separated CIDRs")
+	ips := fs.String("ip", "", "Only these comma-separated miner IPs")
+	firmware := fs.String("firmware", "", "Only miners with this firmware (vnish, stock)")
+	minerType := fs.String("type", "", "Only miners of this model")
+	includeOffline := fs.Bool("include-offline", false, "Also select miners last seen offline")
+	apply := fs.Bool("apply", false, "Apply changes (default: dry-run)")
+	fs.Usage = func() {
+		fmt.Println("Usage: powerhive pools -f <file> [flags]")
+		fmt.Println("Example: powerhive pools -f pools.json --cidr 10.40.36.0/24 --apply")
+		fmt.Println()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *poolFile == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	set, err := fleet.LoadPoolSet(*poolFile)
+	if err != nil {
+		log.Fatalf("Failed to load pool set: %v", err)
+	}
+	if *site != "" {
+		set.Site = *site
+	}
+	if err := set.Validate(); err != nil {
+		log.Fatalf("Invalid pool set: %v", err)
+	}
+
+	networks, err := fleet.ParseNetworks(*cidr)
+	if err != nil {
+		log.Fatalf("Invalid --cidr: %v", err)
+	}
+	sel := fleet.Selector{
+		Networks:       networks,
+		FirmwareType:   *firmware,
+		MinerType:      *minerType,
+		IncludeOffline: *includeOffline,
+	}
+	for _, ip := range strings.Split(*ips, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			sel.IPs = append(sel.IPs, ip)
+		}
+	}
+
+	ctx := context.Background()
+
+	dbPath := getDBPath()
+	repo, err := database.NewSQLiteRepository(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer repo.Close()
+
+	vnishAuth, stockAuth := newWriteAuth(repo, dbPath)
+	pm := fleet.NewPoolManager(repo, vnishAuth, stockAuth)
+
+	results, err := pm.Plan(ctx, set, sel)
+	if err != nil {
+		log.Fatalf("Failed to plan pool changes: %v", err)
+	}
+	if len(results) == 0 {
+		fmt.Println("No miners match the selection.")
+		return
+	}
+
+	if *apply {
+		fmt.Printf("Applying pools to %d miners...\n", len(results))
+		pm.Apply(ctx, results)
+	} else {
+		fmt.Printf("Dry-run for %d miners (use --apply to change them):\n", len(results))
+	}
+
+	var changed, unchanged, skipped, failed int
+	fmt.Println()
+	for _, r := range results {
+		m := r.Miner
+		var status string
+		switch {
+		case r.Skipped != "":
+			status = "SKIP " + r.Skipped
+			skipped++
+		case !r.NeedsChange():
+			status = "OK   up to date"
+			unchanged++
+		case r.Err != nil:
+			status = "FAIL " + r.Err.Error()
+			failed++
+		case r.Applied:
+			status = "DONE applied"
+			changed++
+		default:
+			status = "PLAN would change"
+			changed++
+		}
+
+		fmt.Printf("  %-15s - %-20s (%s) %s\n", m.IPAddress, m.MinerType, m.FirmwareType, status)
+		for _, c := range r.Changes {
+			fmt.Printf("      %s\n", c)
+		}
+	}
+
+	fmt.Println()
+	if *apply {
+		fmt.Printf("Applied: %d, Up to date: %d, Skipped: %d, Failed: %d\n", changed, unchanged, skipped, failed)
+	} else {
+		fmt.Printf("To change: %d, Up to date: %d, Skipped: %d\n", changed, unchanged, skipped)
+	}
+	if failed > 0 {
+		os.Exit(1)
+	}
+}
+
+func runFirmwareReport(args []string) {
+	fs := flag.NewFlagSet("firmware report", flag.ExitOnError)
+	policyFile := fs.String("policy", os.Getenv("FIRMWARE_POLICY"), "Allowed-version policy (JSON, see firmware-policy.example.json)")
+	format := fs.String("format", "table", "Output: table, or csv/json of non-compliant miners")
+	output := fs.String("o", "", "Write csv/json output to file instead of stdout")
+	cidr := fs.String("cidr", "", "Only miners in these comma-separated CIDRs")
+	firmware := fs.String("firmware", "", "Only miners with this firmware")
+	minerType := fs.String("type", "", "Only miners of this model")
+	fs.Parse(args)
+
+	var policy *fleet.FirmwarePolicy
+	if *policyFile != "" {
+		var err error
+		policy, err = fleet.LoadFirmwarePolicy(*policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load firmware policy: %v", err)
+		}
+	}
+
+	networks, err := fleet.ParseNetworks(*cidr)
+	if err != nil {
+		log.Fatalf("Invalid --cidr: %v", err)
+	}
+
+	ctx := context.Background()
+
+	repo, err := database.NewSQLiteRepository(getDBPath())
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer repo.Close()
+
+	miners, err := fleet.SelectMiners(ctx, repo, fleet.Selector{
+		Networks:       networks,
+		FirmwareType:   *firmware,
+		MinerType:      *minerType,
+		IncludeOffline: true,
+	})
+	if err != nil {
+		log.Fatalf("Failed to select miners: %v", err)
+	}
+
+	report := fleet.BuildFirmwareReport(miners, policy)
+
+	if *format == "csv" || *format == "json" {
+		w := os.Stdout
+		if *output != "" {
+			f, err := os.Create(*output)
+			if err != nil {
+				log.Fatalf("Failed to create output file: %v", err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if *format == "csv" {
+			err = report.WriteViolationsCSV(w)
+		} else {
+			err = report.WriteViolationsJSON(w)
+		}
+		if err != nil {
+			log.Fatalf("Failed to write report: %v", err)
+		}
+		return
+	}
+	if *format != "table" {
+		log.Fatalf("Unknown format: %s (expected table, csv or json)", *format)
+	}
+
+	fmt.Printf("Firmware Inventory (%d miners)\n", report.Total)
+	fmt.Println("--------------------------------")
+	fmt.Printf("  %-10s %-28s %-20s %-8s %6s %6s  %s\n", "FIRMWARE", "VERSION", "MODEL", "PLATFORM", "COUNT", "ONLINE", "POLICY")
+	for _, g := range report.Groups {
+		status := string(g.Compliance)
+		if g.Compliance == fleet.ComplianceNonCompliant {
+			status += " (allowed: " + strings.Join(g.Allowed, ", ") + ")"
+		}
+		fmt.Printf("  %-10s %-28s %-20s %-8s %6d %6d  %s\n",
+			g.FirmwareType, g.FirmwareVersion, g.Model, g.Platform, g.Count, g.Online, status)
+	}
+
+	fmt.Printf("\nCompliant: %d, Non-compliant: %d, No policy: %d\n",
+		report.Compliant, report.NonCompliant, report.NoPolicy)
+
+	if len(report.Violations) > 0 {
+		fmt.Println("\nNon-compliant Miners:")
+		fmt.Println("---------------------")
+		for _, v := range report.Violations {
+			fmt.Printf("  %-15s - %-20s (%s %s)\n", v.IPAddress, v.MinerType, v.FirmwareType, v.FirmwareVersion)
+		}
+	}
+}
+
+// newVNishAuth creates VNish authentication with its API keys persisted next
+// to the database, so every command reuses the key it created on a miner.
+func newVNishAuth(repo *database.SQLiteRepository, dbPath string) *vnish.AuthManager {
+	credStore, err := database.NewSQLiteCredentialStore(repo.DB(), dbPath+".key")
+	if err != nil {
+		log.Fatalf("Failed to open credential store: %v", err)
+	}
+
+	return vnish.NewAuthManager(getVNishPassword()).
+		WithCredentialStore(credStore).
+		WithAPIKeyDescription("powerhive-cli").
+		WithPruneErrorHandler(func(host string, err error) {
+			log.Printf("[%s] Warning: failed to prune stale API keys: %v", host, err)
+		})
+}
+
+// openVNishAuth opens the database for the VNish authentication of commands
+// that only read miners. The returned function closes the database.
+func openVNishAuth() (*vnish.AuthManager, func()) {
+	dbPath := getDBPath()
+	repo, err := database.NewSQLiteRepository(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	return newVNishAuth(repo, dbPath), func() { repo.Close() }
+}
+
+// newWriteAuth creates VNish and stock authentication for commands that change
+// miners: API keys are persisted next to the database and CREDENTIALS_FILE is honored.
+func newWriteAuth(repo *database.SQLiteRepository, dbPath string) (*vnish.AuthManager, *stock.DigestAuth) {
+	vnishAuth := newVNishAuth(repo, dbPath)
+	stockAuth := stock.NewDigestAuthWithCredentials(getStockUsername(), getStockPassword())
+
+	if path := os.Getenv("CREDENTIALS_FILE"); path != "" {
+		creds, err := credentials.Load(path)
+		if err != nil {
+			log.Fatalf("Failed to load credentials: %v", err)
+		}
+		creds.WithFallback(credentials.Set{
+			Name:          "env",
+			VNishPassword: getVNishPassword(),
+			StockUsername: getStockUsername(),
+			StockPassword: getStockPassword(),
+		})
+		vnishAuth.WithCredentialResolver(creds)
+		stockAuth.WithCredentialResolver(creds)
+	}
+
+	return vnishAuth, stockAuth
+}
+
+func runFirmwareRollout(args []string) {
+	fs := flag.NewFlagSet("firmware rollout", flag.ExitOnError)
+	imagePath := fs.String("image", "", "Firmware image to upload")
+	version := fs.String("version", "", "Firmware version expected after the upgrade (wildcards allowed)")
+	firmware := fs.String("firmware", "", "Firmware of the miners to upgrade (vnish, stock)")
+	minerType := fs.String("type", "", "Only miners of this model")
+	cidr := fs.String("cidr", "", "Only miners in these comma-separated CIDRs")
+	ips := fs.String("ip", "", "Only these comma-separated miner IPs")
+	canary := fs.Int("canary", 1, "Miners in the canary wave")
+	batch := fs.Int("batch", 25, "Percentage of the remaining miners per wave")
+	maxFailures := fs.Int("max-failures", 0, "Halt once more miners than this have failed")
+	rebootDelay := fs.Duration("reboot-delay", time.Minute, "Wait after upload before polling the miner")
+	recoveryTimeout := fs.Duration("recovery-timeout", 15*time.Minute, "Time for a miner to come back healthy")
+	apply := fs.Bool("apply", false, "Upgrade miners (default: show the wave plan)")
+	fs.Usage = func() {
+		fmt.Println("Usage: powerhive firmware rollout -image <file> -version <version> -firmware <vnish|stock> [flags]")
+		fmt.Println("Example: powerhive firmware rollout -image fw.tar -version 1.2.7 -firmware vnish -type \"Antminer S19j Pro\" --apply")
+		fmt.Println()
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *imagePath == "" || *version == "" || *firmware == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	image, err := os.ReadFile(*imagePath)
+	if err != nil {
+		log.Fatalf("Failed to read image: %v", err)
+	}
+
+	networks, err := fleet.ParseNetworks(*cidr)
+	if err != nil {
+		log.Fatalf("Invalid --cidr: %v", err)
+	}
+	sel := fleet.Selector{
+		Networks:     networks,
+		FirmwareType: *firmware,
+		MinerType:    *minerType,
+	}
+	for _, ip := range strings.Split(*ips, ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			sel.IPs = append(sel.IPs, ip)
+		}
+	}
+
+	// Stop between waves on Ctrl+C; miners being upgraded are left to finish
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	dbPath := getDBPath()
+	repo, err := database.NewSQLiteRepository(dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer repo.Close()
+
+	miners, err := fleet.SelectMiners(ctx, repo, sel)
+	if err != nil {
+		log.Fatalf("Failed to select miners: %v", err)
+	}
+
+	vnishAuth, stockAuth := newWriteAuth(repo, dbPath)
+	detector := discovery.NewDetector([]miner.FirmwareProber{
+		vnish.NewProber(vnishAuth, vnish.WithProberTimeout(5*time.Second)),
+		stock.NewProber(stockAuth, stock.WithProberTimeout(5*time.Second)),
+	}, discovery.WithDetectorTimeout(10*time.Second))
+
+	rollout, err := fleet.NewRollout(detector, fleet.DefaultUpdater(vnishAuth, stockAuth, 10*time.Minute), fleet.RolloutConfig{
+		Image:           image,
+		ImageName:       filepath.Base(*imagePath),
+		TargetVersion:   *version,
+		Canary:          *canary,
+		BatchPercent:    *batch,
+		MaxFailures:     *maxFailures,
+		RebootDelay:     *rebootDelay,
+		RecoveryTimeout: *recoveryTimeout,
+	}, fleet.WithProgress(func(e fleet.RolloutEvent) {
+		if e.Miner != nil {
+			log.Printf("[wave %d] %s: %s", e.Wave, e.Miner.IPAddress, e.Message)
+		} else {
+			log.Printf("[wave %d] %s", e.Wave, e.Message)
+		}
+	}))
+	if err != nil {
+		log.Fatalf("Invalid rollout: %v", err)
+	}
+
+	if !*apply {
+		plan, waves := rollout.Plan(miners)
+		fmt.Printf("Rollout plan for %d miners to %s (use --apply to upgrade):\n", len(miners), *version)
+		for i, wave := range waves {
+			name := fmt.Sprintf("Wave %d", i+1)
+			if i == 0 {
+				name += " (canary)"
+			}
+			fmt.Printf("\n%s:\n", name)
+			for _, res := range wave {
+				fmt.Printf("  %-15s - %-20s (%s)\n", res.Miner.IPAddress, res.Miner.MinerType, res.FromVersion)
+			}
+		}
+		if n := plan.Count(fleet.RolloutSkipped); n > 0 {
+			fmt.Printf("\nSkipped:\n")
+			for _, res := range plan.Results {
+				if res.Status == fleet.RolloutSkipped {
+					fmt.Printf("  %-15s - %s\n", res.Miner.IPAddress, res.Reason)
+				}
+			}
+		}
+		return
+	}
+
+	report := rollout.Run(ctx, miners)
+
+	fmt.Println("\nRollout Results:")
+	fmt.Println("----------------")
+	for _, res := range report.Results {
+		detail := res.Reason
+		switch res.Status {
+		case fleet.RolloutUpgraded:
+			detail = fmt.Sprintf("%s -> %s in %s", res.FromVersion, res.ToVersion, res.Duration.Round(time.Second))
+		case fleet.RolloutFailed:
+			detail = res.Err.Error()
+		}
+		fmt.Printf("  %-15s - %-9s %s\n", res.Miner.IPAddress, res.Status, detail)
+	}
+
+	fmt.Printf("\nUpgraded: %d, Failed: %d, Skipped: %d, Pending: %d\n",
+		report.Count(fleet.RolloutUpgraded), report.Count(fleet.RolloutFailed),
+		report.Count(fleet.RolloutSkipped), report.Count(fleet.RolloutPending))
+	if report.Halted {
+		fmt.Printf("Rollout halted: %s\n", report.HaltReason)
+		os.Exit(1)
+	}
+}
+
+func getDBPath() string {
+	if path := os.Getenv("POWERHIVE_DB"); path != "" {
+		return path
+	}
+	return "powerhive.db"
+}
+
 func getVNishPassword() string {
 	if pw := os.Getenv("VNISH_PASSWORD"); pw != "" {
 		return pw
@@ -241,3 +710,24 @@ func getStockPassword() string {
 	}
 	return "root"
 }
+
+func getBraiinsUsername() string {
+	if u := os.Getenv("BRAIINS_USERNAME"); u != "" {
+		return u
+	}
+	return braiins.DefaultUsername
+}
+
+func getBraiinsPassword() string {
+	if pw, ok := os.LookupEnv("BRAIINS_PASSWORD"); ok {
+		return pw
+	}
+	return braiins.DefaultPassword
+}
+
+func getWhatsminerPassword() string {
+	if pw := os.Getenv("WHATSMINER_PASSWORD"); pw != "" {
+		return pw
+	}
+	return whatsminer.DefaultPassword
+}
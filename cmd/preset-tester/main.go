@@ -9,6 +9,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/database"
 	"github.com/powerhive/powerhive-v2/pkg/vnish"
 )
 
@@ -19,6 +20,7 @@ func main() {
 	maxPreset := flag.String("max-preset", "3000", "Maximum preset for testing")
 	pollInterval := flag.Duration("poll", 1*time.Second, "Polling interval for stability checks")
 	timeout := flag.Duration("timeout", 5*time.Minute, "Timeout for waiting for stability")
+	dbPath := flag.String("db", "", "SQLite database keeping the VNish API key across runs (default: POWERHIVE_DB env or 'powerhive.db')")
 	flag.Parse()
 
 	if *host == "" {
@@ -38,6 +40,26 @@ func main() {
 		pass = "admin"
 	}
 
+	// Reuse the API key of earlier runs, so the miner's key list doesn't grow
+	path := *dbPath
+	if path == "" {
+		path = os.Getenv("POWERHIVE_DB")
+	}
+	if path == "" {
+		path = "powerhive.db"
+	}
+	repo, err := database.NewSQLiteRepository(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open database: %v\n", err)
+		os.Exit(1)
+	}
+	defer repo.Close()
+	credStore, err := database.NewSQLiteCredentialStore(repo.DB(), path+".key")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to open credential store: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Create context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -52,7 +74,13 @@ func main() {
 	}()
 
 	// Create VNish client
-	auth := vnish.NewAuthManager(pass)
+	auth := vnish.NewAuthManager(pass).
+		WithCredentialStore(credStore).
+		WithAPIKeyDescription("powerhive-preset-tester").
+		WithStoreErrorHandler(func(err error) { fmt.Fprintf(os.Stderr, "Warning: credential store: %v\n", err) }).
+		WithPruneErrorHandler(func(host string, err error) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to prune stale API keys on %s: %v\n", host, err)
+		})
 	client := vnish.NewClient(*host, auth)
 
 	// Print banner
@@ -3,6 +3,6 @@ module github.com/powerhive/powerhive-v2
 go 1.25.2
 
 require (
-	github.com/joho/godotenv v1.5.1 // indirect
-	github.com/mattn/go-sqlite3 v1.14.32 // indirect
+	github.com/joho/godotenv v1.5.1
+	github.com/mattn/go-sqlite3 v1.14.32
 )
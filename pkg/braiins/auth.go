@@ -0,0 +1,95 @@
+package braiins
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUsername is the default Braiins OS username.
+	DefaultUsername = "root"
+
+	// DefaultPassword is the default Braiins OS password (empty on fresh installs).
+	DefaultPassword = ""
+
+	// DefaultTokenTTL is used when the login response doesn't include a timeout.
+	DefaultTokenTTL = 30 * time.Minute
+)
+
+// tokenInfo holds a session token and its expiry.
+type tokenInfo struct {
+	token     string
+	expiresAt time.Time
+}
+
+// isExpired returns true if the token has expired or is about to expire.
+func (t *tokenInfo) isExpired() bool {
+	// Consider expired if less than 1 minute remaining
+	return time.Now().Add(time.Minute).After(t.expiresAt)
+}
+
+// AuthManager manages credentials and caches session tokens per miner host.
+type AuthManager struct {
+	mu       sync.RWMutex
+	tokens   map[string]*tokenInfo // host -> token info
+	username string
+	password string
+}
+
+// NewAuthManager creates a new authentication manager.
+func NewAuthManager(username, password string) *AuthManager {
+	return &AuthManager{
+		tokens:   make(map[string]*tokenInfo),
+		username: username,
+		password: password,
+	}
+}
+
+// GetCredentials returns the login username and password.
+func (am *AuthManager) GetCredentials() (string, string) {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.username, am.password
+}
+
+// SetCredentials updates the login username and password.
+func (am *AuthManager) SetCredentials(username, password string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.username = username
+	am.password = password
+}
+
+// GetToken returns the cached token for a host, or empty if not cached/expired.
+func (am *AuthManager) GetToken(host string) string {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	info, ok := am.tokens[host]
+	if !ok || info.isExpired() {
+		return ""
+	}
+	return info.token
+}
+
+// SetToken caches a token for a host with the given lifetime.
+func (am *AuthManager) SetToken(host, token string, ttl time.Duration) {
+	if ttl <= 0 {
+		ttl = DefaultTokenTTL
+	}
+
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.tokens[host] = &tokenInfo{
+		token:     token,
+		expiresAt: time.Now().Add(ttl),
+	}
+}
+
+// ClearToken removes the cached token for a host.
+func (am *AuthManager) ClearToken(host string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	delete(am.tokens, host)
+}
@@ -0,0 +1,404 @@
+package braiins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Client is the interface for interacting with the Braiins OS+ REST API.
+type Client interface {
+	miner.Client
+
+	// Authentication
+	Login(ctx context.Context) (string, error)
+	EnsureAuthenticated(ctx context.Context) error
+
+	// Info & Stats
+	GetMinerDetails(ctx context.Context) (*MinerDetails, error)
+	GetMinerStats(ctx context.Context) (*MinerStats, error)
+	GetHashboards(ctx context.Context) (*HashboardsResponse, error)
+	GetCoolingState(ctx context.Context) (*CoolingState, error)
+	GetPools(ctx context.Context) (*PoolGroupsResponse, error)
+	GetNetworkInfo(ctx context.Context) (*NetworkInfo, error)
+
+	// Performance
+	GetTunerState(ctx context.Context) (*TunerState, error)
+	GetTargetProfiles(ctx context.Context) (*TargetProfilesResponse, error)
+	GetConstraints(ctx context.Context) (*Constraints, error)
+	SetPowerTarget(ctx context.Context, watts int) (*PowerTargetResponse, error)
+
+	// Mining Control
+	StartMining(ctx context.Context) error
+	StopMining(ctx context.Context) error
+	PauseMining(ctx context.Context) error
+	ResumeMining(ctx context.Context) error
+	RestartMining(ctx context.Context) error
+	Reboot(ctx context.Context) error
+}
+
+// HTTPClient is the HTTP implementation of the Braiins OS Client.
+type HTTPClient struct {
+	host       string
+	baseURL    string
+	httpClient *http.Client
+	auth       *AuthManager
+}
+
+// ClientOption is a function that configures an HTTPClient.
+type ClientOption func(*HTTPClient)
+
+// WithHTTPClient sets a custom HTTP client.
+func WithHTTPClient(client *http.Client) ClientOption {
+	return func(c *HTTPClient) {
+		c.httpClient = client
+	}
+}
+
+// WithTimeout sets the HTTP client timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *HTTPClient) {
+		c.httpClient.Timeout = timeout
+	}
+}
+
+// NewClient creates a new Braiins OS HTTP client.
+func NewClient(host string, auth *AuthManager, opts ...ClientOption) *HTTPClient {
+	c := &HTTPClient{
+		host:    host,
+		baseURL: fmt.Sprintf("http://%s/api/v1", host),
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		auth: auth,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Host returns the miner host address.
+func (c *HTTPClient) Host() string {
+	return c.host
+}
+
+// request performs an authenticated HTTP request with a JSON body and result.
+// A 401 response clears the cached token and retries once with a fresh login.
+func (c *HTTPClient) request(ctx context.Context, method, endpoint string, body, result interface{}) error {
+	return c.doRequest(ctx, method, endpoint, body, result, true)
+}
+
+func (c *HTTPClient) doRequest(ctx context.Context, method, endpoint string, body, result interface{}, retry bool) error {
+	if err := c.EnsureAuthenticated(ctx); err != nil {
+		return err
+	}
+
+	var bodyReader io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, bodyReader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", c.auth.GetToken(c.host))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized && retry {
+		c.auth.ClearToken(c.host)
+		return c.doRequest(ctx, method, endpoint, body, result, false)
+	}
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		return newAPIError(resp.StatusCode, endpoint, bodyBytes)
+	}
+
+	if result != nil && len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, result); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// newAPIError builds an APIError, extracting the message from the error body when possible.
+func newAPIError(statusCode int, endpoint string, body []byte) *APIError {
+	var errResp ErrorResponse
+	if json.Unmarshal(body, &errResp) == nil && errResp.Message != "" {
+		return &APIError{StatusCode: statusCode, Message: errResp.Message, Endpoint: endpoint}
+	}
+	return &APIError{StatusCode: statusCode, Message: strings.TrimSpace(string(body)), Endpoint: endpoint}
+}
+
+// Login authenticates with the miner and caches the returned session token.
+func (c *HTTPClient) Login(ctx context.Context) (string, error) {
+	username, password := c.auth.GetCredentials()
+	bodyBytes, err := json.Marshal(&LoginRequest{Username: username, Password: password})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/auth/login", bytes.NewReader(bodyBytes))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("login failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", ErrAuthenticationFailed
+	}
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("login failed: %w", newAPIError(resp.StatusCode, "/auth/login", respBody))
+	}
+
+	var result LoginResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return "", fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Token == "" {
+		return "", ErrAuthenticationFailed
+	}
+
+	c.auth.SetToken(c.host, result.Token, time.Duration(result.TimeoutS)*time.Second)
+	return result.Token, nil
+}
+
+// EnsureAuthenticated ensures we have a valid session token.
+func (c *HTTPClient) EnsureAuthenticated(ctx context.Context) error {
+	if token := c.auth.GetToken(c.host); token != "" {
+		return nil
+	}
+
+	_, err := c.Login(ctx)
+	return err
+}
+
+// GetMinerDetails returns miner identity, version and network details.
+func (c *HTTPClient) GetMinerDetails(ctx context.Context) (*MinerDetails, error) {
+	var result MinerDetails
+	err := c.request(ctx, http.MethodGet, "/miner/details", nil, &result)
+	return &result, err
+}
+
+// GetMinerStats returns aggregated hashrate, share and power statistics.
+func (c *HTTPClient) GetMinerStats(ctx context.Context) (*MinerStats, error) {
+	var result MinerStats
+	err := c.request(ctx, http.MethodGet, "/miner/stats", nil, &result)
+	return &result, err
+}
+
+// GetHashboards returns per-hashboard information.
+func (c *HTTPClient) GetHashboards(ctx context.Context) (*HashboardsResponse, error) {
+	var result HashboardsResponse
+	err := c.request(ctx, http.MethodGet, "/miner/hw/hashboards", nil, &result)
+	return &result, err
+}
+
+// GetCoolingState returns fan speeds and the highest measured temperature.
+func (c *HTTPClient) GetCoolingState(ctx context.Context) (*CoolingState, error) {
+	var result CoolingState
+	err := c.request(ctx, http.MethodGet, "/cooling/state", nil, &result)
+	return &result, err
+}
+
+// GetPools returns configured pool groups with their statistics.
+func (c *HTTPClient) GetPools(ctx context.Context) (*PoolGroupsResponse, error) {
+	var result PoolGroupsResponse
+	err := c.request(ctx, http.MethodGet, "/pools", nil, &result)
+	return &result, err
+}
+
+// GetNetworkInfo returns network configuration.
+func (c *HTTPClient) GetNetworkInfo(ctx context.Context) (*NetworkInfo, error) {
+	var result NetworkInfo
+	err := c.request(ctx, http.MethodGet, "/network", nil, &result)
+	return &result, err
+}
+
+// GetTunerState returns the autotuner state and current power target.
+func (c *HTTPClient) GetTunerState(ctx context.Context) (*TunerState, error) {
+	var result TunerState
+	err := c.request(ctx, http.MethodGet, "/performance/tuner-state", nil, &result)
+	return &result, err
+}
+
+// GetTargetProfiles returns the tuned power target profiles.
+func (c *HTTPClient) GetTargetProfiles(ctx context.Context) (*TargetProfilesResponse, error) {
+	var result TargetProfilesResponse
+	err := c.request(ctx, http.MethodGet, "/performance/target-profiles", nil, &result)
+	return &result, err
+}
+
+// GetConstraints returns configuration constraints such as the power target range.
+func (c *HTTPClient) GetConstraints(ctx context.Context) (*Constraints, error) {
+	var result Constraints
+	err := c.request(ctx, http.MethodGet, "/configuration/constraints", nil, &result)
+	return &result, err
+}
+
+// SetPowerTarget sets the autotuner power target in watts.
+func (c *HTTPClient) SetPowerTarget(ctx context.Context, watts int) (*PowerTargetResponse, error) {
+	var result PowerTargetResponse
+	err := c.request(ctx, http.MethodPut, "/performance/power-target", &PowerTargetRequest{Watt: watts}, &result)
+	return &result, err
+}
+
+// StartMining starts the mining process.
+func (c *HTTPClient) StartMining(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/start", nil, nil)
+}
+
+// StopMining stops the mining process.
+func (c *HTTPClient) StopMining(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/stop", nil, nil)
+}
+
+// PauseMining pauses mining.
+func (c *HTTPClient) PauseMining(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/pause", nil, nil)
+}
+
+// ResumeMining resumes paused mining.
+func (c *HTTPClient) ResumeMining(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/resume", nil, nil)
+}
+
+// RestartMining restarts the mining process.
+func (c *HTTPClient) RestartMining(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/restart", nil, nil)
+}
+
+// Reboot reboots the miner.
+func (c *HTTPClient) Reboot(ctx context.Context) error {
+	return c.request(ctx, http.MethodPut, "/actions/reboot", nil, nil)
+}
+
+// =============================================================================
+// miner.Client interface implementation
+// =============================================================================
+
+// GetMinerInfo returns generic miner information (implements miner.Client).
+func (c *HTTPClient) GetMinerInfo(ctx context.Context) (*miner.Info, error) {
+	details, err := c.GetMinerDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return detailsToInfo(details, c.host), nil
+}
+
+// GetMinerStatus returns generic miner status (implements miner.Client).
+func (c *HTTPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error) {
+	details, err := c.GetMinerDetails(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	state := MapMinerState(details.Status)
+	description := strings.TrimPrefix(details.Status, "MINER_STATUS_")
+
+	// Add hashrate to the description when stats are available
+	if stats, err := c.GetMinerStats(ctx); err == nil {
+		description = fmt.Sprintf("Hashrate: %.2f GH/s", stats.MinerStats.RealHashrate.Last5S.GigahashPerSecond)
+	}
+
+	return &miner.Status{
+		State:       state,
+		Description: description,
+		FailureCode: 0,
+	}, nil
+}
+
+// MapMinerState converts a Braiins MINER_STATUS_* value to a generic state string.
+func MapMinerState(status string) string {
+	switch status {
+	case "MINER_STATUS_NORMAL":
+		return "running"
+	case "MINER_STATUS_PAUSED":
+		return "paused"
+	case "MINER_STATUS_NOT_STARTED", "MINER_STATUS_SUSPENDED":
+		return "stopped"
+	case "MINER_STATUS_RESTRICTED":
+		return "failure"
+	default:
+		return "unknown"
+	}
+}
+
+// detailsToInfo converts MinerDetails to miner.Info.
+func detailsToInfo(details *MinerDetails, host string) *miner.Info {
+	name := details.MinerIdentity.Name
+	if name == "" {
+		name = details.MinerIdentity.MinerModel
+	}
+
+	return &miner.Info{
+		Miner:           name,
+		Model:           name,
+		Series:          extractSeries(name),
+		Firmware:        "Braiins OS",
+		FirmwareVersion: details.BOSVersion.Current,
+		Algorithm:       "sha256d", // Braiins OS only supports SHA-256 miners
+		IP:              host,
+		MAC:             details.MACAddress,
+		Hostname:        details.Hostname,
+	}
+}
+
+// extractSeries extracts the series from the miner name (e.g., "Antminer S19J Pro" -> "x19").
+func extractSeries(name string) string {
+	for _, field := range strings.Fields(name) {
+		if len(field) >= 3 && (field[0] == 'S' || field[0] == 'T') && field[1] >= '0' && field[1] <= '9' {
+			return "x" + field[1:3]
+		}
+	}
+	return ""
+}
+
+// Ensure HTTPClient implements the Client interfaces.
+var (
+	_ Client       = (*HTTPClient)(nil)
+	_ miner.Client = (*HTTPClient)(nil)
+)
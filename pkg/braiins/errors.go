@@ -0,0 +1,38 @@
+package braiins
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotBraiinsFirmware indicates the host is not running Braiins OS.
+	ErrNotBraiinsFirmware = errors.New("host is not running Braiins OS firmware")
+
+	// ErrAuthenticationFailed indicates authentication failed.
+	ErrAuthenticationFailed = errors.New("authentication failed")
+)
+
+// APIError represents an error returned by the Braiins OS API.
+type APIError struct {
+	StatusCode int
+	Message    string
+	Endpoint   string
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("braiins API error (HTTP %d) at %s: %s", e.StatusCode, e.Endpoint, e.Message)
+	}
+	return fmt.Sprintf("braiins API error (HTTP %d) at %s", e.StatusCode, e.Endpoint)
+}
+
+// IsUnauthorized returns true if the error indicates authentication is needed.
+func (e *APIError) IsUnauthorized() bool {
+	return e.StatusCode == 401
+}
+
+// IsNotFound returns true if the resource was not found.
+func (e *APIError) IsNotFound() bool {
+	return e.StatusCode == 404
+}
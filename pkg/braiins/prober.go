@@ -0,0 +1,81 @@
+package braiins
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Prober implements miner.FirmwareProber for Braiins OS firmware.
+type Prober struct {
+	auth    *AuthManager
+	timeout time.Duration
+}
+
+// ProberOption configures a Prober.
+type ProberOption func(*Prober)
+
+// WithProberTimeout sets the probe timeout.
+func WithProberTimeout(timeout time.Duration) ProberOption {
+	return func(p *Prober) {
+		p.timeout = timeout
+	}
+}
+
+// NewProber creates a new Braiins OS firmware prober.
+func NewProber(auth *AuthManager, opts ...ProberOption) *Prober {
+	p := &Prober{
+		auth:    auth,
+		timeout: 3 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Probe attempts to connect and identify Braiins OS firmware.
+// Implements miner.FirmwareProber.
+func (p *Prober) Probe(ctx context.Context, host string) (*miner.Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	client := NewClient(host, p.auth, WithTimeout(p.timeout))
+
+	// Other firmwares don't serve /api/v1/auth/login, so a 404 here
+	// means the host isn't running Braiins OS
+	details, err := client.GetMinerDetails(ctx)
+	if err != nil {
+		var apiErr *APIError
+		if errors.As(err, &apiErr) && apiErr.IsNotFound() {
+			return nil, ErrNotBraiinsFirmware
+		}
+		return nil, err
+	}
+
+	// Verify it looks like a valid Braiins OS response
+	if details.BOSVersion.Current == "" && details.UID == "" {
+		return nil, ErrNotBraiinsFirmware
+	}
+
+	return detailsToInfo(details, host), nil
+}
+
+// FirmwareType returns the firmware type this prober detects.
+// Implements miner.FirmwareProber.
+func (p *Prober) FirmwareType() miner.FirmwareType {
+	return miner.FirmwareBraiins
+}
+
+// NewClient creates a client for hosts confirmed to run Braiins OS.
+// Implements miner.FirmwareProber.
+func (p *Prober) NewClient(host string) miner.Client {
+	return NewClient(host, p.auth, WithTimeout(p.timeout))
+}
+
+// Ensure Prober implements miner.FirmwareProber.
+var _ miner.FirmwareProber = (*Prober)(nil)
@@ -0,0 +1,302 @@
+// Package braiins provides a client for the Braiins OS+ public REST API.
+package braiins
+
+// =============================================================================
+// Unit wrappers (the API wraps every measurement in a typed object)
+// =============================================================================
+
+// Hashrate is a hashrate value in GH/s.
+type Hashrate struct {
+	GigahashPerSecond float64 `json:"gigahash_per_second"`
+}
+
+// Power is a power value in watts.
+type Power struct {
+	Watt int `json:"watt"`
+}
+
+// Temperature is a temperature value in degrees Celsius.
+type Temperature struct {
+	DegreeC float64 `json:"degree_c"`
+}
+
+// Frequency is a frequency value in hertz.
+type Frequency struct {
+	Hertz float64 `json:"hertz"`
+}
+
+// Voltage is a voltage value in volts.
+type Voltage struct {
+	Volt float64 `json:"volt"`
+}
+
+// Efficiency is a power efficiency value in J/TH.
+type Efficiency struct {
+	JoulePerTerahash float64 `json:"joule_per_terahash"`
+}
+
+// =============================================================================
+// Authentication
+// =============================================================================
+
+// LoginRequest is the request body for /auth/login.
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// LoginResponse is the response from /auth/login.
+type LoginResponse struct {
+	Token    string `json:"token"`
+	TimeoutS int    `json:"timeout_s"` // Token lifetime in seconds
+}
+
+// ErrorResponse is the error body returned by the API.
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// =============================================================================
+// Miner details and statistics
+// =============================================================================
+
+// MinerDetails contains miner information from /miner/details.
+type MinerDetails struct {
+	UID             string        `json:"uid"`
+	MinerIdentity   MinerIdentity `json:"miner_identity"`
+	Platform        string        `json:"platform"` // e.g., "PLATFORM_AM3_BBB"
+	BOSMode         string        `json:"bos_mode"` // e.g., "BOS_MODE_NAND"
+	BOSVersion      BOSVersion    `json:"bos_version"`
+	Hostname        string        `json:"hostname"`
+	MACAddress      string        `json:"mac_address"`
+	SystemUptimeS   int           `json:"system_uptime_s"`
+	BosminerUptimeS int           `json:"bosminer_uptime_s"`
+	SerialNumber    string        `json:"serial_number"`
+	KernelVersion   string        `json:"kernel_version"`
+	Status          string        `json:"status"` // e.g., "MINER_STATUS_NORMAL"
+	PSUInfo         *PSUInfo      `json:"psu_info"`
+}
+
+// MinerIdentity identifies the hardware the firmware runs on.
+type MinerIdentity struct {
+	Brand      string `json:"brand"`       // e.g., "MINER_BRAND_ANTMINER"
+	Name       string `json:"name"`        // e.g., "Antminer S19J Pro"
+	MinerModel string `json:"miner_model"` // e.g., "Antminer S19J Pro"
+}
+
+// BOSVersion contains Braiins OS version information.
+type BOSVersion struct {
+	Current    string `json:"current"`     // e.g., "2024-03-04-0-7c8c1e5b-24.03-plus"
+	Major      string `json:"major"`       // e.g., "24.03"
+	BOSVersion string `json:"bos_version"` // e.g., "24.03"
+}
+
+// PSUInfo contains power supply information.
+type PSUInfo struct {
+	ModelName    string `json:"model_name"`
+	SerialNumber string `json:"serial_number"`
+}
+
+// MinerStats contains aggregated statistics from /miner/stats.
+type MinerStats struct {
+	PoolStats  PoolStats       `json:"pool_stats"`
+	MinerStats MinerHashStats  `json:"miner_stats"`
+	PowerStats MinerPowerStats `json:"power_stats"`
+}
+
+// PoolStats contains aggregated share statistics.
+type PoolStats struct {
+	AcceptedShares int     `json:"accepted_shares"`
+	RejectedShares int     `json:"rejected_shares"`
+	StaleShares    int     `json:"stale_shares"`
+	LastDifficulty float64 `json:"last_difficulty"`
+	BestShare      int64   `json:"best_share"`
+	GeneratedWork  int64   `json:"generated_work"`
+	FoundBlocks    int     `json:"found_blocks"`
+}
+
+// MinerHashStats contains hashrate statistics.
+type MinerHashStats struct {
+	IdealHashrate   Hashrate     `json:"ideal_hashrate"`
+	NominalHashrate Hashrate     `json:"nominal_hashrate"`
+	ErrorHashrate   Hashrate     `json:"error_hashrate"`
+	RealHashrate    RealHashrate `json:"real_hashrate"`
+}
+
+// RealHashrate contains measured hashrate over several windows.
+type RealHashrate struct {
+	Last5S       Hashrate `json:"last_5s"`
+	Last15S      Hashrate `json:"last_15s"`
+	Last30S      Hashrate `json:"last_30s"`
+	Last1M       Hashrate `json:"last_1m"`
+	Last5M       Hashrate `json:"last_5m"`
+	Last15M      Hashrate `json:"last_15m"`
+	Last30M      Hashrate `json:"last_30m"`
+	Last1H       Hashrate `json:"last_1h"`
+	Last24H      Hashrate `json:"last_24h"`
+	SinceRestart Hashrate `json:"since_restart"`
+}
+
+// MinerPowerStats contains power statistics.
+type MinerPowerStats struct {
+	ApproximatedConsumption Power      `json:"approximated_consumption"`
+	Efficiency              Efficiency `json:"efficiency"`
+}
+
+// =============================================================================
+// Hardware
+// =============================================================================
+
+// HashboardsResponse is the response from /miner/hw/hashboards.
+type HashboardsResponse struct {
+	Hashboards []Hashboard `json:"hashboards"`
+}
+
+// Hashboard contains per-board information.
+type Hashboard struct {
+	ID                string          `json:"id"` // 1-based board index as string
+	Enabled           bool            `json:"enabled"`
+	ChipsCount        int             `json:"chips_count"`
+	CurrentVoltage    Voltage         `json:"current_voltage"`
+	CurrentFrequency  Frequency       `json:"current_frequency"`
+	HighestChipTemp   *TempLocation   `json:"highest_chip_temp"`
+	BoardTemp         *Temperature    `json:"board_temp"`
+	LowestInletTemp   *Temperature    `json:"lowest_inlet_temp"`
+	HighestOutletTemp *Temperature    `json:"highest_outlet_temp"`
+	Stats             *HashboardStats `json:"stats"`
+	SerialNumber      string          `json:"serial_number"`
+	Model             string          `json:"model"`
+}
+
+// TempLocation is a temperature reading with its location on the board.
+type TempLocation struct {
+	Location    string      `json:"location"`
+	Temperature Temperature `json:"temperature"`
+}
+
+// HashboardStats contains per-board hashing statistics.
+type HashboardStats struct {
+	RealHashrate    RealHashrate `json:"real_hashrate"`
+	NominalHashrate Hashrate     `json:"nominal_hashrate"`
+	ErrorHashrate   Hashrate     `json:"error_hashrate"`
+	AcceptedShares  int          `json:"accepted_shares"`
+	HardwareErrors  int          `json:"hardware_errors"`
+}
+
+// CoolingState is the response from /cooling/state.
+type CoolingState struct {
+	Fans               []FanState    `json:"fans"`
+	HighestTemperature *TempLocation `json:"highest_temperature"`
+}
+
+// FanState contains the state of a single fan.
+type FanState struct {
+	Position         int     `json:"position"`
+	RPM              int     `json:"rpm"`
+	TargetSpeedRatio float64 `json:"target_speed_ratio"` // 0.0-1.0
+}
+
+// =============================================================================
+// Pools
+// =============================================================================
+
+// PoolGroupsResponse is the response from /pools.
+type PoolGroupsResponse struct {
+	PoolGroups []PoolGroup `json:"pool_groups"`
+}
+
+// PoolGroup is a named group of pools sharing a quota.
+type PoolGroup struct {
+	UID   string `json:"uid"`
+	Name  string `json:"name"`
+	Pools []Pool `json:"pools"`
+}
+
+// Pool contains pool configuration and statistics.
+type Pool struct {
+	UID     string     `json:"uid"`
+	URL     string     `json:"url"`
+	User    string     `json:"user"`
+	Enabled bool       `json:"enabled"`
+	Alive   bool       `json:"alive"`
+	Active  bool       `json:"active"`
+	Stats   *PoolStats `json:"stats"`
+}
+
+// =============================================================================
+// Network
+// =============================================================================
+
+// NetworkInfo is the response from /network.
+type NetworkInfo struct {
+	Name           string          `json:"name"` // e.g., "eth0"
+	MACAddress     string          `json:"mac_address"`
+	Hostname       string          `json:"hostname"`
+	Protocol       string          `json:"protocol"` // "NETWORK_PROTOCOL_DHCP" or "NETWORK_PROTOCOL_STATIC"
+	DNSServers     []string        `json:"dns_servers"`
+	Networks       []IPv4Interface `json:"networks"`
+	DefaultGateway string          `json:"default_gateway"`
+}
+
+// IPv4Interface is an address assigned to the network interface.
+type IPv4Interface struct {
+	Address string `json:"address"`
+	Netmask string `json:"netmask"`
+}
+
+// =============================================================================
+// Performance / tuner
+// =============================================================================
+
+// TunerState is the response from /performance/tuner-state.
+type TunerState struct {
+	OverallTunerState    string                `json:"overall_tuner_state"` // e.g., "TUNER_STATE_STABLE"
+	PowerTargetModeState *PowerTargetModeState `json:"power_target_mode_state"`
+}
+
+// PowerTargetModeState contains the tuner state when in power target mode.
+type PowerTargetModeState struct {
+	Profile       *TuneProfile `json:"profile"`
+	CurrentTarget Power        `json:"current_target"`
+}
+
+// TuneProfile is a tuned operating point stored by the autotuner.
+type TuneProfile struct {
+	Created                   string   `json:"created"`
+	Target                    Power    `json:"target"`
+	MeasuredHashrate          Hashrate `json:"measured_hashrate"`
+	EstimatedPowerConsumption Power    `json:"estimated_power_consumption"`
+}
+
+// TargetProfilesResponse is the response from /performance/target-profiles.
+type TargetProfilesResponse struct {
+	PowerTargetProfiles []TuneProfile `json:"power_target_profiles"`
+}
+
+// PowerTargetRequest is the request body for /performance/power-target.
+type PowerTargetRequest struct {
+	Watt int `json:"watt"`
+}
+
+// PowerTargetResponse is the response from /performance/power-target.
+type PowerTargetResponse struct {
+	PowerTarget Power `json:"power_target"`
+}
+
+// Constraints is the response from /configuration/constraints.
+type Constraints struct {
+	TunerConstraints TunerConstraints `json:"tuner_constraints"`
+}
+
+// TunerConstraints contains the allowed tuner target ranges.
+type TunerConstraints struct {
+	PowerTarget PowerConstraints `json:"power_target"`
+}
+
+// PowerConstraints contains min/default/max power targets.
+type PowerConstraints struct {
+	Min     Power `json:"min"`
+	Default Power `json:"default"`
+	Max     Power `json:"max"`
+}
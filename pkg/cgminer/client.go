@@ -0,0 +1,248 @@
+package cgminer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// DefaultPort is the standard cgminer API port.
+const DefaultPort = 4028
+
+// Client is the interface for interacting with the cgminer TCP API.
+type Client interface {
+	miner.Client
+
+	// Command sends a raw command and returns the raw JSON response.
+	Command(ctx context.Context, command, parameter string) ([]byte, error)
+
+	// Version returns miner software versions and type.
+	Version(ctx context.Context) (*VersionResponse, error)
+
+	// Summary returns aggregated mining statistics.
+	Summary(ctx context.Context) (*SummaryResponse, error)
+
+	// Stats returns detailed device statistics (fans, chains, temperatures).
+	Stats(ctx context.Context) (*StatsResponse, error)
+
+	// Pools returns pool status.
+	Pools(ctx context.Context) (*PoolsResponse, error)
+
+	// Devs returns per-device statistics.
+	Devs(ctx context.Context) (*DevsResponse, error)
+}
+
+// TCPClient is the TCP implementation of the cgminer Client.
+type TCPClient struct {
+	host    string
+	port    int
+	timeout time.Duration
+}
+
+// ClientOption is a function that configures a TCPClient.
+type ClientOption func(*TCPClient)
+
+// WithPort sets the API port (default: 4028).
+func WithPort(port int) ClientOption {
+	return func(c *TCPClient) {
+		c.port = port
+	}
+}
+
+// WithTimeout sets the dial and read timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *TCPClient) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient creates a new cgminer TCP client.
+// The host may include a port (e.g., "192.168.1.10:80"), which is ignored.
+func NewClient(host string, opts ...ClientOption) *TCPClient {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	c := &TCPClient{
+		host:    host,
+		port:    DefaultPort,
+		timeout: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Host returns the miner host address.
+func (c *TCPClient) Host() string {
+	return c.host
+}
+
+// Command sends a command and returns the raw JSON response.
+// The server answers with a single JSON document, usually NUL-terminated, then closes the connection.
+func (c *TCPClient) Command(ctx context.Context, command, parameter string) ([]byte, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.host, strconv.Itoa(c.port)))
+	if err != nil {
+		return nil, fmt.Errorf("connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	req, err := json.Marshal(&Request{Command: command, Parameter: parameter})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	if _, err := conn.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	resp, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return sanitizeResponse(resp)
+}
+
+// sanitizeResponse strips the NUL terminator and repairs known malformed output.
+func sanitizeResponse(resp []byte) ([]byte, error) {
+	resp = bytes.TrimRight(resp, "\x00")
+	resp = bytes.TrimSpace(resp)
+	if len(resp) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	// Some bmminer builds omit the comma between STATS objects
+	resp = bytes.ReplaceAll(resp, []byte("}{"), []byte("},{"))
+	return resp, nil
+}
+
+// call sends a command, checks the STATUS header and decodes the response.
+func (c *TCPClient) call(ctx context.Context, command string, result interface{}) error {
+	resp, err := c.Command(ctx, command, "")
+	if err != nil {
+		return err
+	}
+
+	var header statusHeader
+	if err := json.Unmarshal(resp, &header); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(header.Status) > 0 {
+		s := header.Status[0]
+		if s.Status == "E" || s.Status == "F" {
+			return &APIError{Command: command, Status: s.Status, Code: s.Code, Msg: s.Msg}
+		}
+	}
+
+	if err := json.Unmarshal(resp, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// Version returns miner software versions and type.
+func (c *TCPClient) Version(ctx context.Context) (*VersionResponse, error) {
+	var result VersionResponse
+	err := c.call(ctx, "version", &result)
+	return &result, err
+}
+
+// Summary returns aggregated mining statistics.
+func (c *TCPClient) Summary(ctx context.Context) (*SummaryResponse, error) {
+	var result SummaryResponse
+	err := c.call(ctx, "summary", &result)
+	return &result, err
+}
+
+// Stats returns detailed device statistics.
+func (c *TCPClient) Stats(ctx context.Context) (*StatsResponse, error) {
+	var result StatsResponse
+	err := c.call(ctx, "stats", &result)
+	return &result, err
+}
+
+// Pools returns pool status.
+func (c *TCPClient) Pools(ctx context.Context) (*PoolsResponse, error) {
+	var result PoolsResponse
+	err := c.call(ctx, "pools", &result)
+	return &result, err
+}
+
+// Devs returns per-device statistics.
+func (c *TCPClient) Devs(ctx context.Context) (*DevsResponse, error) {
+	var result DevsResponse
+	err := c.call(ctx, "devs", &result)
+	return &result, err
+}
+
+// =============================================================================
+// miner.Client interface implementation
+// =============================================================================
+
+// GetMinerInfo returns generic miner information (implements miner.Client).
+// The cgminer API doesn't expose network details, so MAC and hostname are empty.
+func (c *TCPClient) GetMinerInfo(ctx context.Context) (*miner.Info, error) {
+	version, err := c.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(version.Version) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	return versionToInfo(&version.Version[0], c.host), nil
+}
+
+// GetMinerStatus returns generic miner status (implements miner.Client).
+func (c *TCPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error) {
+	summary, err := c.Summary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(summary.Summary) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	s := summary.Summary[0]
+	return &miner.Status{
+		State:       StateFromSummary(&s),
+		Description: fmt.Sprintf("Hashrate: %.2f GH/s", s.Hashrate5s()),
+		FailureCode: 0,
+	}, nil
+}
+
+// StateFromSummary derives a generic state string from a summary.
+func StateFromSummary(s *Summary) string {
+	if s.Elapsed <= 0 {
+		return "unknown"
+	}
+	if s.Hashrate5s() > 0 {
+		return "running"
+	}
+	return "idle"
+}
+
+// Ensure TCPClient implements the Client interfaces.
+var (
+	_ Client       = (*TCPClient)(nil)
+	_ miner.Client = (*TCPClient)(nil)
+)
@@ -0,0 +1,26 @@
+package cgminer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrEmptyResponse indicates the miner closed the connection without a response.
+	ErrEmptyResponse = errors.New("empty response from cgminer API")
+
+	// ErrFirmwareMismatch indicates the host runs a different firmware than the prober expects.
+	ErrFirmwareMismatch = errors.New("cgminer API reports a different firmware")
+)
+
+// APIError represents an error STATUS returned by the cgminer API.
+type APIError struct {
+	Command string
+	Status  string
+	Code    int
+	Msg     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("cgminer API error at %s (status %s, code %d): %s", e.Command, e.Status, e.Code, e.Msg)
+}
@@ -0,0 +1,151 @@
+package cgminer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Prober identifies firmware through the cgminer "version" command.
+// It implements miner.FirmwareProber for a single expected firmware type, so it
+// can be registered next to the HTTP probers; Identify detects any firmware.
+// Clients returned by NewClient only speak the TCP API.
+type Prober struct {
+	fwType  miner.FirmwareType
+	port    int
+	timeout time.Duration
+}
+
+// ProberOption configures a Prober.
+type ProberOption func(*Prober)
+
+// WithProberTimeout sets the probe timeout.
+func WithProberTimeout(timeout time.Duration) ProberOption {
+	return func(p *Prober) {
+		p.timeout = timeout
+	}
+}
+
+// WithProberPort sets the API port (default: 4028).
+func WithProberPort(port int) ProberOption {
+	return func(p *Prober) {
+		p.port = port
+	}
+}
+
+// NewProber creates a prober that accepts hosts identified as fwType.
+func NewProber(fwType miner.FirmwareType, opts ...ProberOption) *Prober {
+	p := &Prober{
+		fwType:  fwType,
+		port:    DefaultPort,
+		timeout: 3 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Identify queries the version command and returns the detected firmware type.
+func (p *Prober) Identify(ctx context.Context, host string) (miner.FirmwareType, *miner.Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	client := NewClient(host, WithPort(p.port), WithTimeout(p.timeout))
+
+	version, err := client.Version(ctx)
+	if err != nil {
+		return miner.FirmwareUnknown, nil, err
+	}
+	if len(version.Version) == 0 {
+		return miner.FirmwareUnknown, nil, ErrEmptyResponse
+	}
+
+	v := &version.Version[0]
+	return IdentifyFirmware(v), versionToInfo(v, client.Host()), nil
+}
+
+// Probe attempts to identify the expected firmware via the cgminer API.
+// Implements miner.FirmwareProber.
+func (p *Prober) Probe(ctx context.Context, host string) (*miner.Info, error) {
+	fwType, info, err := p.Identify(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if fwType != p.fwType {
+		return nil, ErrFirmwareMismatch
+	}
+	return info, nil
+}
+
+// FirmwareType returns the firmware type this prober accepts.
+// Implements miner.FirmwareProber.
+func (p *Prober) FirmwareType() miner.FirmwareType {
+	return p.fwType
+}
+
+// NewClient creates a cgminer TCP client for the host.
+// Implements miner.FirmwareProber.
+func (p *Prober) NewClient(host string) miner.Client {
+	return NewClient(host, WithPort(p.port), WithTimeout(p.timeout))
+}
+
+// IdentifyFirmware determines the firmware type from version information.
+func IdentifyFirmware(v *Version) miner.FirmwareType {
+	minerType := strings.ToLower(v.Type)
+
+	switch {
+	case v.BOSminer != "" || strings.Contains(minerType, "braiins") || strings.Contains(minerType, "bosminer"):
+		return miner.FirmwareBraiins
+	case strings.Contains(minerType, "vnish"):
+		return miner.FirmwareVNish
+	case v.BMMiner != "" || (v.CGMiner != "" && strings.Contains(minerType, "antminer")):
+		return miner.FirmwareStock
+	default:
+		return miner.FirmwareUnknown
+	}
+}
+
+// firmwareNames maps firmware types to display names used in miner.Info.
+var firmwareNames = map[miner.FirmwareType]string{
+	miner.FirmwareVNish:   "Vnish",
+	miner.FirmwareStock:   "Stock",
+	miner.FirmwareBraiins: "Braiins OS",
+	miner.FirmwareUnknown: "Unknown",
+}
+
+// versionToInfo converts version information to miner.Info.
+func versionToInfo(v *Version, host string) *miner.Info {
+	// VNish appends its version to the type, e.g. "Antminer S19 (Vnish 1.2.6)"
+	minerType := v.Type
+	if idx := strings.Index(minerType, " ("); idx > 0 {
+		minerType = minerType[:idx]
+	}
+
+	return &miner.Info{
+		Miner:           minerType,
+		Model:           minerType,
+		Series:          extractSeries(minerType),
+		Firmware:        firmwareNames[IdentifyFirmware(v)],
+		FirmwareVersion: v.MinerSoftware(),
+		Algorithm:       "sha256d",
+		IP:              host,
+	}
+}
+
+// extractSeries extracts the series from the miner type (e.g., "Antminer S19j Pro" -> "x19").
+func extractSeries(minerType string) string {
+	for _, field := range strings.Fields(minerType) {
+		if len(field) >= 3 && (field[0] == 'S' || field[0] == 'T' || field[0] == 'L') && field[1] >= '0' && field[1] <= '9' {
+			return "x" + field[1:3]
+		}
+	}
+	return ""
+}
+
+// Ensure Prober implements miner.FirmwareProber.
+var _ miner.FirmwareProber = (*Prober)(nil)
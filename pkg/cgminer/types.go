@@ -0,0 +1,306 @@
+// Package cgminer provides a client for the cgminer/bmminer JSON-over-TCP API (port 4028).
+// The API is exposed by nearly every ASIC firmware (stock, VNish, Braiins OS),
+// which makes it a useful fallback when the HTTP APIs are unavailable.
+package cgminer
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Number is a float that accepts both JSON numbers and numeric strings.
+// Different miner builds encode hashrates and temperatures either way
+// (e.g., bmminer reports "GHS 5s": "13500.12").
+type Number float64
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (n *Number) UnmarshalJSON(b []byte) error {
+	s := strings.Trim(string(b), `"`)
+	if s == "" || s == "null" {
+		*n = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		// Unparsable values (e.g., "65.5K") are treated as missing
+		*n = 0
+		return nil
+	}
+	*n = Number(f)
+	return nil
+}
+
+// Request is a cgminer API command.
+type Request struct {
+	Command   string `json:"command"`
+	Parameter string `json:"parameter,omitempty"`
+}
+
+// StatusEntry is the STATUS header present in every response.
+type StatusEntry struct {
+	Status      string `json:"STATUS"` // "S" = success, "I" = info, "W" = warning, "E" = error, "F" = fatal
+	When        int64  `json:"When"`
+	Code        int    `json:"Code"`
+	Msg         string `json:"Msg"`
+	Description string `json:"Description"`
+}
+
+// statusHeader is used to check the STATUS of any response before decoding it.
+type statusHeader struct {
+	Status []StatusEntry `json:"STATUS"`
+}
+
+// =============================================================================
+// version
+// =============================================================================
+
+// VersionResponse is the response to the "version" command.
+type VersionResponse struct {
+	Status  []StatusEntry `json:"STATUS"`
+	Version []Version     `json:"VERSION"`
+}
+
+// Version contains miner software versions. Only the keys for the running
+// miner software are populated (e.g., BMMiner on stock, BOSminer on Braiins OS).
+type Version struct {
+	CGMiner     string `json:"CGMiner"`
+	BMMiner     string `json:"BMMiner"`
+	BOSminer    string `json:"BOSminer"`
+	API         string `json:"API"`
+	Miner       string `json:"Miner"`
+	CompileTime string `json:"CompileTime"`
+	Type        string `json:"Type"` // e.g., "Antminer S19j Pro", "Antminer S19 (Vnish 1.2.6)"
+}
+
+// MinerSoftware returns the miner software version string, whichever is present.
+func (v *Version) MinerSoftware() string {
+	switch {
+	case v.BOSminer != "":
+		return v.BOSminer
+	case v.BMMiner != "":
+		return v.BMMiner
+	default:
+		return v.CGMiner
+	}
+}
+
+// =============================================================================
+// summary
+// =============================================================================
+
+// SummaryResponse is the response to the "summary" command.
+type SummaryResponse struct {
+	Status  []StatusEntry `json:"STATUS"`
+	Summary []Summary     `json:"SUMMARY"`
+}
+
+// Summary contains aggregated mining statistics.
+// bmminer reports GHS fields, cgminer/bosminer report MHS fields.
+type Summary struct {
+	Elapsed         int    `json:"Elapsed"`
+	GHS5s           Number `json:"GHS 5s"`
+	GHSAv           Number `json:"GHS av"`
+	GHS30m          Number `json:"GHS 30m"`
+	MHS5s           Number `json:"MHS 5s"`
+	MHSAv           Number `json:"MHS av"`
+	FoundBlocks     int    `json:"Found Blocks"`
+	Getworks        int    `json:"Getworks"`
+	Accepted        int    `json:"Accepted"`
+	Rejected        int    `json:"Rejected"`
+	HardwareErrors  int    `json:"Hardware Errors"`
+	Utility         Number `json:"Utility"`
+	Discarded       int    `json:"Discarded"`
+	Stale           int    `json:"Stale"`
+	BestShare       int64  `json:"Best Share"`
+	DeviceHWPercent Number `json:"Device Hardware%"`
+}
+
+// Hashrate5s returns the 5-second hashrate in GH/s regardless of the reported unit.
+func (s *Summary) Hashrate5s() float64 {
+	if s.GHS5s > 0 {
+		return float64(s.GHS5s)
+	}
+	return float64(s.MHS5s) / 1000
+}
+
+// HashrateAvg returns the average hashrate in GH/s regardless of the reported unit.
+func (s *Summary) HashrateAvg() float64 {
+	if s.GHSAv > 0 {
+		return float64(s.GHSAv)
+	}
+	return float64(s.MHSAv) / 1000
+}
+
+// =============================================================================
+// pools
+// =============================================================================
+
+// PoolsResponse is the response to the "pools" command.
+type PoolsResponse struct {
+	Status []StatusEntry `json:"STATUS"`
+	Pools  []Pool        `json:"POOLS"`
+}
+
+// Pool contains pool configuration and statistics.
+type Pool struct {
+	Index               int    `json:"POOL"`
+	URL                 string `json:"URL"`
+	User                string `json:"User"`
+	Status              string `json:"Status"` // "Alive", "Dead"
+	Priority            int    `json:"Priority"`
+	Quota               int    `json:"Quota"`
+	Getworks            int    `json:"Getworks"`
+	Accepted            int    `json:"Accepted"`
+	Rejected            int    `json:"Rejected"`
+	Discarded           int    `json:"Discarded"`
+	Stale               int    `json:"Stale"`
+	DifficultyAccepted  Number `json:"Difficulty Accepted"`
+	LastShareDifficulty Number `json:"Last Share Difficulty"`
+	StratumActive       bool   `json:"Stratum Active"`
+}
+
+// =============================================================================
+// devs
+// =============================================================================
+
+// DevsResponse is the response to the "devs" command.
+type DevsResponse struct {
+	Status []StatusEntry `json:"STATUS"`
+	Devs   []Dev         `json:"DEVS"`
+}
+
+// Dev contains per-device (hashboard) statistics.
+type Dev struct {
+	ASC            int    `json:"ASC"`
+	Name           string `json:"Name"`
+	ID             int    `json:"ID"`
+	Enabled        string `json:"Enabled"` // "Y" or "N"
+	Status         string `json:"Status"`  // "Alive", "Dead"
+	Temperature    Number `json:"Temperature"`
+	MHSAv          Number `json:"MHS av"`
+	MHS5s          Number `json:"MHS 5s"`
+	Accepted       int    `json:"Accepted"`
+	Rejected       int    `json:"Rejected"`
+	HardwareErrors int    `json:"Hardware Errors"`
+}
+
+// =============================================================================
+// stats
+// =============================================================================
+
+// StatsResponse is the response to the "stats" command.
+// STATS entries are heterogeneous and use numbered keys (fan1, temp2_1, chain_rate3...),
+// so they are kept as raw maps and read through helper methods.
+type StatsResponse struct {
+	Status []StatusEntry            `json:"STATUS"`
+	Stats  []map[string]interface{} `json:"STATS"`
+}
+
+// ChainStats contains per-chain values extracted from the stats response.
+type ChainStats struct {
+	Index    int
+	Rate     float64 // GH/s
+	AsicNum  int
+	HWErrors int
+	FreqAvg  int
+	TempPCB  int
+	TempChip int
+}
+
+// Type returns the miner type reported in the stats header (e.g., "Antminer S19j Pro").
+func (r *StatsResponse) Type() string {
+	for _, s := range r.Stats {
+		if t, ok := s["Type"].(string); ok && t != "" {
+			return t
+		}
+	}
+	return ""
+}
+
+// Data returns the stats entry containing the device data (the one with "Elapsed").
+func (r *StatsResponse) Data() map[string]interface{} {
+	for _, s := range r.Stats {
+		if _, ok := s["Elapsed"]; ok {
+			return s
+		}
+	}
+	return nil
+}
+
+// Fans returns the RPM of each fan slot reported by the miner.
+func (r *StatsResponse) Fans() []int {
+	data := r.Data()
+	if data == nil {
+		return nil
+	}
+
+	count := int(numberValue(data["fan_num"]))
+	var fans []int
+	for i := 1; i <= 8; i++ {
+		v, ok := data["fan"+strconv.Itoa(i)]
+		if !ok {
+			continue
+		}
+		rpm := int(numberValue(v))
+		// Without fan_num, skip empty slots
+		if count == 0 && rpm == 0 {
+			continue
+		}
+		fans = append(fans, rpm)
+	}
+	if count > 0 && len(fans) > count {
+		// Keep only populated slots when the miner reports more slots than fans
+		populated := fans[:0]
+		for _, rpm := range fans {
+			if rpm > 0 {
+				populated = append(populated, rpm)
+			}
+		}
+		fans = populated
+	}
+	return fans
+}
+
+// Chains returns per-chain statistics for every chain with ASICs.
+func (r *StatsResponse) Chains() []ChainStats {
+	data := r.Data()
+	if data == nil {
+		return nil
+	}
+
+	var chains []ChainStats
+	for i := 1; i <= 16; i++ {
+		n := strconv.Itoa(i)
+		asics := int(numberValue(data["chain_acn"+n]))
+		if asics == 0 {
+			continue
+		}
+		chains = append(chains, ChainStats{
+			Index:    i - 1,
+			Rate:     numberValue(data["chain_rate"+n]),
+			AsicNum:  asics,
+			HWErrors: int(numberValue(data["chain_hw"+n])),
+			FreqAvg:  int(numberValue(data["freq_avg"+n])),
+			TempPCB:  int(numberValue(data["temp"+n])),
+			TempChip: int(numberValue(data["temp2_"+n])),
+		})
+	}
+	return chains
+}
+
+// numberValue converts a decoded JSON value (number or numeric string) to float64.
+func numberValue(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(val), 64)
+		return f
+	case json.Number:
+		f, _ := val.Float64()
+		return f
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,273 @@
+// Package credentials resolves per-miner login credentials from a credentials file.
+//
+// The file maps CIDRs, IP addresses and MAC addresses to ordered lists of
+// named credential sets:
+//
+//	{
+//	  "sets": [
+//	    {"name": "site-a", "vnish_password": "secret", "stock_username": "root", "stock_password": "secret"},
+//	    {"name": "factory", "vnish_password": "admin", "stock_username": "root", "stock_password": "root"}
+//	  ],
+//	  "rules": [
+//	    {"match": "10.40.36.0/24", "sets": ["site-a", "factory"]},
+//	    {"match": "aa:bb:cc:dd:ee:ff", "sets": ["factory"]}
+//	  ],
+//	  "default": ["factory"]
+//	}
+//
+// Candidates for a host are taken from its MAC rule, then its IP rule, then
+// CIDR rules (most specific first), then the default list and finally the
+// fallback set. The set last confirmed for a host is always tried first.
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Set is a named group of credentials for the supported firmwares.
+type Set struct {
+	Name          string `json:"name"`
+	VNishPassword string `json:"vnish_password,omitempty"`
+	StockUsername string `json:"stock_username,omitempty"`
+	StockPassword string `json:"stock_password,omitempty"`
+}
+
+// forFirmware returns the credentials of the set for a firmware.
+// ok is false if the set has no credentials for it.
+func (s Set) forFirmware(fw miner.FirmwareType) (miner.Credentials, bool) {
+	switch fw {
+	case miner.FirmwareVNish:
+		if s.VNishPassword == "" {
+			return miner.Credentials{}, false
+		}
+		return miner.Credentials{Name: s.Name, Password: s.VNishPassword}, true
+	case miner.FirmwareStock:
+		if s.StockUsername == "" && s.StockPassword == "" {
+			return miner.Credentials{}, false
+		}
+		return miner.Credentials{Name: s.Name, Username: s.StockUsername, Password: s.StockPassword}, true
+	}
+	return miner.Credentials{}, false
+}
+
+// Rule maps a CIDR, IP or MAC address to credential set names, in the order to try them.
+type Rule struct {
+	Match string   `json:"match"`
+	Sets  []string `json:"sets"`
+}
+
+// File is the credentials file format.
+type File struct {
+	Sets    []Set    `json:"sets"`
+	Rules   []Rule   `json:"rules"`
+	Default []string `json:"default"`
+}
+
+// cidrRule is a parsed CIDR rule.
+type cidrRule struct {
+	network *net.IPNet
+	ones    int
+	sets    []string
+}
+
+// confirmKey identifies a confirmed credential set.
+type confirmKey struct {
+	host string
+	fw   miner.FirmwareType
+}
+
+// Resolver implements miner.CredentialResolver from a credentials file.
+type Resolver struct {
+	mu        sync.RWMutex
+	sets      map[string]Set
+	byMAC     map[string][]string // normalized MAC -> set names
+	byIP      map[string][]string // IP -> set names
+	cidrs     []cidrRule          // most specific first
+	defaults  []string
+	fallback  *Set
+	macs      map[string]string // host -> normalized MAC
+	confirmed map[confirmKey]string
+}
+
+// Load reads and parses a credentials file.
+func Load(path string) (*Resolver, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read credentials file: %w", err)
+	}
+
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to parse credentials file %s: %w", path, err)
+	}
+
+	return New(&f)
+}
+
+// New creates a resolver from a parsed credentials file.
+func New(f *File) (*Resolver, error) {
+	r := &Resolver{
+		sets:      make(map[string]Set),
+		byMAC:     make(map[string][]string),
+		byIP:      make(map[string][]string),
+		macs:      make(map[string]string),
+		confirmed: make(map[confirmKey]string),
+	}
+
+	for _, s := range f.Sets {
+		if s.Name == "" {
+			return nil, fmt.Errorf("credential set without name")
+		}
+		if _, dup := r.sets[s.Name]; dup {
+			return nil, fmt.Errorf("duplicate credential set %q", s.Name)
+		}
+		r.sets[s.Name] = s
+	}
+
+	if err := r.checkSets(f.Default); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+	r.defaults = f.Default
+
+	for _, rule := range f.Rules {
+		if err := r.checkSets(rule.Sets); err != nil {
+			return nil, fmt.Errorf("rule %q: %w", rule.Match, err)
+		}
+
+		match := strings.TrimSpace(rule.Match)
+		if _, network, err := net.ParseCIDR(match); err == nil {
+			ones, _ := network.Mask.Size()
+			r.cidrs = append(r.cidrs, cidrRule{network: network, ones: ones, sets: rule.Sets})
+		} else if ip := net.ParseIP(match); ip != nil {
+			r.byIP[ip.String()] = rule.Sets
+		} else if mac, err := net.ParseMAC(match); err == nil {
+			r.byMAC[mac.String()] = rule.Sets
+		} else {
+			return nil, fmt.Errorf("rule %q: not a CIDR, IP or MAC address", rule.Match)
+		}
+	}
+
+	sort.SliceStable(r.cidrs, func(i, j int) bool {
+		return r.cidrs[i].ones > r.cidrs[j].ones
+	})
+
+	return r, nil
+}
+
+// checkSets verifies that all named sets exist.
+func (r *Resolver) checkSets(names []string) error {
+	for _, name := range names {
+		if _, ok := r.sets[name]; !ok {
+			return fmt.Errorf("unknown credential set %q", name)
+		}
+	}
+	return nil
+}
+
+// WithFallback sets the credentials tried after all matching sets,
+// typically the global credentials from the environment. A file set
+// with the same name takes precedence.
+func (r *Resolver) WithFallback(s Set) *Resolver {
+	r.fallback = &s
+	return r
+}
+
+// hostOnly strips an optional port from a host address.
+func hostOnly(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		return h
+	}
+	return host
+}
+
+// LearnMAC associates a host with its MAC address so MAC rules apply to it.
+func (r *Resolver) LearnMAC(host, mac string) {
+	host = hostOnly(host)
+	hw, err := net.ParseMAC(mac)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.macs[host] = hw.String()
+}
+
+// Candidates returns the credentials to try for a host, in order (implements miner.CredentialResolver).
+func (r *Resolver) Candidates(host string, fw miner.FirmwareType) []miner.Credentials {
+	host = hostOnly(host)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var names []string
+	if name, ok := r.confirmed[confirmKey{host, fw}]; ok {
+		names = append(names, name)
+	}
+	if mac, ok := r.macs[host]; ok {
+		names = append(names, r.byMAC[mac]...)
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		names = append(names, r.byIP[ip.String()]...)
+		for _, c := range r.cidrs {
+			if c.network.Contains(ip) {
+				names = append(names, c.sets...)
+			}
+		}
+	}
+	names = append(names, r.defaults...)
+	if r.fallback != nil {
+		names = append(names, r.fallback.Name)
+	}
+
+	var result []miner.Credentials
+	seen := make(map[string]bool)
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if creds, ok := r.lookup(name).forFirmware(fw); ok {
+			result = append(result, creds)
+		}
+	}
+
+	return result
+}
+
+// lookup returns the named set, including the fallback set.
+func (r *Resolver) lookup(name string) Set {
+	if s, ok := r.sets[name]; ok {
+		return s
+	}
+	if r.fallback != nil && r.fallback.Name == name {
+		return *r.fallback
+	}
+	return Set{}
+}
+
+// Confirm records that creds authenticated against the host (implements miner.CredentialResolver).
+func (r *Resolver) Confirm(host string, fw miner.FirmwareType, creds miner.Credentials) {
+	host = hostOnly(host)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.confirmed[confirmKey{host, fw}] = creds.Name
+}
+
+// Confirmed returns the name of the set last confirmed for a host, or empty if none.
+func (r *Resolver) Confirmed(host string, fw miner.FirmwareType) string {
+	host = hostOnly(host)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.confirmed[confirmKey{host, fw}]
+}
+
+// Ensure Resolver implements miner.CredentialResolver.
+var _ miner.CredentialResolver = (*Resolver)(nil)
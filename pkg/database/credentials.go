@@ -0,0 +1,158 @@
+package database
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// credentialSchema creates the API key table. The store manages its own
+// table so it can share any SQLite database (powerhive.db, power-balancer.db).
+const credentialSchema = `
+CREATE TABLE IF NOT EXISTS vnish_api_keys (
+    host TEXT PRIMARY KEY,
+    api_key TEXT NOT NULL,          -- AES-256-GCM encrypted, base64
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// credentialKeySize is the AES-256 key size in bytes.
+const credentialKeySize = 32
+
+// SQLiteCredentialStore persists VNish API keys in SQLite, encrypted at rest
+// with a key read from a local key file.
+type SQLiteCredentialStore struct {
+	db   *sql.DB
+	aead cipher.AEAD
+}
+
+// NewSQLiteCredentialStore creates a credential store on an open database.
+// The key file holds a hex-encoded 256-bit key and is created if it doesn't exist.
+func NewSQLiteCredentialStore(db *sql.DB, keyFile string) (*SQLiteCredentialStore, error) {
+	key, err := loadOrCreateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	if _, err := db.Exec(credentialSchema); err != nil {
+		return nil, fmt.Errorf("failed to create credential schema: %w", err)
+	}
+
+	return &SQLiteCredentialStore{db: db, aead: aead}, nil
+}
+
+// loadOrCreateKey reads the encryption key from path, generating it on first use.
+func loadOrCreateKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key := make([]byte, credentialKeySize)
+		if _, err := rand.Read(key); err != nil {
+			return nil, fmt.Errorf("failed to generate key: %w", err)
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write key file: %w", err)
+		}
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != credentialKeySize {
+		return nil, fmt.Errorf("invalid key file %s: expected %d hex-encoded bytes", path, credentialKeySize)
+	}
+	return key, nil
+}
+
+// encrypt seals plaintext with a random nonce and returns base64(nonce || ciphertext).
+func (s *SQLiteCredentialStore) encrypt(plaintext string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt reverses encrypt.
+func (s *SQLiteCredentialStore) decrypt(encoded string) (string, error) {
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return "", errors.New("ciphertext too short")
+	}
+	plaintext, err := s.aead.Open(nil, sealed[:n], sealed[n:], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// LoadAPIKey returns the stored API key for a host, or empty if none is stored.
+func (s *SQLiteCredentialStore) LoadAPIKey(host string) (string, error) {
+	var encrypted string
+	err := s.db.QueryRow(`SELECT api_key FROM vnish_api_keys WHERE host = ?`, host).Scan(&encrypted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to load API key for %s: %w", host, err)
+	}
+
+	key, err := s.decrypt(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt API key for %s: %w", host, err)
+	}
+	return key, nil
+}
+
+// SaveAPIKey stores the API key for a host.
+func (s *SQLiteCredentialStore) SaveAPIKey(host, apiKey string) error {
+	encrypted, err := s.encrypt(apiKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt API key for %s: %w", host, err)
+	}
+
+	_, err = s.db.Exec(`
+		INSERT INTO vnish_api_keys (host, api_key, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(host) DO UPDATE SET api_key = excluded.api_key, updated_at = excluded.updated_at`,
+		host, encrypted, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save API key for %s: %w", host, err)
+	}
+	return nil
+}
+
+// DeleteAPIKey removes the stored API key for a host.
+func (s *SQLiteCredentialStore) DeleteAPIKey(host string) error {
+	if _, err := s.db.Exec(`DELETE FROM vnish_api_keys WHERE host = ?`, host); err != nil {
+		return fmt.Errorf("failed to delete API key for %s: %w", host, err)
+	}
+	return nil
+}
+
+// Ensure SQLiteCredentialStore implements vnish.CredentialStore.
+var _ vnish.CredentialStore = (*SQLiteCredentialStore)(nil)
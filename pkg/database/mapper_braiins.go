@@ -0,0 +1,278 @@
+package database
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/powerhive/powerhive-v2/pkg/braiins"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// BraiinsMapper converts Braiins OS API responses to database models.
+// Hashrates are stored in GH/s, matching the unit reported by the API.
+type BraiinsMapper struct{}
+
+// NewBraiinsMapper creates a new Braiins OS mapper.
+func NewBraiinsMapper() *BraiinsMapper {
+	return &BraiinsMapper{}
+}
+
+// MapMinerInfo converts Braiins MinerDetails to database Miner.
+func (m *BraiinsMapper) MapMinerInfo(details *braiins.MinerDetails, ipAddress string) *Miner {
+	name := details.MinerIdentity.Name
+	if name == "" {
+		name = details.MinerIdentity.MinerModel
+	}
+
+	return &Miner{
+		IPAddress:       ipAddress,
+		MACAddress:      details.MACAddress,
+		Hostname:        details.Hostname,
+		SerialNumber:    details.SerialNumber,
+		FirmwareType:    miner.FirmwareBraiins,
+		FirmwareVersion: details.BOSVersion.Current,
+		Model:           extractModelCode(name),
+		MinerType:       name,
+		Algorithm:       "sha256d",
+		Platform:        strings.ToLower(strings.TrimPrefix(details.Platform, "PLATFORM_")),
+		HRMeasure:       "GH/s",
+	}
+}
+
+// MapNetwork converts Braiins NetworkInfo to database MinerNetwork.
+func (m *BraiinsMapper) MapNetwork(info *braiins.NetworkInfo, minerID int64) *MinerNetwork {
+	n := &MinerNetwork{
+		MinerID:    minerID,
+		DHCP:       info.Protocol == "NETWORK_PROTOCOL_DHCP",
+		Gateway:    info.DefaultGateway,
+		DNSServers: strings.Join(info.DNSServers, ","),
+		NetDevice:  info.Name,
+	}
+
+	if len(info.Networks) > 0 {
+		n.IPAddress = info.Networks[0].Address
+		n.Netmask = info.Networks[0].Netmask
+	}
+
+	return n
+}
+
+// MapHardware converts Braiins hashboards, cooling state and PSU details to database MinerHardware.
+// Braiins tunes by power target, so voltage/frequency limits are left unset.
+func (m *BraiinsMapper) MapHardware(boards []braiins.Hashboard, cooling *braiins.CoolingState, details *braiins.MinerDetails, minerID int64) *MinerHardware {
+	hw := &MinerHardware{
+		MinerID:   minerID,
+		NumChains: len(boards),
+	}
+
+	for _, b := range boards {
+		hw.TotalAsicCount += b.ChipsCount
+		if hw.ChipsPerChain == 0 && b.ChipsCount > 0 {
+			hw.ChipsPerChain = b.ChipsCount
+		}
+	}
+
+	if cooling != nil {
+		hw.FanCount = len(cooling.Fans)
+	}
+
+	if details != nil && details.PSUInfo != nil {
+		hw.PSUModel = details.PSUInfo.ModelName
+		hw.PSUSerial = details.PSUInfo.SerialNumber
+	}
+
+	return hw
+}
+
+// MapStatus converts Braiins MinerDetails to database MinerStatus.
+func (m *BraiinsMapper) MapStatus(details *braiins.MinerDetails, minerID int64) *MinerStatus {
+	return &MinerStatus{
+		MinerID:       minerID,
+		State:         braiins.MapMinerState(details.Status),
+		StateTime:     details.BosminerUptimeS,
+		Description:   strings.TrimPrefix(details.Status, "MINER_STATUS_"),
+		UptimeSeconds: details.SystemUptimeS,
+	}
+}
+
+// MapSummary converts Braiins MinerStats, hashboards and cooling state to database MinerSummary.
+func (m *BraiinsMapper) MapSummary(stats *braiins.MinerStats, boards []braiins.Hashboard, cooling *braiins.CoolingState, minerID int64) *MinerSummary {
+	ms := stats.MinerStats
+	ps := stats.PoolStats
+	s := &MinerSummary{
+		MinerID:          minerID,
+		HashrateInstant:  ms.RealHashrate.Last5S.GigahashPerSecond,
+		HashrateAvg:      ms.RealHashrate.SinceRestart.GigahashPerSecond,
+		Hashrate5s:       ms.RealHashrate.Last5S.GigahashPerSecond,
+		Hashrate30m:      ms.RealHashrate.Last30M.GigahashPerSecond,
+		HashrateIdeal:    ms.IdealHashrate.GigahashPerSecond,
+		HashrateNominal:  ms.NominalHashrate.GigahashPerSecond,
+		PowerConsumption: stats.PowerStats.ApproximatedConsumption.Watt,
+		PowerEfficiency:  stats.PowerStats.Efficiency.JoulePerTerahash,
+		Accepted:         ps.AcceptedShares,
+		Rejected:         ps.RejectedShares,
+		Stale:            ps.StaleShares,
+		BestShare:        ps.BestShare,
+		FoundBlocks:      ps.FoundBlocks,
+	}
+
+	if ms.NominalHashrate.GigahashPerSecond > 0 {
+		s.HWErrorPercent = ms.ErrorHashrate.GigahashPerSecond / ms.NominalHashrate.GigahashPerSecond * 100
+	}
+
+	// Temperatures and HW errors come from the individual hashboards
+	for _, b := range boards {
+		pcb, chip := boardTemps(b)
+		s.PCBTempMin = minNonZero(s.PCBTempMin, pcb)
+		s.ChipTempMin = minNonZero(s.ChipTempMin, chip)
+		if pcb > s.PCBTempMax {
+			s.PCBTempMax = pcb
+		}
+		if chip > s.ChipTempMax {
+			s.ChipTempMax = chip
+		}
+		if b.Stats != nil {
+			s.HWErrors += b.Stats.HardwareErrors
+		}
+	}
+
+	if cooling != nil {
+		s.FanCount = len(cooling.Fans)
+		for _, f := range cooling.Fans {
+			if duty := int(f.TargetSpeedRatio * 100); duty > s.FanDuty {
+				s.FanDuty = duty
+			}
+		}
+	}
+
+	return s
+}
+
+// boardTemps returns the board (PCB) and highest chip temperature of a hashboard.
+func boardTemps(b braiins.Hashboard) (int, int) {
+	var pcb, chip int
+	if b.BoardTemp != nil {
+		pcb = int(b.BoardTemp.DegreeC)
+	}
+	if b.HighestChipTemp != nil {
+		chip = int(b.HighestChipTemp.Temperature.DegreeC)
+	}
+	return pcb, chip
+}
+
+// minNonZero returns the smaller of two values, ignoring zeros (missing readings).
+func minNonZero(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// MapChains converts Braiins Hashboard array to database MinerChain array.
+func (m *BraiinsMapper) MapChains(boards []braiins.Hashboard, minerID int64) []*MinerChain {
+	result := make([]*MinerChain, len(boards))
+	for i, b := range boards {
+		// Board IDs are 1-based strings; fall back to position if unparsable
+		index := i
+		if id, err := strconv.Atoi(b.ID); err == nil {
+			index = id - 1
+		}
+
+		pcb, chip := boardTemps(b)
+		chain := &MinerChain{
+			MinerID:      minerID,
+			ChainIndex:   index,
+			SerialNumber: b.SerialNumber,
+			FreqAvg:      int(b.CurrentFrequency.Hertz / 1e6),
+			AsicNum:      b.ChipsCount,
+			Voltage:      int(b.CurrentVoltage.Volt * 1000),
+			TempPCB:      pcb,
+			TempChip:     chip,
+		}
+
+		if b.Stats != nil {
+			chain.HashrateIdeal = b.Stats.NominalHashrate.GigahashPerSecond
+			chain.HashrateReal = b.Stats.RealHashrate.Last5S.GigahashPerSecond
+			chain.HWErrors = b.Stats.HardwareErrors
+		}
+
+		result[i] = chain
+	}
+	return result
+}
+
+// MapPools converts Braiins PoolGroup array to database MinerPool array.
+// Pools from all groups are flattened; pool_index follows group then pool order.
+func (m *BraiinsMapper) MapPools(groups []braiins.PoolGroup, minerID int64) []*MinerPool {
+	var result []*MinerPool
+	for _, g := range groups {
+		for _, p := range g.Pools {
+			status := "Dead"
+			if p.Alive {
+				status = "Alive"
+			}
+			if !p.Enabled {
+				status = "Disabled"
+			}
+
+			pool := &MinerPool{
+				MinerID:   minerID,
+				PoolIndex: len(result),
+				URL:       p.URL,
+				User:      p.User,
+				Status:    status,
+				Priority:  len(result),
+				PoolType:  g.Name,
+			}
+
+			if p.Stats != nil {
+				pool.Accepted = p.Stats.AcceptedShares
+				pool.Rejected = p.Stats.RejectedShares
+				pool.Stale = p.Stats.StaleShares
+				pool.Difficulty = strconv.FormatFloat(p.Stats.LastDifficulty, 'f', -1, 64)
+			}
+
+			result = append(result, pool)
+		}
+	}
+	return result
+}
+
+// MapFans converts Braiins CoolingState to database MinerFan array.
+func (m *BraiinsMapper) MapFans(cooling *braiins.CoolingState, minerID int64) []*MinerFan {
+	result := make([]*MinerFan, len(cooling.Fans))
+	for i, f := range cooling.Fans {
+		status := "ok"
+		if f.RPM == 0 {
+			status = "failed"
+		}
+		result[i] = &MinerFan{
+			MinerID:   minerID,
+			FanIndex:  f.Position,
+			RPM:       f.RPM,
+			DutyCycle: int(f.TargetSpeedRatio * 100),
+			Status:    status,
+		}
+	}
+	return result
+}
+
+// MapTargetProfiles converts Braiins tuner power target profiles to database AutotunePreset array.
+// Each profile is stored as a preset named after its power target in watts.
+func (m *BraiinsMapper) MapTargetProfiles(profiles []braiins.TuneProfile, currentTargetW int, minerID int64) []*AutotunePreset {
+	result := make([]*AutotunePreset, len(profiles))
+	for i, p := range profiles {
+		hashrateTH := p.MeasuredHashrate.GigahashPerSecond / 1000
+		result[i] = &AutotunePreset{
+			MinerID:        minerID,
+			Name:           strconv.Itoa(p.Target.Watt),
+			PrettyName:     fmt.Sprintf("%d watt ~ %.0f TH", p.Target.Watt, hashrateTH),
+			Status:         "tuned",
+			TargetPower:    p.Target.Watt,
+			TargetHashrate: hashrateTH,
+			IsCurrent:      p.Target.Watt == currentTargetW,
+		}
+	}
+	return result
+}
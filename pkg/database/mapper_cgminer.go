@@ -0,0 +1,128 @@
+package database
+
+import (
+	"strconv"
+
+	"github.com/powerhive/powerhive-v2/pkg/cgminer"
+)
+
+// CGMinerMapper converts cgminer TCP API responses to database models.
+// It is used as a fallback when a firmware's HTTP API is unavailable.
+// Hashrates are stored in GH/s.
+type CGMinerMapper struct{}
+
+// NewCGMinerMapper creates a new cgminer mapper.
+func NewCGMinerMapper() *CGMinerMapper {
+	return &CGMinerMapper{}
+}
+
+// MapStatus converts a cgminer SummaryResponse to database MinerStatus.
+func (m *CGMinerMapper) MapStatus(summary *cgminer.SummaryResponse, minerID int64) *MinerStatus {
+	s := &MinerStatus{
+		MinerID: minerID,
+		State:   "unknown",
+	}
+
+	if len(summary.Summary) > 0 {
+		data := summary.Summary[0]
+		s.State = cgminer.StateFromSummary(&data)
+		s.UptimeSeconds = data.Elapsed
+	}
+
+	return s
+}
+
+// MapSummary converts cgminer summary and stats responses to database MinerSummary.
+// stats may be nil.
+func (m *CGMinerMapper) MapSummary(summary *cgminer.SummaryResponse, stats *cgminer.StatsResponse, minerID int64) *MinerSummary {
+	s := &MinerSummary{MinerID: minerID}
+
+	if len(summary.Summary) > 0 {
+		data := summary.Summary[0]
+		s.HashrateInstant = data.Hashrate5s()
+		s.Hashrate5s = data.Hashrate5s()
+		s.HashrateAvg = data.HashrateAvg()
+		s.Hashrate30m = float64(data.GHS30m)
+		s.HWErrors = data.HardwareErrors
+		s.HWErrorPercent = float64(data.DeviceHWPercent)
+		s.Accepted = data.Accepted
+		s.Rejected = data.Rejected
+		s.Stale = data.Stale
+		s.BestShare = data.BestShare
+		s.FoundBlocks = data.FoundBlocks
+	}
+
+	if stats != nil {
+		for _, c := range stats.Chains() {
+			if c.TempPCB > s.PCBTempMax {
+				s.PCBTempMax = c.TempPCB
+			}
+			if c.TempChip > s.ChipTempMax {
+				s.ChipTempMax = c.TempChip
+			}
+		}
+		s.FanCount = len(stats.Fans())
+	}
+
+	return s
+}
+
+// MapChains converts cgminer stats to database MinerChain array.
+func (m *CGMinerMapper) MapChains(stats *cgminer.StatsResponse, minerID int64) []*MinerChain {
+	chains := stats.Chains()
+	result := make([]*MinerChain, len(chains))
+	for i, c := range chains {
+		result[i] = &MinerChain{
+			MinerID:      minerID,
+			ChainIndex:   c.Index,
+			FreqAvg:      c.FreqAvg,
+			HashrateReal: c.Rate,
+			AsicNum:      c.AsicNum,
+			TempPCB:      c.TempPCB,
+			TempChip:     c.TempChip,
+			HWErrors:     c.HWErrors,
+		}
+	}
+	return result
+}
+
+// MapPools converts cgminer Pool array to database MinerPool array.
+func (m *CGMinerMapper) MapPools(pools []cgminer.Pool, minerID int64) []*MinerPool {
+	result := make([]*MinerPool, len(pools))
+	for i, p := range pools {
+		result[i] = &MinerPool{
+			MinerID:    minerID,
+			PoolIndex:  p.Index,
+			URL:        p.URL,
+			User:       p.User,
+			Status:     p.Status,
+			Priority:   p.Priority,
+			Accepted:   p.Accepted,
+			Rejected:   p.Rejected,
+			Stale:      p.Stale,
+			Discarded:  p.Discarded,
+			Difficulty: strconv.FormatFloat(float64(p.LastShareDifficulty), 'f', -1, 64),
+			DiffA:      float64(p.DifficultyAccepted),
+		}
+	}
+	return result
+}
+
+// MapFans converts cgminer stats to database MinerFan array.
+func (m *CGMinerMapper) MapFans(stats *cgminer.StatsResponse, minerID int64) []*MinerFan {
+	fans := stats.Fans()
+	result := make([]*MinerFan, len(fans))
+	for i, rpm := range fans {
+		status := "ok"
+		if rpm == 0 {
+			status = "failed"
+		}
+		result[i] = &MinerFan{
+			MinerID:  minerID,
+			FanIndex: i,
+			RPM:      rpm,
+			Status:   status,
+		}
+	}
+	return result
+}
@@ -0,0 +1,193 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/whatsminer"
+)
+
+// WhatsminerMapper converts Whatsminer API responses to database models.
+// The API reports hashrates in MH/s; they are stored in GH/s.
+type WhatsminerMapper struct{}
+
+// NewWhatsminerMapper creates a new Whatsminer mapper.
+func NewWhatsminerMapper() *WhatsminerMapper {
+	return &WhatsminerMapper{}
+}
+
+// mhsToGHS converts MH/s to GH/s.
+func mhsToGHS(mhs float64) float64 {
+	return mhs / 1000
+}
+
+// MapMinerInfo converts Whatsminer version, model and network info to database Miner.
+// network may be nil; the MAC then falls back to the one reported in the summary.
+func (m *WhatsminerMapper) MapMinerInfo(version *whatsminer.Version, model string, network *whatsminer.MinerInfo, summary *whatsminer.Summary, ipAddress string) *Miner {
+	mi := &Miner{
+		IPAddress:       ipAddress,
+		FirmwareType:    miner.FirmwareWhatsminer,
+		FirmwareVersion: version.FWVersion,
+		Model:           strings.ToLower(model),
+		MinerType:       strings.TrimSpace("WhatsMiner " + model),
+		Algorithm:       "sha256d",
+		Platform:        strings.ToLower(version.Platform),
+		HRMeasure:       "GH/s",
+	}
+
+	if network != nil {
+		mi.MACAddress = network.MAC
+		mi.Hostname = network.Hostname
+	}
+	if mi.MACAddress == "" && summary != nil {
+		mi.MACAddress = summary.MAC
+	}
+
+	return mi
+}
+
+// MapNetwork converts Whatsminer MinerInfo to database MinerNetwork.
+func (m *WhatsminerMapper) MapNetwork(info *whatsminer.MinerInfo, minerID int64) *MinerNetwork {
+	return &MinerNetwork{
+		MinerID:    minerID,
+		DHCP:       info.Proto == "dhcp",
+		IPAddress:  info.IP,
+		Netmask:    info.Netmask,
+		Gateway:    info.Gateway,
+		DNSServers: strings.Join(strings.Fields(info.DNS), ","),
+	}
+}
+
+// MapHardware converts Whatsminer devs and PSU info to database MinerHardware.
+// psu may be nil.
+func (m *WhatsminerMapper) MapHardware(devs []whatsminer.Dev, psu *whatsminer.PSUInfo, minerID int64) *MinerHardware {
+	hw := &MinerHardware{
+		MinerID:   minerID,
+		NumChains: len(devs),
+		FanCount:  2, // Whatsminers have an intake and an exhaust fan
+	}
+
+	for _, d := range devs {
+		hw.TotalAsicCount += d.EffectiveChips
+		if hw.ChipsPerChain == 0 && d.EffectiveChips > 0 {
+			hw.ChipsPerChain = d.EffectiveChips
+		}
+	}
+
+	if psu != nil {
+		hw.PSUModel = psu.Model
+		hw.PSUSerial = psu.SerialNo
+	}
+
+	return hw
+}
+
+// MapStatus converts a Whatsminer Summary to database MinerStatus.
+// state may be nil.
+func (m *WhatsminerMapper) MapStatus(summary *whatsminer.Summary, state *whatsminer.MinerState, minerID int64) *MinerStatus {
+	s := &MinerStatus{
+		MinerID:       minerID,
+		State:         whatsminer.StateFromSummary(summary),
+		StateTime:     summary.Elapsed,
+		Description:   summary.PowerMode,
+		UptimeSeconds: summary.Uptime,
+	}
+
+	if state != nil && state.BTMinerOff == "true" {
+		s.State = "stopped"
+	}
+
+	return s
+}
+
+// MapSummary converts Whatsminer Summary and devs to database MinerSummary.
+func (m *WhatsminerMapper) MapSummary(summary *whatsminer.Summary, devs []whatsminer.Dev, minerID int64) *MinerSummary {
+	s := &MinerSummary{
+		MinerID:          minerID,
+		HashrateInstant:  mhsToGHS(summary.MHS5s),
+		HashrateAvg:      mhsToGHS(summary.MHSAv),
+		Hashrate5s:       mhsToGHS(summary.MHS5s),
+		Hashrate30m:      mhsToGHS(summary.MHS15m), // Closest window reported by btminer
+		HashrateIdeal:    summary.FactoryGHS,
+		HashrateNominal:  summary.FactoryGHS,
+		PowerConsumption: summary.Power,
+		PowerEfficiency:  summary.PowerRate,
+		ChipTempMin:      int(summary.ChipTempMin),
+		ChipTempMax:      int(summary.ChipTempMax),
+		Accepted:         summary.Accepted,
+		Rejected:         summary.Rejected,
+		FanCount:         2,
+	}
+
+	// Board temperature is per hashboard
+	for _, d := range devs {
+		t := int(d.Temperature)
+		s.PCBTempMin = minNonZero(s.PCBTempMin, t)
+		if t > s.PCBTempMax {
+			s.PCBTempMax = t
+		}
+	}
+
+	return s
+}
+
+// MapChains converts Whatsminer Dev array to database MinerChain array.
+func (m *WhatsminerMapper) MapChains(devs []whatsminer.Dev, minerID int64) []*MinerChain {
+	result := make([]*MinerChain, len(devs))
+	for i, d := range devs {
+		result[i] = &MinerChain{
+			MinerID:       minerID,
+			ChainIndex:    d.Slot,
+			SerialNumber:  d.PCBSN,
+			FreqAvg:       d.ChipFrequency,
+			HashrateIdeal: d.FactoryGHS,
+			HashrateReal:  mhsToGHS(d.MHS5s),
+			AsicNum:       d.EffectiveChips,
+			TempPCB:       int(d.Temperature),
+			TempChip:      int(d.ChipTempMax),
+		}
+	}
+	return result
+}
+
+// MapPools converts Whatsminer Pool array to database MinerPool array.
+func (m *WhatsminerMapper) MapPools(pools []whatsminer.Pool, minerID int64) []*MinerPool {
+	result := make([]*MinerPool, len(pools))
+	for i, p := range pools {
+		result[i] = &MinerPool{
+			MinerID:    minerID,
+			PoolIndex:  p.Index,
+			URL:        p.URL,
+			User:       p.User,
+			Status:     p.Status,
+			Priority:   p.Priority,
+			Accepted:   p.Accepted,
+			Rejected:   p.Rejected,
+			Stale:      p.Stale,
+			Discarded:  p.Discarded,
+			Difficulty: strconv.FormatFloat(p.LastShareDifficulty, 'f', -1, 64),
+			DiffA:      p.DifficultyAccepted,
+		}
+	}
+	return result
+}
+
+// MapFans converts the Whatsminer intake/exhaust fan speeds to database MinerFan array.
+func (m *WhatsminerMapper) MapFans(summary *whatsminer.Summary, minerID int64) []*MinerFan {
+	rpms := []int{summary.FanSpeedIn, summary.FanSpeedOut}
+	result := make([]*MinerFan, len(rpms))
+	for i, rpm := range rpms {
+		status := "ok"
+		if rpm == 0 {
+			status = "failed"
+		}
+		result[i] = &MinerFan{
+			MinerID:  minerID,
+			FanIndex: i,
+			RPM:      rpm,
+			Status:   status,
+		}
+	}
+	return result
+}
@@ -126,6 +126,18 @@ type MinerSummary struct {
 	UpdatedAt        time.Time `json:"updated_at"`
 }
 
+// PresetSummary is a miner's current summary with the autotune preset it was
+// harvested on (VNish).
+type PresetSummary struct {
+	MinerID          int64     `json:"miner_id"`
+	MACAddress       string    `json:"mac_address"`
+	HRMeasure        string    `json:"hr_measure"` // Unit of HashrateInstant
+	Preset           string    `json:"preset"`
+	HashrateInstant  float64   `json:"hashrate_instant"`
+	PowerConsumption int       `json:"power_consumption"` // Watts
+	UpdatedAt        time.Time `json:"updated_at"`        // When the summary was harvested
+}
+
 // MinerChain represents a hash board (chain) in a miner.
 type MinerChain struct {
 	ID            int64     `json:"id"`
@@ -240,6 +252,27 @@ type MinerNote struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// MinerCredential records which credential set last authenticated against a miner.
+type MinerCredential struct {
+	MinerID       int64     `json:"miner_id"`
+	CredentialSet string    `json:"credential_set"`
+	VerifiedAt    time.Time `json:"verified_at"`
+}
+
+// MinerBackup is a VNish settings backup.
+// Identical backups of the same miner are stored once; LastSeenAt is refreshed instead.
+type MinerBackup struct {
+	ID              int64     `json:"id"`
+	MACAddress      string    `json:"mac_address"`
+	IPAddress       string    `json:"ip_address"`
+	FirmwareVersion string    `json:"firmware_version"`
+	ContentHash     string    `json:"content_hash"`
+	Size            int       `json:"size"`
+	Data            []byte    `json:"-"` // Not loaded by ListMinerBackups
+	CreatedAt       time.Time `json:"created_at"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+}
+
 // MinerLogSession represents a boot cycle for a miner.
 // Each time a miner reboots, a new session is created.
 type MinerLogSession struct {
@@ -53,6 +53,7 @@ type Repository interface {
 	GetMinerSummary(ctx context.Context, minerID int64) (*MinerSummary, error)
 	UpsertMinerSummary(ctx context.Context, s *MinerSummary) error
 	ZeroMinerSummary(ctx context.Context, minerID int64) error
+	ListPresetSummaries(ctx context.Context) ([]*PresetSummary, error)
 
 	// Chains
 	GetMinerChains(ctx context.Context, minerID int64) ([]*MinerChain, error)
@@ -93,6 +94,17 @@ type Repository interface {
 	UpsertMinerNote(ctx context.Context, n *MinerNote) error
 	DeleteMinerNote(ctx context.Context, minerID int64, key string) error
 
+	// Credentials
+	GetMinerCredential(ctx context.Context, minerID int64) (*MinerCredential, error)
+	UpsertMinerCredential(ctx context.Context, c *MinerCredential) error
+
+	// Backups (VNish)
+	SaveMinerBackup(ctx context.Context, b *MinerBackup) (created bool, err error)
+	GetMinerBackup(ctx context.Context, id int64) (*MinerBackup, error)
+	GetLatestMinerBackup(ctx context.Context, mac string) (*MinerBackup, error)
+	ListMinerBackups(ctx context.Context, mac string) ([]*MinerBackup, error)
+	PruneMinerBackups(ctx context.Context, mac string, keep int) error
+
 	// Log Sessions
 	GetCurrentLogSession(ctx context.Context, minerID int64) (*MinerLogSession, error)
 	GetLogSessionByBootTime(ctx context.Context, minerID int64, bootTime time.Time) (*MinerLogSession, error)
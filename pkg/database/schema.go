@@ -11,7 +11,7 @@ CREATE TABLE IF NOT EXISTS miners (
     ip_address TEXT NOT NULL,          -- Current IP (can change)
     hostname TEXT,
     serial_number TEXT,
-    firmware_type TEXT NOT NULL, -- 'vnish', 'stock', 'braiins', 'unknown'
+    firmware_type TEXT NOT NULL, -- 'vnish', 'stock', 'braiins', 'whatsminer', 'unknown'
     firmware_version TEXT,
     model TEXT,                  -- e.g., "s19", "ks5"
     miner_type TEXT,             -- Full name e.g., "Antminer S19"
@@ -248,6 +248,30 @@ CREATE TABLE IF NOT EXISTS miner_notes (
 
 CREATE INDEX IF NOT EXISTS idx_miner_notes_miner ON miner_notes(miner_id);
 
+-- Credential set that last authenticated against each miner (see pkg/credentials)
+CREATE TABLE IF NOT EXISTS miner_credentials (
+    miner_id INTEGER PRIMARY KEY,
+    credential_set TEXT NOT NULL,
+    verified_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE
+);
+
+-- VNish settings backups, deduplicated by content
+CREATE TABLE IF NOT EXISTS miner_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mac_address TEXT NOT NULL,        -- Keyed by MAC so backups outlive the miner row
+    ip_address TEXT,                  -- IP at backup time
+    firmware_version TEXT,
+    content_hash TEXT NOT NULL,       -- SHA-256 of the archive contents (see vnish.BackupHash)
+    size INTEGER NOT NULL,
+    data BLOB NOT NULL,               -- tar.gz from /settings/backup
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- Last time the miner had this exact config
+    UNIQUE(mac_address, content_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_miner_backups_mac ON miner_backups(mac_address, last_seen_at);
+
 -- Log sessions (one per boot cycle)
 -- Each time a miner reboots, a new session is created
 CREATE TABLE IF NOT EXISTS miner_log_sessions (
@@ -288,10 +312,32 @@ CREATE TABLE IF NOT EXISTS schema_version (
 `
 
 // SchemaVersion is the current schema version.
-const SchemaVersion = 1
+const SchemaVersion = 3
 
 // Migrations contains SQL migrations indexed by version.
 // Each migration upgrades from version N-1 to version N.
 var Migrations = map[int]string{
 	1: Schema, // Initial schema
+	2: `
+CREATE TABLE IF NOT EXISTS miner_credentials (
+    miner_id INTEGER PRIMARY KEY,
+    credential_set TEXT NOT NULL,
+    verified_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (miner_id) REFERENCES miners(id) ON DELETE CASCADE
+);`,
+	3: `
+CREATE TABLE IF NOT EXISTS miner_backups (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    mac_address TEXT NOT NULL,        -- Keyed by MAC so backups outlive the miner row
+    ip_address TEXT,                  -- IP at backup time
+    firmware_version TEXT,
+    content_hash TEXT NOT NULL,       -- SHA-256 of the archive contents (see vnish.BackupHash)
+    size INTEGER NOT NULL,
+    data BLOB NOT NULL,               -- tar.gz from /settings/backup
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME DEFAULT CURRENT_TIMESTAMP, -- Last time the miner had this exact config
+    UNIQUE(mac_address, content_hash)
+);
+
+CREATE INDEX IF NOT EXISTS idx_miner_backups_mac ON miner_backups(mac_address, last_seen_at);`,
 }
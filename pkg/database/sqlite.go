@@ -502,6 +502,35 @@ func (r *SQLiteRepository) ZeroMinerSummary(ctx context.Context, minerID int64)
 	return err
 }
 
+// ListPresetSummaries returns the summaries of online miners along with the
+// autotune preset harvested with them. Summaries whose preset wasn't harvested
+// with them (or since) are left out, as the preset may have changed.
+func (r *SQLiteRepository) ListPresetSummaries(ctx context.Context) ([]*PresetSummary, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT m.id, m.mac_address, COALESCE(m.hr_measure, ''), p.name,
+			COALESCE(s.hashrate_instant, 0), COALESCE(s.power_consumption, 0), s.updated_at
+		FROM miners m
+		JOIN miner_summary s ON s.miner_id = m.id
+		JOIN autotune_presets p ON p.miner_id = m.id AND p.is_current = 1
+		WHERE m.is_online = 1 AND p.updated_at >= s.updated_at
+		ORDER BY m.id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var summaries []*PresetSummary
+	for rows.Next() {
+		s := &PresetSummary{}
+		if err := rows.Scan(&s.MinerID, &s.MACAddress, &s.HRMeasure, &s.Preset,
+			&s.HashrateInstant, &s.PowerConsumption, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
 // =============================================================================
 // Chains
 // =============================================================================
@@ -954,6 +983,139 @@ func (r *SQLiteRepository) DeleteMinerNote(ctx context.Context, minerID int64, k
 	return err
 }
 
+// =============================================================================
+// Credentials
+// =============================================================================
+
+func (r *SQLiteRepository) GetMinerCredential(ctx context.Context, minerID int64) (*MinerCredential, error) {
+	c := &MinerCredential{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT miner_id, credential_set, verified_at
+		FROM miner_credentials WHERE miner_id = ?`, minerID).Scan(
+		&c.MinerID, &c.CredentialSet, &c.VerifiedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return c, err
+}
+
+func (r *SQLiteRepository) UpsertMinerCredential(ctx context.Context, c *MinerCredential) error {
+	c.VerifiedAt = time.Now()
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO miner_credentials (miner_id, credential_set, verified_at)
+		VALUES (?, ?, ?)
+		ON CONFLICT(miner_id) DO UPDATE SET
+			credential_set = excluded.credential_set, verified_at = excluded.verified_at`,
+		c.MinerID, c.CredentialSet, c.VerifiedAt)
+	return err
+}
+
+// =============================================================================
+// Backups
+// =============================================================================
+
+// SaveMinerBackup stores a backup unless the miner already has one with the same
+// content hash, in which case only its last_seen_at is refreshed.
+// Returns true if a new backup was stored.
+func (r *SQLiteRepository) SaveMinerBackup(ctx context.Context, b *MinerBackup) (bool, error) {
+	now := time.Now()
+	b.LastSeenAt = now
+
+	var id int64
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id FROM miner_backups WHERE mac_address = ? AND content_hash = ?`,
+		b.MACAddress, b.ContentHash).Scan(&id)
+	if err == nil {
+		b.ID = id
+		_, err = r.db.ExecContext(ctx, `
+			UPDATE miner_backups SET last_seen_at = ?, ip_address = ? WHERE id = ?`,
+			now, b.IPAddress, id)
+		return false, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	b.CreatedAt = now
+	b.Size = len(b.Data)
+	result, err := r.db.ExecContext(ctx, `
+		INSERT INTO miner_backups (mac_address, ip_address, firmware_version, content_hash,
+			size, data, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		b.MACAddress, b.IPAddress, b.FirmwareVersion, b.ContentHash,
+		b.Size, b.Data, b.CreatedAt, b.LastSeenAt)
+	if err != nil {
+		return false, err
+	}
+	b.ID, _ = result.LastInsertId()
+	return true, nil
+}
+
+func (r *SQLiteRepository) GetMinerBackup(ctx context.Context, id int64) (*MinerBackup, error) {
+	b := &MinerBackup{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, mac_address, ip_address, firmware_version, content_hash, size, data,
+			created_at, last_seen_at
+		FROM miner_backups WHERE id = ?`, id).Scan(
+		&b.ID, &b.MACAddress, &b.IPAddress, &b.FirmwareVersion, &b.ContentHash, &b.Size, &b.Data,
+		&b.CreatedAt, &b.LastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// GetLatestMinerBackup returns the most recently seen backup for a MAC address, including its data.
+func (r *SQLiteRepository) GetLatestMinerBackup(ctx context.Context, mac string) (*MinerBackup, error) {
+	b := &MinerBackup{}
+	err := r.db.QueryRowContext(ctx, `
+		SELECT id, mac_address, ip_address, firmware_version, content_hash, size, data,
+			created_at, last_seen_at
+		FROM miner_backups WHERE mac_address = ?
+		ORDER BY last_seen_at DESC, id DESC LIMIT 1`, mac).Scan(
+		&b.ID, &b.MACAddress, &b.IPAddress, &b.FirmwareVersion, &b.ContentHash, &b.Size, &b.Data,
+		&b.CreatedAt, &b.LastSeenAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return b, err
+}
+
+// ListMinerBackups returns the backups for a MAC address, most recently seen first.
+// Backup data is not loaded.
+func (r *SQLiteRepository) ListMinerBackups(ctx context.Context, mac string) ([]*MinerBackup, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, mac_address, ip_address, firmware_version, content_hash, size,
+			created_at, last_seen_at
+		FROM miner_backups WHERE mac_address = ?
+		ORDER BY last_seen_at DESC, id DESC`, mac)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []*MinerBackup
+	for rows.Next() {
+		b := &MinerBackup{}
+		if err := rows.Scan(&b.ID, &b.MACAddress, &b.IPAddress, &b.FirmwareVersion, &b.ContentHash,
+			&b.Size, &b.CreatedAt, &b.LastSeenAt); err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	return backups, rows.Err()
+}
+
+// PruneMinerBackups keeps only the keep most recently seen backups for a MAC address.
+func (r *SQLiteRepository) PruneMinerBackups(ctx context.Context, mac string, keep int) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM miner_backups WHERE mac_address = ? AND id NOT IN (
+			SELECT id FROM miner_backups WHERE mac_address = ?
+			ORDER BY last_seen_at DESC, id DESC LIMIT ?)`,
+		mac, mac, keep)
+	return err
+}
+
 // =============================================================================
 // Log Sessions
 // =============================================================================
@@ -0,0 +1,152 @@
+package discovery_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/discovery"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// serve serves h and returns its host.
+func serve(t *testing.T, h http.Handler) string {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return strings.TrimPrefix(srv.URL, "http://")
+}
+
+// closedHost returns a loopback host with nothing listening.
+func closedHost(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	host := l.Addr().String()
+	l.Close()
+	return host
+}
+
+func TestDetectMiner(t *testing.T) {
+	cfg := minersim.DefaultConfig()
+	newMiner := func(fw miner.FirmwareType) *minersim.Miner {
+		spec := minersim.MinerSpec{
+			IP:              "127.0.0.1",
+			Firmware:        fw,
+			Model:           cfg.VNishModel,
+			FirmwareVersion: cfg.VNishVersion,
+			MAC:             "02:00:7f:00:00:01",
+			Hostname:        "sim-vnish-001",
+			Password:        cfg.VNishPassword,
+		}
+		if fw == miner.FirmwareStock {
+			spec.Model = cfg.StockModel
+			spec.FirmwareVersion = cfg.StockVersion
+			spec.MAC = "02:00:7f:00:00:02"
+			spec.Hostname = "sim-stock-001"
+			spec.Username = cfg.StockUsername
+			spec.Password = cfg.StockPassword
+		}
+		return minersim.NewMiner(spec, cfg.Timing, 1)
+	}
+
+	notMiner := http.NewServeMux()
+	notMiner.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html>router login</html>"))
+	})
+
+	tests := []struct {
+		name    string
+		host    string
+		want    *discovery.DiscoveredMiner
+		wantErr bool
+	}{
+		{
+			name: "vnish",
+			host: serve(t, newMiner(miner.FirmwareVNish).Handler()),
+			want: &discovery.DiscoveredMiner{
+				Hostname:        "sim-vnish-001",
+				MAC:             "02:00:7f:00:00:01",
+				Model:           "Antminer S19",
+				Firmware:        "Vnish",
+				FirmwareVersion: cfg.VNishVersion,
+				Algorithm:       "sha256d",
+				State:           minersim.StateMining,
+				FirmwareType:    miner.FirmwareVNish,
+			},
+		},
+		{
+			name: "stock",
+			host: serve(t, newMiner(miner.FirmwareStock).Handler()),
+			want: &discovery.DiscoveredMiner{
+				Hostname:        "sim-stock-001",
+				MAC:             "02:00:7f:00:00:02",
+				Model:           "Antminer S19k Pro",
+				Series:          "x19",
+				Firmware:        "Stock",
+				FirmwareVersion: cfg.StockVersion,
+				Algorithm:       "sha256d",
+				State:           "running",
+				FirmwareType:    miner.FirmwareStock,
+			},
+		},
+		{name: "not a miner", host: serve(t, notMiner), wantErr: true},
+		{name: "nothing listening", host: closedHost(t), wantErr: true},
+	}
+
+	detector := discovery.NewDetector([]miner.FirmwareProber{
+		vnish.NewProber(vnish.NewAuthManager(cfg.VNishPassword)),
+		stock.NewProber(stock.NewDigestAuthWithCredentials(cfg.StockUsername, cfg.StockPassword)),
+	}, discovery.WithDetectorTimeout(2*time.Second))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := detector.DetectMiner(context.Background(), tt.host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DetectMiner: err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			tt.want.IP = tt.host
+			tt.want.DiscoveredAt = got.DiscoveredAt
+			if *got != *tt.want {
+				t.Errorf("got %+v\nwant %+v", *got, *tt.want)
+			}
+
+			fw, err := detector.DetectFirmwareType(context.Background(), tt.host)
+			if err != nil || fw != tt.want.FirmwareType {
+				t.Errorf("DetectFirmwareType = %s, %v, want %s", fw, err, tt.want.FirmwareType)
+			}
+		})
+	}
+}
+
+func TestDetectMinerWrongCredentials(t *testing.T) {
+	cfg := minersim.DefaultConfig()
+	m := minersim.NewMiner(minersim.MinerSpec{
+		IP:       "127.0.0.1",
+		Firmware: miner.FirmwareStock,
+		Username: "root",
+		Password: "s3cret",
+	}, cfg.Timing, 1)
+	host := serve(t, m.Handler())
+
+	detector := discovery.NewDetector([]miner.FirmwareProber{
+		stock.NewProber(stock.NewDigestAuth()),
+	}, discovery.WithDetectorTimeout(2*time.Second))
+
+	if _, err := detector.DetectMiner(context.Background(), host); err == nil {
+		t.Error("expected detection to fail with the wrong digest credentials")
+	}
+}
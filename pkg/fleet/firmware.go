@@ -0,0 +1,283 @@
+package fleet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+)
+
+// Compliance is the result of checking a miner's firmware against the policy.
+type Compliance string
+
+const (
+	ComplianceOK           Compliance = "compliant"
+	ComplianceNonCompliant Compliance = "non_compliant"
+	ComplianceNoPolicy     Compliance = "no_policy" // No rule covers the miner
+)
+
+// PolicyRule lists the firmware versions allowed for a firmware type,
+// optionally narrowed to a model and platform. Versions may use
+// path.Match wildcards (e.g., "1.2.*").
+type PolicyRule struct {
+	Firmware string   `json:"firmware"`           // "vnish", "stock", ...
+	Model    string   `json:"model,omitempty"`    // Matches Model or MinerType, case-insensitive
+	Platform string   `json:"platform,omitempty"` // VNish: "xil", "aml", ...
+	Allowed  []string `json:"allowed"`
+}
+
+// specificity ranks rules so the most specific matching rule wins.
+func (r *PolicyRule) specificity() int {
+	n := 0
+	if r.Model != "" {
+		n += 2
+	}
+	if r.Platform != "" {
+		n++
+	}
+	return n
+}
+
+// matches returns true if the rule applies to the miner.
+func (r *PolicyRule) matches(m *database.Miner) bool {
+	if !strings.EqualFold(r.Firmware, string(m.FirmwareType)) {
+		return false
+	}
+	if r.Model != "" && !strings.EqualFold(r.Model, m.Model) && !strings.EqualFold(r.Model, m.MinerType) {
+		return false
+	}
+	if r.Platform != "" && !strings.EqualFold(r.Platform, m.Platform) {
+		return false
+	}
+	return true
+}
+
+// allows returns true if the version is allowed by the rule.
+func (r *PolicyRule) allows(version string) bool {
+	if version == "" {
+		return false
+	}
+	for _, pattern := range r.Allowed {
+		if ok, _ := path.Match(pattern, version); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// FirmwarePolicy is the allowed-version policy:
+//
+//	{
+//	  "rules": [
+//	    {"firmware": "vnish", "allowed": ["1.2.6", "1.2.7"]},
+//	    {"firmware": "vnish", "model": "Antminer S19j Pro", "platform": "aml", "allowed": ["1.2.6"]},
+//	    {"firmware": "stock", "model": "Antminer S19", "allowed": ["Wed Aug 28 * 2024"]}
+//	  ]
+//	}
+type FirmwarePolicy struct {
+	Rules []PolicyRule `json:"rules"`
+}
+
+// LoadFirmwarePolicy reads and validates a policy file.
+func LoadFirmwarePolicy(path string) (*FirmwarePolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read firmware policy: %w", err)
+	}
+
+	var p FirmwarePolicy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse firmware policy %s: %w", path, err)
+	}
+	for i, r := range p.Rules {
+		if r.Firmware == "" {
+			return nil, fmt.Errorf("rule %d: firmware is required", i)
+		}
+		if len(r.Allowed) == 0 {
+			return nil, fmt.Errorf("rule %d: no allowed versions", i)
+		}
+	}
+	return &p, nil
+}
+
+// Check returns the compliance of a miner and the rule applied, if any.
+// A nil policy has no rules.
+func (p *FirmwarePolicy) Check(m *database.Miner) (Compliance, *PolicyRule) {
+	if p == nil {
+		return ComplianceNoPolicy, nil
+	}
+
+	var best *PolicyRule
+	for i := range p.Rules {
+		r := &p.Rules[i]
+		if r.matches(m) && (best == nil || r.specificity() > best.specificity()) {
+			best = r
+		}
+	}
+	if best == nil {
+		return ComplianceNoPolicy, nil
+	}
+	if best.allows(m.FirmwareVersion) {
+		return ComplianceOK, best
+	}
+	return ComplianceNonCompliant, best
+}
+
+// =============================================================================
+// Report
+// =============================================================================
+
+// FirmwareGroup is a set of miners with the same firmware, version, model and platform.
+type FirmwareGroup struct {
+	FirmwareType    string     `json:"firmware_type"`
+	FirmwareVersion string     `json:"firmware_version"`
+	Model           string     `json:"model"`
+	Platform        string     `json:"platform"`
+	Count           int        `json:"count"`
+	Online          int        `json:"online"`
+	Compliance      Compliance `json:"compliance"`
+	Allowed         []string   `json:"allowed,omitempty"`
+}
+
+// FirmwareViolation is a miner running a firmware version outside the policy.
+type FirmwareViolation struct {
+	MinerID         int64     `json:"miner_id"`
+	IPAddress       string    `json:"ip_address"`
+	MACAddress      string    `json:"mac_address"`
+	Hostname        string    `json:"hostname"`
+	MinerType       string    `json:"miner_type"`
+	Model           string    `json:"model"`
+	Platform        string    `json:"platform"`
+	FirmwareType    string    `json:"firmware_type"`
+	FirmwareVersion string    `json:"firmware_version"`
+	Allowed         []string  `json:"allowed"`
+	Online          bool      `json:"online"`
+	LastSeenAt      time.Time `json:"last_seen_at"`
+}
+
+// FirmwareReport is the firmware inventory of a set of miners.
+type FirmwareReport struct {
+	GeneratedAt  time.Time            `json:"generated_at"`
+	Total        int                  `json:"total"`
+	Compliant    int                  `json:"compliant"`
+	NonCompliant int                  `json:"non_compliant"`
+	NoPolicy     int                  `json:"no_policy"`
+	Groups       []*FirmwareGroup     `json:"groups"`
+	Violations   []*FirmwareViolation `json:"violations"`
+}
+
+// BuildFirmwareReport groups miners by firmware and checks them against the policy.
+// policy may be nil, in which case every miner is reported as ComplianceNoPolicy.
+func BuildFirmwareReport(miners []*database.Miner, policy *FirmwarePolicy) *FirmwareReport {
+	report := &FirmwareReport{GeneratedAt: time.Now(), Total: len(miners)}
+
+	type groupKey struct {
+		fw, version, model, platform string
+	}
+	groups := make(map[groupKey]*FirmwareGroup)
+
+	for _, m := range miners {
+		compliance, rule := policy.Check(m)
+
+		var allowed []string
+		if rule != nil {
+			allowed = rule.Allowed
+		}
+
+		switch compliance {
+		case ComplianceOK:
+			report.Compliant++
+		case ComplianceNonCompliant:
+			report.NonCompliant++
+			report.Violations = append(report.Violations, &FirmwareViolation{
+				MinerID:         m.ID,
+				IPAddress:       m.IPAddress,
+				MACAddress:      m.MACAddress,
+				Hostname:        m.Hostname,
+				MinerType:       m.MinerType,
+				Model:           m.Model,
+				Platform:        m.Platform,
+				FirmwareType:    string(m.FirmwareType),
+				FirmwareVersion: m.FirmwareVersion,
+				Allowed:         allowed,
+				Online:          m.IsOnline,
+				LastSeenAt:      m.LastSeenAt,
+			})
+		default:
+			report.NoPolicy++
+		}
+
+		key := groupKey{string(m.FirmwareType), m.FirmwareVersion, m.Model, m.Platform}
+		g, ok := groups[key]
+		if !ok {
+			// Model, platform and version are shared by the group, so the first result applies to all
+			g = &FirmwareGroup{
+				FirmwareType:    key.fw,
+				FirmwareVersion: key.version,
+				Model:           key.model,
+				Platform:        key.platform,
+				Compliance:      compliance,
+				Allowed:         allowed,
+			}
+			groups[key] = g
+		}
+		g.Count++
+		if m.IsOnline {
+			g.Online++
+		}
+	}
+
+	for _, g := range groups {
+		report.Groups = append(report.Groups, g)
+	}
+	sort.Slice(report.Groups, func(i, j int) bool {
+		a, b := report.Groups[i], report.Groups[j]
+		if a.FirmwareType != b.FirmwareType {
+			return a.FirmwareType < b.FirmwareType
+		}
+		if a.Model != b.Model {
+			return a.Model < b.Model
+		}
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		return a.FirmwareVersion < b.FirmwareVersion
+	})
+
+	return report
+}
+
+// WriteViolationsCSV writes the non-compliant miners as CSV.
+func (r *FirmwareReport) WriteViolationsCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"miner_id", "ip_address", "mac_address", "hostname", "miner_type", "model", "platform",
+		"firmware_type", "firmware_version", "allowed", "online", "last_seen_at"})
+	for _, v := range r.Violations {
+		cw.Write([]string{
+			strconv.FormatInt(v.MinerID, 10), v.IPAddress, v.MACAddress, v.Hostname, v.MinerType, v.Model, v.Platform,
+			v.FirmwareType, v.FirmwareVersion, strings.Join(v.Allowed, " "),
+			strconv.FormatBool(v.Online), v.LastSeenAt.Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteViolationsJSON writes the non-compliant miners as a JSON array.
+func (r *FirmwareReport) WriteViolationsJSON(w io.Writer) error {
+	violations := r.Violations
+	if violations == nil {
+		violations = []*FirmwareViolation{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(violations)
+}
@@ -0,0 +1,112 @@
+// Package fleet applies configuration changes and builds reports across many
+// miners at once. Miners are selected from the database populated by data-harvest.
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Selector chooses the miners a fleet operation applies to.
+// Empty fields match all miners.
+type Selector struct {
+	// Networks limits the selection to miners in these CIDRs.
+	Networks []*net.IPNet
+
+	// IPs limits the selection to these addresses.
+	IPs []string
+
+	// FirmwareType limits the selection to one firmware (e.g., "vnish").
+	FirmwareType string
+
+	// MinerType limits the selection to one model (e.g., "Antminer S19").
+	MinerType string
+
+	// IncludeOffline also selects miners data-harvest last saw offline.
+	IncludeOffline bool
+}
+
+// ParseNetworks parses comma-separated CIDRs into Selector.Networks.
+func ParseNetworks(cidrs string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, cidr := range strings.Split(cidrs, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// matches returns true if the miner's address passes the network and IP filters.
+func (s *Selector) matches(m *database.Miner) bool {
+	if len(s.IPs) > 0 {
+		found := false
+		for _, ip := range s.IPs {
+			if ip == m.IPAddress {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(s.Networks) > 0 {
+		ip := net.ParseIP(m.IPAddress)
+		if ip == nil {
+			return false
+		}
+		for _, network := range s.Networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// SelectMiners returns the miners matching the selector, ordered by IP.
+func SelectMiners(ctx context.Context, repo database.Repository, sel Selector) ([]*database.Miner, error) {
+	filter := database.MinerFilter{
+		MinerType:    sel.MinerType,
+		FirmwareType: sel.FirmwareType,
+		OnlineStatus: "online",
+		SortBy:       "ip",
+		SortOrder:    "asc",
+	}
+	if sel.IncludeOffline {
+		filter.OnlineStatus = "all"
+	}
+
+	miners, err := repo.ListMinersFiltered(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("list miners: %w", err)
+	}
+
+	var selected []*database.Miner
+	for _, m := range miners {
+		if sel.matches(m) {
+			selected = append(selected, m)
+		}
+	}
+	return selected, nil
+}
+
+// supportsPools returns true if pools can be configured on the firmware.
+func supportsPools(fw miner.FirmwareType) bool {
+	return fw == miner.FirmwareVNish || fw == miner.FirmwareStock
+}
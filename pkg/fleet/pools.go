@@ -0,0 +1,300 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// MaxPools is the number of pool slots supported by VNish and stock firmware.
+const MaxPools = 3
+
+// PoolSpec is a desired pool. Worker is a template expanded per miner:
+//
+//	{site}      PoolSet.Site
+//	{mac}       MAC address without separators (e.g., "aabbccddeeff")
+//	{ip}        IP address with dashes (e.g., "10-40-36-15")
+//	{hostname}  miner hostname
+type PoolSpec struct {
+	URL      string `json:"url"`
+	Worker   string `json:"worker"`
+	Password string `json:"password"`
+}
+
+// PoolSet is the desired pool configuration, in priority order.
+type PoolSet struct {
+	Site  string     `json:"site"`
+	Pools []PoolSpec `json:"pools"`
+}
+
+// LoadPoolSet reads and validates a pool set file.
+func LoadPoolSet(path string) (*PoolSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pool file: %w", err)
+	}
+
+	var set PoolSet
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse pool file %s: %w", path, err)
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Validate checks the pool set.
+func (s *PoolSet) Validate() error {
+	if len(s.Pools) == 0 {
+		return fmt.Errorf("pool set has no pools")
+	}
+	if len(s.Pools) > MaxPools {
+		return fmt.Errorf("pool set has %d pools, firmware supports at most %d", len(s.Pools), MaxPools)
+	}
+	for i, p := range s.Pools {
+		if p.URL == "" {
+			return fmt.Errorf("pool %d: url is required", i)
+		}
+		if p.Worker == "" {
+			return fmt.Errorf("pool %d: worker is required", i)
+		}
+		if strings.Contains(p.Worker, "{site}") && s.Site == "" {
+			return fmt.Errorf("pool %d: worker uses {site} but no site is set", i)
+		}
+	}
+	return nil
+}
+
+// PoolConfig is a pool rendered for a specific miner.
+type PoolConfig struct {
+	URL      string
+	User     string
+	Password string
+}
+
+// Render expands the worker templates for a miner.
+func (s *PoolSet) Render(m *database.Miner) []PoolConfig {
+	r := strings.NewReplacer(
+		"{site}", s.Site,
+		"{mac}", strings.ToLower(strings.NewReplacer(":", "", "-", "").Replace(m.MACAddress)),
+		"{ip}", strings.ReplaceAll(m.IPAddress, ".", "-"),
+		"{hostname}", m.Hostname,
+	)
+
+	result := make([]PoolConfig, len(s.Pools))
+	for i, p := range s.Pools {
+		result[i] = PoolConfig{
+			URL:      p.URL,
+			User:     r.Replace(p.Worker),
+			Password: p.Password,
+		}
+	}
+	return result
+}
+
+// PoolResult is the plan and outcome of a pool change on one miner.
+type PoolResult struct {
+	Miner   *database.Miner
+	Desired []PoolConfig
+
+	// Changes describes the differences from the pools stored by data-harvest.
+	// Empty if the miner is already configured.
+	Changes []string
+
+	// Skipped is the reason the miner is left untouched, if any.
+	Skipped string
+
+	Applied bool
+	Err     error
+}
+
+// NeedsChange returns true if the pools should be applied to the miner.
+func (r *PoolResult) NeedsChange() bool {
+	return r.Skipped == "" && len(r.Changes) > 0
+}
+
+// diffPools compares the desired pools with the stored ones.
+// Firmware fee pools (VNish DevFee) are ignored.
+func diffPools(desired []PoolConfig, stored []*database.MinerPool) []string {
+	var current []*database.MinerPool
+	for _, p := range stored {
+		if strings.EqualFold(p.PoolType, "DevFee") {
+			continue
+		}
+		current = append(current, p)
+	}
+
+	var changes []string
+	for i := 0; i < len(desired) || i < len(current); i++ {
+		switch {
+		case i >= len(current):
+			changes = append(changes, fmt.Sprintf("pool %d: add %s (%s)", i, desired[i].URL, desired[i].User))
+		case i >= len(desired):
+			if current[i].URL != "" {
+				changes = append(changes, fmt.Sprintf("pool %d: remove %s (%s)", i, current[i].URL, current[i].User))
+			}
+		default:
+			if current[i].URL != desired[i].URL {
+				changes = append(changes, fmt.Sprintf("pool %d: url %s -> %s", i, current[i].URL, desired[i].URL))
+			}
+			if current[i].User != desired[i].User {
+				changes = append(changes, fmt.Sprintf("pool %d: user %s -> %s", i, current[i].User, desired[i].User))
+			}
+		}
+	}
+	return changes
+}
+
+// =============================================================================
+// PoolManager
+// =============================================================================
+
+// PoolManager plans and applies pool configuration across the fleet.
+type PoolManager struct {
+	repo        database.Repository
+	vnishAuth   *vnish.AuthManager
+	stockAuth   *stock.DigestAuth
+	timeout     time.Duration
+	concurrency int
+}
+
+// PoolManagerOption configures a PoolManager.
+type PoolManagerOption func(*PoolManager)
+
+// WithTimeout sets the per-miner timeout (default: 30s).
+func WithTimeout(timeout time.Duration) PoolManagerOption {
+	return func(m *PoolManager) {
+		m.timeout = timeout
+	}
+}
+
+// WithConcurrency sets how many miners are changed in parallel (default: 10).
+func WithConcurrency(n int) PoolManagerOption {
+	return func(m *PoolManager) {
+		m.concurrency = n
+	}
+}
+
+// NewPoolManager creates a new pool manager.
+func NewPoolManager(repo database.Repository, vnishAuth *vnish.AuthManager, stockAuth *stock.DigestAuth, opts ...PoolManagerOption) *PoolManager {
+	m := &PoolManager{
+		repo:        repo,
+		vnishAuth:   vnishAuth,
+		stockAuth:   stockAuth,
+		timeout:     30 * time.Second,
+		concurrency: 10,
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	return m
+}
+
+// Plan selects miners and diffs the desired pools against the stored ones.
+// Nothing is changed on the miners.
+func (pm *PoolManager) Plan(ctx context.Context, set *PoolSet, sel Selector) ([]*PoolResult, error) {
+	miners, err := SelectMiners(ctx, pm.repo, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]*PoolResult, 0, len(miners))
+	for _, m := range miners {
+		r := &PoolResult{Miner: m, Desired: set.Render(m)}
+		results = append(results, r)
+
+		if !supportsPools(m.FirmwareType) {
+			r.Skipped = fmt.Sprintf("pool configuration not supported for %s firmware", m.FirmwareType)
+			continue
+		}
+
+		stored, err := pm.repo.GetMinerPools(ctx, m.ID)
+		if err != nil {
+			return nil, fmt.Errorf("get pools for %s: %w", m.IPAddress, err)
+		}
+		r.Changes = diffPools(r.Desired, stored)
+	}
+	return results, nil
+}
+
+// Apply applies the desired pools to every miner in the plan that needs a change.
+// Per-miner errors are recorded in the results.
+func (pm *PoolManager) Apply(ctx context.Context, plan []*PoolResult) {
+	sem := make(chan struct{}, pm.concurrency)
+	var wg sync.WaitGroup
+
+	for _, r := range plan {
+		if !r.NeedsChange() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(r *PoolResult) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(ctx, pm.timeout)
+			defer cancel()
+
+			r.Err = pm.applyOne(ctx, r.Miner, r.Desired)
+			r.Applied = r.Err == nil
+		}(r)
+	}
+
+	wg.Wait()
+}
+
+// applyOne writes the pools to a single miner.
+func (pm *PoolManager) applyOne(ctx context.Context, m *database.Miner, pools []PoolConfig) error {
+	switch m.FirmwareType {
+	case miner.FirmwareVNish:
+		client := vnish.NewClient(m.IPAddress, pm.vnishAuth, vnish.WithTimeout(pm.timeout))
+
+		settings := make([]vnish.PoolSettings, len(pools))
+		for i, p := range pools {
+			settings[i] = vnish.PoolSettings{URL: p.URL, User: p.User, Pass: p.Password}
+		}
+		return client.SaveSettings(ctx, &vnish.SettingsUpdate{
+			Miner: vnish.MinerSettings{Pools: settings},
+		})
+
+	case miner.FirmwareStock:
+		client := stock.NewClient(m.IPAddress, pm.stockAuth, stock.WithTimeout(pm.timeout))
+
+		config, err := client.GetMinerConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("get miner config: %w", err)
+		}
+
+		// Stock firmware always has three slots; unused ones are empty
+		config.Pools = make([]stock.PoolConfig, MaxPools)
+		for i, p := range pools {
+			config.Pools[i] = stock.PoolConfig{URL: p.URL, User: p.User, Pass: p.Password}
+		}
+
+		resp, err := client.SetMinerConfig(ctx, config)
+		if err != nil {
+			return fmt.Errorf("set miner config: %w", err)
+		}
+		if resp.Stats == "error" {
+			return fmt.Errorf("set miner config: %s (%s)", resp.Msg, resp.Code)
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("pool configuration not supported for %s firmware", m.FirmwareType)
+	}
+}
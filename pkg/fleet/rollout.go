@@ -0,0 +1,416 @@
+package fleet
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/discovery"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// healthyStates are the miner states accepted after an upgrade.
+var healthyStates = map[string]bool{
+	"running":     true,
+	"mining":      true,
+	"auto-tuning": true,
+}
+
+// MinerDetector identifies a miner at a host. Implemented by discovery.Detector.
+type MinerDetector interface {
+	DetectMiner(ctx context.Context, ip string) (*discovery.DiscoveredMiner, error)
+}
+
+// UpdaterFunc returns a firmware updater for a host.
+type UpdaterFunc func(host string, fw miner.FirmwareType) (miner.FirmwareUpdater, error)
+
+// DefaultUpdater returns an UpdaterFunc for VNish and stock miners.
+func DefaultUpdater(vnishAuth *vnish.AuthManager, stockAuth *stock.DigestAuth, uploadTimeout time.Duration) UpdaterFunc {
+	return func(host string, fw miner.FirmwareType) (miner.FirmwareUpdater, error) {
+		switch fw {
+		case miner.FirmwareVNish:
+			return vnish.NewClient(host, vnishAuth, vnish.WithTimeout(uploadTimeout)), nil
+		case miner.FirmwareStock:
+			return stock.NewClient(host, stockAuth, stock.WithTimeout(uploadTimeout)), nil
+		}
+		return nil, fmt.Errorf("firmware upgrade not supported for %s firmware", fw)
+	}
+}
+
+// RolloutConfig configures a staged firmware rollout.
+type RolloutConfig struct {
+	// Image is the firmware image uploaded to every miner.
+	Image     []byte
+	ImageName string
+
+	// TargetVersion is the FirmwareVersion expected after the upgrade.
+	// path.Match wildcards are allowed (e.g., "1.2.7*").
+	TargetVersion string
+
+	// Canary is the number of miners in the first wave (default: 1).
+	// Any failure in the canary wave halts the rollout.
+	Canary int
+
+	// BatchPercent is the share of the remaining miners upgraded per wave
+	// after the canary (default: 25).
+	BatchPercent int
+
+	// MaxFailures halts the rollout once more miners than this have failed.
+	MaxFailures int
+
+	// RebootDelay is how long to wait after the upload before polling the miner (default: 1m).
+	RebootDelay time.Duration
+
+	// RecoveryTimeout is how long a miner has to come back healthy on the new version (default: 15m).
+	RecoveryTimeout time.Duration
+
+	// PollInterval is the detection interval while waiting for a miner (default: 15s).
+	PollInterval time.Duration
+
+	// UploadTimeout bounds the image upload (default: 10m).
+	UploadTimeout time.Duration
+}
+
+// withDefaults returns the config with zero values replaced by defaults.
+func (c RolloutConfig) withDefaults() RolloutConfig {
+	if c.Canary <= 0 {
+		c.Canary = 1
+	}
+	if c.BatchPercent <= 0 || c.BatchPercent > 100 {
+		c.BatchPercent = 25
+	}
+	if c.RebootDelay <= 0 {
+		c.RebootDelay = time.Minute
+	}
+	if c.RecoveryTimeout <= 0 {
+		c.RecoveryTimeout = 15 * time.Minute
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = 15 * time.Second
+	}
+	if c.UploadTimeout <= 0 {
+		c.UploadTimeout = 10 * time.Minute
+	}
+	return c
+}
+
+// RolloutStatus is the outcome of a rollout on one miner.
+type RolloutStatus string
+
+const (
+	RolloutPending  RolloutStatus = "pending"  // Not attempted (rollout halted first)
+	RolloutSkipped  RolloutStatus = "skipped"  // Already on the target version, or unsupported
+	RolloutUpgraded RolloutStatus = "upgraded" // Back healthy on the target version
+	RolloutFailed   RolloutStatus = "failed"
+)
+
+// RolloutResult is the outcome of a rollout on one miner.
+type RolloutResult struct {
+	Miner       *database.Miner
+	Wave        int // 1 is the canary wave, 0 if not in any wave
+	Status      RolloutStatus
+	FromVersion string
+	ToVersion   string // Version detected after the upgrade
+	Reason      string // Why the miner was skipped
+	Err         error
+	Duration    time.Duration
+}
+
+// RolloutReport is the outcome of a rollout.
+type RolloutReport struct {
+	Results    []*RolloutResult
+	Waves      int // Waves started
+	Halted     bool
+	HaltReason string
+}
+
+// Count returns the number of results with the given status.
+func (r *RolloutReport) Count(status RolloutStatus) int {
+	n := 0
+	for _, res := range r.Results {
+		if res.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// RolloutEvent reports rollout progress.
+type RolloutEvent struct {
+	Wave    int
+	Miner   *database.Miner // nil for wave-level events
+	Message string
+}
+
+// Rollout upgrades miners in waves: a canary set first, then percentage
+// batches, halting when failures exceed the configured threshold.
+type Rollout struct {
+	detector MinerDetector
+	updater  UpdaterFunc
+	cfg      RolloutConfig
+	progress func(RolloutEvent)
+}
+
+// RolloutOption configures a Rollout.
+type RolloutOption func(*Rollout)
+
+// WithProgress sets a callback for progress events.
+// It may be called concurrently from the miners of a wave.
+func WithProgress(fn func(RolloutEvent)) RolloutOption {
+	return func(r *Rollout) {
+		r.progress = fn
+	}
+}
+
+// NewRollout creates a rollout. The detector is used to wait for miners to come
+// back after the upgrade; the updater uploads the image.
+func NewRollout(detector MinerDetector, updater UpdaterFunc, cfg RolloutConfig, opts ...RolloutOption) (*Rollout, error) {
+	if len(cfg.Image) == 0 {
+		return nil, fmt.Errorf("firmware image is empty")
+	}
+	if cfg.TargetVersion == "" {
+		return nil, fmt.Errorf("target version is required")
+	}
+	if cfg.ImageName == "" {
+		cfg.ImageName = "firmware.tar.gz"
+	}
+
+	r := &Rollout{
+		detector: detector,
+		updater:  updater,
+		cfg:      cfg.withDefaults(),
+		progress: func(RolloutEvent) {},
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// isTarget returns true if the version matches the target version.
+func (r *Rollout) isTarget(version string) bool {
+	if version == "" {
+		return false
+	}
+	ok, _ := path.Match(r.cfg.TargetVersion, version)
+	return ok
+}
+
+// PlanWaves splits the miners into a canary wave of canary miners followed by
+// waves of batchPercent of the remaining miners (at least one per wave).
+func PlanWaves(miners []*database.Miner, canary, batchPercent int) [][]*database.Miner {
+	if len(miners) == 0 {
+		return nil
+	}
+	if canary > len(miners) {
+		canary = len(miners)
+	}
+
+	waves := [][]*database.Miner{miners[:canary]}
+	rest := miners[canary:]
+
+	batch := (len(rest)*batchPercent + 99) / 100
+	if batch < 1 {
+		batch = 1
+	}
+	for len(rest) > 0 {
+		n := batch
+		if n > len(rest) {
+			n = len(rest)
+		}
+		waves = append(waves, rest[:n])
+		rest = rest[n:]
+	}
+	return waves
+}
+
+// Plan returns the results before any upgrade: miners already on the target
+// version or on unsupported firmware are skipped, the rest are pending in
+// their wave.
+func (r *Rollout) Plan(miners []*database.Miner) (*RolloutReport, [][]*RolloutResult) {
+	report := &RolloutReport{}
+
+	var candidates []*database.Miner
+	byMiner := make(map[*database.Miner]*RolloutResult)
+	for _, m := range miners {
+		res := &RolloutResult{Miner: m, Status: RolloutPending, FromVersion: m.FirmwareVersion}
+		report.Results = append(report.Results, res)
+
+		if r.isTarget(m.FirmwareVersion) {
+			res.Status = RolloutSkipped
+			res.Reason = "already on target version"
+			continue
+		}
+		if _, err := r.updater(m.IPAddress, m.FirmwareType); err != nil {
+			res.Status = RolloutSkipped
+			res.Reason = err.Error()
+			continue
+		}
+		candidates = append(candidates, m)
+		byMiner[m] = res
+	}
+
+	var waves [][]*RolloutResult
+	for i, wave := range PlanWaves(candidates, r.cfg.Canary, r.cfg.BatchPercent) {
+		results := make([]*RolloutResult, len(wave))
+		for j, m := range wave {
+			results[j] = byMiner[m]
+			results[j].Wave = i + 1
+		}
+		waves = append(waves, results)
+	}
+
+	return report, waves
+}
+
+// Run upgrades the miners wave by wave. Miners in a wave are upgraded in parallel.
+// The rollout halts after a failed canary, when failures exceed MaxFailures,
+// or when ctx is cancelled; miners not attempted are left pending.
+func (r *Rollout) Run(ctx context.Context, miners []*database.Miner) *RolloutReport {
+	report, waves := r.Plan(miners)
+
+	failures := 0
+	for i, wave := range waves {
+		if ctx.Err() != nil {
+			report.Halted = true
+			report.HaltReason = "cancelled"
+			break
+		}
+
+		waveNum := i + 1
+		report.Waves = waveNum
+		r.progress(RolloutEvent{Wave: waveNum, Message: fmt.Sprintf("starting wave %d/%d (%d miners)", waveNum, len(waves), len(wave))})
+
+		var wg sync.WaitGroup
+		for _, res := range wave {
+			wg.Add(1)
+			go func(res *RolloutResult) {
+				defer wg.Done()
+				r.upgradeOne(ctx, res)
+			}(res)
+		}
+		wg.Wait()
+
+		waveFailures := 0
+		for _, res := range wave {
+			if res.Status == RolloutFailed {
+				waveFailures++
+			}
+		}
+		failures += waveFailures
+
+		if waveNum == 1 && waveFailures > 0 {
+			report.Halted = true
+			report.HaltReason = fmt.Sprintf("canary wave failed (%d/%d miners)", waveFailures, len(wave))
+			break
+		}
+		if failures > r.cfg.MaxFailures {
+			report.Halted = true
+			report.HaltReason = fmt.Sprintf("%d failures exceed the limit of %d", failures, r.cfg.MaxFailures)
+			break
+		}
+	}
+
+	if report.Halted {
+		r.progress(RolloutEvent{Wave: report.Waves, Message: "rollout halted: " + report.HaltReason})
+	}
+	return report
+}
+
+// upgradeOne uploads the image to a miner and waits for it to come back
+// healthy on the target version.
+func (r *Rollout) upgradeOne(ctx context.Context, res *RolloutResult) {
+	m := res.Miner
+	start := time.Now()
+	defer func() { res.Duration = time.Since(start) }()
+
+	fail := func(err error) {
+		res.Status = RolloutFailed
+		res.Err = err
+		r.progress(RolloutEvent{Wave: res.Wave, Miner: m, Message: "failed: " + err.Error()})
+	}
+
+	updater, err := r.updater(m.IPAddress, m.FirmwareType)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	r.progress(RolloutEvent{Wave: res.Wave, Miner: m, Message: "uploading firmware"})
+	uploadCtx, cancel := context.WithTimeout(ctx, r.cfg.UploadTimeout)
+	err = updater.UpdateFirmware(uploadCtx, r.cfg.Image, r.cfg.ImageName)
+	cancel()
+	if err != nil {
+		fail(fmt.Errorf("upload: %w", err))
+		return
+	}
+
+	r.progress(RolloutEvent{Wave: res.Wave, Miner: m, Message: "upload complete, waiting for reboot"})
+	if err := sleepCtx(ctx, r.cfg.RebootDelay); err != nil {
+		fail(err)
+		return
+	}
+
+	detected, err := r.waitHealthy(ctx, m.IPAddress)
+	if detected != nil {
+		res.ToVersion = detected.FirmwareVersion
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	res.Status = RolloutUpgraded
+	r.progress(RolloutEvent{Wave: res.Wave, Miner: m, Message: "upgraded to " + detected.FirmwareVersion})
+}
+
+// waitHealthy polls the miner until it reports the target version in a healthy
+// state, or the recovery timeout expires. Returns the last detection, if any.
+func (r *Rollout) waitHealthy(ctx context.Context, ip string) (*discovery.DiscoveredMiner, error) {
+	deadline := time.Now().Add(r.cfg.RecoveryTimeout)
+
+	var last *discovery.DiscoveredMiner
+	for {
+		if d, err := r.detector.DetectMiner(ctx, ip); err == nil {
+			last = d
+			if r.isTarget(d.FirmwareVersion) && healthyStates[d.State] {
+				return d, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			break
+		}
+		if err := sleepCtx(ctx, r.cfg.PollInterval); err != nil {
+			return last, err
+		}
+	}
+
+	switch {
+	case last == nil:
+		return nil, fmt.Errorf("miner did not come back within %s", r.cfg.RecoveryTimeout)
+	case !r.isTarget(last.FirmwareVersion):
+		return last, fmt.Errorf("miner is on version %q, expected %q", last.FirmwareVersion, r.cfg.TargetVersion)
+	default:
+		return last, fmt.Errorf("miner is in state %q after the upgrade", last.State)
+	}
+}
+
+// sleepCtx waits for d or until ctx is done.
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
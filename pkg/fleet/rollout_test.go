@@ -0,0 +1,313 @@
+package fleet_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/database"
+	"github.com/powerhive/powerhive-v2/pkg/discovery"
+	"github.com/powerhive/powerhive-v2/pkg/fleet"
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// How a fake miner takes the upgrade.
+const (
+	upgrades    = ""            // Installs the image and reboots into it
+	unreachable = "unreachable" // Nothing listening
+	ignores     = "ignores"     // Accepts the upload but stays on its version
+)
+
+// testTiming makes reboots and mining restarts near instant.
+var testTiming = minersim.Timing{
+	Boot:    50 * time.Millisecond,
+	Restart: 10 * time.Millisecond,
+	Tune:    10 * time.Millisecond,
+}
+
+// fakeMiner describes a simulated miner of a rollout test.
+type fakeMiner struct {
+	fw       miner.FirmwareType
+	version  string
+	behavior string
+}
+
+// startMiners serves the fake miners on loopback ports and returns them as
+// DB miners, with the simulated miners by host (nil when unreachable).
+func startMiners(t *testing.T, fakes []fakeMiner) ([]*database.Miner, map[string]*minersim.Miner) {
+	t.Helper()
+
+	var miners []*database.Miner
+	sims := make(map[string]*minersim.Miner)
+	for i, f := range fakes {
+		spec := minersim.MinerSpec{
+			IP:              "127.0.0.1",
+			Firmware:        f.fw,
+			FirmwareVersion: f.version,
+			Password:        "admin",
+		}
+		if f.fw == miner.FirmwareStock {
+			spec.Username, spec.Password = "root", "root"
+		}
+		sim := minersim.NewMiner(spec, testTiming, int64(i+1))
+
+		var host string
+		if f.behavior == unreachable {
+			l, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("listen: %v", err)
+			}
+			host = l.Addr().String()
+			l.Close()
+		} else {
+			var h http.Handler = sim.Handler()
+			if f.behavior == ignores {
+				h = ignoreUploads(h)
+			}
+			srv := httptest.NewServer(h)
+			t.Cleanup(srv.Close)
+			host = strings.TrimPrefix(srv.URL, "http://")
+			sims[host] = sim
+		}
+
+		miners = append(miners, &database.Miner{
+			ID:              int64(i + 1),
+			IPAddress:       host,
+			FirmwareType:    f.fw,
+			FirmwareVersion: f.version,
+		})
+	}
+	return miners, sims
+}
+
+// ignoreUploads answers firmware uploads with success without installing them.
+func ignoreUploads(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/firmware/update" || r.URL.Path == "/cgi-bin/upgrade.cgi" {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"status":"installing"}`))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newRollout returns a rollout of the "1.2.7" image with short waits.
+func newRollout(t *testing.T, canary, batchPercent, maxFailures int) *fleet.Rollout {
+	t.Helper()
+
+	vnishAuth := vnish.NewAuthManager("admin")
+	stockAuth := stock.NewDigestAuth()
+	detector := discovery.NewDetector([]miner.FirmwareProber{
+		vnish.NewProber(vnishAuth),
+		stock.NewProber(stockAuth),
+	}, discovery.WithDetectorTimeout(time.Second))
+
+	r, err := fleet.NewRollout(detector, fleet.DefaultUpdater(vnishAuth, stockAuth, 5*time.Second), fleet.RolloutConfig{
+		Image:           []byte("SIMFW 1.2.7\n"),
+		ImageName:       "firmware-1.2.7.tar.gz",
+		TargetVersion:   "1.2.7*",
+		Canary:          canary,
+		BatchPercent:    batchPercent,
+		MaxFailures:     maxFailures,
+		RebootDelay:     20 * time.Millisecond,
+		RecoveryTimeout: 500 * time.Millisecond,
+		PollInterval:    20 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("NewRollout: %v", err)
+	}
+	return r
+}
+
+func TestPlanWaves(t *testing.T) {
+	tests := []struct {
+		name         string
+		miners       int
+		canary       int
+		batchPercent int
+		want         []int // Wave sizes
+	}{
+		{"no miners", 0, 1, 25, nil},
+		{"canary only", 1, 1, 25, []int{1}},
+		{"canary larger than fleet", 2, 5, 25, []int{2}},
+		{"quarters", 9, 1, 25, []int{1, 2, 2, 2, 2}},
+		{"rounds batches up", 5, 2, 50, []int{2, 2, 1}},
+		{"at least one per wave", 4, 1, 1, []int{1, 1, 1, 1}},
+		{"all at once", 10, 2, 100, []int{2, 8}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var miners []*database.Miner
+			for i := 0; i < tt.miners; i++ {
+				miners = append(miners, &database.Miner{ID: int64(i + 1)})
+			}
+
+			var sizes []int
+			var next int64 = 1
+			for _, wave := range fleet.PlanWaves(miners, tt.canary, tt.batchPercent) {
+				sizes = append(sizes, len(wave))
+				for _, m := range wave {
+					if m.ID != next {
+						t.Errorf("miner %d planned out of order, want %d", m.ID, next)
+					}
+					next++
+				}
+			}
+			if !reflect.DeepEqual(sizes, tt.want) {
+				t.Errorf("wave sizes = %v, want %v", sizes, tt.want)
+			}
+		})
+	}
+}
+
+func TestRolloutPlanSkips(t *testing.T) {
+	r := newRollout(t, 1, 50, 0)
+	miners := []*database.Miner{
+		{ID: 1, IPAddress: "10.0.0.1", FirmwareType: miner.FirmwareVNish, FirmwareVersion: "1.2.6"},
+		{ID: 2, IPAddress: "10.0.0.2", FirmwareType: miner.FirmwareVNish, FirmwareVersion: "1.2.7-beta"},
+		{ID: 3, IPAddress: "10.0.0.3", FirmwareType: miner.FirmwareType("whatsminer"), FirmwareVersion: "1.0"},
+		{ID: 4, IPAddress: "10.0.0.4", FirmwareType: miner.FirmwareStock, FirmwareVersion: "2024.08"},
+	}
+
+	report, waves := r.Plan(miners)
+
+	want := []struct {
+		status fleet.RolloutStatus
+		wave   int
+	}{
+		{fleet.RolloutPending, 1},
+		{fleet.RolloutSkipped, 0},
+		{fleet.RolloutSkipped, 0},
+		{fleet.RolloutPending, 2},
+	}
+	for i, w := range want {
+		res := report.Results[i]
+		if res.Status != w.status || res.Wave != w.wave {
+			t.Errorf("miner %d: %s in wave %d, want %s in wave %d", res.Miner.ID, res.Status, res.Wave, w.status, w.wave)
+		}
+	}
+	if len(waves) != 2 {
+		t.Errorf("got %d waves, want 2", len(waves))
+	}
+}
+
+func TestRolloutRun(t *testing.T) {
+	vnishMiner := func(behavior string) fakeMiner {
+		return fakeMiner{fw: miner.FirmwareVNish, version: "1.2.6", behavior: behavior}
+	}
+	stockMiner := func(behavior string) fakeMiner {
+		return fakeMiner{fw: miner.FirmwareStock, version: "Thu Aug 29 16:39:06 CST 2024", behavior: behavior}
+	}
+
+	tests := []struct {
+		name         string
+		miners       []fakeMiner
+		canary       int
+		batchPercent int
+		maxFailures  int
+		want         []fleet.RolloutStatus
+		wantWaves    int
+		wantHalt     string // Start of the halt reason, "" if not halted
+	}{
+		{
+			name:         "all upgraded",
+			miners:       []fakeMiner{vnishMiner(upgrades), stockMiner(upgrades), vnishMiner(upgrades)},
+			canary:       1,
+			batchPercent: 100,
+			want:         []fleet.RolloutStatus{fleet.RolloutUpgraded, fleet.RolloutUpgraded, fleet.RolloutUpgraded},
+			wantWaves:    2,
+		},
+		{
+			name:         "canary failure halts",
+			miners:       []fakeMiner{vnishMiner(unreachable), vnishMiner(upgrades), stockMiner(upgrades)},
+			canary:       1,
+			batchPercent: 100,
+			maxFailures:  5,
+			want:         []fleet.RolloutStatus{fleet.RolloutFailed, fleet.RolloutPending, fleet.RolloutPending},
+			wantWaves:    1,
+			wantHalt:     "canary wave failed",
+		},
+		{
+			name:         "failures over the limit halt",
+			miners:       []fakeMiner{vnishMiner(upgrades), vnishMiner(ignores), stockMiner(unreachable), vnishMiner(upgrades)},
+			canary:       1,
+			batchPercent: 50,
+			maxFailures:  1,
+			want:         []fleet.RolloutStatus{fleet.RolloutUpgraded, fleet.RolloutFailed, fleet.RolloutFailed, fleet.RolloutPending},
+			wantWaves:    2,
+			wantHalt:     "2 failures exceed the limit of 1",
+		},
+		{
+			name:         "failures within the limit continue",
+			miners:       []fakeMiner{vnishMiner(upgrades), vnishMiner(ignores), stockMiner(unreachable), vnishMiner(upgrades)},
+			canary:       1,
+			batchPercent: 50,
+			maxFailures:  2,
+			want:         []fleet.RolloutStatus{fleet.RolloutUpgraded, fleet.RolloutFailed, fleet.RolloutFailed, fleet.RolloutUpgraded},
+			wantWaves:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			miners, sims := startMiners(t, tt.miners)
+			report := newRollout(t, tt.canary, tt.batchPercent, tt.maxFailures).Run(context.Background(), miners)
+
+			if report.Waves != tt.wantWaves {
+				t.Errorf("Waves = %d, want %d", report.Waves, tt.wantWaves)
+			}
+			if tt.wantHalt == "" && report.Halted {
+				t.Errorf("halted: %s", report.HaltReason)
+			}
+			if tt.wantHalt != "" && (!report.Halted || !strings.HasPrefix(report.HaltReason, tt.wantHalt)) {
+				t.Errorf("Halted = %v (%q), want halt %q", report.Halted, report.HaltReason, tt.wantHalt)
+			}
+
+			for i, res := range report.Results {
+				if res.Status != tt.want[i] {
+					t.Errorf("miner %d: %s (%v), want %s", i+1, res.Status, res.Err, tt.want[i])
+				}
+
+				sim := sims[res.Miner.IPAddress]
+				switch res.Status {
+				case fleet.RolloutUpgraded:
+					if res.ToVersion != "1.2.7" || sim.Status().FirmwareVersion != "1.2.7" {
+						t.Errorf("miner %d: upgraded to %q, miner on %q", i+1, res.ToVersion, sim.Status().FirmwareVersion)
+					}
+				case fleet.RolloutPending:
+					if sim != nil && sim.Status().FirmwareUploads != 0 {
+						t.Errorf("miner %d: pending but got the image", i+1)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRolloutCancel(t *testing.T) {
+	miners, _ := startMiners(t, []fakeMiner{
+		{fw: miner.FirmwareVNish, version: "1.2.6"},
+		{fw: miner.FirmwareVNish, version: "1.2.6"},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report := newRollout(t, 1, 100, 0).Run(ctx, miners)
+
+	if !report.Halted || report.HaltReason != "cancelled" {
+		t.Errorf("Halted = %v (%q), want cancelled", report.Halted, report.HaltReason)
+	}
+	if n := report.Count(fleet.RolloutPending); n != 2 {
+		t.Errorf("%d miners pending, want 2", n)
+	}
+}
@@ -6,7 +6,8 @@ package miner
 import "context"
 
 // Client abstracts miner API operations.
-// Implementations include vnish.HTTPClient for VNish firmware.
+// Implementations include vnish.HTTPClient, stock.HTTPClient, braiins.HTTPClient
+// and whatsminer.TCPClient.
 type Client interface {
 	// Host returns the miner's host address (IP or hostname).
 	Host() string
@@ -19,6 +20,44 @@ type Client interface {
 	GetMinerStatus(ctx context.Context) (*Status, error)
 }
 
+// PowerController abstracts power profile management.
+// Implemented by firmware clients that can change consumption at runtime:
+// vnish.HTTPClient (autotune presets), stock.HTTPClient (work modes)
+// and whatsminer.TCPClient (power limits).
+type PowerController interface {
+	// ListPowerProfiles returns the profiles that can be applied, ordered by power.
+	ListPowerProfiles(ctx context.Context) ([]PowerProfile, error)
+
+	// GetCurrentPowerProfile returns the name of the active profile.
+	GetCurrentPowerProfile(ctx context.Context) (string, error)
+
+	// ApplyPowerProfile switches the miner to the named profile.
+	ApplyPowerProfile(ctx context.Context, name string) error
+
+	// RestartRequired reports whether the miner must be restarted
+	// for an applied profile to take effect.
+	RestartRequired(ctx context.Context) (bool, error)
+}
+
+// FirmwareUpdater abstracts firmware upgrades.
+// Implemented by vnish.HTTPClient and stock.HTTPClient.
+type FirmwareUpdater interface {
+	// UpdateFirmware uploads and installs a firmware image.
+	// The miner reboots afterwards and is unreachable for a few minutes.
+	UpdateFirmware(ctx context.Context, image []byte, filename string) error
+}
+
+// CredentialResolver selects login credentials per host.
+// Used by vnish.AuthManager and stock.DigestAuth when miners don't share a password.
+type CredentialResolver interface {
+	// Candidates returns the credentials to try for a host, in order.
+	// The set last confirmed for the host comes first.
+	Candidates(host string, fw FirmwareType) []Credentials
+
+	// Confirm records that creds authenticated successfully against the host.
+	Confirm(host string, fw FirmwareType, creds Credentials)
+}
+
 // ClientFactory creates miner clients for specific hosts.
 // This is injected into the discovery package to decouple it from
 // specific firmware implementations.
@@ -28,7 +67,7 @@ type ClientFactory interface {
 }
 
 // FirmwareProber attempts to detect a specific firmware type on a host.
-// Each firmware implementation (vnish, stock, braiins) provides its own prober.
+// Each firmware implementation (vnish, stock, braiins, whatsminer) provides its own prober.
 type FirmwareProber interface {
 	// Probe attempts to connect to the host and retrieve miner information.
 	// Returns an error if this firmware type is not detected.
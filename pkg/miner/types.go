@@ -43,11 +43,43 @@ type Status struct {
 	FailureCode int
 }
 
+// PowerProfile is a selectable power setting (autotune preset, work mode, power limit).
+type PowerProfile struct {
+	// Name identifies the profile when applying it (e.g., "1100", "2").
+	Name string
+
+	// DisplayName is a human-readable description (e.g., "1100 watt ~ 53 TH").
+	DisplayName string
+
+	// Watts is the expected power consumption, or 0 if the firmware doesn't report it.
+	Watts int
+
+	// HashrateTH is the expected hashrate in TH/s, or 0 if unknown.
+	HashrateTH float64
+
+	// RequiresModdedPSU is true if the profile needs a modified power supply (VNish).
+	RequiresModdedPSU bool
+}
+
+// Credentials is a named set of login credentials for a firmware.
+type Credentials struct {
+	// Name identifies the credential set (e.g., "site-a"), empty for the built-in default.
+	Name string
+
+	// Username is the login user (stock firmware), empty if the firmware has none.
+	Username string
+
+	// Password is the login or unlock password.
+	Password string
+}
+
 // FirmwareType represents different firmware types.
 type FirmwareType string
 
 const (
-	FirmwareVNish   FirmwareType = "vnish"
-	FirmwareStock   FirmwareType = "stock"
-	FirmwareUnknown FirmwareType = "unknown"
+	FirmwareVNish      FirmwareType = "vnish"
+	FirmwareStock      FirmwareType = "stock"
+	FirmwareBraiins    FirmwareType = "braiins"
+	FirmwareWhatsminer FirmwareType = "whatsminer"
+	FirmwareUnknown    FirmwareType = "unknown"
 )
@@ -0,0 +1,76 @@
+package minersim
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Log types, matching the VNish /logs endpoints.
+const (
+	logStatus   = "status"
+	logMiner    = "miner"
+	logAutotune = "autotune"
+	logSystem   = "system"
+	logMessages = "messages"
+	logAPI      = "api"
+)
+
+// maxLogLines is the number of lines kept per log type.
+const maxLogLines = 1000
+
+// logBuffer keeps the most recent lines of a log.
+type logBuffer struct {
+	lines []string
+}
+
+func (b *logBuffer) add(line string) {
+	if len(b.lines) >= maxLogLines {
+		b.lines = append(b.lines[:0], b.lines[len(b.lines)-maxLogLines+1:]...)
+	}
+	b.lines = append(b.lines, line)
+}
+
+func (b *logBuffer) String() string {
+	if b == nil || len(b.lines) == 0 {
+		return ""
+	}
+	return strings.Join(b.lines, "\n") + "\n"
+}
+
+// logf appends a line in the format VNish uses for the log type.
+// Timestamps in 1970 are seconds since boot, as logged before the clock is set.
+// Must be called with mu held.
+func (m *Miner) logf(kind string, at time.Time, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+
+	var line string
+	switch kind {
+	case logSystem:
+		line = msg
+	case logMessages:
+		line = fmt.Sprintf("%s %s %s", at.Format("Jan _2 15:04:05"), m.spec.Hostname, msg)
+	case logAPI:
+		line = fmt.Sprintf("[%s] %s", at.Format("02 Jan 15:04:05"), msg)
+	default:
+		line = fmt.Sprintf("[%s] %s", at.Format("2006/01/02 15:04:05"), msg)
+	}
+
+	b, ok := m.logs[kind]
+	if !ok {
+		b = &logBuffer{}
+		m.logs[kind] = b
+	}
+	b.add(line)
+}
+
+// log returns the contents of a log.
+// Stock firmware has a single log combining the kernel and miner lines.
+// Must be called with mu held.
+func (m *Miner) log(kind string) string {
+	if kind == "" {
+		return m.logs[logSystem].String() + m.logs[logMiner].String() +
+			m.logs[logMessages].String() + m.logs[logStatus].String()
+	}
+	return m.logs[kind].String()
+}
@@ -0,0 +1,538 @@
+package minersim
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// Miner states, as reported by VNish.
+const (
+	StateStarting   = "starting"
+	StateAutoTuning = "auto-tuning"
+	StateMining     = "mining"
+	StatePaused     = "paused"
+	StateStopped    = "stopped"
+	StateFailure    = "failure"
+	StateRebooting  = "rebooting" // Unreachable, never reported by the API
+)
+
+// PresetDisabled is the VNish preset running the stock frequency and voltage.
+const PresetDisabled = "disabled"
+
+// idleWatts is the consumption of a miner that isn't hashing (fans and control board).
+const idleWatts = 45
+
+// Failure is an injected miner failure.
+type Failure struct {
+	Code        int
+	Description string
+}
+
+// failures are picked at random by the failure rate.
+var failures = []Failure{
+	{Code: 1001, Description: "Fan failure"},
+	{Code: 1002, Description: "Failed to parse miner configuration"},
+	{Code: 1003, Description: "Chain initialization failed"},
+	{Code: 1004, Description: "Overheat protection"},
+	{Code: 1005, Description: "PSU failure"},
+}
+
+// Timing controls how long the simulated state transitions take.
+type Timing struct {
+	Boot    time.Duration // Reboot until the API is reachable again
+	Restart time.Duration // Mining restart until hashing starts
+	Tune    time.Duration // Auto-tuning after a preset change
+
+	// FailureRate is the expected number of failures per mining hour.
+	FailureRate float64
+}
+
+// DefaultTiming returns timings close to real hardware.
+func DefaultTiming() Timing {
+	return Timing{
+		Boot:    90 * time.Second,
+		Restart: 20 * time.Second,
+		Tune:    60 * time.Second,
+	}
+}
+
+// MinerSpec identifies a simulated miner.
+type MinerSpec struct {
+	IP              string
+	Firmware        miner.FirmwareType // miner.FirmwareVNish or miner.FirmwareStock
+	Model           *Model
+	FirmwareVersion string
+	MAC             string
+	Hostname        string
+	Username        string // Stock digest auth user
+	Password        string // VNish unlock password or stock digest auth password
+}
+
+// Miner is a simulated miner. Its state advances lazily when it's read,
+// so an idle farm costs nothing.
+type Miner struct {
+	mu     sync.Mutex
+	spec   MinerSpec
+	timing Timing
+	rng    *rand.Rand
+
+	bootedAt    time.Time
+	state       string
+	stateSince  time.Time
+	stateUntil  time.Time // end of a transient state (zero if none)
+	failure     Failure
+	lastAdvance time.Time
+
+	// Power transitions ramp linearly from fromWatts/fromTH
+	fromWatts float64
+	fromTH    float64
+
+	preset          string
+	pendingPreset   string // applied on the next restart
+	restartRequired bool
+	tuned           map[string]bool
+	workMode        string // Stock bitmain-work-mode
+	findMiner       bool
+	nonce           string // Stock digest auth nonce, new on every boot
+
+	tokens  map[string]bool
+	apiKeys []vnish.APIKey
+	notes   map[string]string
+	pools   []vnish.PoolSettings
+	uploads int
+
+	logs map[string]*logBuffer
+}
+
+// NewMiner creates a simulated miner that has just finished booting.
+func NewMiner(spec MinerSpec, timing Timing, seed int64) *Miner {
+	if spec.Model == nil {
+		if spec.Firmware == miner.FirmwareStock {
+			spec.Model = AntminerS19kPro
+		} else {
+			spec.Model = AntminerS19
+		}
+	}
+
+	now := time.Now()
+	m := &Miner{
+		spec:     spec,
+		timing:   timing,
+		rng:      rand.New(rand.NewSource(seed)),
+		preset:   PresetDisabled,
+		tuned:    make(map[string]bool),
+		workMode: stock.WorkModeNormal,
+		tokens:   make(map[string]bool),
+		notes:    make(map[string]string),
+		pools: []vnish.PoolSettings{
+			{URL: "stratum+tcp://pool.example.com:3333", User: "powerhive." + spec.Hostname, Pass: "x"},
+		},
+		logs: make(map[string]*logBuffer),
+	}
+	m.boot(now)
+	m.enter(StateMining, now, time.Time{})
+	return m
+}
+
+// Spec returns the miner's identity.
+func (m *Miner) Spec() MinerSpec {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.spec
+}
+
+// =============================================================================
+// State machine
+// =============================================================================
+
+// advance moves transient states forward to now and injects random failures.
+// Must be called with mu held.
+func (m *Miner) advance(now time.Time) {
+	for !m.stateUntil.IsZero() && !now.Before(m.stateUntil) {
+		at := m.stateUntil
+		switch m.state {
+		case StateRebooting:
+			m.boot(at)
+			m.startMining(at)
+		case StateStarting:
+			if m.preset != PresetDisabled && m.spec.Firmware == miner.FirmwareVNish {
+				m.enter(StateAutoTuning, at, at.Add(m.timing.Tune))
+			} else {
+				m.enter(StateMining, at, time.Time{})
+			}
+		case StateAutoTuning:
+			m.tuned[m.preset] = true
+			m.logf(logAutotune, at, "INFO: Preset %s tuned", m.preset)
+			m.enter(StateMining, at, time.Time{})
+		default:
+			m.stateUntil = time.Time{}
+		}
+	}
+
+	if m.state == StateMining && m.timing.FailureRate > 0 && !m.lastAdvance.IsZero() {
+		hours := now.Sub(m.lastAdvance).Hours()
+		if m.rng.Float64() < 1-math.Exp(-m.timing.FailureRate*hours) {
+			m.fail(failures[m.rng.Intn(len(failures))], now)
+		}
+	}
+	m.lastAdvance = now
+}
+
+// enter switches to a state, keeping the current power as the ramp start.
+func (m *Miner) enter(state string, at, until time.Time) {
+	m.fromWatts, m.fromTH = m.output(at)
+	if state != m.state {
+		m.logf(logStatus, at, "INFO: %s", stateMessage(state))
+	}
+	m.state = state
+	m.stateSince = at
+	m.stateUntil = until
+}
+
+// stateMessage returns the status log line of a state change.
+func stateMessage(state string) string {
+	switch state {
+	case StateStarting:
+		return "Starting mining"
+	case StateAutoTuning:
+		return "Auto-tuning started"
+	case StateMining:
+		return "Mining"
+	case StatePaused:
+		return "Mining paused"
+	case StateStopped:
+		return "Mining stopped"
+	case StateFailure:
+		return "Mining failed"
+	case StateRebooting:
+		return "Rebooting"
+	}
+	return state
+}
+
+// boot resets what a reboot clears. Must be called with mu held.
+func (m *Miner) boot(at time.Time) {
+	m.bootedAt = at
+	m.state = StateStopped
+	m.fromWatts, m.fromTH = 0, 0
+	m.failure = Failure{}
+	m.findMiner = false
+	m.tokens = make(map[string]bool)
+	m.nonce = fmt.Sprintf("%016x", m.rng.Uint64())
+	if m.restartRequired {
+		m.preset = m.pendingPreset
+		m.restartRequired = false
+	}
+
+	m.logs = make(map[string]*logBuffer)
+	m.logf(logSystem, at, "Booting Linux on physical CPU 0x0")
+	m.logf(logSystem, at, "Linux version 4.6.0-xilinx (%s %s)", m.firmwareName(), m.spec.FirmwareVersion)
+	m.logf(logSystem, at, "Memory: 200692K/245760K available")
+	m.logf(logMiner, time.Unix(9, 0).UTC(), "INFO: Detected 256 Mb of RAM")
+	m.logf(logMiner, time.Unix(11, 0).UTC(), "INFO: Initializing PSU")
+	for i := 1; i <= m.spec.Model.Fans; i++ {
+		m.logf(logMiner, at, "INFO: fan#%d - ok", i)
+	}
+	for i := 1; i <= m.spec.Model.Chains; i++ {
+		m.logf(logMiner, at, "INFO: chain#%d - connected", i)
+	}
+	m.logf(logMessages, at, "syslog.info syslogd started: BusyBox v1.36.1")
+	m.logf(logStatus, at, "INFO: Initializing [%s (%s)]", m.spec.Model.Name, m.spec.FirmwareVersion)
+}
+
+// startMining restarts the mining process. Must be called with mu held.
+func (m *Miner) startMining(at time.Time) {
+	if m.restartRequired {
+		m.preset = m.pendingPreset
+		m.restartRequired = false
+	}
+	m.failure = Failure{}
+	m.enter(StateStarting, at, at.Add(m.timing.Restart))
+}
+
+// fail puts the miner in the failure state. Must be called with mu held.
+func (m *Miner) fail(f Failure, at time.Time) {
+	m.failure = f
+	m.logf(logStatus, at, "ERROR: %s (code %d)", f.Description, f.Code)
+	m.enter(StateFailure, at, time.Time{})
+}
+
+// reboot makes the miner unreachable for the boot time. Must be called with mu held.
+func (m *Miner) reboot(at time.Time) {
+	m.enter(StateRebooting, at, at.Add(m.timing.Boot))
+}
+
+// setPreset changes the VNish autotune preset. Switching between the stock
+// profile ("disabled") and a tuned preset only takes effect after a mining
+// restart, other changes are applied live through auto-tuning.
+// Must be called with mu held.
+func (m *Miner) setPreset(name string, at time.Time) error {
+	if name != PresetDisabled && m.spec.Model.Preset(name) == nil {
+		return fmt.Errorf("unknown preset: %s", name)
+	}
+
+	current := m.preset
+	if m.restartRequired {
+		current = m.pendingPreset
+	}
+	if name == current {
+		return nil
+	}
+
+	m.logf(logStatus, at, "INFO: Preset changed to %s", name)
+	if name == m.preset {
+		// Reverts a pending change
+		m.restartRequired = false
+		return nil
+	}
+	if (name == PresetDisabled) != (m.preset == PresetDisabled) {
+		m.pendingPreset = name
+		m.restartRequired = true
+		return nil
+	}
+
+	m.restartRequired = false
+	if m.state == StateMining || m.state == StateAutoTuning {
+		// Enter before switching so the ramp starts from the old preset
+		m.logf(logAutotune, at, "INFO: Tuning preset %s", name)
+		m.enter(StateAutoTuning, at, at.Add(m.timing.Tune))
+	}
+	m.preset = name
+	return nil
+}
+
+// setWorkMode changes the stock work mode, restarting mining like stock firmware does.
+// Must be called with mu held.
+func (m *Miner) setWorkMode(mode string, at time.Time) {
+	if mode == m.workMode {
+		return
+	}
+	m.workMode = mode
+	m.logf(logStatus, at, "INFO: Work mode changed to %s", mode)
+	if m.state != StateFailure {
+		m.startMining(at)
+	}
+}
+
+// =============================================================================
+// Power model
+// =============================================================================
+
+// target returns the consumption and hashrate the miner settles at while mining.
+func (m *Miner) target() (float64, float64) {
+	model := m.spec.Model
+	if m.spec.Firmware == miner.FirmwareStock {
+		switch m.workMode {
+		case stock.WorkModeSleep:
+			return idleWatts, 0
+		case stock.WorkModeLowPower:
+			return float64(model.StockWatts) * 0.7, model.StockTH * 0.7
+		}
+		return float64(model.StockWatts), model.StockTH
+	}
+
+	if p := model.Preset(m.preset); p != nil {
+		return float64(p.Watts), p.HashrateTH
+	}
+	return float64(model.StockWatts), model.StockTH
+}
+
+// output returns the current consumption (W) and hashrate (TH/s), without noise.
+func (m *Miner) output(now time.Time) (float64, float64) {
+	switch m.state {
+	case StateMining:
+		return m.target()
+	case StateAutoTuning:
+		watts, th := m.target()
+		f := 1.0
+		if d := m.stateUntil.Sub(m.stateSince); d > 0 {
+			f = math.Min(1, float64(now.Sub(m.stateSince))/float64(d))
+		}
+		return m.fromWatts + (watts-m.fromWatts)*f, m.fromTH + (th-m.fromTH)*f
+	case StateRebooting:
+		return 0, 0
+	}
+	return idleWatts, 0
+}
+
+// reading is a snapshot of the miner's sensors.
+type reading struct {
+	Watts      int
+	HashrateTH float64
+	ChipTemp   int
+	PCBTemp    int
+	FanRPM     int
+	FanDuty    int
+}
+
+// read returns the current sensor values with some noise. Must be called with mu held.
+func (m *Miner) read(now time.Time) reading {
+	watts, th := m.output(now)
+	if th > 0 {
+		watts *= 1 + (m.rng.Float64()-0.5)*0.01
+		th *= 1 + (m.rng.Float64()-0.5)*0.03
+	}
+
+	load := math.Min(1.2, watts/float64(m.spec.Model.StockWatts))
+	duty := int(30 + 60*load)
+	return reading{
+		Watts:      int(watts),
+		HashrateTH: th,
+		ChipTemp:   int(35 + 35*load),
+		PCBTemp:    int(30 + 28*load),
+		FanRPM:     duty * 65,
+		FanDuty:    duty,
+	}
+}
+
+// uptime returns the time since the last boot.
+func (m *Miner) uptime(now time.Time) time.Duration {
+	return now.Sub(m.bootedAt)
+}
+
+// firmwareName returns the firmware name reported in logs.
+func (m *Miner) firmwareName() string {
+	if m.spec.Firmware == miner.FirmwareStock {
+		return "Stock"
+	}
+	return "Vnish"
+}
+
+// =============================================================================
+// Control
+// =============================================================================
+
+// Status is a snapshot of a simulated miner.
+type Status struct {
+	IP              string  `json:"ip"`
+	Firmware        string  `json:"firmware"`
+	FirmwareVersion string  `json:"firmware_version"`
+	Model           string  `json:"model"`
+	State           string  `json:"state"`
+	FailureCode     int     `json:"failure_code,omitempty"`
+	Preset          string  `json:"preset,omitempty"`
+	PendingPreset   string  `json:"pending_preset,omitempty"`
+	RestartRequired bool    `json:"restart_required"`
+	WorkMode        string  `json:"work_mode,omitempty"`
+	Watts           int     `json:"watts"`
+	HashrateTH      float64 `json:"hashrate_th"`
+	UptimeSeconds   int     `json:"uptime_seconds"`
+	APIKeys         int     `json:"api_keys"`
+	FirmwareUploads int     `json:"firmware_uploads"`
+}
+
+// Status returns the current state of the miner.
+func (m *Miner) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.advance(now)
+	r := m.read(now)
+
+	s := Status{
+		IP:              m.spec.IP,
+		Firmware:        string(m.spec.Firmware),
+		FirmwareVersion: m.spec.FirmwareVersion,
+		Model:           m.spec.Model.Name,
+		State:           m.state,
+		FailureCode:     m.failure.Code,
+		RestartRequired: m.restartRequired,
+		Watts:           r.Watts,
+		HashrateTH:      r.HashrateTH,
+		UptimeSeconds:   int(m.uptime(now).Seconds()),
+		APIKeys:         len(m.apiKeys),
+		FirmwareUploads: m.uploads,
+	}
+	if m.spec.Firmware == miner.FirmwareStock {
+		s.WorkMode = m.workMode
+	} else {
+		s.Preset = m.preset
+		if m.restartRequired {
+			s.PendingPreset = m.pendingPreset
+		}
+	}
+	return s
+}
+
+// Fail puts the miner in the failure state until mining is restarted.
+func (m *Miner) Fail(f Failure) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.advance(now)
+	if m.state != StateRebooting {
+		m.fail(f, now)
+	}
+}
+
+// Reboot reboots the miner.
+func (m *Miner) Reboot() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.advance(now)
+	m.reboot(now)
+}
+
+// RestartMining restarts mining, clearing failures and applying a pending preset.
+func (m *Miner) RestartMining() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.advance(now)
+	if m.state != StateRebooting {
+		m.startMining(now)
+	}
+}
+
+// reachable advances the state and reports whether the API is up.
+func (m *Miner) reachable(now time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.advance(now)
+	return m.state != StateRebooting
+}
+
+// installFirmware records an uploaded image and reboots into it.
+// The version is read from a first line "SIMFW <version>" of the image,
+// or from a dotted version number in the filename.
+// Must be called with mu held.
+func (m *Miner) installFirmware(image []byte, filename string, at time.Time) error {
+	version := imageVersion(image, filename)
+	if version == "" {
+		return fmt.Errorf("invalid firmware image")
+	}
+
+	m.uploads++
+	m.logf(logStatus, at, "INFO: Installing firmware %s", version)
+	m.spec.FirmwareVersion = version
+	m.reboot(at)
+	return nil
+}
+
+var versionRe = regexp.MustCompile(`\d+\.\d+(\.\d+)*`)
+
+// imageVersion returns the firmware version of an uploaded image.
+func imageVersion(image []byte, filename string) string {
+	line := string(image)
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	if v, ok := strings.CutPrefix(strings.TrimSpace(line), "SIMFW "); ok {
+		return strings.TrimSpace(v)
+	}
+	return versionRe.FindString(filename)
+}
@@ -0,0 +1,170 @@
+// Package minersim simulates VNish and stock Antminer firmware over HTTP, so
+// discovery, data-harvest, power-balancer and fleet operations can be developed
+// and tested without hardware. Each simulated miner listens on its own loopback
+// address; consumption follows the active preset or work mode, and changes go
+// through the same restarts, auto-tuning, reboots and failures as real miners.
+package minersim
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Config configures a simulated farm.
+type Config struct {
+	// FirstIP is the address of the first miner; the others follow it.
+	// Linux routes all of 127.0.0.0/8 to the loopback interface.
+	FirstIP string
+
+	// Port is the HTTP port of every miner. Discovery only probes port 80.
+	Port int
+
+	// VNish and Stock are the number of miners of each firmware.
+	VNish int
+	Stock int
+
+	VNishPassword string
+	StockUsername string
+	StockPassword string
+	VNishVersion  string
+	StockVersion  string
+	VNishModel    *Model
+	StockModel    *Model
+	Timing        Timing
+	Seed          int64
+}
+
+// DefaultConfig returns a small farm with the firmware default credentials.
+func DefaultConfig() Config {
+	return Config{
+		FirstIP:       "127.0.1.1",
+		Port:          80,
+		VNish:         8,
+		Stock:         2,
+		VNishPassword: "admin",
+		StockUsername: "root",
+		StockPassword: "root",
+		VNishVersion:  "1.2.6",
+		StockVersion:  "Thu Aug 29 16:39:06 CST 2024",
+		VNishModel:    AntminerS19,
+		StockModel:    AntminerS19kPro,
+		Timing:        DefaultTiming(),
+		Seed:          1,
+	}
+}
+
+// Farm is a set of simulated miners, each served on its own address.
+type Farm struct {
+	cfg     Config
+	miners  []*Miner
+	servers []*http.Server
+}
+
+// NewFarm creates the miners of a farm. VNish miners come first.
+func NewFarm(cfg Config) (*Farm, error) {
+	ip := net.ParseIP(cfg.FirstIP).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid first IP: %s", cfg.FirstIP)
+	}
+
+	f := &Farm{cfg: cfg}
+	for i := 0; i < cfg.VNish+cfg.Stock; i++ {
+		if i > 0 {
+			if ip = nextIP(ip); ip == nil {
+				return nil, fmt.Errorf("too many miners after %s", cfg.FirstIP)
+			}
+		}
+		spec := MinerSpec{
+			IP:              ip.String(),
+			Firmware:        miner.FirmwareVNish,
+			Model:           cfg.VNishModel,
+			FirmwareVersion: cfg.VNishVersion,
+			MAC:             fmt.Sprintf("02:00:%02x:%02x:%02x:%02x", ip[0], ip[1], ip[2], ip[3]),
+			Hostname:        fmt.Sprintf("sim-vnish-%03d", i+1),
+			Password:        cfg.VNishPassword,
+		}
+		if i >= cfg.VNish {
+			spec.Firmware = miner.FirmwareStock
+			spec.Model = cfg.StockModel
+			spec.FirmwareVersion = cfg.StockVersion
+			spec.Hostname = fmt.Sprintf("sim-stock-%03d", i-cfg.VNish+1)
+			spec.Username = cfg.StockUsername
+			spec.Password = cfg.StockPassword
+		}
+		f.miners = append(f.miners, NewMiner(spec, cfg.Timing, cfg.Seed+int64(i)))
+	}
+	return f, nil
+}
+
+// nextIP returns the address after ip, skipping .0 and .255, or nil past 127.255.255.254.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for {
+		for i := len(next) - 1; i >= 0; i-- {
+			next[i]++
+			if next[i] != 0 {
+				break
+			}
+		}
+		if next[0] != ip[0] {
+			return nil
+		}
+		if next[3] != 0 && next[3] != 255 {
+			return next
+		}
+	}
+}
+
+// Start listens on every miner address. Servers run until Close.
+func (f *Farm) Start() error {
+	for _, m := range f.miners {
+		addr := net.JoinHostPort(m.spec.IP, strconv.Itoa(f.cfg.Port))
+		ln, err := net.Listen("tcp", addr)
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("listen %s: %w", addr, err)
+		}
+
+		srv := &http.Server{Handler: m.Handler(), ReadHeaderTimeout: 10 * time.Second}
+		f.servers = append(f.servers, srv)
+		go srv.Serve(ln)
+	}
+	return nil
+}
+
+// Close stops all servers.
+func (f *Farm) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var firstErr error
+	for _, srv := range f.servers {
+		if err := srv.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	f.servers = nil
+	return firstErr
+}
+
+// Miners returns the simulated miners in address order.
+func (f *Farm) Miners() []*Miner {
+	return f.miners
+}
+
+// Miner returns the miner at ip, or nil.
+func (f *Farm) Miner(ip string) *Miner {
+	for _, m := range f.miners {
+		if m.spec.IP == ip {
+			return m
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package minersim
+
+import "fmt"
+
+// Preset is a VNish autotune preset of a simulated model.
+type Preset struct {
+	Name       string
+	Watts      int
+	HashrateTH float64
+	ModdedPSU  bool
+}
+
+// Pretty returns the preset description as VNish reports it (e.g., "1100 watt ~ 53 TH").
+func (p Preset) Pretty() string {
+	return fmt.Sprintf("%d watt ~ %g TH", p.Watts, p.HashrateTH)
+}
+
+// Model describes the hardware of a simulated miner.
+type Model struct {
+	Name          string // "Antminer S19"
+	Code          string // VNish model code, "s19"
+	Series        string // "x19"
+	Platform      string // VNish platform, "xil"
+	Chains        int
+	ChipsPerChain int
+	Fans          int
+	StockWatts    int     // consumption with autotune disabled / normal work mode
+	StockTH       float64 // hashrate with autotune disabled / normal work mode
+	Presets       []Preset
+}
+
+// Preset returns the preset with the given name, or nil.
+func (m *Model) Preset(name string) *Preset {
+	for i := range m.Presets {
+		if m.Presets[i].Name == name {
+			return &m.Presets[i]
+		}
+	}
+	return nil
+}
+
+// AntminerS19 is an Antminer S19 with the VNish 1.2.x preset list.
+var AntminerS19 = &Model{
+	Name:          "Antminer S19",
+	Code:          "s19",
+	Series:        "x19",
+	Platform:      "xil",
+	Chains:        3,
+	ChipsPerChain: 76,
+	Fans:          4,
+	StockWatts:    3250,
+	StockTH:       95,
+	Presets: []Preset{
+		{Name: "1100", Watts: 1100, HashrateTH: 53},
+		{Name: "1300", Watts: 1300, HashrateTH: 58},
+		{Name: "1500", Watts: 1500, HashrateTH: 63},
+		{Name: "1700", Watts: 1700, HashrateTH: 68},
+		{Name: "1900", Watts: 1900, HashrateTH: 73},
+		{Name: "2100", Watts: 2100, HashrateTH: 78},
+		{Name: "2350", Watts: 2350, HashrateTH: 85},
+		{Name: "2620", Watts: 2620, HashrateTH: 91},
+		{Name: "2950", Watts: 2950, HashrateTH: 99},
+		{Name: "3200", Watts: 3200, HashrateTH: 102},
+		{Name: "3350", Watts: 3350, HashrateTH: 105},
+		{Name: "3750", Watts: 3750, HashrateTH: 110, ModdedPSU: true},
+		{Name: "4000", Watts: 4000, HashrateTH: 115, ModdedPSU: true},
+	},
+}
+
+// AntminerS19kPro is an Antminer S19k Pro on stock firmware.
+var AntminerS19kPro = &Model{
+	Name:          "Antminer S19k Pro",
+	Code:          "s19kpro",
+	Series:        "x19",
+	Platform:      "aml",
+	Chains:        3,
+	ChipsPerChain: 77,
+	Fans:          4,
+	StockWatts:    2760,
+	StockTH:       120,
+}
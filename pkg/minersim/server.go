@@ -0,0 +1,130 @@
+package minersim
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Handler returns the HTTP API of the miner: VNish /api/v1 or stock /cgi-bin,
+// plus the /sim control endpoints used to drive the simulation.
+func (m *Miner) Handler() http.Handler {
+	mux := http.NewServeMux()
+	if m.spec.Firmware == miner.FirmwareStock {
+		m.stockRoutes(mux)
+	} else {
+		m.vnishRoutes(mux)
+	}
+
+	mux.HandleFunc("/sim/status", m.handleSimStatus)
+	mux.HandleFunc("/sim/fail", m.handleSimFail)
+	mux.HandleFunc("/sim/restart", m.handleSimRestart)
+	mux.HandleFunc("/sim/reboot", m.handleSimReboot)
+
+	return &handler{m: m, mux: mux}
+}
+
+// handler drops connections while the miner reboots and records requests in the API log.
+type handler struct {
+	m   *Miner
+	mux *http.ServeMux
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if !strings.HasPrefix(r.URL.Path, "/sim/") && !h.m.reachable(start) {
+		// A rebooting miner doesn't answer at all
+		if hj, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hj.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		http.Error(w, "Service Unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	h.mux.ServeHTTP(rec, r)
+
+	if strings.HasPrefix(r.URL.Path, "/sim/") {
+		return
+	}
+	h.m.mu.Lock()
+	h.m.logf(logAPI, start, "%s \"%s %s\" %d \"-\" %.2fms",
+		r.RemoteAddr, r.Method, r.URL.Path, rec.status, float64(time.Since(start).Microseconds())/1000)
+	h.m.mu.Unlock()
+}
+
+// statusRecorder captures the response status for the API log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// =============================================================================
+// Simulation control
+// =============================================================================
+
+func (m *Miner) handleSimStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, m.Status())
+}
+
+// handleSimFail injects a failure, by ?code= or the first known failure.
+func (m *Miner) handleSimFail(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+
+	f := failures[0]
+	if s := r.URL.Query().Get("code"); s != "" {
+		code, err := strconv.Atoi(s)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid code: %s", s))
+			return
+		}
+		f = Failure{Code: code, Description: "Simulated failure"}
+		for _, known := range failures {
+			if known.Code == code {
+				f = known
+			}
+		}
+	}
+
+	m.Fail(f)
+	writeJSON(w, m.Status())
+}
+
+func (m *Miner) handleSimRestart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	m.RestartMining()
+	writeJSON(w, m.Status())
+}
+
+func (m *Miner) handleSimReboot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	m.Reboot()
+	writeJSON(w, m.Status())
+}
+
+var _ http.Handler = (*handler)(nil)
@@ -0,0 +1,468 @@
+package minersim
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// digestRealm is the digest auth realm of stock firmware.
+const digestRealm = "antMiner Configuration"
+
+// stockRoutes registers the stock /cgi-bin endpoints, all behind digest auth.
+func (m *Miner) stockRoutes(mux *http.ServeMux) {
+	cgi := func(name, method string, h http.HandlerFunc) {
+		mux.HandleFunc("/cgi-bin/"+name, m.requireDigest(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != method {
+				http.Error(w, "Method Not Allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			h(w, r)
+		}))
+	}
+
+	cgi("get_system_info.cgi", http.MethodGet, m.handleSystemInfo)
+	cgi("get_miner_status.cgi", http.MethodGet, m.handleMinerStatus)
+	cgi("get_miner_conf.cgi", http.MethodGet, m.handleGetMinerConf)
+	cgi("stats.cgi", http.MethodGet, m.handleStats)
+	cgi("summary.cgi", http.MethodGet, m.handleStockSummary)
+	cgi("pools.cgi", http.MethodGet, m.handleStockPools)
+	cgi("get_network_info.cgi", http.MethodGet, m.handleNetworkInfo)
+	cgi("get_blink_status.cgi", http.MethodGet, m.handleBlinkStatus)
+	cgi("log.cgi", http.MethodGet, m.handleStockLog)
+	cgi("reboot.cgi", http.MethodGet, m.handleStockReboot)
+	cgi("reset_conf.cgi", http.MethodGet, m.handleResetConf)
+	cgi("set_miner_conf.cgi", http.MethodPost, m.handleSetMinerConf)
+	cgi("set_network_conf.cgi", http.MethodPost, m.handleSetNetworkConf)
+	cgi("blink.cgi", http.MethodPost, m.handleBlink)
+	cgi("upgrade.cgi", http.MethodPost, m.handleUpgrade)
+}
+
+// =============================================================================
+// Digest authentication
+// =============================================================================
+
+// requireDigest challenges requests without valid digest credentials.
+func (m *Miner) requireDigest(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.mu.Lock()
+		nonce := m.nonce
+		ok := m.checkDigest(r)
+		m.mu.Unlock()
+
+		if !ok {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Digest realm="%s", nonce="%s", algorithm=MD5, qop="auth"`, digestRealm, nonce))
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// checkDigest verifies the Authorization header. Must be called with mu held.
+func (m *Miner) checkDigest(r *http.Request) bool {
+	header, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Digest ")
+	if !ok {
+		return false
+	}
+
+	params := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			params[strings.ToLower(k)] = strings.Trim(v, `"`)
+		}
+	}
+
+	if params["username"] != m.spec.Username || params["nonce"] != m.nonce || params["realm"] != digestRealm {
+		return false
+	}
+
+	ha1 := md5Hex(m.spec.Username + ":" + digestRealm + ":" + m.spec.Password)
+	ha2 := md5Hex(r.Method + ":" + params["uri"])
+	want := md5Hex(ha1 + ":" + m.nonce + ":" + ha2)
+	if params["qop"] != "" {
+		want = md5Hex(strings.Join([]string{ha1, m.nonce, params["nc"], params["cnonce"], params["qop"], ha2}, ":"))
+	}
+	return params["response"] == want
+}
+
+func md5Hex(s string) string {
+	sum := md5.Sum([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+// =============================================================================
+// Status endpoints
+// =============================================================================
+
+func (m *Miner) handleSystemInfo(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	up := m.uptime(now)
+	writeJSON(w, &stock.SystemInfo{
+		MinerType:               m.spec.Model.Name,
+		NetType:                 "DHCP",
+		NetDevice:               "eth0",
+		MACAddr:                 m.spec.MAC,
+		Hostname:                m.spec.Hostname,
+		IPAddress:               m.spec.IP,
+		Netmask:                 "255.255.255.0",
+		CurTime:                 now.Format("15:04:05"),
+		Uptime:                  fmt.Sprintf("%d:%02d", int(up.Hours()), int(up.Minutes())%60),
+		MemTotal:                233712,
+		MemFree:                 173956,
+		SystemMode:              "GNU/Linux",
+		SystemKernelVersion:     "Linux 4.9.113 #1 SMP PREEMPT",
+		SystemFilesystemVersion: m.spec.FirmwareVersion,
+		CGMinerVersion:          "4.11.1",
+		FirmwareType:            "Release",
+		Algorithm:               "sha256d",
+		Serinum:                 "SIM" + strings.ReplaceAll(m.spec.MAC, ":", ""),
+	})
+}
+
+// stockElapsed returns the seconds since mining started, 0 if not hashing. Must be called with mu held.
+func (m *Miner) stockElapsed(now time.Time, rd reading) int {
+	if rd.HashrateTH == 0 {
+		return 0
+	}
+	return int(now.Sub(m.stateSince).Seconds()) + 1
+}
+
+func (m *Miner) handleMinerStatus(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	ghs := rd.HashrateTH * 1000
+
+	status := stock.MinerStatus{
+		Summary: stock.Summary{
+			Elapsed:  m.stockElapsed(now, rd),
+			GHS5s:    ghs,
+			GHSav:    ghs,
+			Accepted: m.stockElapsed(now, rd) / 12,
+		},
+	}
+	for i, p := range m.pools {
+		status.Pools = append(status.Pools, stock.Pool{URL: p.URL, User: p.User, Status: m.poolStatus(rd), Priority: i})
+	}
+	for i := 0; i < m.spec.Model.Chains; i++ {
+		status.Devs = append(status.Devs, stock.Dev{
+			Index:       i,
+			Enabled:     "Y",
+			Status:      "Alive",
+			Temperature: float64(rd.ChipTemp),
+			ChipFreq:    525,
+			FanSpeed:    rd.FanRPM,
+			Hashrate:    ghs / float64(m.spec.Model.Chains),
+		})
+	}
+	writeJSON(w, &status)
+}
+
+// poolStatus returns the stock pool status.
+func (m *Miner) poolStatus(rd reading) string {
+	if rd.HashrateTH > 0 {
+		return "Alive"
+	}
+	return "Dead"
+}
+
+// apiStatus returns the STATUS header of stats.cgi, summary.cgi and pools.cgi.
+func apiStatus(now time.Time, msg string) stock.APIStatus {
+	return stock.APIStatus{Status: "S", When: now.Unix(), Msg: msg, APIVersion: "1.0.0"}
+}
+
+// apiInfo returns the INFO header of stats.cgi, summary.cgi and pools.cgi.
+func (m *Miner) apiInfo() stock.APIInfo {
+	return stock.APIInfo{MinerVersion: "uart_trans.1.3", CompileTime: m.spec.FirmwareVersion, Type: m.spec.Model.Name}
+}
+
+func (m *Miner) handleStats(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	model := m.spec.Model
+	ghs := rd.HashrateTH * 1000
+	n := float64(model.Chains)
+
+	data := stock.StatsData{
+		Elapsed:   m.stockElapsed(now, rd),
+		Rate5s:    ghs,
+		RateAvg:   ghs,
+		Rate30m:   ghs,
+		RateIdeal: model.StockTH * 1000,
+		RateUnit:  "GH/s",
+		ChainNum:  model.Chains,
+		FanNum:    model.Fans,
+	}
+	for i := 0; i < model.Fans; i++ {
+		rpm := rd.FanRPM
+		if m.state == StateFailure && m.failure.Code == 1001 && i == 0 {
+			rpm = 0
+		}
+		data.Fan = append(data.Fan, rpm)
+	}
+	for i := 0; i < model.Chains; i++ {
+		data.Chain = append(data.Chain, stock.Chain{
+			Index:        i,
+			FreqAvg:      525,
+			RateIdeal:    model.StockTH * 1000 / n,
+			RateReal:     ghs / n,
+			AsicNum:      model.ChipsPerChain,
+			TempPIC:      []int{rd.PCBTemp - 4, rd.PCBTemp - 4, rd.PCBTemp, rd.PCBTemp},
+			TempPCB:      []int{rd.PCBTemp - 4, rd.PCBTemp - 4, rd.PCBTemp, rd.PCBTemp},
+			TempChip:     []int{rd.ChipTemp - 6, rd.ChipTemp - 6, rd.ChipTemp, rd.ChipTemp},
+			EepromLoaded: true,
+			SN:           fmt.Sprintf("SIM%s%d", strings.ReplaceAll(m.spec.MAC, ":", ""), i),
+		})
+	}
+
+	writeJSON(w, &stock.StatsResponse{
+		Status: apiStatus(now, "stats"),
+		Info:   m.apiInfo(),
+		Stats:  []stock.StatsData{data},
+	})
+}
+
+func (m *Miner) handleStockSummary(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	ghs := rd.HashrateTH * 1000
+
+	items := []stock.StatusItem{
+		{Type: "rate", Status: "s"},
+		{Type: "network", Status: "s"},
+		{Type: "fans", Status: "s"},
+		{Type: "temp", Status: "s"},
+	}
+	if m.state == StateFailure {
+		kind := "rate"
+		switch m.failure.Code {
+		case 1001:
+			kind = "fans"
+		case 1004:
+			kind = "temp"
+		}
+		for i := range items {
+			if items[i].Type == kind {
+				items[i] = stock.StatusItem{Type: kind, Status: "e", Code: m.failure.Code, Msg: m.failure.Description}
+			}
+		}
+	}
+
+	writeJSON(w, &stock.SummaryResponse{
+		Status: apiStatus(now, "summary"),
+		Info:   m.apiInfo(),
+		Summary: []stock.SummaryData{{
+			Elapsed:   m.stockElapsed(now, rd),
+			Rate5s:    ghs,
+			RateAvg:   ghs,
+			Rate30m:   ghs,
+			RateIdeal: m.spec.Model.StockTH * 1000,
+			RateUnit:  "GH/s",
+			Status:    items,
+		}},
+	})
+}
+
+func (m *Miner) handleStockPools(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	resp := stock.PoolsResponse{Status: apiStatus(now, "pools"), Info: m.apiInfo()}
+	for i, p := range m.pools {
+		pool := stock.PoolData{Index: i, URL: p.URL, User: p.User, Status: m.poolStatus(rd), Priority: i, Diff: "65.5K"}
+		if i == 0 {
+			pool.Accepted = m.stockElapsed(now, rd) / 12
+			pool.DiffA = float64(pool.Accepted) * 65536
+		}
+		resp.Pools = append(resp.Pools, pool)
+	}
+	writeJSON(w, &resp)
+}
+
+func (m *Miner) handleNetworkInfo(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeJSON(w, &stock.NetworkInfo{
+		NetType:       "DHCP",
+		NetDevice:     "eth0",
+		MACAddr:       m.spec.MAC,
+		IPAddress:     m.spec.IP,
+		Netmask:       "255.255.255.0",
+		ConfNetType:   "DHCP",
+		ConfHostname:  m.spec.Hostname,
+		ConfIPAddress: m.spec.IP,
+		ConfNetmask:   "255.255.255.0",
+	})
+}
+
+func (m *Miner) handleBlinkStatus(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, &stock.BlinkStatus{Blink: m.findMiner})
+}
+
+func (m *Miner) handleStockLog(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	text := m.log("")
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, text)
+}
+
+// =============================================================================
+// Configuration endpoints
+// =============================================================================
+
+// configOK is the response of successful configuration changes.
+var configOK = &stock.ConfigResponse{Stats: "success", Code: "M000", Msg: "OK!"}
+
+func (m *Miner) handleGetMinerConf(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	conf := stock.MinerConfig{
+		BitmainFanCtrl:   false,
+		BitmainFanPWM:    "100",
+		BitmainFreq:      "525",
+		BitmainVoltage:   "1320",
+		BitmainWorkMode:  m.workMode,
+		BitmainFreqLevel: "100",
+	}
+	for _, p := range m.pools {
+		conf.Pools = append(conf.Pools, stock.PoolConfig{URL: p.URL, User: p.User, Pass: p.Pass})
+	}
+	writeJSON(w, &conf)
+}
+
+func (m *Miner) handleSetMinerConf(w http.ResponseWriter, r *http.Request) {
+	var conf stock.MinerConfig
+	if err := json.NewDecoder(r.Body).Decode(&conf); err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "M001", Msg: "Invalid configuration!"})
+		return
+	}
+
+	switch conf.BitmainWorkMode {
+	case "", stock.WorkModeNormal, stock.WorkModeSleep, stock.WorkModeLowPower:
+	default:
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "M002", Msg: "Invalid work mode!"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	var pools []vnish.PoolSettings
+	for _, p := range conf.Pools {
+		if p.URL != "" {
+			pools = append(pools, vnish.PoolSettings{URL: p.URL, User: p.User, Pass: p.Pass})
+		}
+	}
+	m.pools = pools
+
+	mode := conf.BitmainWorkMode
+	if mode == "" {
+		mode = stock.WorkModeNormal
+	}
+	if mode != m.workMode {
+		m.setWorkMode(mode, now)
+	} else if m.state != StateFailure {
+		// Saving the configuration always restarts cgminer
+		m.startMining(now)
+	}
+	writeJSON(w, configOK)
+}
+
+func (m *Miner) handleSetNetworkConf(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "N001", Msg: "Invalid request!"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Only the hostname is simulated, the address is fixed by the simulator
+	if h := r.PostForm.Get("conf_hostname"); h != "" {
+		m.spec.Hostname = h
+	}
+	writeJSON(w, configOK)
+}
+
+func (m *Miner) handleBlink(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "B001", Msg: "Invalid request!"})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.findMiner = r.PostForm.Get("blink") == "true"
+	writeJSON(w, configOK)
+}
+
+func (m *Miner) handleStockReboot(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reboot(time.Now())
+	io.WriteString(w, "ok")
+}
+
+func (m *Miner) handleResetConf(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.logf(logStatus, now, "INFO: Configuration reset")
+	m.pools = nil
+	m.setWorkMode(stock.WorkModeNormal, now)
+	io.WriteString(w, "ok")
+}
+
+func (m *Miner) handleUpgrade(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "U001", Msg: "Missing firmware file!"})
+		return
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(file)
+	if err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "U001", Msg: err.Error()})
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.installFirmware(image, header.Filename, time.Now()); err != nil {
+		writeJSON(w, &stock.ConfigResponse{Stats: "error", Code: "U002", Msg: "Invalid firmware image!"})
+		return
+	}
+	writeJSON(w, configOK)
+}
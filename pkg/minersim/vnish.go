@@ -0,0 +1,876 @@
+package minersim
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// vnishSettingsFile is the settings file inside a backup archive.
+const vnishSettingsFile = "config/miner.json"
+
+// vnishRoutes registers the VNish /api/v1 endpoints.
+func (m *Miner) vnishRoutes(mux *http.ServeMux) {
+	api := func(path string, methods map[string]http.HandlerFunc) {
+		mux.HandleFunc("/api/v1"+path, func(w http.ResponseWriter, r *http.Request) {
+			h, ok := methods[r.Method]
+			if !ok {
+				writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+				return
+			}
+			h(w, r)
+		})
+	}
+
+	// Public endpoints
+	api("/unlock", map[string]http.HandlerFunc{http.MethodPost: m.handleUnlock})
+	api("/info", map[string]http.HandlerFunc{http.MethodGet: m.handleVNishInfo})
+	api("/model", map[string]http.HandlerFunc{http.MethodGet: m.handleVNishModel})
+	api("/status", map[string]http.HandlerFunc{http.MethodGet: m.handleVNishStatus})
+	api("/perf-summary", map[string]http.HandlerFunc{http.MethodGet: m.handlePerfSummary})
+	api("/chains", map[string]http.HandlerFunc{http.MethodGet: m.handleChains})
+	api("/chains/factory-info", map[string]http.HandlerFunc{http.MethodGet: m.handleFactoryInfo})
+	api("/logs/", map[string]http.HandlerFunc{http.MethodGet: m.handleVNishLogs})
+	api("/metrics", map[string]http.HandlerFunc{http.MethodGet: m.handleMetrics})
+	api("/find-miner", map[string]http.HandlerFunc{http.MethodPost: m.handleFindMiner})
+	api("/notes", map[string]http.HandlerFunc{
+		http.MethodGet:  m.handleNotes,
+		http.MethodPost: m.requireToken(m.handleAddNote),
+	})
+	api("/notes/", map[string]http.HandlerFunc{
+		http.MethodGet:    m.handleNote,
+		http.MethodPut:    m.requireToken(m.handleNote),
+		http.MethodDelete: m.requireToken(m.handleNote),
+	})
+	api("/settings", map[string]http.HandlerFunc{
+		http.MethodGet:  m.handleGetSettings,
+		http.MethodPost: m.requireToken(m.requireKey(m.handleSaveSettings)),
+	})
+
+	// Bearer token
+	api("/summary", map[string]http.HandlerFunc{http.MethodGet: m.requireToken(m.handleVNishSummary)})
+	api("/autotune/presets", map[string]http.HandlerFunc{http.MethodGet: m.requireToken(m.handlePresets)})
+	api("/apikeys", map[string]http.HandlerFunc{
+		http.MethodGet:  m.requireToken(m.handleAPIKeys),
+		http.MethodPost: m.requireToken(m.handleAddAPIKey),
+	})
+	api("/mining/", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.handleMining)})
+
+	// Bearer token and API key
+	api("/apikeys/delete", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleDeleteAPIKey))})
+	api("/settings/backup", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleBackup))})
+	api("/settings/restore", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleRestore))})
+	api("/settings/factory-reset", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleFactoryReset))})
+	api("/system/reboot", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleReboot))})
+	api("/firmware/update", map[string]http.HandlerFunc{http.MethodPost: m.requireToken(m.requireKey(m.handleFirmwareUpdate))})
+}
+
+// =============================================================================
+// Authentication
+// =============================================================================
+
+// requireToken rejects requests without a valid bearer token (401).
+func (m *Miner) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		m.mu.Lock()
+		ok := m.tokens[token]
+		m.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// requireKey rejects requests without a registered API key (403).
+func (m *Miner) requireKey(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get("x-api-key")
+		m.mu.Lock()
+		ok := false
+		for _, k := range m.apiKeys {
+			if k.Key == key {
+				ok = true
+				break
+			}
+		}
+		m.mu.Unlock()
+		if !ok {
+			writeError(w, http.StatusForbidden, "Invalid API key")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (m *Miner) handleUnlock(w http.ResponseWriter, r *http.Request) {
+	var req vnish.UnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if req.Password != m.spec.Password {
+		m.logf(logMessages, now, "authpriv.warn api: Bad password attempt from %s", r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, "Wrong password")
+		return
+	}
+
+	token, err := vnish.GenerateAPIKey()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	m.tokens[token] = true
+	writeJSON(w, &vnish.UnlockResponse{Token: token})
+}
+
+// =============================================================================
+// Info & Status
+// =============================================================================
+
+func (m *Miner) handleVNishInfo(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	up := m.uptime(now)
+	model := m.spec.Model
+	writeJSON(w, &vnish.MinerInfo{
+		Miner:       model.Name,
+		Model:       model.Code,
+		FWName:      "Vnish",
+		FWVersion:   m.spec.FirmwareVersion,
+		BuildName:   "vnishnet",
+		Platform:    model.Platform,
+		InstallType: "nand",
+		Algorithm:   "sha256d",
+		HRMeasure:   "GH/s",
+		System: vnish.SystemInfo{
+			OS:             "GNU/Linux",
+			MinerName:      model.Code,
+			MemTotal:       233712,
+			MemFree:        173956,
+			MemFreePercent: 74,
+			NetworkStatus: vnish.NetworkStatus{
+				MAC:      m.spec.MAC,
+				DHCP:     true,
+				IP:       m.spec.IP,
+				Netmask:  "255.255.255.0",
+				Hostname: m.spec.Hostname,
+			},
+			Uptime: fmt.Sprintf("%d:%02d", int(up.Hours()), int(up.Minutes())%60),
+		},
+		Serial: "N/A",
+	})
+}
+
+func (m *Miner) handleVNishModel(w http.ResponseWriter, r *http.Request) {
+	model := m.spec.Model
+	writeJSON(w, &vnish.ModelInfo{
+		FullName:    model.Name,
+		Model:       model.Code,
+		Algorithm:   "sha256d",
+		Series:      model.Series,
+		Platform:    model.Platform,
+		InstallType: "nand",
+		HRMeasure:   "GH/s",
+		Serial:      "N/A",
+		Chain: vnish.ChainSpec{
+			ChipsPerChain:  model.ChipsPerChain,
+			ChipsPerDomain: 2,
+			NumChains:      model.Chains,
+		},
+		Overclock: vnish.OverclockSpec{
+			MaxVoltage:     1535,
+			MinVoltage:     1200,
+			DefaultVoltage: 1380,
+			MaxFreq:        1000,
+			MinFreq:        50,
+			DefaultFreq:    675,
+			WarnFreq:       850,
+		},
+	})
+}
+
+// vnishStatus returns the miner status. Must be called with mu held.
+func (m *Miner) vnishStatus(now time.Time, unlocked bool) vnish.MinerStatus {
+	desc := ""
+	if m.state == StateFailure {
+		desc = m.failure.Description
+	}
+	return vnish.MinerStatus{
+		MinerState:      m.state,
+		MinerStateTime:  int(now.Sub(m.stateSince).Seconds()),
+		Description:     desc,
+		FailureCode:     m.failure.Code,
+		FindMiner:       m.findMiner,
+		RestartRequired: m.restartRequired,
+		Unlocked:        unlocked,
+	}
+}
+
+func (m *Miner) handleVNishStatus(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, m.vnishStatus(time.Now(), m.tokens[token]))
+}
+
+func (m *Miner) handleVNishSummary(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	stockGH := m.spec.Model.StockTH * 1000
+	ghs := rd.HashrateTH * 1000
+
+	var efficiency float64
+	if ghs > 0 {
+		efficiency = float64(rd.Watts) / rd.HashrateTH
+	}
+
+	writeJSON(w, &vnish.Summary{Miner: vnish.MinerSummary{
+		MinerStatus:      m.vnishStatus(now, true),
+		MinerType:        fmt.Sprintf("%s (Vnish %s)", m.spec.Model.Name, m.spec.FirmwareVersion),
+		HRStock:          stockGH,
+		AverageHashrate:  ghs,
+		InstantHashrate:  ghs,
+		HRRealtime:       ghs,
+		HRNominal:        stockGH,
+		HRAverage:        ghs,
+		PCBTemp:          vnish.TempRange{Min: rd.PCBTemp - 4, Max: rd.PCBTemp},
+		ChipTemp:         vnish.TempRange{Min: rd.ChipTemp - 6, Max: rd.ChipTemp},
+		PowerConsumption: rd.Watts,
+		PowerUsage:       rd.Watts,
+		PowerEfficiency:  efficiency,
+		DevFeePercent:    2.8,
+		Pools:            m.vnishPools(now, rd),
+		Cooling:          m.vnishCooling(rd),
+		Chains:           m.vnishChains(rd),
+	}})
+}
+
+// vnishPools returns the pool status: the user pools followed by the dev fee.
+// Must be called with mu held.
+func (m *Miner) vnishPools(now time.Time, rd reading) []vnish.Pool {
+	status := "offline"
+	accepted := 0
+	if rd.HashrateTH > 0 {
+		status = "working"
+		accepted = int(m.uptime(now).Seconds() / 12)
+	}
+
+	var pools []vnish.Pool
+	for i, p := range m.pools {
+		pool := vnish.Pool{ID: i, URL: p.URL, PoolType: "UserPool", User: p.User, Status: status, Diff: "65.5K", Ping: 24}
+		if i == 0 {
+			pool.Accepted = accepted
+			pool.DiffA = float64(accepted) * 65536
+		}
+		pools = append(pools, pool)
+	}
+	return append(pools, vnish.Pool{ID: len(pools), URL: "DevFee", PoolType: "DevFee", User: "DevFee", Status: status})
+}
+
+// vnishCooling returns the fan status. Must be called with mu held.
+func (m *Miner) vnishCooling(rd reading) vnish.Cooling {
+	c := vnish.Cooling{
+		FanNum:   m.spec.Model.Fans,
+		Settings: vnish.CoolingSettings{Mode: vnish.FanMode{Name: "auto"}},
+		FanDuty:  rd.FanDuty,
+	}
+	for i := 0; i < m.spec.Model.Fans; i++ {
+		fan := vnish.Fan{ID: i, RPM: rd.FanRPM, Status: "ok", MaxRPM: 6000}
+		if m.state == StateFailure && m.failure.Code == 1001 && i == 0 {
+			fan.RPM, fan.Status = 0, "failed"
+		}
+		c.Fans = append(c.Fans, fan)
+	}
+	return c
+}
+
+// vnishChains returns the hashboard status. Must be called with mu held.
+func (m *Miner) vnishChains(rd reading) []vnish.Chain {
+	model := m.spec.Model
+	n := float64(model.Chains)
+
+	state := "mining"
+	if rd.HashrateTH == 0 {
+		state = "stopped"
+	}
+
+	chains := make([]vnish.Chain, model.Chains)
+	for i := range chains {
+		chains[i] = vnish.Chain{
+			ID:                 i + 1,
+			Frequency:          675,
+			Voltage:            13800,
+			PowerConsumption:   int(float64(rd.Watts) / n),
+			HashrateIdeal:      model.StockTH * 1000 / n,
+			HashrateRT:         rd.HashrateTH * 1000 / n,
+			HashratePercentage: 100 * rd.HashrateTH / model.StockTH,
+			PCBTemp:            vnish.TempRange{Min: rd.PCBTemp - 4, Max: rd.PCBTemp},
+			ChipTemp:           vnish.TempRange{Min: rd.ChipTemp - 6, Max: rd.ChipTemp},
+			ChipStatuses:       vnish.ChipStatuses{Grey: model.ChipsPerChain},
+			Status:             vnish.ChainStatus{State: state},
+		}
+	}
+	return chains
+}
+
+func (m *Miner) handleChains(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, m.vnishChains(m.read(time.Now())))
+}
+
+func (m *Miner) handleFactoryInfo(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stock := m.spec.Model.StockTH * 1000
+	psu := "APW12"
+	writeJSON(w, &vnish.ChainFactoryInfo{
+		HRStock:  &stock,
+		PSUModel: &psu,
+		Chains:   m.vnishChains(m.read(time.Now())),
+	})
+}
+
+// =============================================================================
+// Autotune
+// =============================================================================
+
+// currentPreset returns the active preset. Must be called with mu held.
+func (m *Miner) currentPreset() vnish.CurrentPreset {
+	cp := vnish.CurrentPreset{
+		Name:    PresetDisabled,
+		Pretty:  "Disabled",
+		Status:  "untuned",
+		Globals: vnish.PresetGlobals{Volt: 1380, Freq: 675},
+	}
+	if p := m.spec.Model.Preset(m.preset); p != nil {
+		cp.Name = p.Name
+		cp.Pretty = p.Pretty()
+		cp.ModdedPSURequired = p.ModdedPSU
+		if m.tuned[p.Name] {
+			cp.Status = "tuned"
+		} else if m.state == StateAutoTuning {
+			cp.Status = "tuning"
+		}
+	}
+	return cp
+}
+
+func (m *Miner) handlePerfSummary(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	writeJSON(w, &vnish.PerfSummary{
+		CurrentPreset: m.currentPreset(),
+		PresetSwitcher: vnish.PresetSwitcher{
+			DecreaseTemp: 75,
+			RiseTemp:     55,
+			CheckTime:    300,
+		},
+	})
+}
+
+func (m *Miner) handlePresets(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	presets := []vnish.AutotunePreset{{Name: PresetDisabled, Pretty: "Disabled", Status: "untuned"}}
+	for _, p := range m.spec.Model.Presets {
+		status := "untuned"
+		if m.tuned[p.Name] {
+			status = "tuned"
+		}
+		presets = append(presets, vnish.AutotunePreset{
+			Name:              p.Name,
+			Pretty:            p.Pretty(),
+			Status:            status,
+			ModdedPSURequired: p.ModdedPSU,
+		})
+	}
+	writeJSON(w, presets)
+}
+
+// =============================================================================
+// Logs & Metrics
+// =============================================================================
+
+func (m *Miner) handleVNishLogs(w http.ResponseWriter, r *http.Request) {
+	kind := strings.TrimPrefix(r.URL.Path, "/api/v1/logs/")
+	switch kind {
+	case logStatus, logMiner, logAutotune, logSystem, logMessages, logAPI:
+	default:
+		writeError(w, http.StatusNotFound, "unknown log")
+		return
+	}
+
+	m.mu.Lock()
+	text := m.log(kind)
+	m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain")
+	io.WriteString(w, text)
+}
+
+// handleMetrics returns the current readings resampled over the requested window.
+// The simulator keeps no history, so the series is flat up to the last boot.
+func (m *Miner) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	timeSlice, step := 86400, 900
+	if v, err := strconv.Atoi(r.URL.Query().Get("time_slice")); err == nil && v > 0 {
+		timeSlice = min(v, 259200)
+	}
+	if v, err := strconv.Atoi(r.URL.Query().Get("step")); err == nil && v > 0 {
+		step = v
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	rd := m.read(now)
+	result := vnish.Metrics{Timezone: "GMT"}
+	for t := now.Unix(); t > now.Unix()-int64(timeSlice); t -= int64(step) {
+		var data vnish.MetricData
+		if t >= m.bootedAt.Unix() {
+			data = vnish.MetricData{
+				Hashrate:         rd.HashrateTH * 1000,
+				PCBMaxTemp:       rd.PCBTemp,
+				ChipMaxTemp:      rd.ChipTemp,
+				FanDuty:          rd.FanDuty,
+				PowerConsumption: rd.Watts,
+			}
+		}
+		result.Metrics = append(result.Metrics, vnish.MetricPoint{Time: t, Data: data})
+	}
+	result.Annotations = []vnish.Annotation{{Time: m.bootedAt.Unix(), Data: vnish.AnnotationData{Type: "start"}}}
+	writeJSON(w, &result)
+}
+
+// =============================================================================
+// Notes & API Keys
+// =============================================================================
+
+func (m *Miner) handleNotes(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, m.notes)
+}
+
+func (m *Miner) handleAddNote(w http.ResponseWriter, r *http.Request) {
+	var note vnish.Note
+	if err := json.NewDecoder(r.Body).Decode(&note); err != nil || note.Key == "" {
+		writeError(w, http.StatusBadRequest, "invalid note")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.notes[note.Key]; ok {
+		writeError(w, http.StatusConflict, "note already exists")
+		return
+	}
+	m.notes[note.Key] = note.Value
+	writeJSON(w, map[string]string{"status": "inserted"})
+}
+
+// handleNote reads, updates or deletes /notes/{key}.
+func (m *Miner) handleNote(w http.ResponseWriter, r *http.Request) {
+	key, err := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/api/v1/notes/"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid key")
+		return
+	}
+
+	var note vnish.Note
+	if r.Method == http.MethodPut {
+		if err := json.NewDecoder(r.Body).Decode(&note); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid note")
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value, ok := m.notes[key]
+	if !ok {
+		writeError(w, http.StatusNotFound, "note not found")
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, &vnish.Note{Key: key, Value: value})
+	case http.MethodPut:
+		m.notes[key] = note.Value
+		writeJSON(w, map[string]string{"status": "updated"})
+	case http.MethodDelete:
+		delete(m.notes, key)
+		writeJSON(w, map[string]string{"status": "deleted"})
+	}
+}
+
+func (m *Miner) handleAPIKeys(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := append([]vnish.APIKey{}, m.apiKeys...)
+	writeJSON(w, keys)
+}
+
+func (m *Miner) handleAddAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req vnish.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.Key) != vnish.APIKeyLength {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("API key must be %d characters", vnish.APIKeyLength))
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, k := range m.apiKeys {
+		if k.Key == req.Key {
+			writeError(w, http.StatusConflict, "API key already exists")
+			return
+		}
+	}
+	m.apiKeys = append(m.apiKeys, vnish.APIKey{Key: req.Key, Description: req.Description})
+	writeJSON(w, &vnish.APIKeyResponse{Status: "inserted"})
+}
+
+func (m *Miner) handleDeleteAPIKey(w http.ResponseWriter, r *http.Request) {
+	var req vnish.APIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, k := range m.apiKeys {
+		if k.Key == req.Key {
+			m.apiKeys = append(m.apiKeys[:i], m.apiKeys[i+1:]...)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "API key not found")
+}
+
+// =============================================================================
+// Settings
+// =============================================================================
+
+// vnishSettings is the persisted miner configuration, as stored in backups.
+type vnishSettings struct {
+	Miner struct {
+		Overclock vnish.OverclockSettings `json:"overclock"`
+		Pools     []vnish.PoolSettings    `json:"pools"`
+	} `json:"miner"`
+}
+
+// settings returns the configuration, with a pending preset as saved. Must be called with mu held.
+func (m *Miner) settings() vnishSettings {
+	var s vnishSettings
+	s.Miner.Overclock.Preset = m.preset
+	if m.restartRequired {
+		s.Miner.Overclock.Preset = m.pendingPreset
+	}
+	s.Miner.Pools = append([]vnish.PoolSettings{}, m.pools...)
+	return s
+}
+
+// applySettings applies a settings update. Must be called with mu held.
+func (m *Miner) applySettings(u *vnish.SettingsUpdate, now time.Time) error {
+	if u.Miner.Overclock != nil && u.Miner.Overclock.Preset != "" {
+		if err := m.setPreset(u.Miner.Overclock.Preset, now); err != nil {
+			return err
+		}
+	}
+	if len(u.Miner.Pools) > 0 {
+		m.pools = append([]vnish.PoolSettings{}, u.Miner.Pools...)
+		m.logf(logStatus, now, "INFO: Pools updated")
+	}
+	return nil
+}
+
+func (m *Miner) handleGetSettings(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	writeJSON(w, m.settings())
+}
+
+func (m *Miner) handleSaveSettings(w http.ResponseWriter, r *http.Request) {
+	var update vnish.SettingsUpdate
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid settings")
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := m.applySettings(&update, time.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, map[string]bool{"restart_required": m.restartRequired, "reboot_required": false})
+}
+
+// handleBackup returns the settings as a tar.gz archive. The archive
+// timestamps change on every backup, the contents only with the settings.
+func (m *Miner) handleBackup(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	data, err := json.MarshalIndent(m.settings(), "", "  ")
+	m.mu.Unlock()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	archive, err := settingsBackup(data)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(archive)
+}
+
+// settingsBackup packs the settings file into a tar.gz archive.
+func settingsBackup(settings []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	hdr := &tar.Header{Name: vnishSettingsFile, Mode: 0644, Size: int64(len(settings)), ModTime: time.Now()}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(settings); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (m *Miner) handleRestore(w http.ResponseWriter, r *http.Request) {
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "missing backup file")
+		return
+	}
+	defer file.Close()
+
+	update, err := readSettingsBackup(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if err := m.applySettings(update, now); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	m.logf(logStatus, now, "INFO: Settings restored")
+	writeJSON(w, map[string]string{"status": "restored"})
+}
+
+// readSettingsBackup extracts the settings from a backup archive.
+func readSettingsBackup(r io.Reader) (*vnish.SettingsUpdate, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("backup has no %s", vnishSettingsFile)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if hdr.Name != vnishSettingsFile {
+			continue
+		}
+
+		var s vnishSettings
+		if err := json.NewDecoder(tr).Decode(&s); err != nil {
+			return nil, fmt.Errorf("invalid settings: %w", err)
+		}
+		overclock := s.Miner.Overclock
+		return &vnish.SettingsUpdate{Miner: vnish.MinerSettings{Overclock: &overclock, Pools: s.Miner.Pools}}, nil
+	}
+}
+
+func (m *Miner) handleFactoryReset(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.logf(logStatus, now, "INFO: Factory reset")
+	m.preset = PresetDisabled
+	m.restartRequired = false
+	m.tuned = make(map[string]bool)
+	m.notes = make(map[string]string)
+	m.apiKeys = nil
+	m.pools = nil
+	m.startMining(now)
+	writeJSON(w, map[string]string{"status": "ok"})
+}
+
+// =============================================================================
+// Mining Control & System
+// =============================================================================
+
+// handleMining handles /mining/{start,stop,pause,resume,restart,switch-pool}.
+func (m *Miner) handleMining(w http.ResponseWriter, r *http.Request) {
+	action := strings.TrimPrefix(r.URL.Path, "/api/v1/mining/")
+
+	var switchReq vnish.SwitchPoolRequest
+	if action == "switch-pool" {
+		if err := json.NewDecoder(r.Body).Decode(&switchReq); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body")
+			return
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.advance(now)
+	mining := m.state == StateMining || m.state == StateAutoTuning
+
+	switch action {
+	case "start":
+		if !mining && m.state != StateStarting {
+			m.startMining(now)
+		}
+	case "stop":
+		m.enter(StateStopped, now, time.Time{})
+	case "pause":
+		if !mining {
+			writeError(w, http.StatusBadRequest, "CGMiner err: Cgminer error failed-to-activate-pause-mode ")
+			return
+		}
+		m.enter(StatePaused, now, time.Time{})
+	case "resume":
+		if m.state != StatePaused {
+			writeError(w, http.StatusBadRequest, "CGMiner err: Cgminer error failed-to-activate-resume-mode ")
+			return
+		}
+		m.enter(StateMining, now, time.Time{})
+	case "restart":
+		m.logf(logStatus, now, "INFO: Restarting mining")
+		m.startMining(now)
+	case "switch-pool":
+		if switchReq.PoolID < 0 || int(switchReq.PoolID) >= len(m.pools) {
+			writeError(w, http.StatusBadRequest, "invalid pool id")
+			return
+		}
+		// The selected pool becomes the first priority
+		id := int(switchReq.PoolID)
+		pools := []vnish.PoolSettings{m.pools[id]}
+		for i, p := range m.pools {
+			if i != id {
+				pools = append(pools, p)
+			}
+		}
+		m.pools = pools
+	default:
+		writeError(w, http.StatusNotFound, "unknown action")
+	}
+}
+
+func (m *Miner) handleFindMiner(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.findMiner = !m.findMiner
+	writeJSON(w, &vnish.FindMinerResponse{On: m.findMiner})
+}
+
+func (m *Miner) handleReboot(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reboot(time.Now())
+}
+
+func (m *Miner) handleFirmwareUpdate(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "missing firmware file")
+		return
+	}
+	defer file.Close()
+
+	image, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.installFirmware(image, header.Filename, time.Now()); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	writeJSON(w, map[string]string{"status": "installing"})
+}
+
+// =============================================================================
+// Helpers
+// =============================================================================
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes a VNish error response ({"err": "..."}).
+func writeError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(&vnish.ErrorResponse{Err: msg})
+}
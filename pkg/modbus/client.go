@@ -0,0 +1,231 @@
+// Package modbus provides a minimal Modbus TCP client for reading energy meter registers.
+package modbus
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the standard Modbus TCP port.
+const DefaultPort = 502
+
+// Function codes.
+const (
+	FuncReadHoldingRegisters = 0x03
+	FuncReadInputRegisters   = 0x04
+)
+
+// maxRegisters is the most registers a single read may request.
+const maxRegisters = 125
+
+// Exception codes.
+const (
+	ExceptionIllegalFunction    = 0x01
+	ExceptionIllegalDataAddress = 0x02
+	ExceptionIllegalDataValue   = 0x03
+)
+
+// ExceptionError is a Modbus exception response from the device.
+type ExceptionError struct {
+	Function byte
+	Code     byte
+}
+
+func (e *ExceptionError) Error() string {
+	var msg string
+	switch e.Code {
+	case ExceptionIllegalFunction:
+		msg = "illegal function"
+	case ExceptionIllegalDataAddress:
+		msg = "illegal data address"
+	case ExceptionIllegalDataValue:
+		msg = "illegal data value"
+	case 0x04:
+		msg = "server device failure"
+	case 0x06:
+		msg = "server device busy"
+	case 0x0B:
+		msg = "gateway target device failed to respond"
+	default:
+		msg = "unknown exception"
+	}
+	return fmt.Sprintf("modbus exception %d on function %d: %s", e.Code, e.Function, msg)
+}
+
+// TCPClient is a Modbus TCP client. Requests are serialized over a single
+// connection, which is reopened after any error.
+type TCPClient struct {
+	address string
+	timeout time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	transID uint16
+}
+
+// ClientOption is a function that configures a TCPClient.
+type ClientOption func(*TCPClient)
+
+// WithTimeout sets the dial and response timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *TCPClient) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient creates a new Modbus TCP client.
+// The address may omit the port (default: 502).
+func NewClient(address string, opts ...ClientOption) *TCPClient {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, fmt.Sprint(DefaultPort))
+	}
+
+	c := &TCPClient{
+		address: address,
+		timeout: 5 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Address returns the device address.
+func (c *TCPClient) Address() string {
+	return c.address
+}
+
+// Close closes the connection, if open.
+func (c *TCPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closeConn()
+}
+
+func (c *TCPClient) closeConn() error {
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+// ReadHoldingRegisters reads count holding registers starting at addr (0-based).
+func (c *TCPClient) ReadHoldingRegisters(ctx context.Context, unit byte, addr, count uint16) ([]uint16, error) {
+	return c.readRegisters(ctx, unit, FuncReadHoldingRegisters, addr, count)
+}
+
+// ReadInputRegisters reads count input registers starting at addr (0-based).
+func (c *TCPClient) ReadInputRegisters(ctx context.Context, unit byte, addr, count uint16) ([]uint16, error) {
+	return c.readRegisters(ctx, unit, FuncReadInputRegisters, addr, count)
+}
+
+// readRegisters performs a register read with the given function code.
+func (c *TCPClient) readRegisters(ctx context.Context, unit, function byte, addr, count uint16) ([]uint16, error) {
+	if count == 0 || count > maxRegisters {
+		return nil, fmt.Errorf("invalid register count %d (1-%d)", count, maxRegisters)
+	}
+
+	pdu := make([]byte, 5)
+	pdu[0] = function
+	binary.BigEndian.PutUint16(pdu[1:], addr)
+	binary.BigEndian.PutUint16(pdu[3:], count)
+
+	resp, err := c.do(ctx, unit, pdu)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(resp) < 2 || int(resp[1]) != 2*int(count) || len(resp) != 2+2*int(count) {
+		return nil, fmt.Errorf("malformed response for %d registers", count)
+	}
+
+	regs := make([]uint16, count)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(resp[2+2*i:])
+	}
+	return regs, nil
+}
+
+// do sends a request PDU and returns the response PDU.
+func (c *TCPClient) do(ctx context.Context, unit byte, pdu []byte) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp, err := c.roundTrip(ctx, unit, pdu)
+	if err != nil {
+		// The stream may be out of sync; start over on the next request
+		c.closeConn()
+		return nil, err
+	}
+
+	if resp[0] == pdu[0]|0x80 {
+		if len(resp) < 2 {
+			return nil, fmt.Errorf("malformed exception response")
+		}
+		return nil, &ExceptionError{Function: pdu[0], Code: resp[1]}
+	}
+	if resp[0] != pdu[0] {
+		return nil, fmt.Errorf("unexpected function code %d in response", resp[0])
+	}
+	return resp, nil
+}
+
+// roundTrip writes one MBAP framed request and reads its response. Must be called with mu held.
+func (c *TCPClient) roundTrip(ctx context.Context, unit byte, pdu []byte) ([]byte, error) {
+	if c.conn == nil {
+		dialer := net.Dialer{Timeout: c.timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", c.address)
+		if err != nil {
+			return nil, fmt.Errorf("connect failed: %w", err)
+		}
+		c.conn = conn
+	}
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+
+	c.transID++
+	req := make([]byte, 7+len(pdu))
+	binary.BigEndian.PutUint16(req[0:], c.transID)
+	binary.BigEndian.PutUint16(req[2:], 0) // Protocol ID
+	binary.BigEndian.PutUint16(req[4:], uint16(len(pdu)+1))
+	req[6] = unit
+	copy(req[7:], pdu)
+
+	if _, err := c.conn.Write(req); err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	header := make([]byte, 7)
+	if _, err := io.ReadFull(c.conn, header); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	length := binary.BigEndian.Uint16(header[4:])
+	if length < 2 || length > 254 {
+		return nil, fmt.Errorf("invalid response length %d", length)
+	}
+	resp := make([]byte, length-1)
+	if _, err := io.ReadFull(c.conn, resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if id := binary.BigEndian.Uint16(header[0:]); id != c.transID {
+		return nil, fmt.Errorf("transaction ID mismatch: sent %d, got %d", c.transID, id)
+	}
+	if header[6] != unit {
+		return nil, fmt.Errorf("unit ID mismatch: sent %d, got %d", unit, header[6])
+	}
+	return resp, nil
+}
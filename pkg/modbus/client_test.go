@@ -0,0 +1,173 @@
+package modbus
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// startServer serves s on a loopback port and returns its address.
+func startServer(t *testing.T, s *Server) string {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go s.Serve(l)
+	t.Cleanup(func() { s.Close() })
+	return l.Addr().String()
+}
+
+func TestClientReadRegisters(t *testing.T) {
+	s := NewServer()
+	s.SetHoldingRegisters(100, 0x4148, 0xf5c3, 7)
+	s.SetInputRegisters(100, 0x0001, 0x0002)
+
+	c := NewClient(startServer(t, s), WithTimeout(time.Second))
+	defer c.Close()
+
+	tests := []struct {
+		name     string
+		input    bool
+		addr     uint16
+		count    uint16
+		want     []uint16
+		wantCode byte // Expected exception, 0 for none
+	}{
+		{name: "holding", addr: 100, count: 3, want: []uint16{0x4148, 0xf5c3, 7}},
+		{name: "holding offset", addr: 102, count: 1, want: []uint16{7}},
+		{name: "input", input: true, addr: 100, count: 2, want: []uint16{1, 2}},
+		{name: "holding past end", addr: 101, count: 3, wantCode: ExceptionIllegalDataAddress},
+		{name: "input not set", input: true, addr: 0, count: 1, wantCode: ExceptionIllegalDataAddress},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				got []uint16
+				err error
+			)
+			if tt.input {
+				got, err = c.ReadInputRegisters(ctx, 1, tt.addr, tt.count)
+			} else {
+				got, err = c.ReadHoldingRegisters(ctx, 1, tt.addr, tt.count)
+			}
+
+			if tt.wantCode != 0 {
+				var exc *ExceptionError
+				if !errors.As(err, &exc) || exc.Code != tt.wantCode {
+					t.Fatalf("err = %v, want exception %d", err, tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("read: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %04x, want %04x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientDecodesMeterValues(t *testing.T) {
+	s := NewServer()
+	c := NewClient(startServer(t, s), WithTimeout(time.Second))
+	defer c.Close()
+
+	ctx := context.Background()
+	for _, order := range wordOrders {
+		for _, dataType := range []DataType{Int16, Uint32, Float32, Int64, Float64} {
+			t.Run(string(dataType)+"/"+string(order), func(t *testing.T) {
+				want := 12.5
+				if dataType != Float32 && dataType != Float64 {
+					want = -1250
+					if dataType == Uint32 {
+						want = 1250
+					}
+				}
+
+				regs, err := Encode(want, dataType, order)
+				if err != nil {
+					t.Fatalf("Encode: %v", err)
+				}
+				s.SetHoldingRegisters(200, regs...)
+
+				got, err := c.ReadHoldingRegisters(ctx, 1, 200, uint16(dataType.Registers()))
+				if err != nil {
+					t.Fatalf("read: %v", err)
+				}
+				v, err := Decode(got, dataType, order)
+				if err != nil {
+					t.Fatalf("Decode: %v", err)
+				}
+				if v != want {
+					t.Errorf("got %v, want %v", v, want)
+				}
+			})
+		}
+	}
+}
+
+func TestClientInvalidCount(t *testing.T) {
+	c := NewClient("127.0.0.1:1")
+	for _, count := range []uint16{0, maxRegisters + 1} {
+		if _, err := c.ReadHoldingRegisters(context.Background(), 1, 0, count); err == nil {
+			t.Errorf("count %d: expected an error", count)
+		}
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	s := NewServer()
+	s.SetHoldingRegisters(0, 42)
+	addr := startServer(t, s)
+
+	c := NewClient(addr, WithTimeout(time.Second))
+	defer c.Close()
+
+	ctx := context.Background()
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err != nil {
+		t.Fatalf("first read: %v", err)
+	}
+
+	// Drop the open connection; the first read after fails, the next reconnects
+	s.lnMu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.lnMu.Unlock()
+
+	if _, err := c.ReadHoldingRegisters(ctx, 1, 0, 1); err == nil {
+		t.Fatal("expected the read on the dropped connection to fail")
+	}
+	got, err := c.ReadHoldingRegisters(ctx, 1, 0, 1)
+	if err != nil {
+		t.Fatalf("read after reconnect: %v", err)
+	}
+	if got[0] != 42 {
+		t.Errorf("got %d, want 42", got[0])
+	}
+}
+
+func TestNewClientDefaultPort(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"10.0.0.5", "10.0.0.5:502"},
+		{"10.0.0.5:1502", "10.0.0.5:1502"},
+		{"meter.local", "meter.local:502"},
+	}
+
+	for _, tt := range tests {
+		if got := NewClient(tt.address).Address(); got != tt.want {
+			t.Errorf("NewClient(%q).Address() = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
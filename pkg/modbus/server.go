@@ -0,0 +1,173 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// Server is a Modbus TCP server answering register reads from memory.
+// It stands in for an energy meter in tests and simulations; every unit ID
+// sees the same registers.
+type Server struct {
+	mu      sync.RWMutex
+	holding map[uint16]uint16
+	input   map[uint16]uint16
+
+	lnMu     sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+// NewServer creates a server with no registers.
+func NewServer() *Server {
+	return &Server{
+		holding: make(map[uint16]uint16),
+		input:   make(map[uint16]uint16),
+		conns:   make(map[net.Conn]struct{}),
+	}
+}
+
+// SetHoldingRegisters stores values in consecutive holding registers from addr.
+func (s *Server) SetHoldingRegisters(addr uint16, values ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range values {
+		s.holding[addr+uint16(i)] = v
+	}
+}
+
+// SetInputRegisters stores values in consecutive input registers from addr.
+func (s *Server) SetInputRegisters(addr uint16, values ...uint16) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, v := range values {
+		s.input[addr+uint16(i)] = v
+	}
+}
+
+// ListenAndServe listens on the TCP address and serves until Close.
+func (s *Server) ListenAndServe(address string) error {
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on l until Close. It returns nil after Close.
+func (s *Server) Serve(l net.Listener) error {
+	s.lnMu.Lock()
+	if s.closed {
+		s.lnMu.Unlock()
+		l.Close()
+		return nil
+	}
+	s.listener = l
+	s.lnMu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.lnMu.Lock()
+			closed := s.closed
+			s.lnMu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+
+		s.lnMu.Lock()
+		s.conns[conn] = struct{}{}
+		s.lnMu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+// Close stops the server and closes all connections.
+func (s *Server) Close() error {
+	s.lnMu.Lock()
+	defer s.lnMu.Unlock()
+
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer func() {
+		conn.Close()
+		s.lnMu.Lock()
+		delete(s.conns, conn)
+		s.lnMu.Unlock()
+	}()
+
+	header := make([]byte, 7)
+	for {
+		if _, err := io.ReadFull(conn, header); err != nil {
+			return
+		}
+		length := binary.BigEndian.Uint16(header[4:])
+		if length < 2 || length > 254 {
+			return
+		}
+		pdu := make([]byte, length-1)
+		if _, err := io.ReadFull(conn, pdu); err != nil {
+			return
+		}
+
+		resp := s.handle(pdu)
+		binary.BigEndian.PutUint16(header[4:], uint16(len(resp)+1))
+		if _, err := conn.Write(append(header, resp...)); err != nil {
+			return
+		}
+	}
+}
+
+// handle returns the response PDU for a request PDU.
+func (s *Server) handle(pdu []byte) []byte {
+	function := pdu[0]
+
+	var regs map[uint16]uint16
+	switch function {
+	case FuncReadHoldingRegisters:
+		regs = s.holding
+	case FuncReadInputRegisters:
+		regs = s.input
+	default:
+		return []byte{function | 0x80, ExceptionIllegalFunction}
+	}
+
+	if len(pdu) != 5 {
+		return []byte{function | 0x80, ExceptionIllegalDataValue}
+	}
+	addr := binary.BigEndian.Uint16(pdu[1:])
+	count := binary.BigEndian.Uint16(pdu[3:])
+	if count == 0 || count > maxRegisters {
+		return []byte{function | 0x80, ExceptionIllegalDataValue}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	resp := make([]byte, 2+2*int(count))
+	resp[0] = function
+	resp[1] = byte(2 * count)
+	for i := uint16(0); i < count; i++ {
+		v, ok := regs[addr+i]
+		if !ok {
+			return []byte{function | 0x80, ExceptionIllegalDataAddress}
+		}
+		binary.BigEndian.PutUint16(resp[2+2*i:], v)
+	}
+	return resp
+}
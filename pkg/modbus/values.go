@@ -0,0 +1,167 @@
+package modbus
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DataType is how a value is encoded in registers.
+type DataType string
+
+const (
+	Uint16  DataType = "uint16"
+	Int16   DataType = "int16"
+	Uint32  DataType = "uint32"
+	Int32   DataType = "int32"
+	Float32 DataType = "float32"
+	Uint64  DataType = "uint64"
+	Int64   DataType = "int64"
+	Float64 DataType = "float64"
+)
+
+// Registers returns the number of registers a value of the type spans, or 0 if unknown.
+func (t DataType) Registers() int {
+	switch t {
+	case Uint16, Int16:
+		return 1
+	case Uint32, Int32, Float32:
+		return 2
+	case Uint64, Int64, Float64:
+		return 4
+	}
+	return 0
+}
+
+// WordOrder is the byte order of a multi-register value, written as the
+// position of each byte from most to least significant ("ABCD" is big-endian).
+type WordOrder string
+
+const (
+	OrderABCD WordOrder = "ABCD" // Big-endian
+	OrderCDAB WordOrder = "CDAB" // Word swapped, common on meters
+	OrderBADC WordOrder = "BADC" // Byte swapped
+	OrderDCBA WordOrder = "DCBA" // Little-endian
+)
+
+// ParseWordOrder parses a word order, case-insensitively. Empty means ABCD.
+func ParseWordOrder(s string) (WordOrder, error) {
+	o := WordOrder(strings.ToUpper(s))
+	switch o {
+	case "":
+		return OrderABCD, nil
+	case OrderABCD, OrderCDAB, OrderBADC, OrderDCBA:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown word order %q (ABCD, CDAB, BADC or DCBA)", s)
+}
+
+// ParseDataType parses a data type, case-insensitively. Empty means float32.
+func ParseDataType(s string) (DataType, error) {
+	t := DataType(strings.ToLower(s))
+	if t == "" {
+		return Float32, nil
+	}
+	if t.Registers() == 0 {
+		return "", fmt.Errorf("unknown data type %q", s)
+	}
+	return t, nil
+}
+
+// Decode converts registers, as read from the device, to a value.
+func Decode(regs []uint16, t DataType, order WordOrder) (float64, error) {
+	n := t.Registers()
+	if n == 0 {
+		return 0, fmt.Errorf("unknown data type %q", t)
+	}
+	if len(regs) != n {
+		return 0, fmt.Errorf("%s needs %d registers, got %d", t, n, len(regs))
+	}
+
+	b := toBigEndian(regs, order)
+	switch t {
+	case Uint16:
+		return float64(binary.BigEndian.Uint16(b)), nil
+	case Int16:
+		return float64(int16(binary.BigEndian.Uint16(b))), nil
+	case Uint32:
+		return float64(binary.BigEndian.Uint32(b)), nil
+	case Int32:
+		return float64(int32(binary.BigEndian.Uint32(b))), nil
+	case Float32:
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case Uint64:
+		return float64(binary.BigEndian.Uint64(b)), nil
+	case Int64:
+		return float64(int64(binary.BigEndian.Uint64(b))), nil
+	default:
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	}
+}
+
+// Encode converts a value to registers as the device would store it.
+// Integer types are rounded to the nearest integer.
+func Encode(v float64, t DataType, order WordOrder) ([]uint16, error) {
+	n := t.Registers()
+	if n == 0 {
+		return nil, fmt.Errorf("unknown data type %q", t)
+	}
+
+	b := make([]byte, 2*n)
+	switch t {
+	case Uint16, Int16:
+		binary.BigEndian.PutUint16(b, uint16(int64(math.Round(v))))
+	case Uint32, Int32:
+		binary.BigEndian.PutUint32(b, uint32(int64(math.Round(v))))
+	case Float32:
+		binary.BigEndian.PutUint32(b, math.Float32bits(float32(v)))
+	case Uint64, Int64:
+		binary.BigEndian.PutUint64(b, uint64(int64(math.Round(v))))
+	default:
+		binary.BigEndian.PutUint64(b, math.Float64bits(v))
+	}
+	return fromBigEndian(b, order), nil
+}
+
+// toBigEndian returns the value bytes most significant first.
+func toBigEndian(regs []uint16, order WordOrder) []byte {
+	b := make([]byte, 2*len(regs))
+	for i, r := range regs {
+		binary.BigEndian.PutUint16(b[2*i:], r)
+	}
+	return reorder(b, order)
+}
+
+// fromBigEndian returns the registers of value bytes given most significant first.
+func fromBigEndian(b []byte, order WordOrder) []uint16 {
+	b = reorder(b, order)
+	regs := make([]uint16, len(b)/2)
+	for i := range regs {
+		regs[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return regs
+}
+
+// reorder converts between the wire order and big-endian. Each conversion is
+// its own inverse: CDAB reverses the words, BADC swaps the bytes of each word
+// and DCBA reverses all bytes.
+func reorder(b []byte, order WordOrder) []byte {
+	out := append([]byte(nil), b...)
+	switch order {
+	case OrderCDAB:
+		for i := 0; i < len(out)/2; i += 2 {
+			j := len(out) - 2 - i
+			out[i], out[i+1], out[j], out[j+1] = out[j], out[j+1], out[i], out[i+1]
+		}
+	case OrderBADC:
+		for i := 0; i+1 < len(out); i += 2 {
+			out[i], out[i+1] = out[i+1], out[i]
+		}
+	case OrderDCBA:
+		for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+	return out
+}
@@ -0,0 +1,147 @@
+package modbus
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+var wordOrders = []WordOrder{OrderABCD, OrderCDAB, OrderBADC, OrderDCBA}
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	tests := []struct {
+		dataType DataType
+		values   []float64
+	}{
+		{Uint16, []float64{0, 1, 12345, 65535}},
+		{Int16, []float64{0, -1, 12345, -32768, 32767}},
+		{Uint32, []float64{0, 1, 65536, 4294967295}},
+		{Int32, []float64{0, -1, -2147483648, 2147483647}},
+		{Float32, []float64{0, 1.5, -273.25, 12345.5}},
+		{Uint64, []float64{0, 1, 1 << 40}},
+		{Int64, []float64{0, -1, -(1 << 40), 1 << 52}},
+		{Float64, []float64{0, math.Pi, -1e-9, 6.02214076e23}},
+	}
+
+	for _, tt := range tests {
+		for _, order := range wordOrders {
+			t.Run(string(tt.dataType)+"/"+string(order), func(t *testing.T) {
+				for _, v := range tt.values {
+					regs, err := Encode(v, tt.dataType, order)
+					if err != nil {
+						t.Fatalf("Encode(%v): %v", v, err)
+					}
+					if len(regs) != tt.dataType.Registers() {
+						t.Fatalf("Encode(%v) = %d registers, want %d", v, len(regs), tt.dataType.Registers())
+					}
+					got, err := Decode(regs, tt.dataType, order)
+					if err != nil {
+						t.Fatalf("Decode(%v): %v", regs, err)
+					}
+					if got != v {
+						t.Errorf("Decode(Encode(%v)) = %v", v, got)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestWordOrders(t *testing.T) {
+	// 0x01020304 and 0x0102030405060700 (exact as a float64), bytes given as A B C D ...
+	tests := []struct {
+		order  WordOrder
+		regs32 []uint16
+		regs64 []uint16
+	}{
+		{OrderABCD, []uint16{0x0102, 0x0304}, []uint16{0x0102, 0x0304, 0x0506, 0x0700}},
+		{OrderCDAB, []uint16{0x0304, 0x0102}, []uint16{0x0700, 0x0506, 0x0304, 0x0102}},
+		{OrderBADC, []uint16{0x0201, 0x0403}, []uint16{0x0201, 0x0403, 0x0605, 0x0007}},
+		{OrderDCBA, []uint16{0x0403, 0x0201}, []uint16{0x0007, 0x0605, 0x0403, 0x0201}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.order), func(t *testing.T) {
+			got, err := Decode(tt.regs32, Uint32, tt.order)
+			if err != nil || got != 0x01020304 {
+				t.Errorf("Decode(%04x) = %x, %v, want 1020304", tt.regs32, uint32(got), err)
+			}
+			regs, _ := Encode(0x01020304, Uint32, tt.order)
+			if !reflect.DeepEqual(regs, tt.regs32) {
+				t.Errorf("Encode(0x01020304) = %04x, want %04x", regs, tt.regs32)
+			}
+
+			got, err = Decode(tt.regs64, Uint64, tt.order)
+			if err != nil || got != 0x0102030405060700 {
+				t.Errorf("Decode(%04x) = %x, %v, want 102030405060700", tt.regs64, uint64(got), err)
+			}
+			regs, _ = Encode(0x0102030405060700, Uint64, tt.order)
+			if !reflect.DeepEqual(regs, tt.regs64) {
+				t.Errorf("Encode(0x0102030405060700) = %04x, want %04x", regs, tt.regs64)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		regs     []uint16
+		dataType DataType
+	}{
+		{"unknown type", []uint16{1}, DataType("int8")},
+		{"too few registers", []uint16{1}, Float32},
+		{"too many registers", []uint16{1, 2, 3}, Int32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.regs, tt.dataType, OrderABCD); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseWordOrder(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    WordOrder
+		wantErr bool
+	}{
+		{"", OrderABCD, false},
+		{"abcd", OrderABCD, false},
+		{"CDAB", OrderCDAB, false},
+		{"badc", OrderBADC, false},
+		{"DcBa", OrderDCBA, false},
+		{"ACBD", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseWordOrder(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseWordOrder(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestParseDataType(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    DataType
+		wantErr bool
+	}{
+		{"", Float32, false},
+		{"UINT16", Uint16, false},
+		{"int32", Int32, false},
+		{"Float64", Float64, false},
+		{"double", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDataType(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseDataType(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+// Package mqtt provides a minimal MQTT 3.1.1 subscriber for telemetry topics.
+// Only QoS 0 subscriptions are supported, which is what meter gateways publish.
+package mqtt
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPort is the standard MQTT port.
+const DefaultPort = 1883
+
+// Packet types (upper nibble of the fixed header).
+const (
+	packetConnect     = 1
+	packetConnAck     = 2
+	packetPublish     = 3
+	packetPubAck      = 4
+	packetSubscribe   = 8
+	packetSubAck      = 9
+	packetPingReq     = 12
+	packetPingResp    = 13
+	packetDisconnect  = 14
+	maxRemainingBytes = 268435455
+)
+
+// Handler receives published messages.
+type Handler func(topic string, payload []byte)
+
+// Subscriber connects to a broker and delivers messages of its topics.
+type Subscriber struct {
+	broker    string
+	clientID  string
+	username  string
+	password  string
+	keepAlive time.Duration
+	timeout   time.Duration
+}
+
+// SubscriberOption is a function that configures a Subscriber.
+type SubscriberOption func(*Subscriber)
+
+// WithClientID sets the MQTT client identifier (default: "powerhive-" and a time-based suffix).
+func WithClientID(id string) SubscriberOption {
+	return func(s *Subscriber) {
+		s.clientID = id
+	}
+}
+
+// WithCredentials sets the broker username and password.
+func WithCredentials(username, password string) SubscriberOption {
+	return func(s *Subscriber) {
+		s.username = username
+		s.password = password
+	}
+}
+
+// WithKeepAlive sets the keep alive interval (default: 30s).
+func WithKeepAlive(keepAlive time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.keepAlive = keepAlive
+	}
+}
+
+// WithTimeout sets the dial and handshake timeout (default: 10s).
+func WithTimeout(timeout time.Duration) SubscriberOption {
+	return func(s *Subscriber) {
+		s.timeout = timeout
+	}
+}
+
+// NewSubscriber creates a subscriber for a broker ("host", "host:port" or "tcp://host:port").
+func NewSubscriber(broker string, opts ...SubscriberOption) *Subscriber {
+	broker = strings.TrimPrefix(broker, "tcp://")
+	broker = strings.TrimPrefix(broker, "mqtt://")
+	if _, _, err := net.SplitHostPort(broker); err != nil {
+		broker = net.JoinHostPort(broker, fmt.Sprint(DefaultPort))
+	}
+
+	s := &Subscriber{
+		broker:    broker,
+		clientID:  fmt.Sprintf("powerhive-%d", time.Now().UnixNano()%1_000_000),
+		keepAlive: 30 * time.Second,
+		timeout:   10 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Broker returns the broker address.
+func (s *Subscriber) Broker() string {
+	return s.broker
+}
+
+// Subscribe connects, subscribes to the topics and calls handler for every
+// message until ctx is done or the connection fails. It doesn't reconnect.
+func (s *Subscriber) Subscribe(ctx context.Context, topics []string, handler Handler) error {
+	if len(topics) == 0 {
+		return fmt.Errorf("no topics")
+	}
+
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.broker)
+	if err != nil {
+		return fmt.Errorf("connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock reads on cancellation
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	w := &packetWriter{conn: conn}
+
+	conn.SetDeadline(time.Now().Add(s.timeout))
+	if err := w.write(packetConnect<<4, s.connectPayload()); err != nil {
+		return fmt.Errorf("failed to send connect: %w", err)
+	}
+	typ, body, err := readPacket(r)
+	if err != nil {
+		return fmt.Errorf("failed to read connack: %w", err)
+	}
+	if typ>>4 != packetConnAck || len(body) < 2 {
+		return fmt.Errorf("expected connack, got packet type %d", typ>>4)
+	}
+	if body[1] != 0 {
+		return fmt.Errorf("connection refused: %s", connAckReason(body[1]))
+	}
+
+	if err := w.write(packetSubscribe<<4|0x02, subscribePayload(1, topics)); err != nil {
+		return fmt.Errorf("failed to send subscribe: %w", err)
+	}
+	conn.SetDeadline(time.Time{})
+
+	// Keep alive pings; the broker drops us after 1.5x the interval without traffic
+	go func() {
+		ticker := time.NewTicker(s.keepAlive / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := w.write(packetPingReq<<4, nil); err != nil {
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(s.keepAlive * 2))
+		typ, body, err := readPacket(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				w.write(packetDisconnect<<4, nil)
+				return ctx.Err()
+			}
+			return fmt.Errorf("connection lost: %w", err)
+		}
+
+		switch typ >> 4 {
+		case packetSubAck:
+			for _, rc := range body[min(2, len(body)):] {
+				if rc == 0x80 {
+					return fmt.Errorf("subscription refused by broker")
+				}
+			}
+		case packetPublish:
+			topic, payload, packetID, err := parsePublish(typ, body)
+			if err != nil {
+				return err
+			}
+			if packetID != 0 {
+				ack := make([]byte, 2)
+				binary.BigEndian.PutUint16(ack, packetID)
+				if err := w.write(packetPubAck<<4, ack); err != nil {
+					return fmt.Errorf("failed to send puback: %w", err)
+				}
+			}
+			handler(topic, payload)
+		case packetPingResp:
+		}
+	}
+}
+
+// connectPayload returns the variable header and payload of CONNECT.
+func (s *Subscriber) connectPayload() []byte {
+	var b []byte
+	b = appendString(b, "MQTT")
+	b = append(b, 4) // Protocol level 3.1.1
+
+	flags := byte(0x02) // Clean session
+	if s.username != "" {
+		flags |= 0x80
+		if s.password != "" {
+			flags |= 0x40
+		}
+	}
+	b = append(b, flags)
+	b = binary.BigEndian.AppendUint16(b, uint16(s.keepAlive/time.Second))
+
+	b = appendString(b, s.clientID)
+	if s.username != "" {
+		b = appendString(b, s.username)
+		if s.password != "" {
+			b = appendString(b, s.password)
+		}
+	}
+	return b
+}
+
+// subscribePayload returns the variable header and payload of SUBSCRIBE at QoS 0.
+func subscribePayload(packetID uint16, topics []string) []byte {
+	b := binary.BigEndian.AppendUint16(nil, packetID)
+	for _, t := range topics {
+		b = appendString(b, t)
+		b = append(b, 0)
+	}
+	return b
+}
+
+// parsePublish decodes a PUBLISH packet. packetID is 0 for QoS 0.
+func parsePublish(header byte, body []byte) (string, []byte, uint16, error) {
+	if len(body) < 2 {
+		return "", nil, 0, fmt.Errorf("malformed publish")
+	}
+	n := int(binary.BigEndian.Uint16(body))
+	if len(body) < 2+n {
+		return "", nil, 0, fmt.Errorf("malformed publish topic")
+	}
+	topic := string(body[2 : 2+n])
+	rest := body[2+n:]
+
+	var packetID uint16
+	if qos := (header >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return "", nil, 0, fmt.Errorf("malformed publish packet ID")
+		}
+		packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+	return topic, rest, packetID, nil
+}
+
+func connAckReason(code byte) string {
+	switch code {
+	case 1:
+		return "unacceptable protocol version"
+	case 2:
+		return "identifier rejected"
+	case 3:
+		return "server unavailable"
+	case 4:
+		return "bad username or password"
+	case 5:
+		return "not authorized"
+	}
+	return fmt.Sprintf("code %d", code)
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(s)))
+	return append(b, s...)
+}
+
+// packetWriter serializes writes from the reader loop and the pinger.
+type packetWriter struct {
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func (w *packetWriter) write(header byte, body []byte) error {
+	b := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		b = append(b, digit)
+		if n == 0 {
+			break
+		}
+	}
+	b = append(b, body...)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, err := w.conn.Write(b)
+	return err
+}
+
+// readPacket reads one packet, returning its fixed header byte and body.
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	header, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	length, multiplier := 0, 1
+	for {
+		digit, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(digit&0x7F) * multiplier
+		if length > maxRemainingBytes {
+			return 0, nil, errors.New("malformed remaining length")
+		}
+		if digit&0x80 == 0 {
+			break
+		}
+		multiplier *= 128
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return 0, nil, err
+	}
+	return header, body, nil
+}
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strings"
 	"sync/atomic"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
 )
 
 const (
@@ -22,6 +24,8 @@ type DigestAuth struct {
 	Username string
 	Password string
 	nc       uint64 // nonce counter
+
+	resolver miner.CredentialResolver // optional per-host credentials
 }
 
 // NewDigestAuth creates a new digest auth handler with default credentials.
@@ -40,6 +44,30 @@ func NewDigestAuthWithCredentials(username, password string) *DigestAuth {
 	}
 }
 
+// WithCredentialResolver resolves credentials per host.
+// Username and Password are only used for hosts without candidates.
+func (a *DigestAuth) WithCredentialResolver(r miner.CredentialResolver) *DigestAuth {
+	a.resolver = r
+	return a
+}
+
+// Candidates returns the credentials to try for a host, in order.
+func (a *DigestAuth) Candidates(host string) []miner.Credentials {
+	if a.resolver != nil {
+		if creds := a.resolver.Candidates(host, miner.FirmwareStock); len(creds) > 0 {
+			return creds
+		}
+	}
+	return []miner.Credentials{{Username: a.Username, Password: a.Password}}
+}
+
+// confirm records that creds authenticated against the host.
+func (a *DigestAuth) confirm(host string, creds miner.Credentials) {
+	if a.resolver != nil {
+		a.resolver.Confirm(host, miner.FirmwareStock, creds)
+	}
+}
+
 // DigestTransport is an http.RoundTripper that handles digest authentication.
 type DigestTransport struct {
 	Auth      *DigestAuth
@@ -59,34 +87,51 @@ func (t *DigestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
 		return nil, err
 	}
 
-	// If not 401, return response
-	if resp.StatusCode != http.StatusUnauthorized {
-		return resp, nil
-	}
+	// Answer each challenge with the next candidate credentials until one is accepted
+	host := req.URL.Hostname()
+	for _, creds := range t.Auth.Candidates(host) {
+		// If not 401, return response
+		if resp.StatusCode != http.StatusUnauthorized {
+			return resp, nil
+		}
 
-	// Get WWW-Authenticate header
-	authHeader := resp.Header.Get("WWW-Authenticate")
-	if authHeader == "" || !strings.HasPrefix(authHeader, "Digest ") {
-		return resp, nil
-	}
+		// Get WWW-Authenticate header
+		authHeader := resp.Header.Get("WWW-Authenticate")
+		if authHeader == "" || !strings.HasPrefix(authHeader, "Digest ") {
+			return resp, nil
+		}
 
-	// Close first response body
-	io.Copy(io.Discard, resp.Body)
-	resp.Body.Close()
+		// Parse digest challenge
+		challenge := parseDigestChallenge(authHeader)
+		if challenge == nil {
+			return resp, nil
+		}
 
-	// Parse digest challenge
-	challenge := parseDigestChallenge(authHeader)
-	if challenge == nil {
-		return resp, nil
-	}
+		// Close previous response body
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
 
-	// Create new request with auth
-	newReq := req.Clone(req.Context())
-	authValue := t.Auth.createAuthHeader(req.Method, req.URL.Path, challenge)
-	newReq.Header.Set("Authorization", authValue)
+		// Create new request with auth
+		newReq := req.Clone(req.Context())
+		if req.GetBody != nil {
+			if newReq.Body, err = req.GetBody(); err != nil {
+				return nil, err
+			}
+		}
+		authValue := t.Auth.createAuthHeader(creds, req.Method, req.URL.Path, challenge)
+		newReq.Header.Set("Authorization", authValue)
+
+		// Retry with auth
+		resp, err = transport.RoundTrip(newReq)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Auth.confirm(host, creds)
+		}
+	}
 
-	// Retry with auth
-	return transport.RoundTrip(newReq)
+	return resp, nil
 }
 
 // digestChallenge contains parsed WWW-Authenticate header values.
@@ -132,7 +177,7 @@ func parseDigestChallenge(header string) *digestChallenge {
 }
 
 // createAuthHeader creates the Authorization header for digest auth.
-func (a *DigestAuth) createAuthHeader(method, uri string, c *digestChallenge) string {
+func (a *DigestAuth) createAuthHeader(creds miner.Credentials, method, uri string, c *digestChallenge) string {
 	// Increment nonce counter
 	nc := atomic.AddUint64(&a.nc, 1)
 	ncStr := fmt.Sprintf("%08x", nc)
@@ -141,7 +186,7 @@ func (a *DigestAuth) createAuthHeader(method, uri string, c *digestChallenge) st
 	cnonce := fmt.Sprintf("%08x", nc*12345)
 
 	// Calculate HA1 = MD5(username:realm:password)
-	ha1 := md5Hash(fmt.Sprintf("%s:%s:%s", a.Username, c.Realm, a.Password))
+	ha1 := md5Hash(fmt.Sprintf("%s:%s:%s", creds.Username, c.Realm, creds.Password))
 
 	// Calculate HA2 = MD5(method:uri)
 	ha2 := md5Hash(fmt.Sprintf("%s:%s", method, uri))
@@ -159,7 +204,7 @@ func (a *DigestAuth) createAuthHeader(method, uri string, c *digestChallenge) st
 
 	// Build header
 	header := fmt.Sprintf(`Digest username="%s", realm="%s", nonce="%s", uri="%s", response="%s"`,
-		a.Username, c.Realm, c.Nonce, uri, response)
+		creds.Username, c.Realm, c.Nonce, uri, response)
 
 	if c.QOP != "" {
 		header += fmt.Sprintf(`, qop=%s, nc=%s, cnonce="%s"`, c.QOP, ncStr, cnonce)
@@ -6,11 +6,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
 
+	"github.com/powerhive/powerhive-v2/pkg/cgminer"
 	"github.com/powerhive/powerhive-v2/pkg/miner"
 )
 
@@ -60,6 +62,9 @@ type Client interface {
 
 	// ResetConfig resets miner configuration to factory defaults.
 	ResetConfig(ctx context.Context) error
+
+	// UpdateFirmware uploads and installs a firmware image.
+	UpdateFirmware(ctx context.Context, image []byte, filename string) error
 }
 
 // HTTPClient is the HTTP implementation of the stock firmware Client.
@@ -409,6 +414,51 @@ func (c *HTTPClient) ResetConfig(ctx context.Context) error {
 	return err
 }
 
+// UpdateFirmware uploads and installs a firmware image via upgrade.cgi.
+// The miner reboots when the install finishes (implements miner.FirmwareUpdater).
+func (c *HTTPClient) UpdateFirmware(ctx context.Context, image []byte, filename string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(image); err != nil {
+		return fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	// bytes.Reader lets the digest transport replay the body after a 401 challenge
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/upgrade.cgi", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upgrade request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upgrade failed with status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var result ConfigResponse
+	if len(respBody) > 0 && json.Unmarshal(respBody, &result) == nil && result.Stats == "error" {
+		return fmt.Errorf("upgrade failed: %s (%s)", result.Msg, result.Code)
+	}
+	return nil
+}
+
 // =============================================================================
 // miner.Client interface implementation
 // =============================================================================
@@ -440,7 +490,8 @@ func (c *HTTPClient) GetMinerInfo(ctx context.Context) (*miner.Info, error) {
 }
 
 // GetMinerStatus returns generic miner status (implements miner.Client).
-// Tries summary.cgi first (KS5, newer models), falls back to get_miner_status.cgi (S19, older models).
+// Tries summary.cgi first (KS5, newer models), falls back to get_miner_status.cgi (S19, older models),
+// then to the cgminer TCP API.
 func (c *HTTPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error) {
 	// Try summary.cgi first (KS5, newer models)
 	var summaryResp SummaryResponse
@@ -494,6 +545,12 @@ func (c *HTTPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error)
 		}, nil
 	}
 
+	// Fallback to the cgminer TCP API (port 4028) when neither CGI endpoint works
+	cgClient := cgminer.NewClient(c.host, cgminer.WithTimeout(c.httpClient.Timeout))
+	if status, cgErr := cgClient.GetMinerStatus(ctx); cgErr == nil {
+		return status, nil
+	}
+
 	if err != nil {
 		return nil, err
 	}
@@ -520,5 +577,112 @@ func extractSeries(minerType string) string {
 	return ""
 }
 
-// Ensure HTTPClient implements miner.Client interface.
-var _ miner.Client = (*HTTPClient)(nil)
+// =============================================================================
+// miner.PowerController interface implementation
+// =============================================================================
+
+// Work modes for the bitmain-work-mode config field.
+const (
+	WorkModeNormal   = "0"
+	WorkModeSleep    = "1"
+	WorkModeLowPower = "2"
+)
+
+// LowPowerFactor is the share of the rated power and hashrate drawn in low
+// power mode, as stock firmware doesn't report per-mode consumption.
+const LowPowerFactor = 0.7
+
+// ratedPower is the rated consumption and hashrate of stock models in the
+// normal work mode, from the manufacturer's specifications.
+var ratedPower = map[string]struct {
+	watts      int
+	hashrateTH float64
+}{
+	"Antminer S19":      {3250, 95},
+	"Antminer S19 Pro":  {3250, 110},
+	"Antminer S19j Pro": {3068, 104},
+	"Antminer S19k Pro": {2760, 120},
+	"Antminer S19 XP":   {3010, 140},
+	"Antminer S21":      {3500, 200},
+	"Antminer T21":      {3610, 190},
+}
+
+// RatedPower returns the rated consumption and hashrate of a model in the
+// normal work mode, and false for models without a known rating.
+func RatedPower(minerType string) (int, float64, bool) {
+	r, ok := ratedPower[minerType]
+	return r.watts, r.hashrateTH, ok
+}
+
+// ListPowerProfiles returns the work modes as power profiles (implements miner.PowerController).
+// Stock firmware doesn't report per-mode consumption: Watts and HashrateTH are
+// estimated from the model's rating (see RatedPower), and left unset for
+// models without one. Sleep isn't listed, as it stops hashing rather than
+// tuning it: apply WorkModeSleep directly to put a miner to sleep.
+func (c *HTTPClient) ListPowerProfiles(ctx context.Context) ([]miner.PowerProfile, error) {
+	sysInfo, err := c.GetSystemInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := []miner.PowerProfile{
+		{Name: WorkModeLowPower, DisplayName: "Low Power"},
+		{Name: WorkModeNormal, DisplayName: "Normal"},
+	}
+	if watts, th, ok := RatedPower(sysInfo.MinerType); ok {
+		profiles[0].Watts = int(float64(watts) * LowPowerFactor)
+		profiles[0].HashrateTH = th * LowPowerFactor
+		profiles[1].Watts = watts
+		profiles[1].HashrateTH = th
+	}
+	return profiles, nil
+}
+
+// GetCurrentPowerProfile returns the active work mode (implements miner.PowerController).
+func (c *HTTPClient) GetCurrentPowerProfile(ctx context.Context) (string, error) {
+	config, err := c.GetMinerConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	if config.BitmainWorkMode == "" {
+		return WorkModeNormal, nil
+	}
+	return config.BitmainWorkMode, nil
+}
+
+// ApplyPowerProfile sets the work mode, keeping the rest of the configuration (implements miner.PowerController).
+func (c *HTTPClient) ApplyPowerProfile(ctx context.Context, name string) error {
+	switch name {
+	case WorkModeNormal, WorkModeSleep, WorkModeLowPower:
+	default:
+		return fmt.Errorf("invalid work mode: %q", name)
+	}
+
+	config, err := c.GetMinerConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("get miner config: %w", err)
+	}
+
+	config.BitmainWorkMode = name
+	resp, err := c.SetMinerConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("set miner config: %w", err)
+	}
+	if resp.Stats == "error" {
+		return fmt.Errorf("set miner config: %s (%s)", resp.Msg, resp.Code)
+	}
+	return nil
+}
+
+// RestartRequired always returns false: stock firmware restarts mining itself
+// when the configuration is saved (implements miner.PowerController).
+func (c *HTTPClient) RestartRequired(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// Ensure HTTPClient implements the miner interfaces.
+var (
+	_ miner.Client          = (*HTTPClient)(nil)
+	_ miner.PowerController = (*HTTPClient)(nil)
+	_ miner.FirmwareUpdater = (*HTTPClient)(nil)
+)
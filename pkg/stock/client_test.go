@@ -0,0 +1,202 @@
+package stock_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/stock"
+)
+
+// testTiming makes reboots and mining restarts near instant.
+var testTiming = minersim.Timing{
+	Boot:    50 * time.Millisecond,
+	Restart: 10 * time.Millisecond,
+	Tune:    10 * time.Millisecond,
+}
+
+// startMiner serves a simulated stock miner and returns it with its host.
+func startMiner(t *testing.T) (*minersim.Miner, string) {
+	t.Helper()
+
+	m := minersim.NewMiner(minersim.MinerSpec{
+		IP:              "127.0.0.1",
+		Firmware:        miner.FirmwareStock,
+		Model:           minersim.AntminerS19kPro,
+		FirmwareVersion: "Thu Aug 29 16:39:06 CST 2024",
+		MAC:             "02:00:7f:00:00:02",
+		Hostname:        "sim-stock-001",
+		Username:        "root",
+		Password:        "root",
+	}, testTiming, 1)
+
+	srv := httptest.NewServer(m.Handler())
+	t.Cleanup(srv.Close)
+	return m, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestClientGetMinerInfo(t *testing.T) {
+	_, host := startMiner(t)
+	client := stock.NewClient(host, stock.NewDigestAuth())
+
+	info, err := client.GetMinerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetMinerInfo: %v", err)
+	}
+
+	tests := []struct {
+		field, got, want string
+	}{
+		{"Miner", info.Miner, "Antminer S19k Pro"},
+		{"Firmware", info.Firmware, "Stock"},
+		{"FirmwareVersion", info.FirmwareVersion, "Thu Aug 29 16:39:06 CST 2024"},
+		{"MAC", info.MAC, "02:00:7f:00:00:02"},
+		{"Hostname", info.Hostname, "sim-stock-001"},
+		{"Algorithm", info.Algorithm, "sha256d"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	status, err := client.GetMinerStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetMinerStatus: %v", err)
+	}
+	if status.State != "running" {
+		t.Errorf("State = %q, want running", status.State)
+	}
+}
+
+func TestClientDigestAuth(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{"correct credentials", "root", "root", false},
+		{"wrong password", "root", "admin", true},
+		{"wrong username", "admin", "root", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, host := startMiner(t)
+			client := stock.NewClient(host, stock.NewDigestAuthWithCredentials(tt.username, tt.password))
+
+			_, err := client.GetMinerConfig(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetMinerConfig: err = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientWorkModes(t *testing.T) {
+	m, host := startMiner(t)
+	client := stock.NewClient(host, stock.NewDigestAuth())
+	ctx := context.Background()
+
+	profiles, err := client.ListPowerProfiles(ctx)
+	if err != nil {
+		t.Fatalf("ListPowerProfiles: %v", err)
+	}
+	model := minersim.AntminerS19kPro
+	byName := make(map[string]miner.PowerProfile)
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+	// Estimated from the model's rating, which the simulated miner matches
+	rated := []struct {
+		mode       string
+		watts      int
+		hashrateTH float64
+	}{
+		{stock.WorkModeLowPower, int(float64(model.StockWatts) * stock.LowPowerFactor), model.StockTH * stock.LowPowerFactor},
+		{stock.WorkModeNormal, model.StockWatts, model.StockTH},
+	}
+	if _, ok := byName[stock.WorkModeSleep]; ok {
+		t.Errorf("sleep listed as a power profile in %+v", profiles)
+	}
+	for _, r := range rated {
+		p, ok := byName[r.mode]
+		if !ok {
+			t.Errorf("work mode %s not listed in %+v", r.mode, profiles)
+			continue
+		}
+		if p.Watts != r.watts || p.HashrateTH != r.hashrateTH {
+			t.Errorf("work mode %s = %d W, %g TH, want %d W, %g TH", r.mode, p.Watts, p.HashrateTH, r.watts, r.hashrateTH)
+		}
+	}
+	tests := []struct {
+		mode      string
+		wantWatts int
+	}{
+		{stock.WorkModeLowPower, model.StockWatts * 7 / 10},
+		{stock.WorkModeSleep, 45},
+		{stock.WorkModeNormal, model.StockWatts},
+	}
+
+	for _, tt := range tests {
+		if err := client.ApplyPowerProfile(ctx, tt.mode); err != nil {
+			t.Fatalf("ApplyPowerProfile(%s): %v", tt.mode, err)
+		}
+
+		current, err := client.GetCurrentPowerProfile(ctx)
+		if err != nil {
+			t.Fatalf("GetCurrentPowerProfile: %v", err)
+		}
+		if current != tt.mode {
+			t.Errorf("current work mode = %q, want %q", current, tt.mode)
+		}
+
+		// Stock firmware restarts mining to apply the work mode
+		time.Sleep(50 * time.Millisecond)
+		if watts := m.Status().Watts; watts < tt.wantWatts*98/100 || watts > tt.wantWatts*102/100 {
+			t.Errorf("work mode %s: miner draws %d W, want about %d", tt.mode, watts, tt.wantWatts)
+		}
+	}
+
+	if err := client.ApplyPowerProfile(ctx, "turbo"); err == nil {
+		t.Error("expected an error applying an unknown work mode")
+	}
+}
+
+func TestClientRebootAndUpdateFirmware(t *testing.T) {
+	m, host := startMiner(t)
+	client := stock.NewClient(host, stock.NewDigestAuth(), stock.WithTimeout(time.Second))
+	ctx := context.Background()
+
+	if err := client.Reboot(ctx); err != nil {
+		t.Fatalf("Reboot: %v", err)
+	}
+	if state := m.Status().State; state != minersim.StateRebooting {
+		t.Errorf("state after reboot = %q, want %q", state, minersim.StateRebooting)
+	}
+	if _, err := client.GetMinerConfig(ctx); err == nil {
+		t.Error("expected a rebooting miner to be unreachable")
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if err := client.UpdateFirmware(ctx, []byte("SIMFW 2024.09.01\n"), "Antminer-S19kPro.bmu"); err != nil {
+		t.Fatalf("UpdateFirmware: %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	info, err := client.GetMinerInfo(ctx)
+	if err != nil {
+		t.Fatalf("GetMinerInfo after upgrade: %v", err)
+	}
+	if info.FirmwareVersion != "2024.09.01" {
+		t.Errorf("FirmwareVersion = %q, want 2024.09.01", info.FirmwareVersion)
+	}
+	if n := m.Status().FirmwareUploads; n != 1 {
+		t.Errorf("firmware uploads = %d, want 1", n)
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"sync"
 	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
 )
 
 const (
@@ -14,8 +16,20 @@ const (
 	// DefaultTokenTTL is the default time-to-live for cached tokens.
 	// VNish doesn't specify token expiration, so we use a conservative default.
 	DefaultTokenTTL = 30 * time.Minute
+
+	// DefaultAPIKeyDescription is the description of API keys created by EnsureAPIKey.
+	DefaultAPIKeyDescription = "powerhive-auto-generated"
 )
 
+// CredentialStore persists API keys across restarts, so a miner's key list
+// doesn't grow every time a process starts. LoadAPIKey returns an empty
+// string if no key is stored for the host.
+type CredentialStore interface {
+	LoadAPIKey(host string) (string, error)
+	SaveAPIKey(host, apiKey string) error
+	DeleteAPIKey(host string) error
+}
+
 // TokenInfo holds a bearer token and its metadata.
 type TokenInfo struct {
 	Token     string
@@ -30,12 +44,18 @@ func (t *TokenInfo) IsExpired() bool {
 
 // AuthManager manages authentication credentials for miners.
 // It caches bearer tokens and API keys per miner (identified by host address).
+// API keys are written through to the CredentialStore, if one is set.
 type AuthManager struct {
-	mu       sync.RWMutex
-	tokens   map[string]*TokenInfo // host -> token info
-	apiKeys  map[string]string     // host -> 32-char API key
-	password string                // default unlock password
-	tokenTTL time.Duration         // how long to cache tokens
+	mu         sync.RWMutex
+	tokens     map[string]*TokenInfo        // host -> token info
+	apiKeys    map[string]string            // host -> 32-char API key
+	password   string                       // default unlock password
+	tokenTTL   time.Duration                // how long to cache tokens
+	keyDesc    string                       // description of API keys we create
+	store      CredentialStore              // optional persistent API key store
+	onStoreErr func(error)                  // optional store error handler
+	onPruneErr func(host string, err error) // optional prune error handler
+	resolver   miner.CredentialResolver     // optional per-host passwords
 }
 
 // NewAuthManager creates a new authentication manager.
@@ -45,6 +65,7 @@ func NewAuthManager(password string) *AuthManager {
 		apiKeys:  make(map[string]string),
 		password: password,
 		tokenTTL: DefaultTokenTTL,
+		keyDesc:  DefaultAPIKeyDescription,
 	}
 }
 
@@ -54,11 +75,92 @@ func (am *AuthManager) WithTokenTTL(ttl time.Duration) *AuthManager {
 	return am
 }
 
-// GetPassword returns the unlock password.
+// WithCredentialStore persists API keys in the given store.
+func (am *AuthManager) WithCredentialStore(store CredentialStore) *AuthManager {
+	am.store = store
+	return am
+}
+
+// WithStoreErrorHandler sets a handler for CredentialStore errors.
+// Store errors never fail a request: the in-memory cache is used instead.
+func (am *AuthManager) WithStoreErrorHandler(fn func(error)) *AuthManager {
+	am.onStoreErr = fn
+	return am
+}
+
+// WithPruneErrorHandler sets a handler for errors pruning stale API keys after
+// EnsureAPIKey creates a key. Pruning never fails a request: the new key works.
+func (am *AuthManager) WithPruneErrorHandler(fn func(host string, err error)) *AuthManager {
+	am.onPruneErr = fn
+	return am
+}
+
+// WithAPIKeyDescription sets the description of API keys created by EnsureAPIKey.
+// Processes sharing miners should use distinct descriptions, as PruneAPIKeys
+// deletes all keys with our description except the current one. Keys with
+// DefaultAPIKeyDescription, created before processes set their own, are
+// deleted too.
+func (am *AuthManager) WithAPIKeyDescription(desc string) *AuthManager {
+	am.keyDesc = desc
+	return am
+}
+
+// WithCredentialResolver resolves unlock passwords per host.
+// The manager's password is only used for hosts without candidates.
+func (am *AuthManager) WithCredentialResolver(r miner.CredentialResolver) *AuthManager {
+	am.resolver = r
+	return am
+}
+
+// APIKeyDescription returns the description of API keys created by EnsureAPIKey.
+func (am *AuthManager) APIKeyDescription() string {
+	return am.keyDesc
+}
+
+// OwnsAPIKey returns true if an API key with the given description was
+// created by us, under the current or the default description.
+func (am *AuthManager) OwnsAPIKey(desc string) bool {
+	return desc == am.keyDesc || desc == DefaultAPIKeyDescription
+}
+
+// pruneError reports an API key pruning error to the handler, if any.
+func (am *AuthManager) pruneError(host string, err error) {
+	if err != nil && am.onPruneErr != nil {
+		am.onPruneErr(host, err)
+	}
+}
+
+// storeError reports a CredentialStore error to the handler, if any.
+func (am *AuthManager) storeError(err error) {
+	if err != nil && am.onStoreErr != nil {
+		am.onStoreErr(err)
+	}
+}
+
+// GetPassword returns the default unlock password.
 func (am *AuthManager) GetPassword() string {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
 	return am.password
 }
 
+// Candidates returns the unlock credentials to try for a host, in order.
+func (am *AuthManager) Candidates(host string) []miner.Credentials {
+	if am.resolver != nil {
+		if creds := am.resolver.Candidates(host, miner.FirmwareVNish); len(creds) > 0 {
+			return creds
+		}
+	}
+	return []miner.Credentials{{Password: am.GetPassword()}}
+}
+
+// Confirm records that creds unlocked the host, so they are tried first next time.
+func (am *AuthManager) Confirm(host string, creds miner.Credentials) {
+	if am.resolver != nil {
+		am.resolver.Confirm(host, miner.FirmwareVNish, creds)
+	}
+}
+
 // SetPassword updates the unlock password.
 func (am *AuthManager) SetPassword(password string) {
 	am.mu.Lock()
@@ -97,43 +199,61 @@ func (am *AuthManager) ClearToken(host string) {
 }
 
 // GetAPIKey returns the cached API key for a host, or empty if not cached.
+// On a cache miss the key is loaded from the CredentialStore.
 func (am *AuthManager) GetAPIKey(host string) string {
 	am.mu.RLock()
-	defer am.mu.RUnlock()
-	return am.apiKeys[host]
+	key, ok := am.apiKeys[host]
+	am.mu.RUnlock()
+	if ok || am.store == nil {
+		return key
+	}
+
+	key, err := am.store.LoadAPIKey(host)
+	if err != nil {
+		am.storeError(err)
+		return ""
+	}
+	if key == "" {
+		return ""
+	}
+
+	am.mu.Lock()
+	am.apiKeys[host] = key
+	am.mu.Unlock()
+	return key
 }
 
-// SetAPIKey caches an API key for a host.
+// SetAPIKey caches an API key for a host and saves it to the CredentialStore.
 func (am *AuthManager) SetAPIKey(host, apiKey string) {
 	am.mu.Lock()
-	defer am.mu.Unlock()
 	am.apiKeys[host] = apiKey
+	am.mu.Unlock()
+
+	if am.store != nil {
+		am.storeError(am.store.SaveAPIKey(host, apiKey))
+	}
 }
 
-// ClearAPIKey removes the cached API key for a host.
+// ClearAPIKey removes the API key for a host from the cache and the CredentialStore.
 func (am *AuthManager) ClearAPIKey(host string) {
 	am.mu.Lock()
-	defer am.mu.Unlock()
 	delete(am.apiKeys, host)
+	am.mu.Unlock()
+
+	if am.store != nil {
+		am.storeError(am.store.DeleteAPIKey(host))
+	}
 }
 
 // HasCredentials returns true if we have both token and API key for a host.
 func (am *AuthManager) HasCredentials(host string) bool {
-	am.mu.RLock()
-	defer am.mu.RUnlock()
-
-	info, hasToken := am.tokens[host]
-	_, hasKey := am.apiKeys[host]
-
-	return hasToken && !info.IsExpired() && hasKey
+	return am.GetToken(host) != "" && am.GetAPIKey(host) != ""
 }
 
-// ClearAll removes all cached credentials for a host.
+// ClearAll removes all credentials for a host.
 func (am *AuthManager) ClearAll(host string) {
-	am.mu.Lock()
-	defer am.mu.Unlock()
-	delete(am.tokens, host)
-	delete(am.apiKeys, host)
+	am.ClearToken(host)
+	am.ClearAPIKey(host)
 }
 
 // GenerateAPIKey generates a new 32-character API key.
@@ -0,0 +1,88 @@
+package vnish
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// BackupFile is a file inside a settings backup archive.
+type BackupFile struct {
+	Name string
+	Size int64
+}
+
+// ListBackupFiles returns the files in a settings backup (tar.gz).
+func ListBackupFiles(backup []byte) ([]BackupFile, error) {
+	var files []BackupFile
+	err := walkBackup(backup, func(hdr *tar.Header, _ io.Reader) error {
+		files = append(files, BackupFile{Name: hdr.Name, Size: hdr.Size})
+		return nil
+	})
+	return files, err
+}
+
+// BackupHash returns a hash of the files in a settings backup.
+// Archive timestamps are ignored, so two backups of unchanged settings
+// have the same hash. Unreadable archives are hashed as raw bytes.
+func BackupHash(backup []byte) string {
+	type entry struct {
+		name string
+		sum  [sha256.Size]byte
+	}
+	var entries []entry
+	err := walkBackup(backup, func(hdr *tar.Header, r io.Reader) error {
+		h := sha256.New()
+		if _, err := io.Copy(h, r); err != nil {
+			return err
+		}
+		e := entry{name: hdr.Name}
+		copy(e.sum[:], h.Sum(nil))
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		sum := sha256.Sum256(backup)
+		return hex.EncodeToString(sum[:])
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].name < entries[j].name })
+
+	h := sha256.New()
+	for _, e := range entries {
+		fmt.Fprintf(h, "%s\x00", e.name)
+		h.Write(e.sum[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// walkBackup calls fn for each regular file in a settings backup.
+func walkBackup(backup []byte, fn func(hdr *tar.Header, r io.Reader) error) error {
+	gz, err := gzip.NewReader(bytes.NewReader(backup))
+	if err != nil {
+		return fmt.Errorf("invalid backup archive: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid backup archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -4,10 +4,16 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/powerhive/powerhive-v2/pkg/miner"
@@ -52,6 +58,7 @@ type Client interface {
 	GetAPIKeys(ctx context.Context) ([]APIKey, error)
 	AddAPIKey(ctx context.Context, key, description string) error
 	DeleteAPIKey(ctx context.Context, key string) error
+	PruneAPIKeys(ctx context.Context) (int, error)
 
 	// Settings
 	GetSettings(ctx context.Context) (map[string]interface{}, error)
@@ -71,6 +78,7 @@ type Client interface {
 	// System Operations
 	FindMiner(ctx context.Context) (bool, error)
 	Reboot(ctx context.Context) error
+	UpdateFirmware(ctx context.Context, image []byte, filename string) error
 
 	// Authentication
 	Unlock(ctx context.Context) (string, error)
@@ -296,20 +304,33 @@ func (c *HTTPClient) requestRaw(ctx context.Context, opts requestOptions) ([]byt
 }
 
 // Unlock authenticates with the miner and returns a bearer token.
+// Candidate passwords are tried in order; the one that works is confirmed.
 func (c *HTTPClient) Unlock(ctx context.Context) (string, error) {
-	var result UnlockResponse
-	err := c.request(ctx, requestOptions{
-		method:   http.MethodPost,
-		endpoint: "/unlock",
-		body:     &UnlockRequest{Password: c.auth.GetPassword()},
-		result:   &result,
-	})
-	if err != nil {
-		return "", fmt.Errorf("unlock failed: %w", err)
+	var lastErr error
+	for _, creds := range c.auth.Candidates(c.host) {
+		var result UnlockResponse
+		err := c.request(ctx, requestOptions{
+			method:   http.MethodPost,
+			endpoint: "/unlock",
+			body:     &UnlockRequest{Password: creds.Password},
+			result:   &result,
+		})
+		if err != nil {
+			lastErr = err
+			// Only a rejected password is worth retrying with the next candidate
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && (apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden) {
+				continue
+			}
+			break
+		}
+
+		c.auth.Confirm(c.host, creds)
+		c.auth.SetToken(c.host, result.Token)
+		return result.Token, nil
 	}
 
-	c.auth.SetToken(c.host, result.Token)
-	return result.Token, nil
+	return "", fmt.Errorf("unlock failed: %w", lastErr)
 }
 
 // EnsureAuthenticated ensures we have a valid bearer token.
@@ -340,14 +361,46 @@ func (c *HTTPClient) EnsureAPIKey(ctx context.Context) error {
 	}
 
 	// Register the API key with the miner
-	if err := c.AddAPIKey(ctx, newKey, "powerhive-auto-generated"); err != nil {
+	if err := c.AddAPIKey(ctx, newKey, c.auth.APIKeyDescription()); err != nil {
 		return fmt.Errorf("failed to register API key: %w", err)
 	}
 
 	c.auth.SetAPIKey(c.host, newKey)
+
+	// Keys we created before (lost on restart or rejected) are stale now.
+	// Best effort: the new key works regardless.
+	if _, err := c.PruneAPIKeys(ctx); err != nil {
+		c.auth.pruneError(c.host, err)
+	}
 	return nil
 }
 
+// PruneAPIKeys deletes API keys we created except the current one: keys with
+// our description or DefaultAPIKeyDescription. Returns the number of deleted keys.
+func (c *HTTPClient) PruneAPIKeys(ctx context.Context) (int, error) {
+	current := c.auth.GetAPIKey(c.host)
+	if current == "" {
+		return 0, nil
+	}
+
+	keys, err := c.GetAPIKeys(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	var deleted int
+	for _, k := range keys {
+		if k.Key == current || !c.auth.OwnsAPIKey(k.Description) {
+			continue
+		}
+		if err := c.DeleteAPIKey(ctx, k.Key); err != nil {
+			return deleted, fmt.Errorf("failed to delete API key: %w", err)
+		}
+		deleted++
+	}
+	return deleted, nil
+}
+
 // GetInfo returns detailed miner information.
 func (c *HTTPClient) GetInfo(ctx context.Context) (*MinerInfo, error) {
 	var result MinerInfo
@@ -700,10 +753,68 @@ func (c *HTTPClient) BackupSettings(ctx context.Context) ([]byte, error) {
 	return io.ReadAll(resp.Body)
 }
 
-// RestoreSettings restores settings from a backup.
+// RestoreSettings restores settings from a backup created by BackupSettings.
 func (c *HTTPClient) RestoreSettings(ctx context.Context, backup []byte) error {
-	// This requires multipart form data - simplified implementation
-	return fmt.Errorf("RestoreSettings not yet implemented (requires multipart form)")
+	return c.upload(ctx, "/settings/restore", "backup.tar.gz", "application/gzip", backup, nil)
+}
+
+// upload posts a file as multipart form data in the "file" field, with optional extra fields.
+func (c *HTTPClient) upload(ctx context.Context, endpoint, filename, contentType string, data []byte, fields map[string]string) error {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for k, v := range fields {
+		if err := writer.WriteField(k, v); err != nil {
+			return fmt.Errorf("failed to write form field: %w", err)
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename=%q`, filename))
+	header.Set("Content-Type", contentType)
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create form file: %w", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		return fmt.Errorf("failed to write form file: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close multipart writer: %w", err)
+	}
+
+	fullURL := c.baseURL + endpoint
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fullURL, &body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	// Ensure authentication
+	if err := c.EnsureAuthenticated(ctx); err != nil {
+		return err
+	}
+	if err := c.EnsureAPIKey(ctx); err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+c.auth.GetToken(c.host))
+	req.Header.Set("x-api-key", c.auth.GetAPIKey(c.host))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("upload request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(respBody)), Endpoint: endpoint}
+	}
+
+	return nil
 }
 
 // FactoryReset resets all settings to factory defaults.
@@ -792,6 +903,14 @@ func (c *HTTPClient) Reboot(ctx context.Context) error {
 	})
 }
 
+// UpdateFirmware uploads and installs a firmware image, keeping the current
+// settings. The miner reboots when the install finishes
+// (implements miner.FirmwareUpdater).
+func (c *HTTPClient) UpdateFirmware(ctx context.Context, image []byte, filename string) error {
+	return c.upload(ctx, "/firmware/update", filename, "application/octet-stream", image,
+		map[string]string{"keep_settings": "true"})
+}
+
 // =============================================================================
 // miner.Client interface implementation
 // =============================================================================
@@ -837,5 +956,92 @@ func (c *HTTPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error)
 	}, nil
 }
 
-// Ensure HTTPClient implements miner.Client interface.
-var _ miner.Client = (*HTTPClient)(nil)
+// =============================================================================
+// miner.PowerController interface implementation
+// =============================================================================
+
+// ListPowerProfiles returns the autotune presets as power profiles (implements miner.PowerController).
+func (c *HTTPClient) ListPowerProfiles(ctx context.Context) ([]miner.PowerProfile, error) {
+	if err := c.EnsureAuthenticated(ctx); err != nil {
+		return nil, fmt.Errorf("authenticate: %w", err)
+	}
+
+	presets, err := c.GetAutotunePresets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]miner.PowerProfile, len(presets))
+	for i, p := range presets {
+		watts, hashrate := parsePresetPretty(p.Pretty)
+		profiles[i] = miner.PowerProfile{
+			Name:              p.Name,
+			DisplayName:       p.Pretty,
+			Watts:             watts,
+			HashrateTH:        hashrate,
+			RequiresModdedPSU: p.ModdedPSURequired,
+		}
+	}
+	return profiles, nil
+}
+
+// GetCurrentPowerProfile returns the active autotune preset (implements miner.PowerController).
+func (c *HTTPClient) GetCurrentPowerProfile(ctx context.Context) (string, error) {
+	perf, err := c.GetPerfSummary(ctx)
+	if err != nil {
+		return "", err
+	}
+	return perf.CurrentPreset.Name, nil
+}
+
+// ApplyPowerProfile sets the autotune preset (implements miner.PowerController).
+func (c *HTTPClient) ApplyPowerProfile(ctx context.Context, name string) error {
+	if err := c.EnsureAuthenticated(ctx); err != nil {
+		return fmt.Errorf("authenticate: %w", err)
+	}
+
+	// Settings changes require an API key
+	if err := c.EnsureAPIKey(ctx); err != nil {
+		return fmt.Errorf("get API key: %w", err)
+	}
+
+	return c.SetPreset(ctx, name)
+}
+
+// RestartRequired reports whether VNish needs a restart to apply settings (implements miner.PowerController).
+func (c *HTTPClient) RestartRequired(ctx context.Context) (bool, error) {
+	status, err := c.GetStatus(ctx)
+	if err != nil {
+		return false, err
+	}
+	return status.RestartRequired, nil
+}
+
+var (
+	presetPrettyRe = regexp.MustCompile(`(\d+)\s*watt.*?~\s*([\d.]+)\s*TH`)
+	leadingDigits  = regexp.MustCompile(`^(\d+)`)
+)
+
+// parsePresetPretty extracts watts and hashrate from a pretty string like "1100 watt ~ 53 TH".
+func parsePresetPretty(pretty string) (int, float64) {
+	if matches := presetPrettyRe.FindStringSubmatch(pretty); len(matches) >= 3 {
+		watts, _ := strconv.Atoi(matches[1])
+		hashrate, _ := strconv.ParseFloat(matches[2], 64)
+		return watts, hashrate
+	}
+
+	// Fallback: try to get just the number at the start
+	if matches := leadingDigits.FindStringSubmatch(strings.TrimSpace(pretty)); len(matches) >= 2 {
+		watts, _ := strconv.Atoi(matches[1])
+		return watts, 0
+	}
+
+	return 0, 0
+}
+
+// Ensure HTTPClient implements the miner interfaces.
+var (
+	_ miner.Client          = (*HTTPClient)(nil)
+	_ miner.PowerController = (*HTTPClient)(nil)
+	_ miner.FirmwareUpdater = (*HTTPClient)(nil)
+)
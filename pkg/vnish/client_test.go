@@ -0,0 +1,253 @@
+package vnish_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+	"github.com/powerhive/powerhive-v2/pkg/minersim"
+	"github.com/powerhive/powerhive-v2/pkg/vnish"
+)
+
+// testTiming makes restarts and auto-tuning near instant.
+var testTiming = minersim.Timing{
+	Boot:    50 * time.Millisecond,
+	Restart: 10 * time.Millisecond,
+	Tune:    10 * time.Millisecond,
+}
+
+// startMiner serves a simulated VNish miner and returns it with its host.
+func startMiner(t *testing.T) (*minersim.Miner, string) {
+	t.Helper()
+
+	m := minersim.NewMiner(minersim.MinerSpec{
+		IP:              "127.0.0.1",
+		Firmware:        miner.FirmwareVNish,
+		Model:           minersim.AntminerS19,
+		FirmwareVersion: "1.2.6",
+		MAC:             "02:00:7f:00:00:01",
+		Hostname:        "sim-vnish-001",
+		Password:        "admin",
+	}, testTiming, 1)
+
+	srv := httptest.NewServer(m.Handler())
+	t.Cleanup(srv.Close)
+	return m, strings.TrimPrefix(srv.URL, "http://")
+}
+
+func TestClientGetMinerInfo(t *testing.T) {
+	_, host := startMiner(t)
+	client := vnish.NewClient(host, vnish.NewAuthManager("admin"))
+
+	info, err := client.GetMinerInfo(context.Background())
+	if err != nil {
+		t.Fatalf("GetMinerInfo: %v", err)
+	}
+
+	tests := []struct {
+		field, got, want string
+	}{
+		{"Miner", info.Miner, "Antminer S19"},
+		{"Model", info.Model, "s19"},
+		{"Series", info.Series, "x19"},
+		{"Firmware", info.Firmware, "Vnish"},
+		{"FirmwareVersion", info.FirmwareVersion, "1.2.6"},
+		{"MAC", info.MAC, "02:00:7f:00:00:01"},
+		{"Hostname", info.Hostname, "sim-vnish-001"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+
+	status, err := client.GetMinerStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetMinerStatus: %v", err)
+	}
+	if status.State != minersim.StateMining {
+		t.Errorf("State = %q, want %q", status.State, minersim.StateMining)
+	}
+}
+
+func TestClientUnlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"correct password", "admin", false},
+		{"wrong password", "letmein", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, host := startMiner(t)
+			auth := vnish.NewAuthManager(tt.password)
+			client := vnish.NewClient(host, auth)
+
+			token, err := client.Unlock(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unlock: err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if token == "" || auth.GetToken(host) != token {
+				t.Errorf("token %q not cached", token)
+			}
+
+			// Token-protected endpoints work with it
+			if _, err := client.GetSummary(context.Background()); err != nil {
+				t.Errorf("GetSummary: %v", err)
+			}
+		})
+	}
+}
+
+func TestClientPowerProfiles(t *testing.T) {
+	m, host := startMiner(t)
+	client := vnish.NewClient(host, vnish.NewAuthManager("admin"))
+	ctx := context.Background()
+
+	profiles, err := client.ListPowerProfiles(ctx)
+	if err != nil {
+		t.Fatalf("ListPowerProfiles: %v", err)
+	}
+	if len(profiles) != len(minersim.AntminerS19.Presets)+1 {
+		t.Fatalf("got %d profiles, want %d", len(profiles), len(minersim.AntminerS19.Presets)+1)
+	}
+	byName := make(map[string]miner.PowerProfile)
+	for _, p := range profiles {
+		byName[p.Name] = p
+	}
+	for _, want := range minersim.AntminerS19.Presets {
+		got := byName[want.Name]
+		if got.Watts != want.Watts || got.HashrateTH != want.HashrateTH || got.RequiresModdedPSU != want.ModdedPSU {
+			t.Errorf("profile %s = %+v, want %d W, %g TH", want.Name, got, want.Watts, want.HashrateTH)
+		}
+	}
+
+	// Leaving the stock profile only applies after a mining restart; later
+	// changes are tuned live
+	steps := []struct {
+		preset          string
+		restart         bool
+		restartRequired bool
+		wantWatts       int
+	}{
+		{preset: "1500", restartRequired: true},
+		{preset: "1500", restart: true, wantWatts: 1500},
+		{preset: "2100", wantWatts: 2100},
+		{preset: "1100", wantWatts: 1100},
+	}
+
+	for _, step := range steps {
+		if step.restart {
+			if err := client.RestartMining(ctx); err != nil {
+				t.Fatalf("RestartMining: %v", err)
+			}
+		} else if err := client.ApplyPowerProfile(ctx, step.preset); err != nil {
+			t.Fatalf("ApplyPowerProfile(%s): %v", step.preset, err)
+		}
+
+		required, err := client.RestartRequired(ctx)
+		if err != nil {
+			t.Fatalf("RestartRequired: %v", err)
+		}
+		if required != step.restartRequired {
+			t.Errorf("%s: RestartRequired = %v, want %v", step.preset, required, step.restartRequired)
+		}
+		if step.restartRequired {
+			continue
+		}
+
+		current, err := client.GetCurrentPowerProfile(ctx)
+		if err != nil {
+			t.Fatalf("GetCurrentPowerProfile: %v", err)
+		}
+		if current != step.preset {
+			t.Errorf("current profile = %q, want %q", current, step.preset)
+		}
+
+		// Power settles at the preset once restarted and tuned
+		time.Sleep(50 * time.Millisecond)
+		if watts := m.Status().Watts; watts < step.wantWatts*98/100 || watts > step.wantWatts*102/100 {
+			t.Errorf("%s: miner draws %d W, want about %d", step.preset, watts, step.wantWatts)
+		}
+	}
+
+	if err := client.ApplyPowerProfile(ctx, "9999"); err == nil {
+		t.Error("expected an error applying an unknown profile")
+	}
+}
+
+func TestClientEnsureAPIKeyPrunesOwnKeys(t *testing.T) {
+	m, host := startMiner(t)
+	ctx := context.Background()
+
+	// Keys left by earlier runs, before and after they had their own
+	// description, and keys added by others
+	other := vnish.NewClient(host, vnish.NewAuthManager("admin"))
+	if err := other.EnsureAuthenticated(ctx); err != nil {
+		t.Fatalf("EnsureAuthenticated: %v", err)
+	}
+	for _, k := range []struct{ key, desc string }{
+		{strings.Repeat("a", vnish.APIKeyLength), vnish.DefaultAPIKeyDescription},
+		{strings.Repeat("b", vnish.APIKeyLength), "powerhive-test"},
+		{strings.Repeat("c", vnish.APIKeyLength), "pool-monitor"},
+		{strings.Repeat("d", vnish.APIKeyLength), "powerhive-data-harvest"},
+	} {
+		if err := other.AddAPIKey(ctx, k.key, k.desc); err != nil {
+			t.Fatalf("AddAPIKey: %v", err)
+		}
+	}
+
+	auth := vnish.NewAuthManager("admin").
+		WithAPIKeyDescription("powerhive-test").
+		WithPruneErrorHandler(func(host string, err error) {
+			t.Errorf("prune %s: %v", host, err)
+		})
+	client := vnish.NewClient(host, auth)
+	if err := client.EnsureAPIKey(ctx); err != nil {
+		t.Fatalf("EnsureAPIKey: %v", err)
+	}
+
+	current := auth.GetAPIKey(host)
+	if len(current) != vnish.APIKeyLength {
+		t.Fatalf("API key %q not cached", current)
+	}
+
+	keys, err := client.GetAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("GetAPIKeys: %v", err)
+	}
+	got := make(map[string]string)
+	for _, k := range keys {
+		got[k.Key] = k.Description
+	}
+	want := map[string]string{
+		current:                                 "powerhive-test",
+		strings.Repeat("c", vnish.APIKeyLength): "pool-monitor",
+		strings.Repeat("d", vnish.APIKeyLength): "powerhive-data-harvest",
+	}
+	if len(got) != len(want) {
+		t.Errorf("keys = %v, want %v", got, want)
+	}
+	for k, desc := range want {
+		if got[k] != desc {
+			t.Errorf("key %s = %q, want %q", k, got[k], desc)
+		}
+	}
+	if n := m.Status().APIKeys; n != len(want) {
+		t.Errorf("miner has %d API keys, want %d", n, len(want))
+	}
+
+	// The key works for endpoints that need one
+	if _, err := client.BackupSettings(ctx); err != nil {
+		t.Errorf("BackupSettings: %v", err)
+	}
+}
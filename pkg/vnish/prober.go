@@ -63,6 +63,12 @@ func (p *Prober) Probe(ctx context.Context, host string) (*miner.Info, error) {
 		return nil, ErrNotVNishFirmware
 	}
 
+	// With per-host passwords, find the one that unlocks this miner now so
+	// later requests don't have to. A miner we can't unlock is still VNish.
+	if p.auth.resolver != nil {
+		_ = client.EnsureAuthenticated(ctx)
+	}
+
 	// Return basic info without fetching model (can be fetched later if needed)
 	return &miner.Info{
 		Miner:           info.Miner,
@@ -400,10 +400,18 @@ type OverclockSettings struct {
 	Preset string `json:"preset,omitempty"`
 }
 
+// PoolSettings contains a pool entry of the miner settings.
+type PoolSettings struct {
+	URL  string `json:"url"`
+	User string `json:"user"`
+	Pass string `json:"pass"`
+}
+
 // MinerSettings contains miner-specific settings.
 type MinerSettings struct {
 	Misc      MinerMiscSettings  `json:"misc,omitempty"`
 	Overclock *OverclockSettings `json:"overclock,omitempty"`
+	Pools     []PoolSettings     `json:"pools,omitempty"` // replaces all user pools, in priority order
 }
 
 // SettingsUpdate is the request body for updating settings.
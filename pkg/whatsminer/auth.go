@@ -0,0 +1,86 @@
+package whatsminer
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPassword is the default Whatsminer admin password.
+	DefaultPassword = "admin"
+
+	// TokenTTL is how long btminer accepts a write token.
+	TokenTTL = 30 * time.Minute
+)
+
+// tokenEntry holds a derived write token and its expiry.
+type tokenEntry struct {
+	token     *Token
+	expiresAt time.Time
+}
+
+// isExpired returns true if the token has expired or is about to expire.
+func (t *tokenEntry) isExpired() bool {
+	// Consider expired if less than 1 minute remaining
+	return time.Now().Add(time.Minute).After(t.expiresAt)
+}
+
+// AuthManager manages the admin password and caches write tokens per miner host.
+// btminer limits the number of active tokens, so tokens are reused until they expire.
+type AuthManager struct {
+	mu       sync.RWMutex
+	tokens   map[string]*tokenEntry // host -> token
+	password string
+}
+
+// NewAuthManager creates a new authentication manager.
+func NewAuthManager(password string) *AuthManager {
+	return &AuthManager{
+		tokens:   make(map[string]*tokenEntry),
+		password: password,
+	}
+}
+
+// GetPassword returns the admin password.
+func (am *AuthManager) GetPassword() string {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+	return am.password
+}
+
+// SetPassword updates the admin password.
+func (am *AuthManager) SetPassword(password string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	am.password = password
+}
+
+// GetToken returns the cached token for a host, or nil if not cached/expired.
+func (am *AuthManager) GetToken(host string) *Token {
+	am.mu.RLock()
+	defer am.mu.RUnlock()
+
+	entry, ok := am.tokens[host]
+	if !ok || entry.isExpired() {
+		return nil
+	}
+	return entry.token
+}
+
+// SetToken caches a token for a host.
+func (am *AuthManager) SetToken(host string, token *Token) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	am.tokens[host] = &tokenEntry{
+		token:     token,
+		expiresAt: time.Now().Add(TokenTTL),
+	}
+}
+
+// ClearToken removes the cached token for a host.
+func (am *AuthManager) ClearToken(host string) {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+	delete(am.tokens, host)
+}
@@ -0,0 +1,625 @@
+package whatsminer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// DefaultPort is the btminer API port.
+const DefaultPort = 4028
+
+// Power modes accepted by SetPowerMode.
+const (
+	PowerModeLow    = "low"
+	PowerModeNormal = "normal"
+	PowerModeHigh   = "high"
+)
+
+// Client is the interface for interacting with the Whatsminer API.
+type Client interface {
+	miner.Client
+
+	// Token
+	EnsureToken(ctx context.Context) (*Token, error)
+
+	// Read commands (no token required)
+	GetVersion(ctx context.Context) (*Version, error)
+	GetNetworkInfo(ctx context.Context) (*MinerInfo, error)
+	GetState(ctx context.Context) (*MinerState, error)
+	GetPSU(ctx context.Context) (*PSUInfo, error)
+	GetSummary(ctx context.Context) (*SummaryResponse, error)
+	GetDevs(ctx context.Context) (*DevsResponse, error)
+	GetDevDetails(ctx context.Context) (*DevDetailsResponse, error)
+	GetPools(ctx context.Context) (*PoolsResponse, error)
+
+	// Write commands (token + AES encryption)
+	Exec(ctx context.Context, cmd string, params map[string]string) (*Response, error)
+	SetPowerLimit(ctx context.Context, watts int) error
+	SetPowerPercent(ctx context.Context, percent int) error
+	SetPowerMode(ctx context.Context, mode string) error
+	RestartMining(ctx context.Context) error
+	Reboot(ctx context.Context) error
+	PowerOff(ctx context.Context) error
+	PowerOn(ctx context.Context) error
+}
+
+// TCPClient is the TCP implementation of the Whatsminer Client.
+type TCPClient struct {
+	host    string
+	port    int
+	timeout time.Duration
+	auth    *AuthManager
+}
+
+// ClientOption is a function that configures a TCPClient.
+type ClientOption func(*TCPClient)
+
+// WithPort sets the API port (default: 4028).
+func WithPort(port int) ClientOption {
+	return func(c *TCPClient) {
+		c.port = port
+	}
+}
+
+// WithTimeout sets the dial and read timeout.
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *TCPClient) {
+		c.timeout = timeout
+	}
+}
+
+// NewClient creates a new Whatsminer API client.
+// The host may include a port (e.g., "192.168.1.10:80"), which is ignored.
+func NewClient(host string, auth *AuthManager, opts ...ClientOption) *TCPClient {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	c := &TCPClient{
+		host:    host,
+		port:    DefaultPort,
+		timeout: 10 * time.Second,
+		auth:    auth,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Host returns the miner host address.
+func (c *TCPClient) Host() string {
+	return c.host
+}
+
+// send writes a single request and returns the raw response.
+// btminer answers with one JSON document and closes the connection.
+func (c *TCPClient) send(ctx context.Context, payload []byte) ([]byte, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(c.host, strconv.Itoa(c.port)))
+	if err != nil {
+		return nil, fmt.Errorf("connect failed: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write(payload); err != nil {
+		return nil, fmt.Errorf("failed to send command: %w", err)
+	}
+
+	resp, err := io.ReadAll(conn)
+	if err != nil && len(resp) == 0 {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	resp = bytes.TrimSpace(bytes.TrimRight(resp, "\x00"))
+	if len(resp) == 0 {
+		return nil, ErrEmptyResponse
+	}
+	return resp, nil
+}
+
+// query sends a cgminer-style read command and decodes the response.
+func (c *TCPClient) query(ctx context.Context, cmd string, result interface{}) error {
+	payload, err := json.Marshal(map[string]string{"cmd": cmd})
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	resp, err := c.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	var header struct {
+		Status []StatusEntry `json:"STATUS"`
+	}
+	if err := json.Unmarshal(resp, &header); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if len(header.Status) > 0 && header.Status[0].Status == "E" {
+		return &APIError{Command: cmd, Code: header.Status[0].Code, Msg: header.Status[0].Msg}
+	}
+
+	if err := json.Unmarshal(resp, result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	return nil
+}
+
+// call sends a btminer-specific read command and decodes Msg into result.
+func (c *TCPClient) call(ctx context.Context, cmd string, params map[string]string, result interface{}) error {
+	req := map[string]string{"cmd": cmd}
+	for k, v := range params {
+		req[k] = v
+	}
+
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	raw, err := c.send(ctx, payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := parseResponse(cmd, raw)
+	if err != nil {
+		return err
+	}
+
+	if result != nil {
+		if err := json.Unmarshal(resp.Msg, result); err != nil {
+			return fmt.Errorf("failed to parse %s message: %w", cmd, err)
+		}
+	}
+	return nil
+}
+
+// parseResponse decodes a flat response and converts error statuses to APIError.
+func parseResponse(cmd string, raw []byte) (*Response, error) {
+	var resp Response
+	if err := json.Unmarshal(raw, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if resp.Status != "S" {
+		var msg string
+		if err := json.Unmarshal(resp.Msg, &msg); err != nil {
+			msg = string(resp.Msg)
+		}
+		return nil, &APIError{Command: cmd, Code: resp.Code, Msg: msg}
+	}
+	return &resp, nil
+}
+
+// =============================================================================
+// Token
+// =============================================================================
+
+// EnsureToken returns a cached write token or requests a new one with get_token.
+func (c *TCPClient) EnsureToken(ctx context.Context) (*Token, error) {
+	if token := c.auth.GetToken(c.host); token != nil {
+		return token, nil
+	}
+
+	var info TokenInfo
+	if err := c.call(ctx, "get_token", nil, &info); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrTokenFailed, err)
+	}
+	if info.Salt == "" || info.NewSalt == "" {
+		return nil, ErrTokenFailed
+	}
+
+	token := deriveToken(c.auth.GetPassword(), &info)
+	c.auth.SetToken(c.host, token)
+	return token, nil
+}
+
+// =============================================================================
+// Read commands
+// =============================================================================
+
+// GetVersion returns the firmware and API versions.
+func (c *TCPClient) GetVersion(ctx context.Context) (*Version, error) {
+	var result Version
+	if err := c.call(ctx, "get_version", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetNetworkInfo returns network configuration (get_miner_info).
+func (c *TCPClient) GetNetworkInfo(ctx context.Context) (*MinerInfo, error) {
+	var result MinerInfo
+	params := map[string]string{"info": "ip,proto,netmask,dns,mac,ledstat,gateway,hostname"}
+	if err := c.call(ctx, "get_miner_info", params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetState returns the btminer process state.
+func (c *TCPClient) GetState(ctx context.Context) (*MinerState, error) {
+	var result MinerState
+	if err := c.call(ctx, "status", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPSU returns power supply information.
+func (c *TCPClient) GetPSU(ctx context.Context) (*PSUInfo, error) {
+	var result PSUInfo
+	if err := c.call(ctx, "get_psu", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetSummary returns aggregated mining, power and temperature statistics.
+func (c *TCPClient) GetSummary(ctx context.Context) (*SummaryResponse, error) {
+	var result SummaryResponse
+	if err := c.query(ctx, "summary", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDevs returns per-hashboard statistics.
+// Uses edevs, which only lists enabled boards, falling back to devs on older firmware.
+func (c *TCPClient) GetDevs(ctx context.Context) (*DevsResponse, error) {
+	var result DevsResponse
+	if err := c.query(ctx, "edevs", &result); err == nil {
+		return &result, nil
+	}
+	if err := c.query(ctx, "devs", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDevDetails returns hashboard hardware details, including the miner model.
+func (c *TCPClient) GetDevDetails(ctx context.Context) (*DevDetailsResponse, error) {
+	var result DevDetailsResponse
+	if err := c.query(ctx, "devdetails", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPools returns pool status.
+func (c *TCPClient) GetPools(ctx context.Context) (*PoolsResponse, error) {
+	var result PoolsResponse
+	if err := c.query(ctx, "pools", &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// =============================================================================
+// Write commands
+// =============================================================================
+
+// Exec sends an encrypted write command.
+// If the token was rejected (expired or revoked), a new token is fetched and the command retried once.
+func (c *TCPClient) Exec(ctx context.Context, cmd string, params map[string]string) (*Response, error) {
+	resp, err := c.exec(ctx, cmd, params)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.IsTokenError() {
+		c.auth.ClearToken(c.host)
+		resp, err = c.exec(ctx, cmd, params)
+	}
+
+	return resp, err
+}
+
+// exec performs a single encrypted write command.
+func (c *TCPClient) exec(ctx context.Context, cmd string, params map[string]string) (*Response, error) {
+	token, err := c.EnsureToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req := map[string]string{"cmd": cmd, "token": token.Sign}
+	for k, v := range params {
+		req[k] = v
+	}
+
+	plaintext, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	data, err := encrypt(token.Key, plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt request: %w", err)
+	}
+
+	payload, err := json.Marshal(&EncryptedRequest{Enc: 1, Data: data})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	raw, err := c.send(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	// Token and decoding errors are returned in plain text
+	var enc EncryptedResponse
+	if err := json.Unmarshal(raw, &enc); err == nil && enc.Enc != "" {
+		raw, err = decrypt(token.Key, enc.Enc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decrypt response: %w", err)
+		}
+	}
+
+	return parseResponse(cmd, raw)
+}
+
+// SetPowerLimit sets the maximum power consumption in watts.
+// btminer restarts mining to apply the new limit.
+func (c *TCPClient) SetPowerLimit(ctx context.Context, watts int) error {
+	_, err := c.Exec(ctx, "adjust_power_limit", map[string]string{"power_limit": strconv.Itoa(watts)})
+	return err
+}
+
+// SetPowerPercent reduces power to a percentage (0-100) of the current limit without restarting mining.
+// Supported on firmware 20210322 and newer; the setting is not persisted across reboots.
+func (c *TCPClient) SetPowerPercent(ctx context.Context, percent int) error {
+	_, err := c.Exec(ctx, "set_power_pct", map[string]string{"percent": strconv.Itoa(percent)})
+	return err
+}
+
+// SetPowerMode switches between the low, normal and high power modes.
+func (c *TCPClient) SetPowerMode(ctx context.Context, mode string) error {
+	switch mode {
+	case PowerModeLow, PowerModeNormal, PowerModeHigh:
+	default:
+		return fmt.Errorf("invalid power mode: %q", mode)
+	}
+	_, err := c.Exec(ctx, "set_"+mode+"_power", nil)
+	return err
+}
+
+// RestartMining restarts the btminer process.
+func (c *TCPClient) RestartMining(ctx context.Context) error {
+	_, err := c.Exec(ctx, "restart_btminer", nil)
+	return err
+}
+
+// Reboot reboots the miner.
+func (c *TCPClient) Reboot(ctx context.Context) error {
+	_, err := c.Exec(ctx, "reboot", nil)
+	return err
+}
+
+// PowerOff stops mining and powers off the hashboards.
+func (c *TCPClient) PowerOff(ctx context.Context) error {
+	_, err := c.Exec(ctx, "power_off", map[string]string{"respbefore": "true"})
+	return err
+}
+
+// PowerOn powers on the hashboards and resumes mining.
+func (c *TCPClient) PowerOn(ctx context.Context) error {
+	_, err := c.Exec(ctx, "power_on", nil)
+	return err
+}
+
+// =============================================================================
+// miner.Client interface implementation
+// =============================================================================
+
+// GetMinerInfo returns generic miner information (implements miner.Client).
+// Model and network details are optional; only get_version is required.
+func (c *TCPClient) GetMinerInfo(ctx context.Context) (*miner.Info, error) {
+	version, err := c.GetVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var model string
+	if details, err := c.GetDevDetails(ctx); err == nil {
+		model = ModelFromDevDetails(details)
+	}
+
+	var network *MinerInfo
+	if n, err := c.GetNetworkInfo(ctx); err == nil {
+		network = n
+	}
+
+	return toMinerInfo(version, model, network, c.host), nil
+}
+
+// GetMinerStatus returns generic miner status (implements miner.Client).
+func (c *TCPClient) GetMinerStatus(ctx context.Context) (*miner.Status, error) {
+	summary, err := c.GetSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(summary.Summary) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	s := summary.Summary[0]
+	state := StateFromSummary(&s)
+
+	// The summary can't distinguish a stopped btminer from a booting one
+	if state != "running" {
+		if ms, err := c.GetState(ctx); err == nil && ms.BTMinerOff == "true" {
+			state = "stopped"
+		}
+	}
+
+	return &miner.Status{
+		State:       state,
+		Description: fmt.Sprintf("Hashrate: %.2f TH/s, Power: %d W", s.MHS5s/1e6, s.Power),
+		FailureCode: 0,
+	}, nil
+}
+
+// StateFromSummary derives a generic state string from a summary.
+func StateFromSummary(s *Summary) string {
+	if s.Elapsed <= 0 {
+		return "unknown"
+	}
+	if s.MHS5s > 0 {
+		return "running"
+	}
+	return "idle"
+}
+
+// ModelFromDevDetails extracts the miner model from devdetails (e.g., "M30S+.VE40" -> "M30S+").
+func ModelFromDevDetails(details *DevDetailsResponse) string {
+	for _, d := range details.DevDetails {
+		if d.Model == "" {
+			continue
+		}
+		if idx := strings.Index(d.Model, "."); idx > 0 {
+			return d.Model[:idx]
+		}
+		return d.Model
+	}
+	return ""
+}
+
+// toMinerInfo converts Whatsminer responses to miner.Info.
+func toMinerInfo(version *Version, model string, network *MinerInfo, host string) *miner.Info {
+	info := &miner.Info{
+		Miner:           strings.TrimSpace("WhatsMiner " + model),
+		Model:           model,
+		Series:          extractSeries(model),
+		Firmware:        "Whatsminer",
+		FirmwareVersion: version.FWVersion,
+		Algorithm:       "sha256d",
+		IP:              host,
+	}
+
+	if network != nil {
+		info.MAC = network.MAC
+		info.Hostname = network.Hostname
+	}
+
+	return info
+}
+
+// extractSeries extracts the series from the model (e.g., "M30S+" -> "M30", "M56S" -> "M56").
+func extractSeries(model string) string {
+	if len(model) < 2 || model[0] != 'M' {
+		return ""
+	}
+	end := 1
+	for end < len(model) && model[end] >= '0' && model[end] <= '9' {
+		end++
+	}
+	if end == 1 {
+		return ""
+	}
+	return model[:end]
+}
+
+// =============================================================================
+// miner.PowerController interface implementation
+// =============================================================================
+
+// PowerLimitStep is the spacing between generated power limit profiles (W).
+const PowerLimitStep = 100
+
+// ListPowerProfiles returns power limits from 50% to 100% of the nominal power
+// in PowerLimitStep increments (implements miner.PowerController).
+// Profile names are the power limit in watts; hashrates are linear estimates.
+func (c *TCPClient) ListPowerProfiles(ctx context.Context) ([]miner.PowerProfile, error) {
+	summary, err := c.GetSummary(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(summary.Summary) == 0 {
+		return nil, ErrEmptyResponse
+	}
+
+	s := summary.Summary[0]
+	nominalTH := s.FactoryGHS / 1000
+	nominalW := int(nominalTH * s.PowerRate)
+
+	// Unset limits are reported as large placeholder values, so only trust
+	// the configured limit when it is close to the nominal consumption
+	maxW := nominalW
+	if s.PowerLimit > maxW && (nominalW == 0 || s.PowerLimit <= nominalW*3/2) {
+		maxW = s.PowerLimit
+	}
+	maxW = maxW / PowerLimitStep * PowerLimitStep
+	if maxW <= 0 {
+		return nil, fmt.Errorf("cannot determine nominal power (factory %.0f GH/s, %.1f W/TH)", s.FactoryGHS, s.PowerRate)
+	}
+
+	var profiles []miner.PowerProfile
+	for w := (maxW / 2) / PowerLimitStep * PowerLimitStep; w <= maxW; w += PowerLimitStep {
+		var hashrate float64
+		if nominalW > 0 {
+			hashrate = nominalTH * float64(w) / float64(nominalW)
+		}
+		profiles = append(profiles, miner.PowerProfile{
+			Name:        strconv.Itoa(w),
+			DisplayName: fmt.Sprintf("%d watt ~ %.0f TH", w, hashrate),
+			Watts:       w,
+			HashrateTH:  hashrate,
+		})
+	}
+	return profiles, nil
+}
+
+// GetCurrentPowerProfile returns the configured power limit, rounded to the
+// nearest PowerLimitStep (implements miner.PowerController).
+func (c *TCPClient) GetCurrentPowerProfile(ctx context.Context) (string, error) {
+	summary, err := c.GetSummary(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(summary.Summary) == 0 {
+		return "", ErrEmptyResponse
+	}
+
+	limit := (summary.Summary[0].PowerLimit + PowerLimitStep/2) / PowerLimitStep * PowerLimitStep
+	return strconv.Itoa(limit), nil
+}
+
+// ApplyPowerProfile sets the power limit named by the profile (implements miner.PowerController).
+func (c *TCPClient) ApplyPowerProfile(ctx context.Context, name string) error {
+	watts, err := strconv.Atoi(name)
+	if err != nil || watts <= 0 {
+		return fmt.Errorf("invalid power limit profile: %q", name)
+	}
+	return c.SetPowerLimit(ctx, watts)
+}
+
+// RestartRequired always returns false: btminer restarts itself to apply
+// a new power limit (implements miner.PowerController).
+func (c *TCPClient) RestartRequired(ctx context.Context) (bool, error) {
+	return false, nil
+}
+
+// Ensure TCPClient implements the Client interfaces.
+var (
+	_ Client                = (*TCPClient)(nil)
+	_ miner.Client          = (*TCPClient)(nil)
+	_ miner.PowerController = (*TCPClient)(nil)
+)
@@ -0,0 +1,171 @@
+package whatsminer
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+// Token is a write-command token derived from the admin password and get_token salts.
+type Token struct {
+	// Sign is sent as the "token" field of every write command.
+	Sign string
+
+	// Key is the AES-256 key used to encrypt commands and decrypt responses.
+	Key []byte
+}
+
+// deriveToken derives the write token as described in the btminer API documentation:
+//
+//	key    = md5crypt(password, salt)
+//	aeskey = sha256(key)
+//	sign   = md5crypt(key + time, newsalt)
+func deriveToken(password string, info *TokenInfo) *Token {
+	key := cryptHash(md5Crypt([]byte(password), []byte(info.Salt)))
+	sign := cryptHash(md5Crypt([]byte(key+info.Time), []byte(info.NewSalt)))
+
+	aesKey := sha256.Sum256([]byte(key))
+	return &Token{
+		Sign: sign,
+		Key:  aesKey[:],
+	}
+}
+
+// cryptHash returns the hash part of a "$1$salt$hash" crypt string.
+func cryptHash(crypted string) string {
+	parts := strings.Split(crypted, "$")
+	if len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+// encrypt encrypts plaintext with AES-256-ECB (zero padded) and encodes it as base64.
+func encrypt(key, plaintext []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+
+	size := block.BlockSize()
+	if pad := len(plaintext) % size; pad != 0 {
+		plaintext = append(plaintext, make([]byte, size-pad)...)
+	}
+
+	ciphertext := make([]byte, len(plaintext))
+	for i := 0; i < len(plaintext); i += size {
+		block.Encrypt(ciphertext[i:i+size], plaintext[i:i+size])
+	}
+
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decrypt decodes base64 ciphertext and decrypts it with AES-256-ECB, stripping zero padding.
+func decrypt(key []byte, encoded string) ([]byte, error) {
+	ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := block.BlockSize()
+	if len(ciphertext)%size != 0 {
+		return nil, errors.New("ciphertext is not a multiple of the block size")
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	for i := 0; i < len(ciphertext); i += size {
+		block.Decrypt(plaintext[i:i+size], ciphertext[i:i+size])
+	}
+
+	return bytes.TrimRight(plaintext, "\x00"), nil
+}
+
+// =============================================================================
+// MD5-crypt ($1$)
+// =============================================================================
+
+const (
+	md5CryptMagic = "$1$"
+	cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+// md5Crypt implements the FreeBSD MD5-based crypt(3) used by btminer
+// and returns the full "$1$salt$hash" string.
+func md5Crypt(password, salt []byte) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	alt := md5.New()
+	alt.Write(password)
+	alt.Write(salt)
+	alt.Write(password)
+	altSum := alt.Sum(nil)
+
+	h := md5.New()
+	h.Write(password)
+	h.Write([]byte(md5CryptMagic))
+	h.Write(salt)
+	for i := len(password); i > 0; i -= 16 {
+		h.Write(altSum[:min(i, 16)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			h.Write([]byte{0})
+		} else {
+			h.Write(password[:1])
+		}
+	}
+	sum := h.Sum(nil)
+
+	// 1000 rounds to slow down brute forcing
+	for i := 0; i < 1000; i++ {
+		r := md5.New()
+		if i&1 != 0 {
+			r.Write(password)
+		} else {
+			r.Write(sum)
+		}
+		if i%3 != 0 {
+			r.Write(salt)
+		}
+		if i%7 != 0 {
+			r.Write(password)
+		}
+		if i&1 != 0 {
+			r.Write(sum)
+		} else {
+			r.Write(password)
+		}
+		sum = r.Sum(nil)
+	}
+
+	var out strings.Builder
+	out.WriteString(md5CryptMagic)
+	out.Write(salt)
+	out.WriteByte('$')
+	for _, idx := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		v := uint(sum[idx[0]])<<16 | uint(sum[idx[1]])<<8 | uint(sum[idx[2]])
+		writeCrypt64(&out, v, 4)
+	}
+	writeCrypt64(&out, uint(sum[11]), 2)
+
+	return out.String()
+}
+
+// writeCrypt64 writes n characters of v using the crypt(3) base64 alphabet.
+func writeCrypt64(out *strings.Builder, v uint, n int) {
+	for ; n > 0; n-- {
+		out.WriteByte(cryptAlphabet[v&0x3f])
+		v >>= 6
+	}
+}
@@ -0,0 +1,46 @@
+package whatsminer
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotWhatsminerFirmware indicates the host is not running Whatsminer firmware.
+	ErrNotWhatsminerFirmware = errors.New("not a Whatsminer firmware")
+
+	// ErrEmptyResponse indicates the miner closed the connection without a response.
+	ErrEmptyResponse = errors.New("empty response from Whatsminer API")
+
+	// ErrTokenFailed indicates the write token could not be obtained.
+	ErrTokenFailed = errors.New("failed to obtain Whatsminer API token")
+)
+
+// API status codes returned by btminer.
+const (
+	CodeInvalidCommand  = 14
+	CodeInvalidJSON     = 23
+	CodePermission      = 45
+	CodeCommandOK       = 131
+	CodeCommandError    = 132
+	CodeTokenInfo       = 134
+	CodeTokenError      = 135
+	CodeTooManyTokens   = 136
+	CodeBase64DecodeErr = 137
+)
+
+// APIError represents an error STATUS returned by the btminer API.
+type APIError struct {
+	Command string
+	Code    int
+	Msg     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Whatsminer API error at %s (code %d): %s", e.Command, e.Code, e.Msg)
+}
+
+// IsTokenError returns true if the command was rejected because of an invalid or expired token.
+func (e *APIError) IsTokenError() bool {
+	return e.Code == CodeTokenError
+}
@@ -0,0 +1,97 @@
+package whatsminer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/powerhive/powerhive-v2/pkg/miner"
+)
+
+// Prober implements miner.FirmwareProber for Whatsminer firmware.
+type Prober struct {
+	auth    *AuthManager
+	timeout time.Duration
+}
+
+// ProberOption configures a Prober.
+type ProberOption func(*Prober)
+
+// WithProberTimeout sets the probe timeout.
+func WithProberTimeout(timeout time.Duration) ProberOption {
+	return func(p *Prober) {
+		p.timeout = timeout
+	}
+}
+
+// NewProber creates a new Whatsminer firmware prober.
+func NewProber(auth *AuthManager, opts ...ProberOption) *Prober {
+	p := &Prober{
+		auth:    auth,
+		timeout: 3 * time.Second,
+	}
+
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	return p
+}
+
+// Probe attempts to connect and identify Whatsminer firmware.
+// Implements miner.FirmwareProber.
+func (p *Prober) Probe(ctx context.Context, host string) (*miner.Info, error) {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	client := NewClient(host, p.auth, WithTimeout(p.timeout))
+
+	// get_version is btminer-specific; cgminer/bmminer on other firmwares
+	// reject it or answer in a different format
+	version, err := client.GetVersion(ctx)
+	if err != nil {
+		if !isConnectionError(err) {
+			return nil, ErrNotWhatsminerFirmware
+		}
+		return nil, err
+	}
+
+	if version.FWVersion == "" {
+		return nil, ErrNotWhatsminerFirmware
+	}
+
+	var model string
+	if details, err := client.GetDevDetails(ctx); err == nil {
+		model = ModelFromDevDetails(details)
+	}
+
+	var network *MinerInfo
+	if n, err := client.GetNetworkInfo(ctx); err == nil {
+		network = n
+	}
+
+	return toMinerInfo(version, model, network, client.Host()), nil
+}
+
+// isConnectionError returns true for network-level failures (host down, port closed, timeout).
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	var opErr *net.OpError
+	return errors.As(err, &netErr) || errors.As(err, &opErr) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// FirmwareType returns the firmware type this prober detects.
+// Implements miner.FirmwareProber.
+func (p *Prober) FirmwareType() miner.FirmwareType {
+	return miner.FirmwareWhatsminer
+}
+
+// NewClient creates a client for hosts confirmed to run Whatsminer firmware.
+// Implements miner.FirmwareProber.
+func (p *Prober) NewClient(host string) miner.Client {
+	return NewClient(host, p.auth, WithTimeout(p.timeout))
+}
+
+// Ensure Prober implements miner.FirmwareProber.
+var _ miner.FirmwareProber = (*Prober)(nil)
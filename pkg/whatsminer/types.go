@@ -0,0 +1,203 @@
+// Package whatsminer provides a client for the MicroBT Whatsminer (btminer) API.
+// The API is JSON over TCP (port 4028). Read commands are plain text; write
+// commands require a token obtained with get_token and are AES-encrypted.
+package whatsminer
+
+import "encoding/json"
+
+// StatusEntry is the STATUS header of the cgminer-style read commands
+// (summary, devs, edevs, pools, devdetails).
+type StatusEntry struct {
+	Status string `json:"STATUS"` // "S" = success, "E" = error
+	When   int64  `json:"When"`
+	Code   int    `json:"Code"`
+	Msg    string `json:"Msg"`
+	Desc   string `json:"Description"`
+}
+
+// Response is the flat response format used by btminer-specific commands
+// (get_version, get_token, get_miner_info, status and all write commands).
+// Msg is either a string or a command-specific object.
+type Response struct {
+	Status      string          `json:"STATUS"`
+	When        int64           `json:"When"`
+	Code        int             `json:"Code"`
+	Msg         json.RawMessage `json:"Msg"`
+	Description string          `json:"Description"`
+}
+
+// EncryptedRequest wraps an AES-encrypted write command.
+type EncryptedRequest struct {
+	Enc  int    `json:"enc"`
+	Data string `json:"data"`
+}
+
+// EncryptedResponse wraps an AES-encrypted write command response.
+type EncryptedResponse struct {
+	Enc string `json:"enc"`
+}
+
+// =============================================================================
+// get_version, get_token, get_miner_info, status, get_psu
+// =============================================================================
+
+// Version contains the firmware and API versions (get_version).
+type Version struct {
+	APIVersion string `json:"api_ver"`
+	FWVersion  string `json:"fw_ver"` // e.g., "20220811.22.REL"
+	Platform   string `json:"platform"`
+	Chip       string `json:"chip"`
+}
+
+// TokenInfo contains the salts used to derive the write token (get_token).
+type TokenInfo struct {
+	Time    string `json:"time"`
+	Salt    string `json:"salt"`
+	NewSalt string `json:"newsalt"`
+}
+
+// MinerInfo contains network information (get_miner_info).
+type MinerInfo struct {
+	IP       string `json:"ip"`
+	Proto    string `json:"proto"` // "dhcp" or "static"
+	Netmask  string `json:"netmask"`
+	Gateway  string `json:"gateway"`
+	DNS      string `json:"dns"`
+	Hostname string `json:"hostname"`
+	MAC      string `json:"mac"`
+	LEDStat  string `json:"ledstat"`
+}
+
+// MinerState contains the btminer process state (status).
+type MinerState struct {
+	BTMinerOff      string `json:"btmineroff"` // "true" when mining is stopped
+	FirmwareVersion string `json:"Firmware Version"`
+	PowerMode       string `json:"power_mode"`
+	HashPercent     string `json:"hash_percent"`
+}
+
+// PSUInfo contains power supply details (get_psu).
+type PSUInfo struct {
+	Name      string `json:"name"`
+	HWVersion string `json:"hw_version"`
+	SWVersion string `json:"sw_version"`
+	Model     string `json:"model"`
+	Iin       string `json:"iin"`
+	Vin       string `json:"vin"`
+	FanSpeed  string `json:"fan_speed"`
+	SerialNo  string `json:"serial_no"`
+	Vendor    string `json:"vendor"`
+}
+
+// =============================================================================
+// summary
+// =============================================================================
+
+// SummaryResponse is the response to the summary command.
+type SummaryResponse struct {
+	Status  []StatusEntry `json:"STATUS"`
+	Summary []Summary     `json:"SUMMARY"`
+}
+
+// Summary contains aggregated mining, power and temperature statistics.
+type Summary struct {
+	Elapsed         int     `json:"Elapsed"`
+	MHSAv           float64 `json:"MHS av"`
+	MHS5s           float64 `json:"MHS 5s"`
+	MHS1m           float64 `json:"MHS 1m"`
+	MHS15m          float64 `json:"MHS 15m"`
+	HSRT            float64 `json:"HS RT"`
+	FactoryGHS      float64 `json:"Factory GHS"`
+	Accepted        int     `json:"Accepted"`
+	Rejected        int     `json:"Rejected"`
+	Temperature     float64 `json:"Temperature"`
+	EnvTemp         float64 `json:"Env Temp"`
+	FreqAvg         int     `json:"freq_avg"`
+	FanSpeedIn      int     `json:"Fan Speed In"`
+	FanSpeedOut     int     `json:"Fan Speed Out"`
+	Power           int     `json:"Power"`
+	PowerRate       float64 `json:"Power Rate"` // W/TH
+	PowerMode       string  `json:"Power Mode"` // "Low", "Normal", "High"
+	PowerLimit      int     `json:"Power Limit"`
+	ChipTempMin     float64 `json:"Chip Temp Min"`
+	ChipTempMax     float64 `json:"Chip Temp Max"`
+	ChipTempAvg     float64 `json:"Chip Temp Avg"`
+	PoolRejected    float64 `json:"Pool Rejected%"`
+	PoolStale       float64 `json:"Pool Stale%"`
+	Uptime          int     `json:"Uptime"`
+	UpfreqComplete  int     `json:"Upfreq Complete"`
+	FirmwareVersion string  `json:"Firmware Version"`
+	MAC             string  `json:"MAC"`
+}
+
+// =============================================================================
+// devs / edevs
+// =============================================================================
+
+// DevsResponse is the response to the devs and edevs commands.
+type DevsResponse struct {
+	Status []StatusEntry `json:"STATUS"`
+	Devs   []Dev         `json:"DEVS"`
+}
+
+// Dev contains per-hashboard statistics.
+type Dev struct {
+	ASC            int     `json:"ASC"`
+	Slot           int     `json:"Slot"`
+	Enabled        string  `json:"Enabled"` // "Y" or "N"
+	Status         string  `json:"Status"`  // "Alive", "Dead"
+	Temperature    float64 `json:"Temperature"`
+	ChipFrequency  int     `json:"Chip Frequency"`
+	MHSAv          float64 `json:"MHS av"`
+	MHS5s          float64 `json:"MHS 5s"`
+	FactoryGHS     float64 `json:"Factory GHS"`
+	EffectiveChips int     `json:"Effective Chips"`
+	PCBSN          string  `json:"PCB SN"`
+	ChipTempMin    float64 `json:"Chip Temp Min"`
+	ChipTempMax    float64 `json:"Chip Temp Max"`
+	ChipTempAvg    float64 `json:"Chip Temp Avg"`
+}
+
+// =============================================================================
+// devdetails
+// =============================================================================
+
+// DevDetailsResponse is the response to the devdetails command.
+type DevDetailsResponse struct {
+	Status     []StatusEntry `json:"STATUS"`
+	DevDetails []DevDetail   `json:"DEVDETAILS"`
+}
+
+// DevDetail contains hashboard hardware details.
+type DevDetail struct {
+	ID     int    `json:"ID"`
+	Name   string `json:"Name"`
+	Driver string `json:"Driver"` // "bitmicro"
+	Model  string `json:"Model"`  // e.g., "M30S+.VE40"
+}
+
+// =============================================================================
+// pools
+// =============================================================================
+
+// PoolsResponse is the response to the pools command.
+type PoolsResponse struct {
+	Status []StatusEntry `json:"STATUS"`
+	Pools  []Pool        `json:"POOLS"`
+}
+
+// Pool contains pool configuration and statistics.
+type Pool struct {
+	Index               int     `json:"POOL"`
+	URL                 string  `json:"URL"`
+	User                string  `json:"User"`
+	Status              string  `json:"Status"` // "Alive", "Dead"
+	Priority            int     `json:"Priority"`
+	Accepted            int     `json:"Accepted"`
+	Rejected            int     `json:"Rejected"`
+	Discarded           int     `json:"Discarded"`
+	Stale               int     `json:"Stale"`
+	DifficultyAccepted  float64 `json:"Difficulty Accepted"`
+	LastShareDifficulty float64 `json:"Last Share Difficulty"`
+	StratumActive       bool    `json:"Stratum Active"`
+}